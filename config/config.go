@@ -1,7 +1,9 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -12,12 +14,21 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	File     FileConfig
-	Email    *EmailConfig
-	AWS      AWSConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	File      FileConfig
+	Email     *EmailConfig
+	AWS       AWSConfig
+	RateLimit RateLimitConfig
+	// CredentialsKey is the AES-256 key that encrypts secrets stored in the
+	// database: TOTP secrets and external calendar passwords. Without it neither
+	// two-factor enrollment nor password-protected calendars are available.
+	CredentialsKey []byte
+	// CalendarSync configures importing busy time from lawyers' external calendars
+	CalendarSync CalendarSyncConfig
+	// Meeting configures automatic meeting links for online consultations
+	Meeting MeetingConfig
 }
 
 // ServerConfig holds all server-related configuration
@@ -25,6 +36,13 @@ type ServerConfig struct {
 	Port         string
 	GinMode      string
 	FrontendURLs []string
+	// PublicURL is the externally reachable base URL of this API, used in links
+	// that point back at it such as calendar feeds
+	PublicURL string
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed. When empty the client IP
+	// is always the connection's remote address, so it cannot be spoofed.
+	TrustedProxies []string
 }
 
 // DatabaseConfig holds all database-related configuration
@@ -37,11 +55,24 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// DefaultJWTSecret is the placeholder secret used when JWT_SECRET is unset. It is refused in release mode.
+const DefaultJWTSecret = "your_jwt_secret_key"
+
 // JWTConfig holds all JWT-related configuration
 type JWTConfig struct {
 	Secret          string
 	Expiration      time.Duration
 	ExpirationHours int
+	// Algorithm is HS256 (shared secret), RS256 or EdDSA
+	Algorithm string
+	// KeysDir holds one PEM file per key named <kid>.pem. Private keys sign and verify;
+	// public keys only verify, so retired keys can stay until their tokens expire.
+	KeysDir string
+	// ActiveKeyID is the kid used to sign new tokens
+	ActiveKeyID string
+	// AcceptHS256 keeps accepting tokens signed with Secret after switching to an
+	// asymmetric algorithm, so existing sessions survive the migration
+	AcceptHS256 bool
 }
 
 // FileConfig holds all file-related configuration
@@ -66,6 +97,57 @@ type AWSConfig struct {
 	S3Bucket string
 }
 
+// RateLimitConfig holds brute-force protection settings for login and email-sending endpoints
+type RateLimitConfig struct {
+	// Store selects the limiter backend: "memory" for a single node, "postgres" for multiple nodes
+	Store string
+
+	// Login failures per email before the account is temporarily locked
+	LoginMaxFailures     int
+	LoginFailureWindow   time.Duration
+	LoginLockoutDuration time.Duration
+	// Failures after which each further attempt must wait an exponentially growing delay
+	LoginDelayAfter int
+	LoginBaseDelay  time.Duration
+	LoginMaxDelay   time.Duration
+	// Login failures per IP across all emails
+	LoginIPMaxFailures int
+	LoginIPWindow      time.Duration
+
+	// Password reset and verification emails per address and per IP
+	EmailRequestLimit   int
+	EmailIPRequestLimit int
+	EmailRequestWindow  time.Duration
+}
+
+// CalendarSyncConfig holds settings for importing busy time from external calendars
+type CalendarSyncConfig struct {
+	// CredentialsKey is the application's CredentialsKey, which encrypts calendar
+	// passwords at rest
+	CredentialsKey []byte
+	// Interval is how long imported busy time is trusted before it is fetched again
+	Interval time.Duration
+	// Timeout bounds each fetch from a calendar server
+	Timeout time.Duration
+	// AllowPrivateHosts permits calendar URLs on loopback and private networks, such
+	// as a local CalDAV server used in development. It must stay off in production.
+	AllowPrivateHosts bool
+}
+
+// MeetingConfig selects how meeting links are created for confirmed appointments
+type MeetingConfig struct {
+	// Provider is "none" to leave links to lawyers, "jitsi" to generate room URLs
+	// or "http" to create meetings through a conferencing API
+	Provider string
+	// JitsiBaseURL is the Jitsi Meet server rooms are created on
+	JitsiBaseURL string
+	// APIURL and APIToken address the conferencing API used by the http provider
+	APIURL   string
+	APIToken string
+	// Timeout bounds each call to the conferencing API
+	Timeout time.Duration
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -78,6 +160,11 @@ func Load() (*Config, error) {
 	ginMode := getEnv("GIN_MODE", "debug")
 	frontendURLsStr := getEnv("FRONTEND_URLS", "http://localhost:3000")
 	frontendURLs := strings.Split(frontendURLsStr, ",")
+	publicURL := strings.TrimRight(getEnv("API_PUBLIC_URL", "http://localhost:"+port), "/")
+	trustedProxies, err := parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, err
+	}
 
 	// Database configuration
 	dbHost := getEnv("DB_HOST", "localhost")
@@ -88,7 +175,7 @@ func Load() (*Config, error) {
 	dbSSLMode := getEnv("DB_SSLMODE", "disable")
 
 	// JWT configuration
-	jwtSecret := getEnv("JWT_SECRET", "your_jwt_secret_key")
+	jwtSecret := getEnv("JWT_SECRET", DefaultJWTSecret)
 	jwtExpStr := getEnv("JWT_EXPIRATION", "24h")
 	jwtExp, err := time.ParseDuration(jwtExpStr)
 	if err != nil {
@@ -98,6 +185,27 @@ func Load() (*Config, error) {
 	// Convert duration to hours for ExpirationHours
 	jwtExpHours := int(jwtExp.Hours())
 
+	jwtAlgorithm := getEnv("JWT_ALGORITHM", "HS256")
+	jwtKeysDir := getEnv("JWT_KEYS_DIR", "")
+	jwtActiveKeyID := getEnv("JWT_ACTIVE_KID", "")
+	jwtAcceptHS256, _ := strconv.ParseBool(getEnv("JWT_ACCEPT_HS256", "false"))
+
+	switch jwtAlgorithm {
+	case "HS256":
+	case "RS256", "EdDSA":
+		if jwtKeysDir == "" {
+			return nil, fmt.Errorf("JWT_KEYS_DIR is required when JWT_ALGORITHM is %s", jwtAlgorithm)
+		}
+	default:
+		return nil, fmt.Errorf("invalid JWT_ALGORITHM %q: must be HS256, RS256 or EdDSA", jwtAlgorithm)
+	}
+
+	// Never run production with the placeholder secret while it can still sign or verify tokens
+	usesSecret := jwtAlgorithm == "HS256" || jwtAcceptHS256
+	if ginMode == "release" && usesSecret && (jwtSecret == "" || jwtSecret == DefaultJWTSecret) {
+		return nil, fmt.Errorf("JWT_SECRET must be set to a non-default value when GIN_MODE=release")
+	}
+
 	// File configuration
 	uploadDir := getEnv("FILE_UPLOAD_DIR", "./uploads")
 	maxSizeMB, _ := strconv.Atoi(getEnv("FILE_MAX_SIZE_MB", "10"))
@@ -132,11 +240,34 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("missing AWS_S3_BUCKET in env")
 	}
 
+	rateLimitConfig, err := loadRateLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	credentialsKey, err := loadCredentialsKey()
+	if err != nil {
+		return nil, err
+	}
+
+	calendarSyncConfig, err := loadCalendarSyncConfig()
+	if err != nil {
+		return nil, err
+	}
+	calendarSyncConfig.CredentialsKey = credentialsKey
+
+	meetingConfig, err := loadMeetingConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		Server: ServerConfig{
-			Port:         port,
-			GinMode:      ginMode,
-			FrontendURLs: frontendURLs,
+			Port:           port,
+			GinMode:        ginMode,
+			FrontendURLs:   frontendURLs,
+			PublicURL:      publicURL,
+			TrustedProxies: trustedProxies,
 		},
 		Database: DatabaseConfig{
 			Host:     dbHost,
@@ -150,6 +281,10 @@ func Load() (*Config, error) {
 			Secret:          jwtSecret,
 			Expiration:      jwtExp,
 			ExpirationHours: jwtExpHours,
+			Algorithm:       jwtAlgorithm,
+			KeysDir:         jwtKeysDir,
+			ActiveKeyID:     jwtActiveKeyID,
+			AcceptHS256:     jwtAcceptHS256,
 		},
 		File: FileConfig{
 			UploadDir:    uploadDir,
@@ -161,9 +296,154 @@ func Load() (*Config, error) {
 			Region:   awsRegion,
 			S3Bucket: s3Bucket,
 		},
+		RateLimit:      rateLimitConfig,
+		CredentialsKey: credentialsKey,
+		CalendarSync:   calendarSyncConfig,
+		Meeting:        meetingConfig,
 	}, nil
 }
 
+// loadRateLimitConfig reads the brute-force protection settings
+func loadRateLimitConfig() (RateLimitConfig, error) {
+	cfg := RateLimitConfig{
+		Store: getEnv("RATE_LIMIT_STORE", "memory"),
+	}
+	if cfg.Store != "memory" && cfg.Store != "postgres" {
+		return cfg, fmt.Errorf("invalid RATE_LIMIT_STORE %q: must be memory or postgres", cfg.Store)
+	}
+
+	ints := []struct {
+		key    string
+		def    string
+		target *int
+	}{
+		{"LOGIN_MAX_FAILURES", "5", &cfg.LoginMaxFailures},
+		{"LOGIN_DELAY_AFTER", "3", &cfg.LoginDelayAfter},
+		{"LOGIN_IP_MAX_FAILURES", "50", &cfg.LoginIPMaxFailures},
+		{"EMAIL_REQUEST_LIMIT", "3", &cfg.EmailRequestLimit},
+		{"EMAIL_IP_REQUEST_LIMIT", "20", &cfg.EmailIPRequestLimit},
+	}
+	for _, v := range ints {
+		n, err := strconv.Atoi(getEnv(v.key, v.def))
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid %s: must be a positive integer", v.key)
+		}
+		*v.target = n
+	}
+
+	durations := []struct {
+		key    string
+		def    string
+		target *time.Duration
+	}{
+		{"LOGIN_FAILURE_WINDOW", "15m", &cfg.LoginFailureWindow},
+		{"LOGIN_LOCKOUT_DURATION", "15m", &cfg.LoginLockoutDuration},
+		{"LOGIN_BASE_DELAY", "1s", &cfg.LoginBaseDelay},
+		{"LOGIN_MAX_DELAY", "30s", &cfg.LoginMaxDelay},
+		{"LOGIN_IP_WINDOW", "15m", &cfg.LoginIPWindow},
+		{"EMAIL_REQUEST_WINDOW", "1h", &cfg.EmailRequestWindow},
+	}
+	for _, v := range durations {
+		d, err := time.ParseDuration(getEnv(v.key, v.def))
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid %s format: %v", v.key, err)
+		}
+		*v.target = d
+	}
+
+	return cfg, nil
+}
+
+// parseTrustedProxies splits a comma-separated list of proxy IPs and CIDR ranges
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: must be an IP address or CIDR range", p)
+			}
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, nil
+}
+
+// loadCredentialsKey reads the key that encrypts secrets at rest. CREDENTIALS_KEY
+// replaces CALENDAR_CREDENTIALS_KEY, which is still read so existing deployments
+// keep their calendar passwords.
+func loadCredentialsKey() ([]byte, error) {
+	name := "CREDENTIALS_KEY"
+	key := getEnv(name, "")
+	if key == "" {
+		name = "CALENDAR_CREDENTIALS_KEY"
+		key = getEnv(name, "")
+	}
+	if key == "" {
+		return nil, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("invalid %s: must be 32 bytes encoded as base64", name)
+	}
+	return decoded, nil
+}
+
+// loadCalendarSyncConfig reads the external calendar import settings
+func loadCalendarSyncConfig() (CalendarSyncConfig, error) {
+	cfg := CalendarSyncConfig{}
+
+	durations := []struct {
+		key    string
+		def    string
+		target *time.Duration
+	}{
+		{"CALENDAR_SYNC_INTERVAL", "15m", &cfg.Interval},
+		{"CALENDAR_SYNC_TIMEOUT", "20s", &cfg.Timeout},
+	}
+	for _, v := range durations {
+		d, err := time.ParseDuration(getEnv(v.key, v.def))
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid %s format: %v", v.key, err)
+		}
+		*v.target = d
+	}
+
+	cfg.AllowPrivateHosts, _ = strconv.ParseBool(getEnv("CALENDAR_SYNC_ALLOW_PRIVATE_HOSTS", "false"))
+	return cfg, nil
+}
+
+// loadMeetingConfig reads the meeting link provider settings
+func loadMeetingConfig() (MeetingConfig, error) {
+	cfg := MeetingConfig{
+		Provider:     getEnv("MEETING_PROVIDER", "none"),
+		JitsiBaseURL: strings.TrimRight(getEnv("MEETING_JITSI_BASE_URL", "https://meet.jit.si"), "/"),
+		APIURL:       strings.TrimRight(getEnv("MEETING_API_URL", ""), "/"),
+		APIToken:     getEnv("MEETING_API_TOKEN", ""),
+	}
+
+	switch cfg.Provider {
+	case "none", "jitsi":
+	case "http":
+		if cfg.APIURL == "" {
+			return cfg, fmt.Errorf("missing MEETING_API_URL in env for MEETING_PROVIDER=http")
+		}
+	default:
+		return cfg, fmt.Errorf("invalid MEETING_PROVIDER %q: must be none, jitsi or http", cfg.Provider)
+	}
+
+	timeout, err := time.ParseDuration(getEnv("MEETING_API_TIMEOUT", "10s"))
+	if err != nil || timeout <= 0 {
+		return cfg, fmt.Errorf("invalid MEETING_API_TIMEOUT format: %v", err)
+	}
+	cfg.Timeout = timeout
+
+	return cfg, nil
+}
+
 // GetDSN returns the database connection string
 func (cfg *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf(
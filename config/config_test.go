@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies(" 10.0.0.1, 172.16.0.0/12,,::1 ")
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+	want := []string{"10.0.0.1", "172.16.0.0/12", "::1"}
+	if len(proxies) != len(want) {
+		t.Fatalf("parseTrustedProxies = %v, want %v", proxies, want)
+	}
+	for i := range want {
+		if proxies[i] != want[i] {
+			t.Errorf("proxy %d = %q, want %q", i, proxies[i], want[i])
+		}
+	}
+
+	if proxies, err := parseTrustedProxies(""); err != nil || proxies != nil {
+		t.Errorf("parseTrustedProxies(\"\") = %v, %v; want nothing trusted", proxies, err)
+	}
+	if _, err := parseTrustedProxies("nginx"); err == nil {
+		t.Error("parseTrustedProxies accepted a hostname")
+	}
+}
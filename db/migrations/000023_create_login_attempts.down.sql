@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rate_limit_hits;
+DROP TABLE IF EXISTS login_attempts;
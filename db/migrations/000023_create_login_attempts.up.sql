@@ -0,0 +1,25 @@
+-- Audit trail of authentication attempts, visible to admins
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent TEXT,
+    action VARCHAR(32) NOT NULL,
+    success BOOLEAN NOT NULL DEFAULT false,
+    failure_reason VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_attempts_email_created_at ON login_attempts(email, created_at DESC);
+CREATE INDEX idx_login_attempts_ip_created_at ON login_attempts(ip_address, created_at DESC);
+CREATE INDEX idx_login_attempts_created_at ON login_attempts(created_at DESC);
+
+-- Sliding-window counters shared between nodes when RATE_LIMIT_STORE=postgres
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_hits (
+    id BIGSERIAL PRIMARY KEY,
+    key VARCHAR(320) NOT NULL,
+    hit_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rate_limit_hits_key_hit_at ON rate_limit_hits(key, hit_at);
@@ -0,0 +1,60 @@
+-- Named permissions
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Permissions granted to every user with a role
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (role, permission)
+);
+
+-- Extra permissions granted to individual users
+CREATE TABLE IF NOT EXISTS user_permissions (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    granted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('admin.dashboard', 'View admin dashboard statistics and charts'),
+    ('users.view', 'List and view any user'),
+    ('users.manage', 'Create, update, deactivate and delete users, change roles and reset two-factor authentication'),
+    ('permissions.manage', 'Change role permissions and grant permissions to users'),
+    ('settings.manage', 'Change platform settings'),
+    ('security.audit', 'View login attempts and other security audit data'),
+    ('appointments.manage', 'View, update and delete any appointment'),
+    ('appointments.reject', 'Reject appointments'),
+    ('reviews.moderate', 'Approve, reject, pin, edit and delete any review'),
+    ('questions.moderate', 'Hide and unhide questions'),
+    ('lawyers.verify', 'Verify lawyer profiles'),
+    ('lawyers.manage', 'Edit and delete any lawyer profile'),
+    ('articles.write', 'Write articles and edit their own articles'),
+    ('articles.publish', 'Publish articles'),
+    ('articles.manage', 'Edit any article')
+ON CONFLICT (name) DO NOTHING;
+
+-- Admins get everything
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('lawyer', 'appointments.reject'),
+    ('lawyer', 'articles.write'),
+    ('lawyer', 'articles.publish'),
+    ('moderator', 'admin.dashboard'),
+    ('moderator', 'users.view'),
+    ('moderator', 'reviews.moderate'),
+    ('moderator', 'questions.moderate'),
+    ('moderator', 'lawyers.verify'),
+    ('moderator', 'articles.write'),
+    ('moderator', 'articles.publish'),
+    ('moderator', 'articles.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name IN ('users.impersonate', 'users.impersonate_destructive');
+
+DROP TABLE IF EXISTS impersonation_request_logs;
+DROP TABLE IF EXISTS impersonation_sessions;
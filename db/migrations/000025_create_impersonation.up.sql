@@ -0,0 +1,43 @@
+-- Staff sessions acting as another user
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users(id),
+    subject_id INTEGER NOT NULL REFERENCES users(id),
+    reason TEXT NOT NULL,
+    allow_destructive BOOLEAN NOT NULL DEFAULT false,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_impersonation_sessions_actor_id ON impersonation_sessions(actor_id);
+CREATE INDEX idx_impersonation_sessions_subject_id ON impersonation_sessions(subject_id);
+
+-- Every request made with an impersonation token
+CREATE TABLE IF NOT EXISTS impersonation_request_logs (
+    id BIGSERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES impersonation_sessions(id) ON DELETE CASCADE,
+    actor_id INTEGER NOT NULL REFERENCES users(id),
+    subject_id INTEGER NOT NULL REFERENCES users(id),
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    route VARCHAR(255),
+    status_code INTEGER NOT NULL,
+    blocked BOOLEAN NOT NULL DEFAULT false,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_impersonation_request_logs_session_id ON impersonation_request_logs(session_id, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users.impersonate', 'Sign in as another user to see what they see'),
+    ('users.impersonate_destructive', 'Allow destructive actions while impersonating')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'users.impersonate'),
+    ('admin', 'users.impersonate_destructive')
+ON CONFLICT DO NOTHING;
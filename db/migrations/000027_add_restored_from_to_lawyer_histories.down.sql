@@ -0,0 +1 @@
+ALTER TABLE lawyer_histories DROP COLUMN IF EXISTS restored_from;
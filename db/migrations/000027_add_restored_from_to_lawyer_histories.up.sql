@@ -0,0 +1,3 @@
+-- A restore is recorded as a new history entry pointing at the version it restored
+ALTER TABLE lawyer_histories
+    ADD COLUMN IF NOT EXISTS restored_from INTEGER REFERENCES lawyer_histories(id) ON DELETE SET NULL;
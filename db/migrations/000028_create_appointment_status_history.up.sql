@@ -0,0 +1,14 @@
+-- Every appointment status transition, including the initial status on creation
+CREATE TABLE IF NOT EXISTS appointment_status_history (
+    id SERIAL PRIMARY KEY,
+    appointment_id INTEGER NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    changed_by_role VARCHAR(50),
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_appointment_status_history_appointment_id
+    ON appointment_status_history(appointment_id, created_at);
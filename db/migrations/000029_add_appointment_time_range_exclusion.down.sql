@@ -0,0 +1,2 @@
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS appointments_lawyer_time_range_excl;
+ALTER TABLE appointments DROP COLUMN IF EXISTS time_range;
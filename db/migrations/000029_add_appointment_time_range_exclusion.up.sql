@@ -0,0 +1,14 @@
+-- btree_gist lets the exclusion constraint compare lawyer_id with = inside a GiST index
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Half-open so back-to-back appointments do not overlap
+ALTER TABLE appointments
+    ADD COLUMN IF NOT EXISTS time_range TSTZRANGE
+    GENERATED ALWAYS AS (tstzrange(start_time, end_time, '[)')) STORED;
+
+-- A lawyer can hold only one live appointment at any instant. Existing overlapping
+-- bookings have to be resolved before this migration can run.
+ALTER TABLE appointments
+    ADD CONSTRAINT appointments_lawyer_time_range_excl
+    EXCLUDE USING gist (lawyer_id WITH =, time_range WITH &&)
+    WHERE (status NOT IN ('cancelled', 'rejected') AND deleted_at IS NULL);
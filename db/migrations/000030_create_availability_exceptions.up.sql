@@ -0,0 +1,13 @@
+-- Date-specific overrides of a lawyer's weekly schedule: either closed all day or
+-- working only the listed intervals
+CREATE TABLE IF NOT EXISTS availability_exceptions (
+    id SERIAL PRIMARY KEY,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    closed BOOLEAN NOT NULL DEFAULT FALSE,
+    intervals JSONB NOT NULL DEFAULT '[]',
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT availability_exceptions_lawyer_date_key UNIQUE (lawyer_id, date)
+);
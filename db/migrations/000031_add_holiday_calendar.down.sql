@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS platform_closures;
+ALTER TABLE lawyers DROP COLUMN IF EXISTS closed_on_holidays;
@@ -0,0 +1,11 @@
+-- Lawyers can opt out of working on Japanese national holidays
+ALTER TABLE lawyers ADD COLUMN IF NOT EXISTS closed_on_holidays BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Platform-wide closure days set by admins
+CREATE TABLE IF NOT EXISTS platform_closures (
+    id SERIAL PRIMARY KEY,
+    date DATE NOT NULL UNIQUE,
+    reason VARCHAR(255) NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
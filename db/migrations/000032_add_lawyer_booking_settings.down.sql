@@ -0,0 +1 @@
+ALTER TABLE lawyers DROP COLUMN IF EXISTS booking_settings;
@@ -0,0 +1,2 @@
+-- Per-lawyer slot length, buffers, notice, horizon and daily cap. NULL means defaults.
+ALTER TABLE lawyers ADD COLUMN IF NOT EXISTS booking_settings JSONB;
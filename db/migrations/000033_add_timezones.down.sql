@@ -0,0 +1,2 @@
+ALTER TABLE lawyers DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
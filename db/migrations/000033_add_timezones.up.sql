@@ -0,0 +1,3 @@
+-- IANA time zones for rendering and computing appointment times
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Tokyo';
+ALTER TABLE lawyers ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Tokyo';
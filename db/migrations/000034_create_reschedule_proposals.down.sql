@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS reschedule_proposals DROP CONSTRAINT IF EXISTS fk_reschedule_proposals_accepted_option;
+DROP TABLE IF EXISTS reschedule_options;
+DROP TABLE IF EXISTS reschedule_proposals;
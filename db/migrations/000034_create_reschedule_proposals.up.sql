@@ -0,0 +1,38 @@
+-- Proposals to move an appointment to one of several alternative times
+CREATE TABLE IF NOT EXISTS reschedule_proposals (
+    id SERIAL PRIMARY KEY,
+    appointment_id INTEGER NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    proposed_by INTEGER NOT NULL REFERENCES users(id),
+    proposed_by_role VARCHAR(20) NOT NULL,
+    reason TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'declined', 'superseded')),
+    original_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    original_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_option_id INTEGER,
+    responded_by INTEGER REFERENCES users(id),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    decline_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reschedule_proposals_appointment_id ON reschedule_proposals(appointment_id);
+
+-- At most one open proposal per appointment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reschedule_proposals_one_pending
+    ON reschedule_proposals(appointment_id) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS reschedule_options (
+    id SERIAL PRIMARY KEY,
+    proposal_id INTEGER NOT NULL REFERENCES reschedule_proposals(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reschedule_options_proposal_id ON reschedule_options(proposal_id);
+
+ALTER TABLE reschedule_proposals
+    ADD CONSTRAINT fk_reschedule_proposals_accepted_option
+    FOREIGN KEY (accepted_option_id) REFERENCES reschedule_options(id);
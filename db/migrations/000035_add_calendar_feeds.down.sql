@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS calendar_feed_tokens;
+ALTER TABLE appointments DROP COLUMN IF EXISTS calendar_sequence;
@@ -0,0 +1,11 @@
+-- iCalendar SEQUENCE of each appointment, bumped on every time or status change
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS calendar_sequence INTEGER NOT NULL DEFAULT 0;
+
+-- Secret subscription tokens for per-user iCalendar feeds, stored hashed
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_accessed_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,32 @@
+-- Lawyers' external calendars (court schedules etc.) whose busy time blocks bookings
+CREATE TABLE IF NOT EXISTS external_calendars (
+    id SERIAL PRIMARY KEY,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('ics', 'caldav')),
+    url TEXT NOT NULL,
+    username VARCHAR(255),
+    -- AES-256-GCM ciphertext, never the plain password
+    encrypted_password TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_sync_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_calendars_lawyer_id ON external_calendars(lawyer_id);
+
+-- Busy periods imported from external calendars, replaced on every sync
+CREATE TABLE IF NOT EXISTS busy_blocks (
+    id SERIAL PRIMARY KEY,
+    calendar_id INTEGER NOT NULL REFERENCES external_calendars(id) ON DELETE CASCADE,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_busy_blocks_calendar_id ON busy_blocks(calendar_id);
+CREATE INDEX IF NOT EXISTS idx_busy_blocks_lawyer_time ON busy_blocks(lawyer_id, start_time, end_time);
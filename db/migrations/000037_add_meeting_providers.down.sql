@@ -0,0 +1,2 @@
+ALTER TABLE appointments DROP COLUMN IF EXISTS meeting_id;
+ALTER TABLE appointments DROP COLUMN IF EXISTS meeting_provider;
@@ -0,0 +1,4 @@
+-- Provider and provider-side ID of automatically created meeting links. Both are
+-- NULL for links entered by hand.
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS meeting_provider VARCHAR(20);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS meeting_id VARCHAR(255);
@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN IF EXISTS last_no_show_at;
+ALTER TABLE users DROP COLUMN IF EXISTS no_show_count;
+
+UPDATE appointment_status_history SET to_status = 'no_show' WHERE to_status IN ('no_show_client', 'no_show_lawyer');
+UPDATE appointment_status_history SET from_status = 'no_show' WHERE from_status IN ('no_show_client', 'no_show_lawyer');
+UPDATE appointments SET status = 'no_show' WHERE status IN ('no_show_client', 'no_show_lawyer');
@@ -0,0 +1,20 @@
+-- No-shows now record which party did not attend. Earlier ones were reported by
+-- lawyers about their clients.
+UPDATE appointments SET status = 'no_show_client' WHERE status = 'no_show';
+UPDATE appointment_status_history SET from_status = 'no_show_client' WHERE from_status = 'no_show';
+UPDATE appointment_status_history SET to_status = 'no_show_client' WHERE to_status = 'no_show';
+
+-- Per-user no-show counters, used to restrict booking for repeat no-shows
+ALTER TABLE users ADD COLUMN IF NOT EXISTS no_show_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_no_show_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE users SET
+    no_show_count = counts.total,
+    last_no_show_at = counts.last_at
+FROM (
+    SELECT user_id, COUNT(*) AS total, MAX(end_time) AS last_at
+    FROM appointments
+    WHERE status = 'no_show_client' AND deleted_at IS NULL
+    GROUP BY user_id
+) AS counts
+WHERE users.id = counts.user_id;
@@ -0,0 +1,43 @@
+-- Clients waiting for a slot with a fully booked lawyer, served oldest first
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    date_ranges JSONB NOT NULL DEFAULT '[]',
+    times_of_day JSONB NOT NULL DEFAULT '[]',
+    note TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'booked', 'cancelled', 'expired')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One active entry per client and lawyer
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_entries_active
+    ON waitlist_entries(user_id, lawyer_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_lawyer_queue
+    ON waitlist_entries(lawyer_id, created_at) WHERE status = 'active';
+
+-- Time-limited reservations of open slots for waitlisted clients
+CREATE TABLE IF NOT EXISTS slot_holds (
+    id SERIAL PRIMARY KEY,
+    entry_id INTEGER NOT NULL REFERENCES waitlist_entries(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'held'
+        CHECK (status IN ('held', 'booked', 'declined', 'expired', 'released')),
+    appointment_id INTEGER REFERENCES appointments(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_slot_holds_entry_id ON slot_holds(entry_id);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_lawyer_time
+    ON slot_holds(lawyer_id, start_time, end_time) WHERE status = 'held';
+-- A slot is held for one client at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_slot_holds_live_slot
+    ON slot_holds(lawyer_id, start_time) WHERE status = 'held';
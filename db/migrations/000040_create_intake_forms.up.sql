@@ -0,0 +1,23 @@
+-- Questionnaires lawyers ask clients to complete when booking, one per specialty
+CREATE TABLE IF NOT EXISTS intake_forms (
+    id SERIAL PRIMARY KEY,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    specialty VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    schema JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (lawyer_id, specialty)
+);
+
+-- Completed questionnaires, with a copy of the form as it was answered
+CREATE TABLE IF NOT EXISTS intake_responses (
+    id SERIAL PRIMARY KEY,
+    appointment_id INTEGER NOT NULL UNIQUE REFERENCES appointments(id) ON DELETE CASCADE,
+    form_id INTEGER REFERENCES intake_forms(id) ON DELETE SET NULL,
+    specialty VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    schema JSONB NOT NULL,
+    answers JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
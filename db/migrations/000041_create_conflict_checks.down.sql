@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS conflict_checks;
+DROP TABLE IF EXISTS opposing_parties;
@@ -0,0 +1,29 @@
+-- People and organizations on the other side of a client's matter, declared when booking
+CREATE TABLE IF NOT EXISTS opposing_parties (
+    id SERIAL PRIMARY KEY,
+    appointment_id INTEGER NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    name_kana VARCHAR(255),
+    relationship VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_opposing_parties_appointment_id ON opposing_parties(appointment_id);
+
+-- Conflict-of-interest checks run before a lawyer confirms an appointment, kept for compliance
+CREATE TABLE IF NOT EXISTS conflict_checks (
+    id SERIAL PRIMARY KEY,
+    appointment_id INTEGER NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    checked_by INTEGER NOT NULL REFERENCES users(id),
+    status VARCHAR(32) NOT NULL,
+    checked_names TEXT[] NOT NULL DEFAULT '{}',
+    matches JSONB NOT NULL DEFAULT '[]',
+    acknowledged_by INTEGER REFERENCES users(id),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledgement_note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_conflict_checks_appointment_id ON conflict_checks(appointment_id);
+CREATE INDEX IF NOT EXISTS idx_conflict_checks_lawyer_id ON conflict_checks(lawyer_id);
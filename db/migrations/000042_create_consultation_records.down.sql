@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS follow_up_tasks;
+DROP TABLE IF EXISTS consultation_records;
@@ -0,0 +1,34 @@
+-- Lawyers' records of completed consultations
+CREATE TABLE IF NOT EXISTS consultation_records (
+    id SERIAL PRIMARY KEY,
+    appointment_id INTEGER NOT NULL UNIQUE REFERENCES appointments(id) ON DELETE CASCADE,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    private_notes TEXT,
+    summary TEXT,
+    next_steps TEXT,
+    shared_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_consultation_records_lawyer_id ON consultation_records(lawyer_id);
+
+-- Follow-up tasks agreed in a consultation, for the lawyer or the client
+CREATE TABLE IF NOT EXISTS follow_up_tasks (
+    id SERIAL PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES consultation_records(id) ON DELETE CASCADE,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    assignee VARCHAR(16) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    due_date DATE NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    completed_at TIMESTAMP WITH TIME ZONE,
+    reminder_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_up_tasks_record_id ON follow_up_tasks(record_id);
+CREATE INDEX IF NOT EXISTS idx_follow_up_tasks_lawyer_id ON follow_up_tasks(lawyer_id);
+CREATE INDEX IF NOT EXISTS idx_follow_up_tasks_overdue ON follow_up_tasks(due_date) WHERE status = 'open' AND reminder_sent_at IS NULL;
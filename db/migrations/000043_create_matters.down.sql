@@ -0,0 +1,8 @@
+DELETE FROM opposing_parties WHERE matter_id IS NOT NULL;
+ALTER TABLE opposing_parties DROP CONSTRAINT IF EXISTS opposing_parties_one_owner;
+ALTER TABLE opposing_parties DROP COLUMN IF EXISTS matter_id;
+ALTER TABLE opposing_parties ALTER COLUMN appointment_id SET NOT NULL;
+
+ALTER TABLE appointments DROP COLUMN IF EXISTS matter_id;
+
+DROP TABLE IF EXISTS matters;
@@ -0,0 +1,26 @@
+-- Clients' cases with a lawyer, grouping their appointments
+CREATE TABLE IF NOT EXISTS matters (
+    id SERIAL PRIMARY KEY,
+    lawyer_id INTEGER NOT NULL REFERENCES lawyers(id) ON DELETE CASCADE,
+    client_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    practice_area VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_matters_lawyer_id ON matters(lawyer_id);
+CREATE INDEX IF NOT EXISTS idx_matters_client_id ON matters(client_id);
+
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS matter_id INTEGER REFERENCES matters(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_appointments_matter_id ON appointments(matter_id);
+
+-- Opposing parties are declared on either an appointment or a matter
+ALTER TABLE opposing_parties ALTER COLUMN appointment_id DROP NOT NULL;
+ALTER TABLE opposing_parties ADD COLUMN IF NOT EXISTS matter_id INTEGER REFERENCES matters(id) ON DELETE CASCADE;
+ALTER TABLE opposing_parties ADD CONSTRAINT opposing_parties_one_owner
+    CHECK ((appointment_id IS NULL) <> (matter_id IS NULL));
+CREATE INDEX IF NOT EXISTS idx_opposing_parties_matter_id ON opposing_parties(matter_id);
@@ -0,0 +1,5 @@
+DELETE FROM attachments WHERE attachmentable_type = 'DocumentVersion';
+DROP TABLE IF EXISTS document_downloads;
+DROP TABLE IF EXISTS document_versions;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS document_folders;
@@ -0,0 +1,58 @@
+-- Folders in a matter's document vault
+CREATE TABLE IF NOT EXISTS document_folders (
+    id SERIAL PRIMARY KEY,
+    matter_id INTEGER NOT NULL REFERENCES matters(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES document_folders(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_folders_matter_id ON document_folders(matter_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_folders_name ON document_folders(matter_id, COALESCE(parent_id, 0), name);
+
+-- Documents in a matter's vault. Each version's file is an attachment of type
+-- DocumentVersion.
+CREATE TABLE IF NOT EXISTS documents (
+    id SERIAL PRIMARY KEY,
+    matter_id INTEGER NOT NULL REFERENCES matters(id) ON DELETE CASCADE,
+    folder_id INTEGER REFERENCES document_folders(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    visibility VARCHAR(16) NOT NULL DEFAULT 'lawyer_only',
+    current_version INTEGER NOT NULL DEFAULT 1,
+    checked_out_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    checked_out_at TIMESTAMP WITH TIME ZONE,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_matter_id ON documents(matter_id);
+CREATE INDEX IF NOT EXISTS idx_documents_folder_id ON documents(folder_id);
+CREATE INDEX IF NOT EXISTS idx_documents_deleted_at ON documents(deleted_at);
+
+CREATE TABLE IF NOT EXISTS document_versions (
+    id SERIAL PRIMARY KEY,
+    document_id INTEGER NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    note TEXT,
+    uploaded_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (document_id, version)
+);
+
+-- Every download of a vault document, for the matter's lawyer to review
+CREATE TABLE IF NOT EXISTS document_downloads (
+    id SERIAL PRIMARY KEY,
+    document_id INTEGER NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    version_id INTEGER NOT NULL REFERENCES document_versions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    impersonator_id INTEGER REFERENCES users(id),
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_downloads_document_id ON document_downloads(document_id);
@@ -0,0 +1,5 @@
+-- Encrypted secrets do not fit the old column, so two-factor setups made since
+-- the upgrade have to be enrolled again
+UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_enabled_at = NULL, totp_last_used_step = 0
+WHERE totp_secret LIKE 'v1:%';
+ALTER TABLE users ALTER COLUMN totp_secret TYPE VARCHAR(64);
@@ -0,0 +1,3 @@
+-- TOTP secrets are now stored encrypted, which is longer than the plain base32
+-- secret. Existing plaintext secrets are encrypted the next time they are used.
+ALTER TABLE users ALTER COLUMN totp_secret TYPE TEXT;
@@ -0,0 +1,2 @@
+-- The entries dropped by the up migration were invalid and cannot be restored
+SELECT 1;
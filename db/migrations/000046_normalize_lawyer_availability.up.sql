@@ -0,0 +1,33 @@
+-- Weekly schedules saved before the format was typed could hold anything. Keep only
+-- the seven weekday keys, each a list of {"start", "end"} HH:MM intervals that start
+-- before they end. Overlapping intervals are resolved when the schedule is read.
+UPDATE lawyers
+SET availability = NULL
+WHERE availability IS NOT NULL AND jsonb_typeof(availability) <> 'object';
+
+UPDATE lawyers l
+SET availability = (
+    SELECT COALESCE(jsonb_object_agg(d.day, d.intervals), '{}'::jsonb)
+    FROM (
+        SELECT day.key AS day,
+               COALESCE(
+                   jsonb_agg(jsonb_build_object('start', i.value->>'start', 'end', i.value->>'end'))
+                       FILTER (WHERE CASE
+                           WHEN jsonb_typeof(i.value) = 'object'
+                                AND i.value->>'start' ~ '^([01]?[0-9]|2[0-3]):[0-5][0-9]$'
+                                AND i.value->>'end' ~ '^(([01]?[0-9]|2[0-3]):[0-5][0-9]|24:00)$'
+                           THEN split_part(i.value->>'start', ':', 1)::int * 60 + split_part(i.value->>'start', ':', 2)::int
+                              < split_part(i.value->>'end', ':', 1)::int * 60 + split_part(i.value->>'end', ':', 2)::int
+                           ELSE false
+                       END),
+                   '[]'::jsonb
+               ) AS intervals
+        FROM jsonb_each(l.availability) AS day
+        LEFT JOIN LATERAL jsonb_array_elements(
+            CASE WHEN jsonb_typeof(day.value) = 'array' THEN day.value ELSE '[]'::jsonb END
+        ) AS i ON true
+        WHERE day.key IN ('monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday')
+        GROUP BY day.key
+    ) d
+)
+WHERE l.availability IS NOT NULL;
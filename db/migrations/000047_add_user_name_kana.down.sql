@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS last_name_kana;
+ALTER TABLE users DROP COLUMN IF EXISTS first_name_kana;
@@ -0,0 +1,4 @@
+-- The reading of a user's name in kana, so conflict checks can match a party
+-- declared in kana with a client whose name is written in kanji
+ALTER TABLE users ADD COLUMN IF NOT EXISTS first_name_kana VARCHAR(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_name_kana VARCHAR(100);
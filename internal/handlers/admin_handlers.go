@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kotolino/lawyer/internal/handlers/responses"
 	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
 	"github.com/kotolino/lawyer/internal/services"
 	"time"
 )
@@ -27,7 +28,7 @@ type AdminStatsResponse struct {
 // @Router /admin/stats [get]
 func GetAdminStatsHandler(c *gin.Context) {
 
-	if role, ok := middleware.GetUserRole(c); !ok || role != "admin" {
+	if !middleware.HasPermission(c, models.PermAdminDashboard) {
 		responses.NewAPIResponse(c).
 			Forbidden("Only administrators can view stats", responses.ErrCodeForbidden)
 		return
@@ -62,7 +63,7 @@ func GetAdminStatsHandler(c *gin.Context) {
 // @Router /admin/chart-data [get]
 func GetAdminChartDataHandler(c *gin.Context) {
 
-	if role, ok := middleware.GetUserRole(c); !ok || role != "admin" {
+	if !middleware.HasPermission(c, models.PermAdminDashboard) {
 		responses.NewAPIResponse(c).
 			Forbidden("Only administrators can view chart data", responses.ErrCodeForbidden)
 		return
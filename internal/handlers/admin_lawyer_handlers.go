@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kotolino/lawyer/internal/handlers/responses"
 	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
 	"github.com/kotolino/lawyer/internal/services"
 )
 
@@ -24,10 +25,9 @@ type LawyerVerificationResponse struct {
 
 // VerifyLawyerHandler handles the request to update a lawyer's verification status
 func VerifyLawyerHandler(c *gin.Context) {
-	// Check admin permissions
-	userRole, ok := middleware.GetUserRole(c)
-	if !ok || userRole != "admin" {
-		responses.NewAPIResponse(c).Forbidden("Admin access required", responses.ErrCodeForbidden)
+	// Check verification permission
+	if !middleware.HasPermission(c, models.PermLawyersVerify) {
+		responses.NewAPIResponse(c).Forbidden("Insufficient permissions", responses.ErrCodeForbidden)
 		return
 	}
 
@@ -49,7 +49,7 @@ func VerifyLawyerHandler(c *gin.Context) {
 	lawyerService := services.NewLawyerService()
 
 	// Check if lawyer exists
-	_, err = lawyerService.GetLawyerByID(lawyerID)
+	lawyer, err := lawyerService.GetLawyerByID(lawyerID)
 	if err != nil {
 		responses.NewAPIResponse(c).NotFound("Lawyer not found", responses.ErrCodeResourceNotFound)
 		return
@@ -62,6 +62,10 @@ func VerifyLawyerHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionVerify, models.AuditEntityLawyer, lawyerID,
+		map[string]interface{}{"is_verified": lawyer.IsVerified},
+		map[string]interface{}{"is_verified": req.IsVerified})
+
 	// Log the verification action
 	userID, _ := middleware.GetUserID(c)
 	if req.IsVerified {
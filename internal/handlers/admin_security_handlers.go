@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// @Summary List login attempts
+// @Description Returns the authentication audit trail (logins, second-factor checks, password reset and verification email requests), newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param email query string false "Filter by email"
+// @Param ip_address query string false "Filter by client IP"
+// @Param action query string false "Filter by action" Enums(login, mfa, forgot_password, resend_verification)
+// @Param success query bool false "Filter by outcome"
+// @Param from query string false "Only attempts at or after this time (RFC3339)"
+// @Param to query string false "Only attempts before this time (RFC3339)"
+// @Success 200 {object} responses.ListResponse{data=[]models.LoginAttempt}
+// @Failure 400 {object} responses.APIErrorResponse "Invalid filter"
+// @Failure 403 {object} responses.APIErrorResponse "Admin access required"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/login-attempts [get]
+func GetLoginAttemptsHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := services.LoginAttemptFilter{
+		Email:     c.Query("email"),
+		IPAddress: c.Query("ip_address"),
+		Action:    c.Query("action"),
+	}
+
+	if s := c.Query("success"); s != "" {
+		success, err := strconv.ParseBool(s)
+		if err != nil {
+			responses.NewAPIResponse(c).BadRequest("Invalid success filter", responses.ErrCodeInvalidRequest)
+			return
+		}
+		filter.Success = &success
+	}
+	if s := c.Query("from"); s != "" {
+		from, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			responses.NewAPIResponse(c).BadRequest("Invalid from time, expected RFC3339", responses.ErrCodeInvalidRequest)
+			return
+		}
+		filter.From = &from
+	}
+	if s := c.Query("to"); s != "" {
+		to, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			responses.NewAPIResponse(c).BadRequest("Invalid to time, expected RFC3339", responses.ErrCodeInvalidRequest)
+			return
+		}
+		filter.To = &to
+	}
+
+	attempts, total, err := services.NewLoginAttemptService().GetLoginAttempts(filter, page, limit)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve login attempts", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+	responses.NewAPIResponse(c).Paginated(http.StatusOK, attempts, page, limit, int(total), totalPages)
+}
@@ -317,12 +317,16 @@ func UpdateAnswerHandler(c *gin.Context) {
 		Content: answerUpdate.Content,
 	}
 
-	result, err := services.GetAnswerService().UpdateAnswer(answerID, lawyer.ID, updatedAnswer)
+	answerService := services.GetAnswerService()
+	previous, _ := answerService.GetAnswerByID(answerID)
+	result, err := answerService.UpdateAnswer(answerID, lawyer.ID, updatedAnswer)
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to update answer", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityAnswer, answerID, previous, result)
+
 	resp := AnswerResponse{
 		ID:         result.ID,
 		Content:    result.Content,
@@ -367,11 +371,16 @@ func AcceptAnswerHandler(c *gin.Context) {
 	}
 
 	// Accept answer
-	if err := services.GetAnswerService().AcceptAnswer(answerID, userID); err != nil {
+	answerService := services.GetAnswerService()
+	previous, _ := answerService.GetAnswerByID(answerID)
+	if err := answerService.AcceptAnswer(answerID, userID); err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to accept answer", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	accepted, _ := answerService.GetAnswerByID(answerID)
+	recordAudit(c, models.AuditActionAccept, models.AuditEntityAnswer, answerID, previous, accepted)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Answer accepted"})
 }
 
@@ -409,11 +418,15 @@ func DeleteAnswerHandler(c *gin.Context) {
 	}
 
 	// Delete answer
-	if err := services.GetAnswerService().DeleteAnswer(answerID, lawyer.ID); err != nil {
+	answerService := services.GetAnswerService()
+	previous, _ := answerService.GetAnswerByID(answerID)
+	if err := answerService.DeleteAnswer(answerID, lawyer.ID); err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to delete answer", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityAnswer, answerID, previous, nil)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Answer deleted"})
 }
 
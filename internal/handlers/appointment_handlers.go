@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -20,18 +21,36 @@ type CreateAppointmentRequest struct {
 	StartTime   time.Time `json:"start_time" binding:"required"`
 	EndTime     time.Time `json:"end_time" binding:"required"`
 	Notes       *string   `json:"notes,omitempty"`
+	// IntakeFormID picks one of the lawyer's intake forms; it is required when the
+	// lawyer has any. File answers hold IDs returned by /appointments/intake-files.
+	IntakeFormID  *int                 `json:"intake_form_id,omitempty"`
+	IntakeAnswers models.IntakeAnswers `json:"intake_answers,omitempty"`
+	// OpposingParties lists who the client is in dispute with, so the lawyer can
+	// check for conflicts of interest before confirming
+	OpposingParties []OpposingPartyRequest `json:"opposing_parties,omitempty"`
+	// MatterID books the appointment under one of the client's open matters with
+	// the lawyer
+	MatterID *int `json:"matter_id,omitempty"`
+}
+
+// OpposingPartyRequest is a person or organization on the other side of the
+// client's matter
+type OpposingPartyRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	NameKana     *string `json:"name_kana,omitempty"`
+	Relationship *string `json:"relationship,omitempty"`
 }
 
 type UpdateAppointmentRequest struct {
-	Description  *string    `json:"description,omitempty"`
-	StartTime    *time.Time `json:"start_time,omitempty"`
-	EndTime      *time.Time `json:"end_time,omitempty"`
-	Status       *string    `json:"status,omitempty" binding:"omitempty,oneof=pending confirmed cancelled completed rejected"`
-	MeetingLink  *string    `json:"meeting_link,omitempty"`
-	Notes        *string    `json:"notes,omitempty"`
-	ChatEnabled  *bool      `json:"chat_enabled,omitempty"`
-	CancelReason *string    `json:"cancel_reason,omitempty"`
-	AdminReason  *string    `json:"admin_reason,omitempty"`
+	Description  *string                   `json:"description,omitempty"`
+	StartTime    *time.Time                `json:"start_time,omitempty"`
+	EndTime      *time.Time                `json:"end_time,omitempty"`
+	Status       *models.AppointmentStatus `json:"status,omitempty" binding:"omitempty,oneof=pending confirmed cancelled completed rejected no_show"`
+	MeetingLink  *string                   `json:"meeting_link,omitempty"`
+	Notes        *string                   `json:"notes,omitempty"`
+	ChatEnabled  *bool                     `json:"chat_enabled,omitempty"`
+	CancelReason *string                   `json:"cancel_reason,omitempty"`
+	AdminReason  *string                   `json:"admin_reason,omitempty"`
 }
 
 // @Summary Get appointments
@@ -130,7 +149,7 @@ func GetAppointmentsHandler(c *gin.Context) {
 
 		appointments, totalItems, err = appointmentService.GetAppointmentsByLawyerID(lawyer.ID, statusFilter, page, limit)
 
-	} else if userRole == "admin" {
+	} else if middleware.HasPermission(c, models.PermAppointmentsManage) {
 		appointments, totalItems, err = appointmentService.GetAllAppointments(
 			statusFilter,
 			clientSearch,
@@ -227,13 +246,13 @@ func GetAppointmentByIDHandler(c *gin.Context) {
 			// Update the local appointment object too
 			appointment.IsLawyerViewed = true
 		}
-	} else {
-
-		if appointment.UserID != userID {
+	} else if appointment.UserID != userID {
+		// Staff can look at any appointment without marking it as viewed
+		if !middleware.HasPermission(c, models.PermAppointmentsManage) {
 			responses.NewAPIResponse(c).Forbidden("You do not have access to this appointment", responses.ErrCodeForbidden)
 			return
 		}
-
+	} else {
 		// Mark appointment as viewed by client when a client accesses it
 		if !appointment.IsClientViewed {
 			// Update IsClientViewed to true
@@ -252,12 +271,13 @@ func GetAppointmentByIDHandler(c *gin.Context) {
 		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve appointment details", responses.ErrCodeDatabaseError)
 		return
 	}
+	hideMeetingLinkUntilAvailable(c, appointmentResponse)
 
 	responses.NewAPIResponse(c).OK(appointmentResponse)
 }
 
 // @Summary Create new appointment
-// @Description Creates a new appointment
+// @Description Creates a new appointment. The time must be one of the lawyer's available slots under their booking settings. Clients booking a lawyer with intake forms must complete one; the answers are validated against the form and stored with the appointment.
 // @Tags appointments
 // @Accept json
 // @Produce json
@@ -291,17 +311,6 @@ func CreateAppointmentHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if appointment is being booked at least 2 full days in advance
-	now := time.Now().UTC()
-	// Get tomorrow's date and reset to midnight
-	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	// Add 2 more days to get the minimum booking time (2 full days from tomorrow)
-	minBookingTime := tomorrow.AddDate(0, 0, 2)
-	if req.StartTime.Before(minBookingTime) {
-		responses.NewAPIResponse(c).BadRequest("Appointments must be booked at least 2 full days in advance", responses.ErrCodeInvalidRequest)
-		return
-	}
-
 	lawyerService := services.NewLawyerService()
 	lawyer, err := lawyerService.GetLawyerByID(req.LawyerID)
 	if err != nil {
@@ -309,31 +318,82 @@ func CreateAppointmentHandler(c *gin.Context) {
 		return
 	}
 
-	appointmentService := services.NewAppointmentService()
-	available, err := appointmentService.CheckAvailability(lawyer.ID, req.StartTime, req.EndTime)
+	// Clients who keep missing appointments may be barred from booking. Otherwise the
+	// policy that lists available slots decides.
+	err = services.NewAttendanceService().CheckBookingAllowed(userID)
+	if err == nil {
+		err = services.NewAvailabilityService().CheckBooking(lawyer, req.StartTime, req.EndTime, 0, userID)
+	}
 	if err != nil {
-		responses.NewAPIResponse(c).InternalServerError("Failed to check lawyer availability", responses.ErrCodeDatabaseError)
+		var policyErr *services.BookingPolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			responses.NewAPIResponse(c).BadRequest(policyErr.Error(), responses.ErrCodeInvalidRequest)
+		case errors.Is(err, services.ErrSlotUnavailable):
+			responses.NewAPIResponse(c).Conflict("Lawyer is not available at the requested time", responses.ErrCodeTimeSlotUnavailable)
+		default:
+			responses.NewAPIResponse(c).InternalServerError("Failed to check availability", responses.ErrCodeDatabaseError)
+		}
 		return
 	}
 
-	if !available {
-		responses.NewAPIResponse(c).BadRequest("Lawyer is not available at the requested time", responses.ErrCodeInvalidRequest)
+	intake, err := services.NewIntakeService().PrepareIntake(lawyer.ID, userID, req.IntakeFormID, req.IntakeAnswers)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidIntake) {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to check intake answers", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	declared := make([]models.OpposingParty, 0, len(req.OpposingParties))
+	for _, party := range req.OpposingParties {
+		declared = append(declared, models.OpposingParty{Name: party.Name, NameKana: party.NameKana, Relationship: party.Relationship})
+	}
+	opposingParties, err := services.NewConflictCheckService().PrepareOpposingParties(declared)
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
 		return
 	}
 
+	if req.MatterID != nil {
+		if err := services.NewMatterService().CheckBookingMatter(*req.MatterID, userID, lawyer.ID); err != nil {
+			if errors.Is(err, services.ErrInvalidMatter) {
+				responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+				return
+			}
+			responses.NewAPIResponse(c).InternalServerError("Failed to check matter", responses.ErrCodeDatabaseError)
+			return
+		}
+	}
+
+	appointmentService := services.NewAppointmentService()
+
 	appointment := models.Appointment{
-		UserID:         userID,
-		LawyerID:       req.LawyerID,
-		Description:    req.Description,
-		StartTime:      req.StartTime,
-		EndTime:        req.EndTime,
-		Status:         "pending",
-		IsClientViewed: true,
-		Notes:          req.Notes,
+		UserID:          userID,
+		LawyerID:        req.LawyerID,
+		MatterID:        req.MatterID,
+		Description:     req.Description,
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+		Status:          models.AppointmentStatusPending,
+		IsClientViewed:  true,
+		Notes:           req.Notes,
+		Intake:          intake,
+		OpposingParties: opposingParties,
 	}
 
 	err = appointmentService.CreateAppointment(&appointment)
 	if err != nil {
+		if errors.Is(err, services.ErrSlotUnavailable) {
+			responses.NewAPIResponse(c).Conflict("Lawyer is not available at the requested time", responses.ErrCodeTimeSlotUnavailable)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidIntake) {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
 		responses.NewAPIResponse(c).InternalServerError("Failed to create appointment", responses.ErrCodeDatabaseError)
 		return
 	}
@@ -357,7 +417,7 @@ func CreateAppointmentHandler(c *gin.Context) {
 }
 
 // @Summary Update appointment
-// @Description Updates an existing appointment
+// @Description Updates an existing appointment. A new time must be bookable under the lawyer's booking settings. Attendance outcomes are recorded through the attendance endpoint. Confirming runs a conflict-of-interest check first; if it finds potential conflicts the appointment stays pending until the lawyer acknowledges the check.
 // @Tags appointments
 // @Accept json
 // @Produce json
@@ -365,11 +425,11 @@ func CreateAppointmentHandler(c *gin.Context) {
 // @Param id path int true "Appointment ID"
 // @Param appointment body UpdateAppointmentRequest true "Updated appointment details"
 // @Success 200 {object} responses.AppointmentResponse
-// @Failure 400 {object} responses.APIErrorResponse "Invalid request or validation error"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid request, validation error or booking policy violation"
 // @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
 // @Failure 403 {object} responses.APIErrorResponse "Forbidden - no access to update this appointment"
 // @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
-// @Failure 409 {object} responses.APIErrorResponse "Time slot not available"
+// @Failure 409 {object} responses.APIErrorResponse "Time slot not available, status transition not allowed or unacknowledged conflict of interest"
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /appointments/{id} [put]
 func UpdateAppointmentHandler(c *gin.Context) {
@@ -380,6 +440,7 @@ func UpdateAppointmentHandler(c *gin.Context) {
 	}
 
 	userRole, _ := middleware.GetUserRole(c)
+	canManage := middleware.HasPermission(c, models.PermAppointmentsManage)
 
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -395,6 +456,7 @@ func UpdateAppointmentHandler(c *gin.Context) {
 		responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
 		return
 	}
+	before := services.AuditSnapshot(existingAppointment)
 
 	if userRole == "lawyer" {
 		lawyerService := services.NewLawyerService()
@@ -409,7 +471,7 @@ func UpdateAppointmentHandler(c *gin.Context) {
 			responses.NewAPIResponse(c).Forbidden("You do not have access to update this appointment", responses.ErrCodeForbidden)
 			return
 		}
-	} else if userRole != "admin" && userRole != "client" {
+	} else if userRole != "client" && !canManage {
 		responses.NewAPIResponse(c).Forbidden("You do not have access to update this appointment", responses.ErrCodeForbidden)
 		return
 	}
@@ -426,15 +488,11 @@ func UpdateAppointmentHandler(c *gin.Context) {
 			responses.NewAPIResponse(c).Forbidden("Clients can only update the status field", responses.ErrCodeForbidden)
 			return
 		}
-		if req.Status == nil || *req.Status != "cancelled" {
+		if req.Status == nil || *req.Status != models.AppointmentStatusCancelled {
 			responses.NewAPIResponse(c).Forbidden("Clients can only cancel appointments by setting status to 'cancelled'", responses.ErrCodeForbidden)
 			return
 		}
 
-		if req.CancelReason != nil {
-			existingAppointment.CancelReason = req.CancelReason
-		}
-
 		// Check if cancellation is being done at least 2 days before the appointment
 		now := time.Now().UTC()
 		// Calculate the cutoff time (2 days before appointment)
@@ -457,26 +515,21 @@ func UpdateAppointmentHandler(c *gin.Context) {
 			responses.NewAPIResponse(c).BadRequest("Start time must be before end time", responses.ErrCodeInvalidRequest)
 			return
 		}
+	}
 
-		newStatus := existingAppointment.Status
-		if req.Status != nil {
-			newStatus = *req.Status
-		}
-
-		if newStatus != "cancelled" &&
-			((req.StartTime != nil && !req.StartTime.Equal(existingAppointment.StartTime)) ||
-				(req.EndTime != nil && !req.EndTime.Equal(existingAppointment.EndTime))) {
-
-			available, err := appointmentService.CheckAvailability(existingAppointment.LawyerID, newStartTime, newEndTime)
-			if err != nil {
-				responses.NewAPIResponse(c).InternalServerError("Failed to check lawyer availability", responses.ErrCodeDatabaseError)
-				return
-			}
-			if !available {
-				responses.NewAPIResponse(c).BadRequest("Lawyer is not available at the requested time", responses.ErrCodeInvalidRequest)
-				return
-			}
-		}
+	// A status equal to the current one is not a transition
+	statusChanged := req.Status != nil && *req.Status != existingAppointment.Status
+	if statusChanged && req.Status.IsAttendanceOutcome() {
+		responses.NewAPIResponse(c).Conflict(
+			"Attendance is recorded through the appointment's attendance endpoint once it has ended",
+			responses.ErrCodeInvalidTransition)
+		return
+	}
+	if statusChanged && !existingAppointment.Status.CanTransitionTo(*req.Status) {
+		responses.NewAPIResponse(c).Conflict(
+			fmt.Sprintf("Cannot change appointment status from '%s' to '%s'", existingAppointment.Status, *req.Status),
+			responses.ErrCodeInvalidTransition)
+		return
 	}
 
 	if req.Description != nil {
@@ -488,9 +541,6 @@ func UpdateAppointmentHandler(c *gin.Context) {
 	if req.EndTime != nil {
 		existingAppointment.EndTime = *req.EndTime
 	}
-	if req.Status != nil {
-		existingAppointment.Status = *req.Status
-	}
 	if req.MeetingLink != nil {
 		existingAppointment.MeetingLink = req.MeetingLink
 	}
@@ -500,49 +550,56 @@ func UpdateAppointmentHandler(c *gin.Context) {
 	if req.ChatEnabled != nil {
 		existingAppointment.ChatEnabled = *req.ChatEnabled
 	}
-	if req.AdminReason != nil && userRole == "admin" && req.Status != nil {
-		existingAppointment.AdminReason = req.AdminReason
-	}
-	// If the appointment is being cancelled, set the cancel reason
-	if req.Status != nil && *req.Status == "cancelled" {
-		existingAppointment.CancelReason = req.CancelReason
-	}
 
-	if err := appointmentService.UpdateAppointment(existingAppointment); err != nil {
-		responses.NewAPIResponse(c).InternalServerError("Failed to update appointment", responses.ErrCodeDatabaseError)
+	// The details and the status change are saved together; confirming requires a
+	// conflict-of-interest check that is clear or that the lawyer has acknowledged
+	auditAction := models.AuditActionUpdate
+	var transition *services.AppointmentTransition
+	if statusChanged {
+		transition = &services.AppointmentTransition{
+			To:        *req.Status,
+			ActorID:   userID,
+			ActorRole: userRole,
+		}
+		if *req.Status == models.AppointmentStatusCancelled {
+			transition.Reason = req.CancelReason
+		}
+		if canManage {
+			transition.AdminReason = req.AdminReason
+		}
+		auditAction = models.AuditActionStatusChange
+	}
+
+	if err := appointmentService.UpdateAppointment(existingAppointment, transition); err != nil {
+		var policyErr *services.BookingPolicyError
+		var reviewErr *services.ConflictReviewError
+		switch {
+		case errors.As(err, &reviewErr):
+			recordAudit(c, models.AuditActionCreate, models.AuditEntityConflictCheck, reviewErr.Check.ID, nil, reviewErr.Check)
+			responses.NewAPIResponse(c).Conflict(
+				fmt.Sprintf("Potential conflicts of interest must be acknowledged before confirming (conflict check %d)", reviewErr.Check.ID),
+				responses.ErrCodeConflictOfInterest)
+		case errors.As(err, &policyErr):
+			responses.NewAPIResponse(c).BadRequest(policyErr.Error(), responses.ErrCodeInvalidRequest)
+		case errors.Is(err, services.ErrSlotUnavailable):
+			responses.NewAPIResponse(c).Conflict("Lawyer is not available at the requested time", responses.ErrCodeTimeSlotUnavailable)
+		case errors.Is(err, services.ErrInvalidStatusTransition):
+			responses.NewAPIResponse(c).Conflict("Appointment status was changed by someone else", responses.ErrCodeInvalidTransition)
+		default:
+			responses.NewAPIResponse(c).InternalServerError("Failed to update appointment", responses.ErrCodeDatabaseError)
+		}
 		return
 	}
 
-	existingAppointment, _ = appointmentService.GetAppointmentByID(existingAppointment.ID)
-
-	// Send email notifications if status was updated
-	if req.Status != nil {
-		if userRole == "lawyer" {
-			// Use the appointment service to send email to client
-			if err := appointmentService.SendLawyerAppointmentStatusUpdateEmail(existingAppointment, *req.Status); err != nil {
-				// Just log the error, don't fail the request
-				fmt.Printf("Failed to send appointment status update email: %v\n", err)
-			}
-		} else if (userRole == "client" || userRole == "admin") && *req.Status == "cancelled" {
-			// Send notification to lawyer when client cancels an appointment
-			if err := appointmentService.SendAppointmentCancelledEmail(existingAppointment); err != nil {
-				// Just log the error, don't fail the request
-				fmt.Printf("Failed to send appointment cancellation notification: %v\n", err)
-			}
-		} else if userRole == "admin" {
-			// Use appointment service to notify both parties
-			if err := appointmentService.SendAppointmentStatusUpdateEmails(existingAppointment, *req.Status); err != nil {
-				// Just log the error, don't fail the request
-				fmt.Printf("Failed to send appointment status update emails: %v\n", err)
-			}
-		}
-	}
+	updated, _ := appointmentService.GetAppointmentByID(id)
+	recordAudit(c, auditAction, models.AuditEntityAppointment, id, before, updated)
 
 	updatedResponse, err := appointmentService.GetAppointmentResponseByID(id)
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve updated appointment", responses.ErrCodeDatabaseError)
 		return
 	}
+	hideMeetingLinkUntilAvailable(c, updatedResponse)
 
 	responses.NewAPIResponse(c).OK(updatedResponse)
 }
@@ -568,9 +625,7 @@ func DeleteAppointmentHandler(c *gin.Context) {
 		return
 	}
 
-	userRole, _ := middleware.GetUserRole(c)
-
-	if userRole != "admin" {
+	if !middleware.HasPermission(c, models.PermAppointmentsManage) {
 		responses.NewAPIResponse(c).Forbidden("Only administrators can delete appointments", responses.ErrCodeForbidden)
 		return
 	}
@@ -584,12 +639,20 @@ func DeleteAppointmentHandler(c *gin.Context) {
 
 	appointmentService := services.NewAppointmentService()
 
+	appointment, err := appointmentService.GetAppointmentByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
+		return
+	}
+
 	err = appointmentService.DeleteAppointment(id)
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to delete appointment", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityAppointment, id, appointment, nil)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Appointment deleted successfully"})
 }
 
@@ -673,25 +736,17 @@ func GetUpcomingAppointmentsHandler(c *gin.Context) {
 }
 
 // @Summary Get available time slots
-// @Description Gets available time slots for a lawyer on a specific date
+// @Description Gets available time slots for a lawyer on a date in the lawyer's time zone. Each slot also carries absolute start_at and end_at times.
 // @Tags appointments
 // @Produce json
 // @Param lawyer_id query int true "Lawyer ID"
 // @Param date query string true "Date in YYYY-MM-DD format"
-// @Param timezone query string false "Timezone name (e.g. 'Asia/Tokyo')"
 // @Success 200 {array} services.TimeSlot "List of available time slots"
 // @Failure 400 {object} responses.APIErrorResponse "Invalid parameters"
 // @Failure 404 {object} responses.APIErrorResponse "Lawyer not found"
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /appointments/available-slots [get]
 func GetAvailableTimeSlotsHandler(c *gin.Context) {
-	loc, err := time.LoadLocation("Asia/Tokyo")
-	if err != nil {
-		responses.NewAPIResponse(c).
-			InternalServerError("Failed to load location data", responses.ErrCodeInvalidRequest)
-		return
-	}
-
 	lawyerIDStr := c.Query("lawyer_id")
 	dateStr := c.Query("date") // Expected format: YYYY-MM-DD
 
@@ -713,9 +768,8 @@ func GetAvailableTimeSlotsHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse the date string using the loaded location.
-	// This ensures 'selectedDate' is a time.Time object representing the start of that day in 'loc'.
-	selectedDate, err := time.ParseInLocation("2006-01-02", dateStr, loc)
+	// The date is a calendar date in the lawyer's time zone
+	selectedDate, err := models.ParseDate(dateStr)
 	if err != nil {
 		responses.NewAPIResponse(c).
 			BadRequest("Invalid date format, expected YYYY-MM-DD", responses.ErrCodeInvalidRequest)
@@ -744,6 +798,55 @@ func GetAvailableTimeSlotsHandler(c *gin.Context) {
 	responses.NewAPIResponse(c).OK(slots)
 }
 
+// @Summary Get availability calendar
+// @Description Gets a lawyer's time slots for every date in a range of up to 62 days, with the next available slot. Dates are in the lawyer's time zone and default to 31 days from today.
+// @Tags appointments
+// @Produce json
+// @Param lawyer_id query int true "Lawyer ID"
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Success 200 {object} services.AvailabilityCalendar
+// @Failure 400 {object} responses.APIErrorResponse "Invalid parameters"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/available-calendar [get]
+func GetAvailabilityCalendarHandler(c *gin.Context) {
+	lawyerID, err := strconv.Atoi(c.Query("lawyer_id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid or missing lawyer_id parameter", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var from, to models.Date
+	if s := c.Query("from"); s != "" {
+		if from, err = models.ParseDate(s); err != nil {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+			return
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if to, err = models.ParseDate(s); err != nil {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+			return
+		}
+	}
+
+	calendar, err := services.NewAvailabilityService().GetAvailabilityCalendar(lawyerID, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCalendarRange):
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		case errors.Is(err, services.ErrLawyerNotFound):
+			responses.NewAPIResponse(c).NotFound(err.Error(), responses.ErrCodeResourceNotFound)
+		default:
+			responses.NewAPIResponse(c).InternalServerError("Failed to retrieve availability calendar", responses.ErrCodeDatabaseError)
+		}
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(calendar)
+}
+
 type RejectAppointmentRequest struct {
 	Reason string `json:"reason" binding:"required"`
 }
@@ -757,10 +860,11 @@ type RejectAppointmentRequest struct {
 // @Param id path int true "Appointment ID"
 // @Param rejection body RejectAppointmentRequest true "Rejection details"
 // @Success 200 {object} responses.AppointmentResponse
-// @Failure 400 {object} responses.APIErrorResponse "Invalid request or already cancelled/rejected"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid request"
 // @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
 // @Failure 403 {object} responses.APIErrorResponse "Forbidden - only lawyers or admins can reject"
 // @Failure 404 {object} responses.APIErrorResponse "Appointment or profile not found"
+// @Failure 409 {object} responses.APIErrorResponse "Appointment can no longer be rejected"
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /appointments/{id}/reject [post]
 func RejectAppointmentHandler(c *gin.Context) {
@@ -771,7 +875,7 @@ func RejectAppointmentHandler(c *gin.Context) {
 	}
 
 	userRole, _ := middleware.GetUserRole(c)
-	if userRole != "lawyer" && userRole != "admin" {
+	if !middleware.HasPermission(c, models.PermAppointmentsReject) {
 		responses.NewAPIResponse(c).Forbidden("Only lawyers or admins can reject appointments", responses.ErrCodeForbidden)
 		return
 	}
@@ -810,30 +914,85 @@ func RejectAppointmentHandler(c *gin.Context) {
 		}
 	}
 
-	if appointment.Status == "cancelled" || appointment.Status == "rejected" {
-		responses.NewAPIResponse(c).BadRequest("Appointment is already cancelled or rejected", responses.ErrCodeInvalidRequest)
+	rejected, err := appointmentService.RejectAppointment(id, req.Reason, userID, userRole)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			responses.NewAPIResponse(c).Conflict(
+				fmt.Sprintf("Cannot reject an appointment that is %s", appointment.Status),
+				responses.ErrCodeInvalidTransition)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to reject appointment", responses.ErrCodeDatabaseError)
 		return
 	}
 
-	if err := appointmentService.RejectAppointment(id, req.Reason); err != nil {
-		responses.NewAPIResponse(c).InternalServerError("Failed to reject appointment", responses.ErrCodeDatabaseError)
+	recordAudit(c, models.AuditActionReject, models.AuditEntityAppointment, id, appointment, rejected)
+
+	updated, err := appointmentService.GetAppointmentResponseByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to fetch updated appointment", responses.ErrCodeDatabaseError)
+		return
+	}
+	hideMeetingLinkUntilAvailable(c, updated)
+
+	responses.NewAPIResponse(c).OK(updated)
+}
+
+// @Summary Get appointment status history
+// @Description Lists every status change of an appointment, oldest first, starting with its creation
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Success 200 {array} models.AppointmentStatusHistory
+// @Failure 400 {object} responses.APIErrorResponse "Invalid appointment ID"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden - no access to this appointment"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/status-history [get]
+func GetAppointmentStatusHistoryHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	userRole, _ := middleware.GetUserRole(c)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid appointment ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	appointmentService := services.NewAppointmentService()
+	appointment, err := appointmentService.GetAppointmentByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
 		return
 	}
 
-	// Send email notification when lawyer rejects appointment
 	if userRole == "lawyer" {
-		appointment, _ := appointmentService.GetAppointmentByID(id)
-		if err := appointmentService.SendLawyerAppointmentStatusUpdateEmail(appointment, "rejected"); err != nil {
-			// Just log the error, don't fail the request
-			fmt.Printf("Failed to send appointment rejection email: %v\n", err)
+		lawyer, err := services.NewLawyerService().GetLawyerByUserID(userID)
+		if err != nil {
+			responses.NewAPIResponse(c).NotFound("Lawyer profile not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		if appointment.LawyerID != lawyer.ID {
+			responses.NewAPIResponse(c).Forbidden("You do not have access to this appointment", responses.ErrCodeForbidden)
+			return
 		}
+	} else if appointment.UserID != userID && !middleware.HasPermission(c, models.PermAppointmentsManage) {
+		responses.NewAPIResponse(c).Forbidden("You do not have access to this appointment", responses.ErrCodeForbidden)
+		return
 	}
 
-	updated, err := appointmentService.GetAppointmentResponseByID(id)
+	history, err := appointmentService.GetStatusHistory(id)
 	if err != nil {
-		responses.NewAPIResponse(c).InternalServerError("Failed to fetch updated appointment", responses.ErrCodeDatabaseError)
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve status history", responses.ErrCodeDatabaseError)
 		return
 	}
 
-	responses.NewAPIResponse(c).OK(updated)
+	responses.NewAPIResponse(c).OK(history)
 }
@@ -255,6 +255,12 @@ func CreateArticleHandler(c *gin.Context) {
 		return
 	}
 
+	// Publishing needs its own permission; writers without it can only save drafts
+	if req.Status == "published" && !middleware.HasPermission(c, models.PermArticlesPublish) {
+		responses.NewAPIResponse(c).Forbidden("You are not allowed to publish articles", responses.ErrCodeForbidden)
+		return
+	}
+
 	// Create article in the database
 	now := time.Now()
 	article := models.Article{
@@ -337,14 +343,10 @@ func UpdateArticleHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if the user is the author of the article
-	if existingArticle.AuthorID != userID {
-		// Check if the user is an admin
-		userRole, _ := middleware.GetUserRole(c)
-		if userRole != "admin" {
-			responses.NewAPIResponse(c).Forbidden("You are not authorized to update this article", responses.ErrCodeForbidden)
-			return
-		}
+	// Check if the user is the author of the article or may edit any article
+	if existingArticle.AuthorID != userID && !middleware.HasPermission(c, models.PermArticlesManage) {
+		responses.NewAPIResponse(c).Forbidden("You are not authorized to update this article", responses.ErrCodeForbidden)
+		return
 	}
 
 	// Parse request body
@@ -354,6 +356,8 @@ func UpdateArticleHandler(c *gin.Context) {
 		return
 	}
 
+	before := services.AuditSnapshot(existingArticle)
+
 	// Update article with the new values
 	if req.Title != "" {
 		existingArticle.Title = req.Title
@@ -371,6 +375,10 @@ func UpdateArticleHandler(c *gin.Context) {
 		existingArticle.Thumbnail = req.Thumbnail
 	}
 	if req.Status != "" {
+		if req.Status == "published" && existingArticle.Status != "published" && !middleware.HasPermission(c, models.PermArticlesPublish) {
+			responses.NewAPIResponse(c).Forbidden("You are not allowed to publish articles", responses.ErrCodeForbidden)
+			return
+		}
 		existingArticle.Status = req.Status
 	}
 	if req.Slug != "" {
@@ -384,6 +392,8 @@ func UpdateArticleHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityArticle, id, before, existingArticle)
+
 	// Convert to response format
 	updatedAt := existingArticle.UpdatedAt // Create a copy
 	response := ArticleResponse{
@@ -428,11 +438,14 @@ func DeleteArticleHandler(c *gin.Context) {
 	}
 
 	// Delete article from the database
+	previous, _ := services.GetArticleService().GetArticleByID(articleID)
 	err = services.GetArticleService().DeleteArticle(articleID, userID)
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to delete article", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityArticle, articleID, previous, nil)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Article deleted"})
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kotolino/lawyer/internal/handlers/responses"
 	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
 	"github.com/kotolino/lawyer/internal/services"
 	"gorm.io/gorm"
 )
@@ -75,6 +76,28 @@ func GetAttachmentURLHandler(c *gin.Context) {
 		}
 	}
 
+	// intake files are private to their uploader until booked, then shared with
+	// the appointment's lawyer and staff
+	switch att.AttachmentableType {
+	case services.AttachmentTypeIntakeUpload:
+		if userID != att.UploadedBy {
+			responses.NewAPIResponse(c).
+				Unauthorized("Not allowed to access this file", responses.ErrCodeUnauthorized)
+			return
+		}
+	case services.AttachmentTypeIntakeResponse:
+		if !canViewIntakeFile(c, userID, att.AttachmentableID) {
+			responses.NewAPIResponse(c).
+				Unauthorized("Not allowed to access this file", responses.ErrCodeUnauthorized)
+			return
+		}
+	case services.AttachmentTypeDocumentVersion:
+		// vault documents are downloaded through their matter so every download is logged
+		responses.NewAPIResponse(c).
+			Unauthorized("Download matter documents from the matter's vault", responses.ErrCodeUnauthorized)
+		return
+	}
+
 	// 4️⃣ generate presigned URL
 	url, err := services.NewUtilService().GetAttachmentURL(
 		c.Request.Context(),
@@ -100,3 +123,25 @@ func GetAttachmentURLHandler(c *gin.Context) {
 	responses.NewAPIResponse(c).
 		OK(url)
 }
+
+// canViewIntakeFile reports whether the user may download a file attached to an
+// intake response: the appointment's client and lawyer, and staff who manage
+// appointments
+func canViewIntakeFile(c *gin.Context, userID, responseID int) bool {
+	if middleware.HasPermission(c, models.PermAppointmentsManage) {
+		return true
+	}
+	response, err := services.NewIntakeService().GetResponseByID(responseID)
+	if err != nil {
+		return false
+	}
+	appointment, err := services.NewAppointmentService().GetAppointmentByID(response.AppointmentID)
+	if err != nil {
+		return false
+	}
+	if appointment.UserID == userID {
+		return true
+	}
+	lawyer, err := services.NewLawyerService().GetLawyerByUserID(userID)
+	return err == nil && lawyer.ID == appointment.LawyerID
+}
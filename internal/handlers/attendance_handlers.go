@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// ConfirmAttendanceRequest reports whether an appointment took place
+type ConfirmAttendanceRequest struct {
+	// Outcome is attended, no_show_client or no_show_lawyer
+	Outcome string  `json:"outcome" binding:"required"`
+	Note    *string `json:"note,omitempty"`
+}
+
+// @Summary Confirm attendance
+// @Description Records whether a confirmed appointment took place once it has ended. The lawyer reports attended, no_show_client or no_show_lawyer; the client can report that the lawyer did not attend. No-shows count against the absent party, and only attended appointments can be reviewed. Appointments nobody reports on are completed after the grace period.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param request body ConfirmAttendanceRequest true "Attendance"
+// @Success 200 {object} responses.AppointmentResponse
+// @Failure 400 {object} responses.APIErrorResponse "Invalid outcome or appointment has not ended"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 409 {object} responses.APIErrorResponse "Appointment is not confirmed"
+// @Router /appointments/{id}/attendance [post]
+func ConfirmAttendanceHandler(c *gin.Context) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	actorRole := party
+	if actorRole == "" {
+		actorRole, _ = middleware.GetUserRole(c)
+	}
+
+	var req ConfirmAttendanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	before := services.AuditSnapshot(appointment)
+	updated, err := services.NewAttendanceService().ConfirmAttendance(appointment, req.Outcome, req.Note, userID, actorRole)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAttendance):
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		case errors.Is(err, services.ErrInvalidStatusTransition):
+			responses.NewAPIResponse(c).Conflict(
+				"Attendance can only be confirmed for confirmed appointments", responses.ErrCodeInvalidTransition)
+		default:
+			responses.NewAPIResponse(c).InternalServerError("Failed to confirm attendance", responses.ErrCodeDatabaseError)
+		}
+		return
+	}
+	recordAudit(c, models.AuditActionAttendance, models.AuditEntityAppointment, appointment.ID, before, updated)
+
+	response, err := services.NewAppointmentService().GetAppointmentResponseByID(appointment.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve updated appointment", responses.ErrCodeDatabaseError)
+		return
+	}
+	hideMeetingLinkUntilAvailable(c, response)
+
+	responses.NewAPIResponse(c).OK(response)
+}
+
+// @Summary Reset a user's no-shows
+// @Description Clears a user's no-show counter, lifting any booking restriction (admin only)
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid user ID"
+// @Failure 404 {object} responses.APIErrorResponse "User not found"
+// @Router /users/{id}/no-shows [delete]
+func ResetUserNoShowsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid user ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	user, err := services.NewUserService().GetUserByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+		return
+	}
+
+	if err := services.NewAttendanceService().ResetNoShows(id); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to reset no-shows", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionNoShowReset, models.AuditEntityUser, id,
+		map[string]interface{}{"no_show_count": user.NoShowCount, "last_no_show_at": user.LastNoShowAt},
+		map[string]interface{}{"no_show_count": 0})
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "No-shows reset"})
+}
+
+// @Summary Get attendance settings
+// @Description Returns the grace period for reporting attendance and the booking restriction for repeat no-shows (admin only)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.AttendanceSettings
+// @Router /admin/settings/attendance [get]
+func GetAttendanceSettingsHandler(c *gin.Context) {
+	settings, err := services.NewPlatformSettingService().GetAttendanceSettings()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load attendance settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(settings)
+}
+
+// @Summary Update attendance settings
+// @Description Sets how many hours after an appointment ends attendance can be reported before it is completed automatically, and after how many no-shows clients cannot book and for how many days (admin only). A no-show limit of 0 turns the restriction off; 0 restriction days keeps it until the counter is reset.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body services.AttendanceSettings true "Attendance settings"
+// @Success 200 {object} services.AttendanceSettings
+// @Failure 400 {object} responses.APIErrorResponse "Invalid settings"
+// @Router /admin/settings/attendance [put]
+func UpdateAttendanceSettingsHandler(c *gin.Context) {
+	var req services.AttendanceSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	settingService := services.NewPlatformSettingService()
+	previous, _ := settingService.GetAttendanceSettings()
+	if err := settingService.SetAttendanceSettings(req, adminID); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	recordAuditByKey(c, models.AuditActionSettingsChange, models.AuditEntityPlatformSetting, "attendance", previous, req)
+
+	responses.NewAPIResponse(c).OK(req)
+}
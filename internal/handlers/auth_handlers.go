@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -49,6 +52,14 @@ type AuthResponse struct {
 	User  *models.User `json:"user"`
 }
 
+// MFAChallengeResponse is returned by login when a second factor is needed before a session token is issued
+type MFAChallengeResponse struct {
+	MFARequired      bool      `json:"mfa_required"`
+	MFASetupRequired bool      `json:"mfa_setup_required"`
+	MFAToken         string    `json:"mfa_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
 // VerifyEmailResponse represents the response for email verification
 type VerifyEmailResponse struct {
 	Success bool   `json:"success"`
@@ -56,14 +67,17 @@ type VerifyEmailResponse struct {
 }
 
 // @Summary User login
-// @Description Authenticates a user and returns a token
+// @Description Authenticates a user and returns a token. Users with two-factor authentication enabled,
+// @Description or whose role requires it, receive an MFAChallengeResponse instead and must complete /auth/mfa/verify or /auth/mfa/setup.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param credentials body LoginRequest true "Login credentials"
 // @Success 200 {object} AuthResponse
+// @Success 202 {object} MFAChallengeResponse
 // @Failure 400 {object} responses.APIErrorResponse "Invalid request"
 // @Failure 401 {object} responses.APIErrorResponse "Invalid credentials or inactive account"
+// @Failure 429 {object} responses.APIErrorResponse "Too many failed attempts; see the Retry-After header"
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /auth/login [post]
 func LoginHandler(c *gin.Context) {
@@ -73,37 +87,86 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
+	// Refuse early if this email or IP is locked out or throttled
+	if !checkLoginThrottle(c, models.LoginActionLogin, req.Email) {
+		return
+	}
+
 	// Get the user service
 	userService := services.NewUserService()
 
 	// Get the user by Email
 	user, err := userService.GetUserByEmail(req.Email)
 	if err != nil {
+		recordLoginFailure(c, models.LoginActionLogin, req.Email, nil, models.LoginFailureUnknownEmail)
+		responses.NewAPIResponse(c).Unauthorized("Invalid email or password", responses.ErrCodeInvalidCredentials)
+		return
+	}
+
+	// Compare the password using the utility service
+	if err := utilService.ComparePassword(user.Password, req.Password); err != nil {
+		recordLoginFailure(c, models.LoginActionLogin, req.Email, &user.ID, models.LoginFailureInvalidPassword)
 		responses.NewAPIResponse(c).Unauthorized("Invalid email or password", responses.ErrCodeInvalidCredentials)
 		return
 	}
 
 	// Check if the user is active
 	if !user.IsActive {
+		recordLoginAttempt(c, models.LoginActionLogin, req.Email, &user.ID, false, models.LoginFailureInactive)
 		responses.NewAPIResponse(c).Unauthorized("Account is inactive", responses.ErrCodeOperationFailed)
 		return
 	}
 
 	// Check if email is verified
 	if !user.EmailVerified {
+		recordLoginAttempt(c, models.LoginActionLogin, req.Email, &user.ID, false, models.LoginFailureEmailNotVerified)
 		responses.NewAPIResponse(c).Unauthorized("Email not verified. Please check your email for verification instructions", responses.ErrCodeEmailNotVerified)
 		return
 	}
 
-	// Compare the password using the utility service
-	if err := utilService.ComparePassword(user.Password, req.Password); err != nil {
-		responses.NewAPIResponse(c).Unauthorized("Invalid email or password", responses.ErrCodeInvalidCredentials)
+	recordLoginAttempt(c, models.LoginActionLogin, req.Email, &user.ID, true, "")
+
+	cfg := services.GetConfig()
+
+	// Require a second factor if the user has enrolled or their role demands it
+	mfaRequired, err := services.NewPlatformSettingService().IsMFARequiredForRole(user.Role)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load security settings", responses.ErrCodeDatabaseError)
+		return
+	}
+	if user.TOTPEnabled || mfaRequired {
+		scope := middleware.ScopeMFAChallenge
+		if !user.TOTPEnabled {
+			scope = middleware.ScopeMFASetup
+		}
+
+		mfaToken, expiresAt, err := middleware.GenerateMFAToken(user, scope, cfg)
+		if err != nil {
+			responses.NewAPIResponse(c).InternalServerError("Failed to generate token", responses.ErrCodeDatabaseError)
+			return
+		}
+
+		responses.NewAPIResponse(c).Success(http.StatusAccepted, MFAChallengeResponse{
+			MFARequired:      user.TOTPEnabled,
+			MFASetupRequired: !user.TOTPEnabled,
+			MFAToken:         mfaToken,
+			ExpiresAt:        expiresAt,
+		})
 		return
 	}
 
+	respondWithSessionToken(c, user)
+}
+
+// respondWithSessionToken issues a regular session token for a fully authenticated user
+func respondWithSessionToken(c *gin.Context, user *models.User) {
+	// A completed login clears the email's failure count
+	if err := services.GetRateLimitService().RecordLoginSuccess(user.Email); err != nil {
+		fmt.Printf("Failed to reset login failures for %s: %v\n", user.Email, err)
+	}
+
 	// Generate a token
-	cfg := services.GetConfig()
-	token, err := middleware.GenerateToken(user, cfg)
+	token, err := middleware.GenerateToken(user, services.GetConfig())
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to generate token", responses.ErrCodeDatabaseError)
 		return
@@ -335,6 +398,7 @@ func VerifyEmailHandler(c *gin.Context) {
 // @Success 200 {object} gin.H "Email sent message"
 // @Failure 400 {object} responses.APIErrorResponse "Invalid request"
 // @Failure 404 {object} responses.APIErrorResponse "User not found"
+// @Failure 429 {object} responses.APIErrorResponse "Too many requests; see the Retry-After header"
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /auth/resend-verification-email [post]
 func ResendVerificationEmailHandler(c *gin.Context) {
@@ -347,10 +411,15 @@ func ResendVerificationEmailHandler(c *gin.Context) {
 		return
 	}
 
+	if !checkEmailRequestThrottle(c, models.LoginActionResendVerification, req.Email) {
+		return
+	}
+
 	// Get the user service
 	userService := services.NewUserService()
 	user, err := userService.GetUserByEmail(req.Email)
 	if err != nil {
+		recordLoginAttempt(c, models.LoginActionResendVerification, req.Email, nil, false, models.LoginFailureUnknownEmail)
 		responses.NewAPIResponse(c).OK(gin.H{
 			"message": "Verification email has been sent. Please check your inbox.",
 		})
@@ -402,6 +471,8 @@ func ResendVerificationEmailHandler(c *gin.Context) {
 		return
 	}
 
+	recordLoginAttempt(c, models.LoginActionResendVerification, req.Email, &user.ID, true, "")
+
 	// Return success response
 	responses.NewAPIResponse(c).OK(gin.H{
 		"message": "Verification email has been sent. Please check your inbox.",
@@ -445,11 +516,19 @@ func GetCurrentUserHandler(c *gin.Context) {
 		Nickname:          user.Nickname,
 		FirstName:         user.FirstName,
 		LastName:          user.LastName,
+		FirstNameKana:     user.FirstNameKana,
+		LastNameKana:      user.LastNameKana,
 		ProfileImage:      user.ProfileImage,
 		Role:              user.Role,
 		CreatedAt:         user.CreatedAt,
 		UpdatedAt:         user.UpdatedAt,
 		HasNewAppointment: false, // Default to false
+		MFAEnabled:        user.TOTPEnabled,
+	}
+
+	// Let the client show that a staff member is acting as this user
+	if actorID, ok := middleware.GetActorID(c); ok {
+		userResponse.ImpersonatorID = &actorID
 	}
 
 	// Check for new appointments based on user role
@@ -514,6 +593,7 @@ type ForgotPasswordRequest struct {
 // @Param email body ForgotPasswordRequest true "User email"
 // @Success 200 {object} gin.H "Email sent message"
 // @Failure 400 {object} responses.APIErrorResponse "Invalid request"
+// @Failure 429 {object} responses.APIErrorResponse "Too many requests; see the Retry-After header"
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /auth/forgot-password [post]
 func ForgotPasswordHandler(c *gin.Context) {
@@ -524,8 +604,13 @@ func ForgotPasswordHandler(c *gin.Context) {
 	}
 
 	userService := services.NewUserService()
+	if !checkEmailRequestThrottle(c, models.LoginActionForgotPassword, req.Email) {
+		return
+	}
+
 	user, err := userService.GetUserByEmail(req.Email)
 	if err != nil {
+		recordLoginAttempt(c, models.LoginActionForgotPassword, req.Email, nil, false, models.LoginFailureUnknownEmail)
 		// always return 200 so clients can’t probe which emails exist
 		responses.NewAPIResponse(c).OK(gin.H{
 			"message": "If that email is in our system, you’ll get a reset link shortly.",
@@ -557,6 +642,8 @@ func ForgotPasswordHandler(c *gin.Context) {
 		return
 	}
 
+	recordLoginAttempt(c, models.LoginActionForgotPassword, req.Email, &user.ID, true, "")
+
 	// done!
 	responses.NewAPIResponse(c).OK(gin.H{
 		"message": "If that email is in our system, you’ll get a reset link shortly.",
@@ -621,3 +708,72 @@ func ResetPasswordHandler(c *gin.Context) {
 		"message": "Your password has been reset successfully.",
 	})
 }
+
+// checkLoginThrottle refuses the request with 429 if the email or client IP is locked out
+// or has not waited out its progressive delay. It returns false when a response was written.
+func checkLoginThrottle(c *gin.Context, action, email string) bool {
+	err := services.GetRateLimitService().CheckLogin(email, c.ClientIP())
+	if err == nil {
+		return true
+	}
+
+	var limitErr *services.RateLimitError
+	if !errors.As(err, &limitErr) {
+		// Fail open: an unavailable limiter store must not lock everyone out
+		fmt.Printf("Rate limit check failed for %s: %v\n", email, err)
+		return true
+	}
+
+	recordLoginAttempt(c, action, email, nil, false, limitErr.Reason)
+	if limitErr.Locked() {
+		responses.NewAPIResponse(c).TooManyRequests("Too many failed login attempts. The account is temporarily locked", responses.ErrCodeAccountLocked, limitErr.RetryAfter)
+		return false
+	}
+	responses.NewAPIResponse(c).TooManyRequests("Too many login attempts. Please wait before trying again", responses.ErrCodeTooManyRequests, limitErr.RetryAfter)
+	return false
+}
+
+// checkEmailRequestThrottle refuses the request with 429 if too many account emails were
+// requested for this address or from this IP. It returns false when a response was written.
+func checkEmailRequestThrottle(c *gin.Context, action, email string) bool {
+	err := services.GetRateLimitService().AllowEmailRequest(action, email, c.ClientIP())
+	if err == nil {
+		return true
+	}
+
+	var limitErr *services.RateLimitError
+	if !errors.As(err, &limitErr) {
+		fmt.Printf("Rate limit check failed for %s: %v\n", email, err)
+		return true
+	}
+
+	recordLoginAttempt(c, action, email, nil, false, limitErr.Reason)
+	responses.NewAPIResponse(c).TooManyRequests("Too many requests. Please try again later", responses.ErrCodeTooManyRequests, limitErr.RetryAfter)
+	return false
+}
+
+// recordLoginFailure counts a failed credential check towards lockout and audits it
+func recordLoginFailure(c *gin.Context, action, email string, userID *int, reason string) {
+	if err := services.GetRateLimitService().RecordLoginFailure(email, c.ClientIP()); err != nil {
+		fmt.Printf("Failed to record login failure for %s: %v\n", email, err)
+	}
+	recordLoginAttempt(c, action, email, userID, false, reason)
+}
+
+// recordLoginAttempt writes an entry to the login_attempts audit table
+func recordLoginAttempt(c *gin.Context, action, email string, userID *int, success bool, reason string) {
+	attempt := &models.LoginAttempt{
+		Email:     email,
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		Action:    action,
+		Success:   success,
+	}
+	if ua := c.Request.UserAgent(); ua != "" {
+		attempt.UserAgent = &ua
+	}
+	if reason != "" {
+		attempt.FailureReason = &reason
+	}
+	services.NewLoginAttemptService().Record(attempt)
+}
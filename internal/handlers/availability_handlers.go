@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// AvailabilityExceptionRequest closes a date or sets custom hours for it
+type AvailabilityExceptionRequest struct {
+	Date      models.Date          `json:"date"`
+	Closed    bool                 `json:"closed"`
+	Intervals models.TimeIntervals `json:"intervals"`
+	Reason    *string              `json:"reason,omitempty"`
+}
+
+// currentLawyerProfile loads the lawyer profile of the current user, writing the
+// error response itself when there is none
+func currentLawyerProfile(c *gin.Context) (*models.Lawyer, bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return nil, false
+	}
+
+	lawyer, err := services.NewLawyerService().GetLawyerByUserID(userID)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("Lawyer profile not found", responses.ErrCodeResourceNotFound)
+		return nil, false
+	}
+	return lawyer, true
+}
+
+// @Summary Get weekly availability
+// @Description Returns the current lawyer's recurring weekly schedule
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Availability
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Router /lawyers/profile/availability [get]
+func GetMyAvailabilityHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	schedule := models.Availability{}
+	if lawyer.Availability != nil {
+		schedule = *lawyer.Availability
+	}
+	responses.NewAPIResponse(c).OK(schedule)
+}
+
+// @Summary Update weekly availability
+// @Description Replaces the current lawyer's recurring weekly schedule. Times are HH:MM and intervals on the same day must not overlap.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param availability body models.Availability true "Weekly schedule"
+// @Success 200 {object} models.Availability
+// @Failure 400 {object} responses.APIErrorResponse "Invalid schedule"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/availability [put]
+func UpdateMyAvailabilityHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	var schedule models.Availability
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	availabilityService := services.NewAvailabilityService()
+	if err := availabilityService.UpdateWeeklySchedule(lawyer.ID, schedule); err != nil {
+		if errors.Is(err, services.ErrInvalidAvailability) {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to update availability", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	updatedLawyer, err := services.NewLawyerService().GetLawyerByID(lawyer.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve updated availability", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	if err := services.CompareAndTrackLawyerChanges(availabilityService.DB, lawyer, updatedLawyer, userID); err != nil {
+		fmt.Printf("Failed to record lawyer history: %v\n", err)
+	}
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityLawyer, lawyer.ID, lawyer, updatedLawyer)
+
+	responses.NewAPIResponse(c).OK(schedule)
+}
+
+// @Summary Get booking settings
+// @Description Returns the current lawyer's slot length, buffers, minimum notice, booking horizon and daily cap. Defaults are returned if none are set.
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.BookingSettings
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Router /lawyers/profile/booking-settings [get]
+func GetMyBookingSettingsHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(lawyer.EffectiveBookingSettings())
+}
+
+// @Summary Update booking settings
+// @Description Replaces the current lawyer's booking settings. Slots last 30, 45, 60 or 90 minutes and a daily cap of 0 means no limit.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param settings body models.BookingSettings true "Booking settings"
+// @Success 200 {object} models.BookingSettings
+// @Failure 400 {object} responses.APIErrorResponse "Invalid settings"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/booking-settings [put]
+func UpdateMyBookingSettingsHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	var settings models.BookingSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	availabilityService := services.NewAvailabilityService()
+	if err := availabilityService.UpdateBookingSettings(lawyer.ID, settings); err != nil {
+		if errors.Is(err, services.ErrInvalidBookingSettings) {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to update booking settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	updatedLawyer, err := services.NewLawyerService().GetLawyerByID(lawyer.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve updated booking settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	if err := services.CompareAndTrackLawyerChanges(availabilityService.DB, lawyer, updatedLawyer, userID); err != nil {
+		fmt.Printf("Failed to record lawyer history: %v\n", err)
+	}
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityLawyer, lawyer.ID, lawyer, updatedLawyer)
+
+	responses.NewAPIResponse(c).OK(settings)
+}
+
+// @Summary List availability exceptions
+// @Description Lists the current lawyer's date exceptions between two dates, inclusive. Defaults to the coming year.
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Success 200 {array} models.AvailabilityException
+// @Failure 400 {object} responses.APIErrorResponse "Invalid date"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/availability/exceptions [get]
+func GetAvailabilityExceptionsHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	exceptions, err := services.NewAvailabilityService().GetExceptions(lawyer.ID, from, to)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve availability exceptions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(exceptions)
+}
+
+// @Summary Create availability exception
+// @Description Closes a date or replaces its hours with custom intervals. Each date can have one exception.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param exception body AvailabilityExceptionRequest true "Exception"
+// @Success 201 {object} models.AvailabilityException
+// @Failure 400 {object} responses.APIErrorResponse "Invalid exception"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 409 {object} responses.APIErrorResponse "The date already has an exception"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/availability/exceptions [post]
+func CreateAvailabilityExceptionHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	var req AvailabilityExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	exception := &models.AvailabilityException{
+		LawyerID:  lawyer.ID,
+		Date:      req.Date,
+		Closed:    req.Closed,
+		Intervals: req.Intervals,
+		Reason:    req.Reason,
+	}
+	if err := services.NewAvailabilityService().CreateException(exception); err != nil {
+		respondAvailabilityExceptionError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityAvailabilityException, exception.ID, nil, exception)
+	responses.NewAPIResponse(c).Created(exception)
+}
+
+// @Summary Update availability exception
+// @Description Replaces an existing date exception
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param exceptionId path int true "Exception ID"
+// @Param exception body AvailabilityExceptionRequest true "Exception"
+// @Success 200 {object} models.AvailabilityException
+// @Failure 400 {object} responses.APIErrorResponse "Invalid exception"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Exception not found"
+// @Failure 409 {object} responses.APIErrorResponse "The date already has an exception"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/availability/exceptions/{exceptionId} [put]
+func UpdateAvailabilityExceptionHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	exceptionID, err := strconv.Atoi(c.Param("exceptionId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid exception ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req AvailabilityExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	availabilityService := services.NewAvailabilityService()
+	exception, err := availabilityService.GetException(lawyer.ID, exceptionID)
+	if err != nil {
+		respondAvailabilityExceptionError(c, err)
+		return
+	}
+	before := services.AuditSnapshot(exception)
+
+	exception.Date = req.Date
+	exception.Closed = req.Closed
+	exception.Intervals = req.Intervals
+	exception.Reason = req.Reason
+	if err := availabilityService.UpdateException(exception); err != nil {
+		respondAvailabilityExceptionError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityAvailabilityException, exception.ID, before, exception)
+	responses.NewAPIResponse(c).OK(exception)
+}
+
+// @Summary Delete availability exception
+// @Description Removes a date exception so the weekly schedule applies again
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param exceptionId path int true "Exception ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid exception ID"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Exception not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/availability/exceptions/{exceptionId} [delete]
+func DeleteAvailabilityExceptionHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	exceptionID, err := strconv.Atoi(c.Param("exceptionId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid exception ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	availabilityService := services.NewAvailabilityService()
+	exception, err := availabilityService.GetException(lawyer.ID, exceptionID)
+	if err != nil {
+		respondAvailabilityExceptionError(c, err)
+		return
+	}
+
+	if err := availabilityService.DeleteException(lawyer.ID, exceptionID); err != nil {
+		respondAvailabilityExceptionError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityAvailabilityException, exceptionID, exception, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Availability exception deleted successfully"})
+}
+
+func respondAvailabilityExceptionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAvailability):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrAvailabilityExceptionNotFound):
+		responses.NewAPIResponse(c).NotFound("Availability exception not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrAvailabilityExceptionExists):
+		responses.NewAPIResponse(c).Conflict("The date already has an availability exception", responses.ErrCodeResourceAlreadyExists)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to save availability exception", responses.ErrCodeDatabaseError)
+	}
+}
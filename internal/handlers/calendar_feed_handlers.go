@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// @Summary Get calendar feed status
+// @Description Returns whether the current user's iCalendar subscription feed is enabled. The feed URL is only shown when the token is created.
+// @Tags calendar
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.CalendarFeed
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /calendar/feed [get]
+func GetCalendarFeedHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	feed, err := services.NewCalendarFeedService().GetFeed(userID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve calendar feed", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(feed)
+}
+
+// @Summary Create calendar feed token
+// @Description Issues a new secret token for the current user's iCalendar subscription feed and returns its URL. Any previous token stops working. Not available while impersonating.
+// @Tags calendar
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 201 {object} services.CalendarFeed
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 403 {object} responses.APIErrorResponse "Impersonating"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /calendar/feed [post]
+func RotateCalendarFeedHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	// The feed URL is a credential that outlives the session, so staff must never
+	// see one, even in sessions that allow destructive actions
+	if middleware.IsImpersonating(c) {
+		responses.NewAPIResponse(c).Forbidden("Calendar feeds cannot be created while impersonating", responses.ErrCodeForbidden)
+		return
+	}
+
+	feed, err := services.NewCalendarFeedService().RotateFeedToken(userID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to create calendar feed", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).Created(feed)
+}
+
+// @Summary Revoke calendar feed token
+// @Description Disables the current user's iCalendar subscription feed
+// @Tags calendar
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /calendar/feed [delete]
+func RevokeCalendarFeedHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	if err := services.NewCalendarFeedService().RevokeFeedToken(userID); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to revoke calendar feed", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Calendar feed revoked"})
+}
+
+// @Summary Get calendar feed
+// @Description Serves a user's appointments as an iCalendar (RFC 5545) feed for calendar apps. The secret token in the path authenticates the request.
+// @Tags calendar
+// @Produce text/calendar
+// @Param token path string true "Feed token, optionally followed by .ics"
+// @Success 200 {string} string "iCalendar document"
+// @Failure 404 {object} responses.APIErrorResponse "Feed not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /public/calendar/{token} [get]
+func GetCalendarFeedDocumentHandler(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	data, err := services.NewCalendarFeedService().RenderFeed(token)
+	if err != nil {
+		if errors.Is(err, services.ErrCalendarFeedNotFound) {
+			responses.NewAPIResponse(c).NotFound("Calendar feed not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to render calendar feed", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
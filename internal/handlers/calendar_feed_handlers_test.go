@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRotateCalendarFeedWhileImpersonating(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/api/calendar/feed", func(c *gin.Context) {
+		// As set by the auth middleware for an impersonation token
+		c.Set("userID", 7)
+		c.Set("impersonationID", 3)
+		c.Next()
+	}, RotateCalendarFeedHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/calendar/feed", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+	}
+}
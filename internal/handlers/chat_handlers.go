@@ -236,11 +236,14 @@ func DeleteChatMessageHandler(c *gin.Context) {
 	}
 
 	// Delete message
+	previous, _ := services.GetChatService().GetChatMessageByID(messageID)
 	if err := services.GetChatService().DeleteMessage(messageID, userID); err != nil {
 		responses.NewAPIResponse(c).InternalServerError(err.Error(), responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityChatMessage, messageID, previous, nil)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Message deleted"})
 }
 
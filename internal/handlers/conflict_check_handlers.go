@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// AcknowledgeConflictRequest records the lawyer's review of potential conflicts
+type AcknowledgeConflictRequest struct {
+	// Note explains why the lawyer may act despite the matches
+	Note string `json:"note" binding:"required"`
+}
+
+// loadAppointmentForConflictCheck loads the appointment in the path for its lawyer or
+// staff. Clients cannot see conflict checks, since matches name the lawyer's other
+// clients.
+func loadAppointmentForConflictCheck(c *gin.Context) (*models.Appointment, bool) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return nil, false
+	}
+	if party == string(models.RoleClient) {
+		responses.NewAPIResponse(c).Forbidden("Only the lawyer can review conflict checks", responses.ErrCodeForbidden)
+		return nil, false
+	}
+	return appointment, true
+}
+
+// @Summary List conflict checks
+// @Description Lists the conflict-of-interest checks run for an appointment, newest first, with the names checked and any matches against the lawyer's other clients and the parties declared against them. Lawyer and staff only.
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Success 200 {array} models.ConflictCheck
+// @Failure 400 {object} responses.APIErrorResponse "Invalid appointment ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/conflict-checks [get]
+func GetConflictChecksHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForConflictCheck(c)
+	if !ok {
+		return
+	}
+
+	checks, err := services.NewConflictCheckService().ListChecks(appointment.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve conflict checks", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(checks)
+}
+
+// @Summary Run a conflict check
+// @Description Checks the appointment's opposing parties against the lawyer's other clients, and its client against parties declared in the lawyer's other appointments and matters. Parties declared on the appointment's matter are checked too. Names are compared after normalizing width, kana, variant kanji and corporate designators, and similar names are reported too. Clients are also matched by the reading of their name (first_name_kana and last_name_kana on their profile), so a party declared in kana can match a client whose name is written in kanji. The result is recorded. Confirming an appointment runs a check automatically.
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Success 201 {object} models.ConflictCheck
+// @Failure 400 {object} responses.APIErrorResponse "Invalid appointment ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/conflict-checks [post]
+func RunConflictCheckHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForConflictCheck(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	check, err := services.NewConflictCheckService().RunCheck(appointment, userID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to run conflict check", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityConflictCheck, check.ID, nil, check)
+	responses.NewAPIResponse(c).Created(check)
+}
+
+// @Summary Acknowledge a conflict check
+// @Description Records that the lawyer reviewed the potential conflicts found by the appointment's latest check and may act, with a note explaining why. The appointment can then be confirmed.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param checkId path int true "Conflict check ID"
+// @Param request body AcknowledgeConflictRequest true "Review note"
+// @Success 200 {object} models.ConflictCheck
+// @Failure 400 {object} responses.APIErrorResponse "Missing note, or check is not the latest or has nothing to acknowledge"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Conflict check not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/conflict-checks/{checkId}/acknowledge [post]
+func AcknowledgeConflictCheckHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForConflictCheck(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	checkID, err := strconv.Atoi(c.Param("checkId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid conflict check ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req AcknowledgeConflictRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	check, err := services.NewConflictCheckService().Acknowledge(appointment.ID, checkID, userID, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAcknowledgement):
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		case errors.Is(err, services.ErrConflictCheckNotFound):
+			responses.NewAPIResponse(c).NotFound("Conflict check not found", responses.ErrCodeResourceNotFound)
+		default:
+			responses.NewAPIResponse(c).InternalServerError("Failed to acknowledge conflict check", responses.ErrCodeDatabaseError)
+		}
+		return
+	}
+
+	recordAudit(c, models.AuditActionAcknowledge, models.AuditEntityConflictCheck, check.ID, nil, check)
+	responses.NewAPIResponse(c).OK(check)
+}
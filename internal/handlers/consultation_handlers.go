@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// ConsultationRecordRequest holds the lawyer's notes on a completed consultation.
+// Omitted or empty fields are cleared.
+type ConsultationRecordRequest struct {
+	// PrivateNotes are never shown to the client
+	PrivateNotes *string `json:"private_notes,omitempty"`
+	Summary      *string `json:"summary,omitempty"`
+	NextSteps    *string `json:"next_steps,omitempty"`
+}
+
+// FollowUpTaskRequest adds a follow-up task to a consultation record
+type FollowUpTaskRequest struct {
+	// Assignee is lawyer (the default) or client
+	Assignee    string      `json:"assignee"`
+	Title       string      `json:"title" binding:"required"`
+	Description *string     `json:"description,omitempty"`
+	DueDate     models.Date `json:"due_date"`
+}
+
+// UpdateFollowUpTaskRequest changes a follow-up task. Clients can only set the status
+// of tasks assigned to them.
+type UpdateFollowUpTaskRequest struct {
+	Assignee    *string      `json:"assignee,omitempty"`
+	Title       *string      `json:"title,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	DueDate     *models.Date `json:"due_date,omitempty"`
+	Status      *string      `json:"status,omitempty"`
+}
+
+// loadAppointmentForLawyer loads the appointment in the path for its own lawyer only
+func loadAppointmentForLawyer(c *gin.Context) (*models.Appointment, bool) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return nil, false
+	}
+	if party != string(models.RoleLawyer) {
+		responses.NewAPIResponse(c).Forbidden("Only the appointment's lawyer can do this", responses.ErrCodeForbidden)
+		return nil, false
+	}
+	return appointment, true
+}
+
+// @Summary Get consultation record
+// @Description Returns the lawyer's record of a completed consultation with its follow-up tasks. The client only sees a shared record, without private notes and with only the tasks assigned to them.
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Success 200 {object} models.ConsultationRecord
+// @Failure 400 {object} responses.APIErrorResponse "Invalid appointment ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment or consultation record not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/consultation-record [get]
+func GetConsultationRecordHandler(c *gin.Context) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+
+	record, err := services.NewConsultationService().GetRecord(appointment.ID)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	if party == string(models.RoleClient) {
+		if record.SharedAt == nil {
+			responses.NewAPIResponse(c).NotFound("Consultation record not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		responses.NewAPIResponse(c).OK(record.ForClient())
+		return
+	}
+	responses.NewAPIResponse(c).OK(record)
+}
+
+// @Summary Save consultation record
+// @Description Writes the lawyer's private notes, client-visible summary and recommended next steps for a completed appointment, creating the record on first save. Changes to a shared record are visible to the client straight away.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param record body ConsultationRecordRequest true "Consultation record"
+// @Success 200 {object} models.ConsultationRecord
+// @Failure 400 {object} responses.APIErrorResponse "Invalid record or appointment not completed"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/consultation-record [put]
+func SaveConsultationRecordHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForLawyer(c)
+	if !ok {
+		return
+	}
+
+	var req ConsultationRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	consultationService := services.NewConsultationService()
+	var before interface{}
+	if existing, err := consultationService.GetRecord(appointment.ID); err == nil {
+		before = services.AuditSnapshot(existing)
+	}
+
+	record, err := consultationService.SaveRecord(appointment, req.PrivateNotes, req.Summary, req.NextSteps)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	action := models.AuditActionUpdate
+	if before == nil {
+		action = models.AuditActionCreate
+	}
+	recordAudit(c, action, models.AuditEntityConsultationRecord, record.ID, before, record)
+	responses.NewAPIResponse(c).OK(record)
+}
+
+// @Summary Share consultation record
+// @Description Shows the record's summary, next steps and client tasks to the client and notifies them. Share again after editing to notify the client of the changes.
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Success 200 {object} models.ConsultationRecord
+// @Failure 400 {object} responses.APIErrorResponse "Record has no summary"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment or consultation record not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/consultation-record/share [post]
+func ShareConsultationRecordHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForLawyer(c)
+	if !ok {
+		return
+	}
+
+	record, err := services.NewConsultationService().ShareRecord(appointment)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityConsultationRecord, record.ID, nil, map[string]interface{}{"shared_at": record.SharedAt})
+	responses.NewAPIResponse(c).OK(record)
+}
+
+// @Summary Add follow-up task
+// @Description Adds a follow-up task with a due date to the appointment's consultation record, for the lawyer or the client. The assignee is reminded once the task is overdue; client tasks only once the record is shared.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param task body FollowUpTaskRequest true "Follow-up task"
+// @Success 201 {object} models.FollowUpTask
+// @Failure 400 {object} responses.APIErrorResponse "Invalid task"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment or consultation record not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/consultation-record/tasks [post]
+func CreateFollowUpTaskHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForLawyer(c)
+	if !ok {
+		return
+	}
+
+	var req FollowUpTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	consultationService := services.NewConsultationService()
+	record, err := consultationService.GetRecord(appointment.ID)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	task := &models.FollowUpTask{
+		Assignee:    req.Assignee,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+	}
+	if err := consultationService.AddTask(record, task); err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityFollowUpTask, task.ID, nil, task)
+	responses.NewAPIResponse(c).Created(task)
+}
+
+// @Summary Update follow-up task
+// @Description Changes a follow-up task. The lawyer can change any field; the client can only mark tasks assigned to them done or open again. Moving the due date or reopening a task lets it be reminded about again.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param taskId path int true "Follow-up task ID"
+// @Param task body UpdateFollowUpTaskRequest true "Changes"
+// @Success 200 {object} models.FollowUpTask
+// @Failure 400 {object} responses.APIErrorResponse "Invalid task"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Follow-up task not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/consultation-record/tasks/{taskId} [put]
+func UpdateFollowUpTaskHandler(c *gin.Context) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+	if party == "" {
+		responses.NewAPIResponse(c).Forbidden("Only the appointment's lawyer or client can do this", responses.ErrCodeForbidden)
+		return
+	}
+
+	taskID, err := strconv.Atoi(c.Param("taskId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid follow-up task ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req UpdateFollowUpTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	consultationService := services.NewConsultationService()
+	record, err := consultationService.GetRecord(appointment.ID)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+	task, err := consultationService.GetTask(record.ID, taskID)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	if party == string(models.RoleClient) {
+		// Clients only know about their own tasks on shared records
+		if record.SharedAt == nil || task.Assignee != models.FollowUpAssigneeClient {
+			responses.NewAPIResponse(c).NotFound("Follow-up task not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		if req.Assignee != nil || req.Title != nil || req.Description != nil || req.DueDate != nil {
+			responses.NewAPIResponse(c).Forbidden("Clients can only update the status field", responses.ErrCodeForbidden)
+			return
+		}
+	}
+
+	before := services.AuditSnapshot(task)
+	if req.Assignee != nil {
+		task.Assignee = *req.Assignee
+	}
+	if req.Title != nil {
+		task.Title = *req.Title
+	}
+	if req.Description != nil {
+		task.Description = req.Description
+	}
+	if req.DueDate != nil {
+		task.DueDate = *req.DueDate
+	}
+	if req.Status != nil {
+		task.Status = *req.Status
+	}
+	if err := consultationService.UpdateTask(task); err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityFollowUpTask, task.ID, before, task)
+	responses.NewAPIResponse(c).OK(task)
+}
+
+// @Summary Delete follow-up task
+// @Description Removes a follow-up task from the appointment's consultation record
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param taskId path int true "Follow-up task ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid task ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Follow-up task not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/consultation-record/tasks/{taskId} [delete]
+func DeleteFollowUpTaskHandler(c *gin.Context) {
+	appointment, ok := loadAppointmentForLawyer(c)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(c.Param("taskId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid follow-up task ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	consultationService := services.NewConsultationService()
+	record, err := consultationService.GetRecord(appointment.ID)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+	task, err := consultationService.GetTask(record.ID, taskID)
+	if err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	if err := consultationService.DeleteTask(record.ID, taskID); err != nil {
+		respondConsultationError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityFollowUpTask, taskID, task, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Follow-up task deleted successfully"})
+}
+
+// @Summary List my follow-up tasks
+// @Description Lists the follow-up tasks across the current lawyer's consultations in due date order
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "Only tasks in this status (open or done)"
+// @Success 200 {array} models.FollowUpTask
+// @Failure 400 {object} responses.APIErrorResponse "Invalid status"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/follow-up-tasks [get]
+func GetMyFollowUpTasksHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && status != models.FollowUpTaskOpen && status != models.FollowUpTaskDone {
+		responses.NewAPIResponse(c).BadRequest("Status must be open or done", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	tasks, err := services.NewConsultationService().ListLawyerTasks(lawyer.ID, status)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve follow-up tasks", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(tasks)
+}
+
+func respondConsultationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidConsultationRecord), errors.Is(err, services.ErrInvalidFollowUpTask):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrConsultationRecordNotFound):
+		responses.NewAPIResponse(c).NotFound("Consultation record not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrFollowUpTaskNotFound):
+		responses.NewAPIResponse(c).NotFound("Follow-up task not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrConsultationRecordExists):
+		responses.NewAPIResponse(c).Conflict("Consultation record was created by another request", responses.ErrCodeResourceAlreadyExists)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to save consultation record", responses.ErrCodeDatabaseError)
+	}
+}
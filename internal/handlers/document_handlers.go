@@ -0,0 +1,712 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// maxDocumentFileSize bounds files uploaded to a matter's vault to 50 MB
+const maxDocumentFileSize = 50 << 20
+
+// DocumentFolderRequest creates, renames or moves a folder in a matter's vault
+type DocumentFolderRequest struct {
+	Name string `json:"name" binding:"required"`
+	// ParentID is the folder to put it in; omit for the top of the vault
+	ParentID *int `json:"parent_id,omitempty"`
+}
+
+// UpdateDocumentRequest changes a document. Omitted fields are left as they are.
+type UpdateDocumentRequest struct {
+	Title *string `json:"title,omitempty"`
+	// FolderID moves the document; 0 moves it to the top of the vault
+	FolderID   *int    `json:"folder_id,omitempty"`
+	Visibility *string `json:"visibility,omitempty" binding:"omitempty,oneof=lawyer_only shared"`
+}
+
+// loadVaultDocument loads the document in the path from the matter's vault. The
+// client only finds documents shared with them.
+func loadVaultDocument(c *gin.Context, matter *models.Matter, party string) (*models.Document, bool) {
+	documentID, err := strconv.Atoi(c.Param("documentId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid document ID", responses.ErrCodeInvalidRequest)
+		return nil, false
+	}
+	document, err := services.NewDocumentService().GetDocument(matter.ID, documentID, party == string(models.RoleClient))
+	if err != nil {
+		respondDocumentError(c, err)
+		return nil, false
+	}
+	return document, true
+}
+
+// receiveVaultFile stores the uploaded "file" form field for the matter's vault and
+// returns its unsaved attachment. With optional set, a missing file returns nil.
+func receiveVaultFile(c *gin.Context, matter *models.Matter, optional bool) (*models.Attachment, bool) {
+	userID, _ := middleware.GetUserID(c)
+
+	file, header, err := c.Request.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) && optional {
+		return nil, true
+	}
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("File is required: "+err.Error(), responses.ErrCodeInvalidRequest)
+		return nil, false
+	}
+	defer file.Close()
+	if header.Size > maxDocumentFileSize {
+		responses.NewAPIResponse(c).BadRequest("File must be at most 50 MB", responses.ErrCodeValidationFailed)
+		return nil, false
+	}
+
+	key := fmt.Sprintf("documents/matters/%d/%d_%d%s", matter.ID, userID, time.Now().UnixNano(), filepath.Ext(header.Filename))
+	if _, err := services.NewUtilService().UploadFileToS3(c.Request.Context(), key, file, header.Header.Get("Content-Type")); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Upload failed", responses.ErrCodeOperationFailed)
+		return nil, false
+	}
+
+	return &models.Attachment{
+		FileName: header.Filename,
+		FileSize: int(header.Size),
+		FileType: header.Header.Get("Content-Type"),
+		FilePath: key,
+	}, true
+}
+
+// optionalFormString returns a form field, or nil when it is absent or blank
+func optionalFormString(c *gin.Context, key string) *string {
+	value := strings.TrimSpace(c.PostForm(key))
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// @Summary List document folders
+// @Description Lists the folders in a matter's document vault by name. Each folder gives its parent, so clients can build the tree.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Success 200 {array} models.DocumentFolder
+// @Failure 400 {object} responses.APIErrorResponse "Invalid matter ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/folders [get]
+func GetDocumentFoldersHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+
+	folders, err := services.NewDocumentService().ListFolders(matter.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve folders", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(folders)
+}
+
+// @Summary Create a document folder
+// @Description Adds a folder to a matter's document vault, at the top or inside another folder
+// @Tags matters
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param folder body DocumentFolderRequest true "Folder"
+// @Success 201 {object} models.DocumentFolder
+// @Failure 400 {object} responses.APIErrorResponse "Invalid folder or name already used"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/folders [post]
+func CreateDocumentFolderHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	var req DocumentFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	folder := &models.DocumentFolder{
+		MatterID:  matter.ID,
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		CreatedBy: userID,
+	}
+	if err := services.NewDocumentService().SaveFolder(folder); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityDocumentFolder, folder.ID, nil, folder)
+	responses.NewAPIResponse(c).Created(folder)
+}
+
+// @Summary Update a document folder
+// @Description Renames a folder or moves it to another parent. A folder cannot be moved inside itself.
+// @Tags matters
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param folderId path int true "Folder ID"
+// @Param folder body DocumentFolderRequest true "Folder"
+// @Success 200 {object} models.DocumentFolder
+// @Failure 400 {object} responses.APIErrorResponse "Invalid folder or name already used"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or folder not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/folders/{folderId} [put]
+func UpdateDocumentFolderHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+
+	folderID, err := strconv.Atoi(c.Param("folderId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid folder ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req DocumentFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	documentService := services.NewDocumentService()
+	folder, err := documentService.GetFolder(matter.ID, folderID)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+	before := services.AuditSnapshot(folder)
+
+	folder.Name = req.Name
+	folder.ParentID = req.ParentID
+	if err := documentService.SaveFolder(folder); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityDocumentFolder, folder.ID, before, folder)
+	responses.NewAPIResponse(c).OK(folder)
+}
+
+// @Summary Delete a document folder
+// @Description Removes an empty folder from a matter's document vault
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param folderId path int true "Folder ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Folder is not empty"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or folder not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/folders/{folderId} [delete]
+func DeleteDocumentFolderHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+
+	folderID, err := strconv.Atoi(c.Param("folderId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid folder ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	documentService := services.NewDocumentService()
+	folder, err := documentService.GetFolder(matter.ID, folderID)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+	if err := documentService.DeleteFolder(folder); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityDocumentFolder, folder.ID, folder, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Folder deleted successfully"})
+}
+
+// @Summary List documents
+// @Description Lists the documents in a matter's vault by title, optionally only those in one folder. The client only sees documents shared with them.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param folder_id query int false "Only documents in this folder"
+// @Success 200 {array} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Invalid matter or folder ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents [get]
+func GetDocumentsHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+
+	var folderID *int
+	if raw := c.Query("folder_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			responses.NewAPIResponse(c).BadRequest("Invalid folder ID", responses.ErrCodeInvalidRequest)
+			return
+		}
+		folderID = &id
+	}
+
+	documents, err := services.NewDocumentService().ListDocuments(matter.ID, folderID, party == string(models.RoleClient))
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve documents", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(documents)
+}
+
+// @Summary Upload a document
+// @Description Adds a document to a matter's vault with the uploaded file as its first version. The lawyer chooses whether the client can see it; documents the client uploads are always shared. The other side is notified of shared documents.
+// @Tags matters
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param file formData file true "File, at most 50 MB"
+// @Param title formData string false "Title, defaults to the file name"
+// @Param folder_id formData int false "Folder to put the document in"
+// @Param visibility formData string false "lawyer_only (the default) or shared; lawyer only"
+// @Param note formData string false "Note on this version"
+// @Success 201 {object} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document, missing file or matter closed"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents [post]
+func CreateDocumentHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	if party == "" {
+		responses.NewAPIResponse(c).Forbidden("Only the matter's lawyer and client can upload documents", responses.ErrCodeForbidden)
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	document := &models.Document{
+		MatterID:   matter.ID,
+		Title:      c.PostForm("title"),
+		Visibility: c.DefaultPostForm("visibility", models.DocumentVisibilityLawyerOnly),
+		CreatedBy:  userID,
+	}
+	if party == string(models.RoleClient) {
+		document.Visibility = models.DocumentVisibilityShared
+	}
+	if raw := c.PostForm("folder_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			responses.NewAPIResponse(c).BadRequest("Invalid folder ID", responses.ErrCodeInvalidRequest)
+			return
+		}
+		document.FolderID = &id
+	}
+	if strings.TrimSpace(document.Title) == "" {
+		if file, header, err := c.Request.FormFile("file"); err == nil {
+			file.Close()
+			document.Title = header.Filename
+		}
+	}
+
+	documentService := services.NewDocumentService()
+	if err := documentService.ValidateDocument(matter, document); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+	file, ok := receiveVaultFile(c, matter, false)
+	if !ok {
+		return
+	}
+
+	if err := documentService.CreateDocument(matter, document, optionalFormString(c, "note"), file); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityDocument, document.ID, nil, document)
+	responses.NewAPIResponse(c).Created(document)
+}
+
+// @Summary Get a document
+// @Description Returns a document in a matter's vault with all its versions, newest first, and who has it checked out
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Router /matters/{id}/documents/{documentId} [get]
+func GetDocumentHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, party)
+	if !ok {
+		return
+	}
+	responses.NewAPIResponse(c).OK(document)
+}
+
+// @Summary Update a document
+// @Description Renames a document, moves it to another folder or changes whether the client can see it. Hiding a document from the client releases their check-out of it.
+// @Tags matters
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Param document body UpdateDocumentRequest true "Changes"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document or matter closed"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId} [put]
+func UpdateDocumentHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, string(models.RoleLawyer))
+	if !ok {
+		return
+	}
+
+	var req UpdateDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	before := services.AuditSnapshot(document)
+	wasShared := document.Shared()
+	if req.Title != nil {
+		document.Title = *req.Title
+	}
+	if req.FolderID != nil {
+		document.FolderID = req.FolderID
+		if *req.FolderID == 0 {
+			document.FolderID = nil
+		}
+	}
+	if req.Visibility != nil {
+		document.Visibility = *req.Visibility
+	}
+
+	if err := services.NewDocumentService().UpdateDocument(matter, document, wasShared); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityDocument, document.ID, before, document)
+	responses.NewAPIResponse(c).OK(document)
+}
+
+// @Summary Delete a document
+// @Description Removes a document from a matter's vault. Its versions are kept for the record.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId} [delete]
+func DeleteDocumentHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, string(models.RoleLawyer))
+	if !ok {
+		return
+	}
+
+	if err := services.NewDocumentService().DeleteDocument(document); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityDocument, document.ID, document, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Document deleted successfully"})
+}
+
+// @Summary Check out a document
+// @Description Locks a document so only the current user can upload its next version, until they check it in. The client can check out documents shared with them.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Matter closed"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Failure 409 {object} responses.APIErrorResponse "Checked out by someone else"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId}/checkout [post]
+func CheckOutDocumentHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	if party == "" {
+		responses.NewAPIResponse(c).Forbidden("Only the matter's lawyer and client can check out documents", responses.ErrCodeForbidden)
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, party)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	if err := services.NewDocumentService().CheckOut(matter, document, userID); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCheckOut, models.AuditEntityDocument, document.ID, nil, gin.H{"checked_out_by": userID})
+	responses.NewAPIResponse(c).OK(document)
+}
+
+// @Summary Check in a document
+// @Description Releases the current user's check-out of a document. With a file, the file is first stored as the document's next version; without one the check-out is abandoned. The other side is notified of new versions of shared documents.
+// @Tags matters
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Param file formData file false "New version, at most 50 MB"
+// @Param note formData string false "Note on the new version"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Not checked out by the current user"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId}/checkin [post]
+func CheckInDocumentHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, party)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	documentService := services.NewDocumentService()
+	if err := documentService.CanCheckIn(document, userID); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+	file, ok := receiveVaultFile(c, matter, true)
+	if !ok {
+		return
+	}
+
+	if err := documentService.CheckIn(matter, document, userID, optionalFormString(c, "note"), file); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCheckIn, models.AuditEntityDocument, document.ID,
+		gin.H{"checked_out_by": userID}, gin.H{"current_version": document.CurrentVersion})
+	responses.NewAPIResponse(c).OK(document)
+}
+
+// @Summary Release a document check-out
+// @Description Lets the matter's lawyer unlock a document someone left checked out. Nothing they have not checked in is kept.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId}/checkout [delete]
+func ReleaseDocumentCheckOutHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, string(models.RoleLawyer))
+	if !ok {
+		return
+	}
+	before := gin.H{"checked_out_by": document.CheckedOutBy}
+
+	if err := services.NewDocumentService().ReleaseCheckOut(document); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityDocument, document.ID, before, gin.H{"checked_out_by": nil})
+	responses.NewAPIResponse(c).OK(document)
+}
+
+// @Summary Get document download URL
+// @Description Generates a presigned URL to download a version of a document, the current one by default. Every download is logged for the matter's lawyer.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Param version query int false "Version number"
+// @Success 200 {string} string "Presigned URL to download the document"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document ID or version"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter, document or version not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId}/download [get]
+func GetDocumentDownloadURLHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, party)
+	if !ok {
+		return
+	}
+
+	number := 0
+	if raw := c.Query("version"); raw != "" {
+		var err error
+		if number, err = strconv.Atoi(raw); err != nil || number < 1 {
+			responses.NewAPIResponse(c).BadRequest("Invalid version", responses.ErrCodeInvalidRequest)
+			return
+		}
+	}
+
+	documentService := services.NewDocumentService()
+	version, err := documentService.GetVersion(document, number)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	url, err := services.NewUtilService().GetAttachmentURL(c.Request.Context(), version.Attachment.FilePath, 15*time.Minute)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to generate download URL", responses.ErrCodeOperationFailed)
+		return
+	}
+
+	// The URL is only handed out once the download is on record
+	audit := middleware.GetAuditContext(c)
+	download := &models.DocumentDownload{
+		DocumentID: document.ID,
+		VersionID:  version.ID,
+		UserID:     audit.ActorID,
+		IPAddress:  &audit.IPAddress,
+		UserAgent:  &audit.UserAgent,
+	}
+	if audit.ImpersonatorID != 0 {
+		download.ImpersonatorID = &audit.ImpersonatorID
+	}
+	if err := documentService.RecordDownload(download); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to record download", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(url)
+}
+
+// @Summary List document downloads
+// @Description Lists who downloaded which version of a document and when, newest first. Lawyer and staff only.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param documentId path int true "Document ID"
+// @Success 200 {array} models.DocumentDownload
+// @Failure 400 {object} responses.APIErrorResponse "Invalid document ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or document not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/documents/{documentId}/downloads [get]
+func GetDocumentDownloadsHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	if party == string(models.RoleClient) {
+		responses.NewAPIResponse(c).Forbidden("Only the lawyer can review downloads", responses.ErrCodeForbidden)
+		return
+	}
+	document, ok := loadVaultDocument(c, matter, party)
+	if !ok {
+		return
+	}
+
+	downloads, err := services.NewDocumentService().ListDownloads(document.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve downloads", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(downloads)
+}
+
+func respondDocumentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidDocument), errors.Is(err, services.ErrInvalidFolder):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrDocumentCheckedOut):
+		responses.NewAPIResponse(c).Conflict("Document is checked out by someone else", responses.ErrCodeConflict)
+	case errors.Is(err, services.ErrDocumentNotFound):
+		responses.NewAPIResponse(c).NotFound("Document not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrFolderNotFound):
+		responses.NewAPIResponse(c).NotFound("Folder not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrDocumentVersionNotFound):
+		responses.NewAPIResponse(c).NotFound("Version not found", responses.ErrCodeResourceNotFound)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to save document", responses.ErrCodeDatabaseError)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// ExternalCalendarRequest connects an external calendar whose busy time blocks bookings
+type ExternalCalendarRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Kind is "ics" for a calendar file URL or "caldav" for a CalDAV collection
+	Kind     string  `json:"kind" binding:"required"`
+	URL      string  `json:"url" binding:"required"`
+	Username *string `json:"username,omitempty"`
+	// Password is stored encrypted. On update, omit it to keep the stored one or send
+	// an empty string to remove it.
+	Password *string `json:"password,omitempty"`
+	Enabled  *bool   `json:"enabled,omitempty"`
+}
+
+// loadMyExternalCalendar loads the calendar in the calendarId path parameter if it
+// belongs to the current lawyer, writing the error response itself on failure
+func loadMyExternalCalendar(c *gin.Context) (*models.ExternalCalendar, bool) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return nil, false
+	}
+
+	calendarID, err := strconv.Atoi(c.Param("calendarId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid calendar ID", responses.ErrCodeInvalidRequest)
+		return nil, false
+	}
+
+	calendar, err := services.NewExternalCalendarService().GetCalendar(lawyer.ID, calendarID)
+	if err != nil {
+		respondExternalCalendarError(c, err)
+		return nil, false
+	}
+	return calendar, true
+}
+
+// @Summary List external calendars
+// @Description Lists the calendars the current lawyer has connected to block bookings during their busy time
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ExternalCalendar
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/calendars [get]
+func GetMyExternalCalendarsHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	calendars, err := services.NewExternalCalendarService().ListCalendars(lawyer.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve calendars", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(calendars)
+}
+
+// @Summary Connect external calendar
+// @Description Connects an ICS or CalDAV calendar, such as a court schedule, and imports its busy time at once. Busy time is treated as unavailable when clients book. A failed first import is reported in last_sync_error and retried periodically.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param calendar body ExternalCalendarRequest true "Calendar"
+// @Success 201 {object} models.ExternalCalendar
+// @Failure 400 {object} responses.APIErrorResponse "Invalid calendar"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/calendars [post]
+func CreateExternalCalendarHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	var req ExternalCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	calendar := &models.ExternalCalendar{
+		LawyerID: lawyer.ID,
+		Name:     req.Name,
+		Kind:     req.Kind,
+		URL:      req.URL,
+		Username: req.Username,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+	calendarService := services.NewExternalCalendarService()
+	if err := calendarService.CreateCalendar(calendar, req.Password); err != nil {
+		respondExternalCalendarError(c, err)
+		return
+	}
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityExternalCalendar, calendar.ID, nil, calendar)
+
+	// The result of the first import is reported on the calendar itself
+	if calendar.Enabled {
+		_ = calendarService.SyncCalendar(calendar)
+	}
+
+	responses.NewAPIResponse(c).Created(calendar)
+}
+
+// @Summary Update external calendar
+// @Description Replaces a connected calendar's settings and imports its busy time again. Disabling a calendar removes its busy time at once.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param calendarId path int true "Calendar ID"
+// @Param calendar body ExternalCalendarRequest true "Calendar"
+// @Success 200 {object} models.ExternalCalendar
+// @Failure 400 {object} responses.APIErrorResponse "Invalid calendar"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Calendar not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/calendars/{calendarId} [put]
+func UpdateExternalCalendarHandler(c *gin.Context) {
+	calendar, ok := loadMyExternalCalendar(c)
+	if !ok {
+		return
+	}
+
+	var req ExternalCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	before := services.AuditSnapshot(calendar)
+	calendar.Name = req.Name
+	calendar.Kind = req.Kind
+	calendar.URL = req.URL
+	calendar.Username = req.Username
+	if req.Enabled != nil {
+		calendar.Enabled = *req.Enabled
+	}
+
+	calendarService := services.NewExternalCalendarService()
+	if err := calendarService.UpdateCalendar(calendar, req.Password); err != nil {
+		respondExternalCalendarError(c, err)
+		return
+	}
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityExternalCalendar, calendar.ID, before, calendar)
+
+	// Import again so a changed address or password takes effect at once
+	if calendar.Enabled {
+		_ = calendarService.SyncCalendar(calendar)
+	}
+
+	responses.NewAPIResponse(c).OK(calendar)
+}
+
+// @Summary Disconnect external calendar
+// @Description Removes a connected calendar and its busy time
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param calendarId path int true "Calendar ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid calendar ID"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Calendar not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/calendars/{calendarId} [delete]
+func DeleteExternalCalendarHandler(c *gin.Context) {
+	calendar, ok := loadMyExternalCalendar(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewExternalCalendarService().DeleteCalendar(calendar.LawyerID, calendar.ID); err != nil {
+		respondExternalCalendarError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityExternalCalendar, calendar.ID, calendar, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Calendar disconnected successfully"})
+}
+
+// @Summary Sync external calendar
+// @Description Imports a connected calendar's busy time now instead of waiting for the next periodic sync
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param calendarId path int true "Calendar ID"
+// @Success 200 {object} models.ExternalCalendar
+// @Failure 400 {object} responses.APIErrorResponse "Calendar is disabled"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Calendar not found"
+// @Failure 502 {object} responses.APIErrorResponse "Calendar server could not be read"
+// @Router /lawyers/profile/calendars/{calendarId}/sync [post]
+func SyncExternalCalendarHandler(c *gin.Context) {
+	calendar, ok := loadMyExternalCalendar(c)
+	if !ok {
+		return
+	}
+	if !calendar.Enabled {
+		responses.NewAPIResponse(c).BadRequest("Enable the calendar before syncing it", responses.ErrCodeValidationFailed)
+		return
+	}
+
+	if err := services.NewExternalCalendarService().SyncCalendar(calendar); err != nil {
+		respondExternalCalendarError(c, err)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(calendar)
+}
+
+// @Summary List imported busy time
+// @Description Lists the busy periods imported from a connected calendar
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param calendarId path int true "Calendar ID"
+// @Success 200 {array} models.BusyBlock
+// @Failure 400 {object} responses.APIErrorResponse "Invalid calendar ID"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Calendar not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/calendars/{calendarId}/busy [get]
+func GetExternalCalendarBusyHandler(c *gin.Context) {
+	calendar, ok := loadMyExternalCalendar(c)
+	if !ok {
+		return
+	}
+
+	blocks, err := services.NewExternalCalendarService().GetBusyBlocks(calendar.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve busy time", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(blocks)
+}
+
+func respondExternalCalendarError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCalendar):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrExternalCalendarNotFound):
+		responses.NewAPIResponse(c).NotFound("Calendar not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrCalendarSyncFailed):
+		responses.NewAPIResponse(c).Error(http.StatusBadGateway, err.Error(), responses.ErrCodeOperationFailed)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to save calendar", responses.ErrCodeDatabaseError)
+	}
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kotolino/lawyer/config"
 	"github.com/kotolino/lawyer/internal/handlers/responses"
 	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
 	"github.com/kotolino/lawyer/internal/services"
 )
 
@@ -18,13 +21,39 @@ func HomeHandler(c *gin.Context) {
 	})
 }
 
+// @Summary JSON Web Key Set
+// @Description Publishes the public keys used to sign session tokens so other services can verify them
+// @Tags auth
+// @Produce json
+// @Success 200 {object} middleware.JWKS
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, middleware.GetJWKS(services.GetConfig()))
+}
+
 // SetupRoutes configures all the routes for our application
 func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 	// Initialize services with configuration
 	services.InitServices(cfg)
 
+	// Load JWT signing keys; a misconfigured key set must stop startup
+	if err := middleware.InitSigningKeys(cfg); err != nil {
+		panic(fmt.Sprintf("failed to load JWT signing keys: %v", err))
+	}
+
+	// Only believe forwarded client IPs from configured proxies, so rate limits
+	// keyed on the IP cannot be dodged by sending X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		panic(fmt.Sprintf("invalid trusted proxies: %v", err))
+	}
+
+	// Tag every request so audit entries and logs can be correlated
+	router.Use(middleware.RequestID())
+
 	// Public routes
 	router.GET("/", HomeHandler)
+	router.GET("/.well-known/jwks.json", JWKSHandler)
 	
 	// Support form endpoint
 	router.POST("/api/support/contact", ContactSupportHandler)
@@ -44,10 +73,17 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 
 		// Get public lawyer profile by ID
 		publicApi.GET("/lawyers/:id", PublicGetLawyerByIDHandler)
+		publicApi.GET("/lawyers/:id/intake-forms", GetLawyerIntakeFormsHandler)
 		publicApi.GET("/reviews/lawyer/:id", GetReviewsForLawyerHandler) // Get reviews for a specific lawyer
 
 		// Get public reviews for a lawyer
 		publicApi.GET("/reviews/pinned", GetPinnedReviewsHandler)
+
+		// Holiday calendar used for booking
+		publicApi.GET("/holidays", GetHolidayCalendarHandler)
+
+		// iCalendar subscription feed, authenticated by its secret token
+		publicApi.GET("/calendar/:token", GetCalendarFeedDocumentHandler)
 	}
 
 	// Auth routes (no authentication required)
@@ -57,6 +93,9 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 	router.POST("/api/auth/resend-verification", ResendVerificationEmailHandler)
 	router.POST("/api/auth/forgot-password", ForgotPasswordHandler)
 	router.POST("/api/auth/reset-password", ResetPasswordHandler)
+	router.POST("/api/auth/mfa/verify", VerifyMFALoginHandler)
+	router.POST("/api/auth/mfa/setup", StartMFASetupHandler)
+	router.POST("/api/auth/mfa/setup/confirm", ConfirmMFASetupHandler)
 
 	// API routes (authentication required)
 	api := router.Group("/api")
@@ -65,6 +104,17 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 		// Current user route
 		api.GET("/auth/me", GetCurrentUserHandler)
 		api.POST("/auth/logout", LogoutHandler)
+		api.GET("/auth/mfa", GetMFAStatusHandler)
+		api.POST("/auth/mfa/enroll", BeginMFAEnrollmentHandler)
+		api.POST("/auth/mfa/enroll/confirm", ConfirmMFAEnrollmentHandler)
+		api.POST("/auth/mfa/disable", DisableMFAHandler)
+		api.POST("/auth/mfa/recovery-codes", RegenerateRecoveryCodesHandler)
+		api.POST("/auth/impersonation/end", EndImpersonationHandler)
+
+		// Calendar feed routes
+		api.GET("/calendar/feed", GetCalendarFeedHandler)
+		api.POST("/calendar/feed", RotateCalendarFeedHandler)
+		api.DELETE("/calendar/feed", RevokeCalendarFeedHandler)
 
 		// User routes
 		users := api.Group("/users")
@@ -76,23 +126,60 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 			users.PUT("/:id", UpdateUserHandler)                // Update user profile
 			users.PATCH("/:id/password", UpdatePasswordHandler) // Update user password
 
-			// Admin-only routes
+			// Staff routes
+			users.GET("/stats", middleware.RequirePermission(models.PermAdminDashboard), GetAdminStatsHandler)
 			admin := users.Group("/")
-			admin.Use(middleware.RequireRole("admin"))
+			admin.Use(middleware.RequirePermission(models.PermUsersManage))
 			{
-				admin.GET("/stats", GetAdminStatsHandler)
 				admin.POST("/create-user", CreateUserHandler)
 				admin.PATCH("/:id/status", UpdateUserStatusHandler) // Update user status
 				admin.PATCH("/:id/role", UpdateUserRoleHandler)     // Update user role
 				admin.DELETE("/:id", DeleteUserHandler)             // Delete user
+				admin.DELETE("/:id/mfa", ResetUserMFAHandler)       // Reset two-factor authentication
+				admin.DELETE("/:id/no-shows", ResetUserNoShowsHandler) // Lift a no-show booking restriction
 			}
 		}
 
 		admin := api.Group("/admin")
-		admin.Use(middleware.RequireRole("admin"))
 		{
-			admin.GET("/stats", GetAdminStatsHandler)
-			admin.GET("/chart", GetAdminChartDataHandler)
+			admin.GET("/stats", middleware.RequirePermission(models.PermAdminDashboard), GetAdminStatsHandler)
+			admin.GET("/chart", middleware.RequirePermission(models.PermAdminDashboard), GetAdminChartDataHandler)
+			admin.GET("/settings/mfa", middleware.RequirePermission(models.PermSettingsManage), GetMFASettingsHandler)
+			admin.PUT("/settings/mfa", middleware.RequirePermission(models.PermSettingsManage), UpdateMFASettingsHandler)
+			admin.GET("/settings/reschedule", middleware.RequirePermission(models.PermSettingsManage), GetRescheduleSettingsHandler)
+			admin.PUT("/settings/reschedule", middleware.RequirePermission(models.PermSettingsManage), UpdateRescheduleSettingsHandler)
+			admin.GET("/settings/meeting-links", middleware.RequirePermission(models.PermSettingsManage), GetMeetingLinkSettingsHandler)
+			admin.PUT("/settings/meeting-links", middleware.RequirePermission(models.PermSettingsManage), UpdateMeetingLinkSettingsHandler)
+			admin.GET("/settings/attendance", middleware.RequirePermission(models.PermSettingsManage), GetAttendanceSettingsHandler)
+			admin.PUT("/settings/attendance", middleware.RequirePermission(models.PermSettingsManage), UpdateAttendanceSettingsHandler)
+			admin.GET("/settings/waitlist", middleware.RequirePermission(models.PermSettingsManage), GetWaitlistSettingsHandler)
+			admin.PUT("/settings/waitlist", middleware.RequirePermission(models.PermSettingsManage), UpdateWaitlistSettingsHandler)
+			admin.GET("/login-attempts", middleware.RequirePermission(models.PermSecurityAudit), GetLoginAttemptsHandler)
+			admin.GET("/audit", middleware.RequirePermission(models.PermSecurityAudit), GetAuditLogsHandler)
+			admin.GET("/audit/export", middleware.RequirePermission(models.PermSecurityAudit), ExportAuditLogsHandler)
+			admin.GET("/audit/verify", middleware.RequirePermission(models.PermSecurityAudit), VerifyAuditLogHandler)
+
+			admin.POST("/lawyers/:id/history/:historyId/restore", middleware.RequirePermission(models.PermLawyersManage), RestoreLawyerVersionHandler)
+
+			// Platform-wide closure days
+			admin.GET("/closures", middleware.RequirePermission(models.PermSettingsManage), GetPlatformClosuresHandler)
+			admin.POST("/closures", middleware.RequirePermission(models.PermSettingsManage), CreatePlatformClosureHandler)
+			admin.DELETE("/closures/:id", middleware.RequirePermission(models.PermSettingsManage), DeletePlatformClosureHandler)
+
+			// Impersonation
+			admin.POST("/impersonate/:id", middleware.RequirePermission(models.PermImpersonate), StartImpersonationHandler)
+			admin.GET("/impersonations", middleware.RequirePermission(models.PermSecurityAudit), GetImpersonationSessionsHandler)
+			admin.GET("/impersonations/:id/requests", middleware.RequirePermission(models.PermSecurityAudit), GetImpersonationRequestsHandler)
+
+			// Permission management
+			perms := admin.Group("/")
+			perms.Use(middleware.RequirePermission(models.PermPermissionsManage))
+			{
+				perms.GET("/permissions", GetPermissionsHandler)                    // List permissions and role mapping
+				perms.PUT("/roles/:role/permissions", UpdateRolePermissionsHandler) // Replace a role's permissions
+				perms.GET("/users/:id/permissions", GetUserPermissionsHandler)      // Get a user's permissions
+				perms.PUT("/users/:id/permissions", UpdateUserPermissionsHandler)   // Replace a user's granted permissions
+			}
 		}
 
 		// Appointment routes
@@ -101,13 +188,58 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 			appointments.GET("", GetAppointmentsHandler)                  // List all appointments
 			appointments.GET("/upcoming", GetUpcomingAppointmentsHandler) // Get upcoming appointments
 			appointments.GET("/available-times", GetAvailableTimeSlotsHandler)
+			appointments.GET("/available-calendar", GetAvailabilityCalendarHandler) // Slots for a range of dates
 			appointments.GET("/:id", GetAppointmentByIDHandler)       // Get appointment by ID
+			appointments.GET("/:id/status-history", GetAppointmentStatusHistoryHandler)
+			appointments.GET("/:id/reschedule-proposals", GetRescheduleProposalsHandler)
+			appointments.POST("/:id/reschedule-proposals", ProposeRescheduleHandler)
+			appointments.POST("/:id/reschedule-proposals/:proposalId/accept", AcceptRescheduleHandler)
+			appointments.POST("/:id/reschedule-proposals/:proposalId/decline", DeclineRescheduleHandler)
+			appointments.POST("/:id/attendance", ConfirmAttendanceHandler)
+			appointments.GET("/:id/conflict-checks", GetConflictChecksHandler)
+			appointments.POST("/:id/conflict-checks", RunConflictCheckHandler)
+			appointments.POST("/:id/conflict-checks/:checkId/acknowledge", AcknowledgeConflictCheckHandler)
+			appointments.GET("/:id/consultation-record", GetConsultationRecordHandler)
+			appointments.PUT("/:id/consultation-record", SaveConsultationRecordHandler)
+			appointments.POST("/:id/consultation-record/share", ShareConsultationRecordHandler)
+			appointments.POST("/:id/consultation-record/tasks", CreateFollowUpTaskHandler)
+			appointments.PUT("/:id/consultation-record/tasks/:taskId", UpdateFollowUpTaskHandler)
+			appointments.DELETE("/:id/consultation-record/tasks/:taskId", DeleteFollowUpTaskHandler)
+			appointments.POST("/intake-files", UploadIntakeFileHandler)
 			appointments.POST("", CreateAppointmentHandler)           // Create new appointment
 			appointments.PUT("/reject/:id", RejectAppointmentHandler) // Lawyer/admin rejects appointment
 			appointments.PUT("/:id", UpdateAppointmentHandler)        // Update appointment
 			appointments.DELETE("/:id", DeleteAppointmentHandler)     // Delete appointment
 		}
 
+		// Matter routes
+		matters := api.Group("/matters")
+		{
+			matters.GET("", GetMattersHandler)                                                 // List current user's matters
+			matters.POST("", CreateMatterHandler)                                              // Lawyer opens a matter
+			matters.GET("/:id", GetMatterHandler)                                              // Get matter by ID
+			matters.PUT("/:id", UpdateMatterHandler)                                           // Update matter
+			matters.POST("/:id/appointments", LinkMatterAppointmentHandler)                    // Group an appointment under the matter
+			matters.DELETE("/:id/appointments/:appointmentId", UnlinkMatterAppointmentHandler) // Take an appointment out of the matter
+			matters.GET("/:id/timeline", GetMatterTimelineHandler)                             // Matter history
+
+			// Document vault
+			matters.GET("/:id/folders", GetDocumentFoldersHandler)
+			matters.POST("/:id/folders", CreateDocumentFolderHandler)
+			matters.PUT("/:id/folders/:folderId", UpdateDocumentFolderHandler)
+			matters.DELETE("/:id/folders/:folderId", DeleteDocumentFolderHandler)
+			matters.GET("/:id/documents", GetDocumentsHandler)
+			matters.POST("/:id/documents", CreateDocumentHandler)
+			matters.GET("/:id/documents/:documentId", GetDocumentHandler)
+			matters.PUT("/:id/documents/:documentId", UpdateDocumentHandler)
+			matters.DELETE("/:id/documents/:documentId", DeleteDocumentHandler)
+			matters.POST("/:id/documents/:documentId/checkout", CheckOutDocumentHandler)
+			matters.DELETE("/:id/documents/:documentId/checkout", ReleaseDocumentCheckOutHandler)
+			matters.POST("/:id/documents/:documentId/checkin", CheckInDocumentHandler)
+			matters.GET("/:id/documents/:documentId/download", GetDocumentDownloadURLHandler)
+			matters.GET("/:id/documents/:documentId/downloads", GetDocumentDownloadsHandler)
+		}
+
 		// Review routes
 		reviews := api.Group("/reviews")
 		{
@@ -119,15 +251,25 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 			reviews.PUT("/:id", UpdateReviewHandler)               // Update review
 			reviews.DELETE("/:id", DeleteReviewHandler)            // Delete review
 
-			// Admin only route for updating review status
+			// Moderation routes for updating review status
 			adminReviews := reviews.Group("/")
-			adminReviews.Use(middleware.RequireRole("admin"))
+			adminReviews.Use(middleware.RequirePermission(models.PermReviewsModerate))
 			{
 				adminReviews.PATCH("/:id/pin", PinReviewHandler)             // pin/unpin review
 				adminReviews.PATCH("/:id/status", UpdateReviewStatusHandler) // Update review status
 			}
 		}
 
+		// Waitlist routes
+		waitlist := api.Group("/waitlist")
+		{
+			waitlist.GET("", GetMyWaitlistEntriesHandler)               // List current user's waitlist entries
+			waitlist.POST("", JoinWaitlistHandler)                      // Join a lawyer's waitlist
+			waitlist.DELETE("/:id", CancelWaitlistEntryHandler)         // Leave a waitlist
+			waitlist.GET("/holds", GetMySlotHoldsHandler)               // Slots held for the current user
+			waitlist.POST("/holds/:id/decline", DeclineSlotHoldHandler) // Give up a held slot
+		}
+
 		// Notification routes
 		notifications := api.Group("/notifications")
 		{
@@ -142,6 +284,7 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 
 		// Article routes
 		articles := api.Group("/articles")
+		articles.Use(middleware.RequirePermission(models.PermArticlesWrite))
 		{
 			articles.POST("", CreateArticleHandler)       // Create new article
 			articles.PUT("/:id", UpdateArticleHandler)    // Update article
@@ -160,9 +303,31 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 			lawyers.DELETE("/:id", DeleteLawyerHandler)                             // Delete lawyer profile
 			lawyers.GET("/:id/history", GetLawyerHistoryHandler)                    // Get lawyer change history
 
-			// Admin-only lawyer routes
+			// Availability of the current lawyer
+			lawyers.GET("/profile/availability", GetMyAvailabilityHandler)
+			lawyers.PUT("/profile/availability", UpdateMyAvailabilityHandler)
+			lawyers.GET("/profile/availability/exceptions", GetAvailabilityExceptionsHandler)
+			lawyers.POST("/profile/availability/exceptions", CreateAvailabilityExceptionHandler)
+			lawyers.PUT("/profile/availability/exceptions/:exceptionId", UpdateAvailabilityExceptionHandler)
+			lawyers.DELETE("/profile/availability/exceptions/:exceptionId", DeleteAvailabilityExceptionHandler)
+			lawyers.GET("/profile/booking-settings", GetMyBookingSettingsHandler)
+			lawyers.PUT("/profile/booking-settings", UpdateMyBookingSettingsHandler)
+			lawyers.GET("/profile/calendars", GetMyExternalCalendarsHandler)
+			lawyers.POST("/profile/calendars", CreateExternalCalendarHandler)
+			lawyers.PUT("/profile/calendars/:calendarId", UpdateExternalCalendarHandler)
+			lawyers.DELETE("/profile/calendars/:calendarId", DeleteExternalCalendarHandler)
+			lawyers.POST("/profile/calendars/:calendarId/sync", SyncExternalCalendarHandler)
+			lawyers.GET("/profile/calendars/:calendarId/busy", GetExternalCalendarBusyHandler)
+			lawyers.GET("/profile/waitlist", GetMyLawyerWaitlistHandler)
+			lawyers.GET("/profile/intake-forms", GetMyIntakeFormsHandler)
+			lawyers.POST("/profile/intake-forms", CreateIntakeFormHandler)
+			lawyers.PUT("/profile/intake-forms/:formId", UpdateIntakeFormHandler)
+			lawyers.DELETE("/profile/intake-forms/:formId", DeleteIntakeFormHandler)
+			lawyers.GET("/profile/follow-up-tasks", GetMyFollowUpTasksHandler)
+
+			// Verification routes
 			adminLawyers := lawyers.Group("/")
-			adminLawyers.Use(middleware.RequireRole("admin"))
+			adminLawyers.Use(middleware.RequirePermission(models.PermLawyersVerify))
 			{
 				adminLawyers.PATCH("/:id/verify", VerifyLawyerHandler) // Update lawyer verification status
 			}
@@ -191,7 +356,7 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config) {
 			questions.DELETE("/:id", DeleteQuestionHandler)             // Delete a question
 
 			adminQ := questions.Group("/")
-			adminQ.Use(middleware.RequireRole("admin"))
+			adminQ.Use(middleware.RequirePermission(models.PermQuestionsModerate))
 			adminQ.PATCH("/:id/hidden", UpdateQuestionHiddenHandler)
 		}
 
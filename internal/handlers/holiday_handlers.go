@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// maxCalendarRangeDays bounds the date range of calendar queries
+const maxCalendarRangeDays = 731
+
+// CreatePlatformClosureRequest closes the platform on a date
+type CreatePlatformClosureRequest struct {
+	Date   models.Date `json:"date"`
+	Reason string      `json:"reason" binding:"required"`
+}
+
+// parseDateRange reads the from and to query parameters. Missing bounds default to
+// today and one year after from.
+func parseDateRange(c *gin.Context) (models.Date, models.Date, error) {
+	from := models.NewDate(time.Now())
+	if s := c.Query("from"); s != "" {
+		parsed, err := models.ParseDate(s)
+		if err != nil {
+			return from, from, err
+		}
+		from = parsed
+	}
+
+	to := models.NewDate(from.AddDate(1, 0, 0))
+	if s := c.Query("to"); s != "" {
+		parsed, err := models.ParseDate(s)
+		if err != nil {
+			return from, to, err
+		}
+		to = parsed
+	}
+
+	if to.Before(from.Time) {
+		return from, to, errors.New("to must not be before from")
+	}
+	if to.Sub(from.Time) > maxCalendarRangeDays*24*time.Hour {
+		return from, to, errors.New("date range must not exceed two years")
+	}
+	return from, to, nil
+}
+
+// @Summary Get holiday calendar
+// @Description Lists Japanese national holidays, including substitute holidays (振替休日) and citizens' holidays (国民の休日), and platform-wide closures between two dates. Defaults to the coming year.
+// @Tags calendar
+// @Produce json
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Success 200 {array} services.CalendarClosure
+// @Failure 400 {object} responses.APIErrorResponse "Invalid date range"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /public/holidays [get]
+func GetHolidayCalendarHandler(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	calendar, err := services.NewHolidayService().GetCalendar(from, to)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve holiday calendar", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(calendar)
+}
+
+// @Summary List platform closures
+// @Description Lists platform-wide closure days between two dates. Defaults to the coming year.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Success 200 {array} models.PlatformClosure
+// @Failure 400 {object} responses.APIErrorResponse "Invalid date range"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/closures [get]
+func GetPlatformClosuresHandler(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	closures, err := services.NewHolidayService().GetPlatformClosures(from, to)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve platform closures", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(closures)
+}
+
+// @Summary Create platform closure
+// @Description Closes the whole platform on a date. No lawyer can be booked on it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param closure body CreatePlatformClosureRequest true "Closure"
+// @Success 201 {object} models.PlatformClosure
+// @Failure 400 {object} responses.APIErrorResponse "Invalid request"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 409 {object} responses.APIErrorResponse "The date is already closed"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/closures [post]
+func CreatePlatformClosureHandler(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req CreatePlatformClosureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Date.IsZero() || req.Reason == "" {
+		responses.NewAPIResponse(c).BadRequest("Date and reason are required", responses.ErrCodeMissingRequiredField)
+		return
+	}
+
+	closure := &models.PlatformClosure{
+		Date:      req.Date,
+		Reason:    req.Reason,
+		CreatedBy: &userID,
+	}
+	if err := services.NewHolidayService().CreatePlatformClosure(closure); err != nil {
+		if errors.Is(err, services.ErrPlatformClosureExists) {
+			responses.NewAPIResponse(c).Conflict("The platform is already closed on this date", responses.ErrCodeResourceAlreadyExists)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to create platform closure", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityPlatformClosure, closure.ID, nil, closure)
+	responses.NewAPIResponse(c).Created(closure)
+}
+
+// @Summary Delete platform closure
+// @Description Reopens the platform on a closed date
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Closure ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid closure ID"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 404 {object} responses.APIErrorResponse "Closure not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/closures/{id} [delete]
+func DeletePlatformClosureHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid closure ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	holidayService := services.NewHolidayService()
+	closure, err := holidayService.GetPlatformClosureByID(id)
+	if err != nil {
+		if errors.Is(err, services.ErrPlatformClosureNotFound) {
+			responses.NewAPIResponse(c).NotFound("Closure not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve platform closure", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	if err := holidayService.DeletePlatformClosure(id); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to delete platform closure", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityPlatformClosure, id, closure, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Platform closure deleted successfully"})
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// StartImpersonationRequest represents a request to act as another user
+type StartImpersonationRequest struct {
+	Reason           string `json:"reason" binding:"required,min=5,max=500"`
+	AllowDestructive bool   `json:"allow_destructive"`
+	DurationMinutes  int    `json:"duration_minutes" binding:"omitempty,min=1,max=120"`
+}
+
+// ImpersonationResponse is returned when an impersonation session starts
+type ImpersonationResponse struct {
+	Token     string                       `json:"token"`
+	ExpiresAt time.Time                    `json:"expires_at"`
+	Session   *models.ImpersonationSession `json:"session"`
+}
+
+// @Summary Start impersonating a user
+// @Description Issues a short-lived token that acts as the given client or lawyer. Every request made with it is logged, and anything other than reading, marking notifications read or ending the session is blocked unless allow_destructive is set (which needs an extra permission).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param request body StartImpersonationRequest true "Reason and options"
+// @Success 201 {object} ImpersonationResponse
+// @Failure 400 {object} responses.APIErrorResponse "Invalid request or user cannot be impersonated"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 404 {object} responses.APIErrorResponse "User not found"
+// @Router /admin/impersonate/{id} [post]
+func StartImpersonationHandler(c *gin.Context) {
+	subjectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid user ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req StartImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	// Sessions cannot be chained
+	if middleware.IsImpersonating(c) {
+		responses.NewAPIResponse(c).Forbidden("End the current impersonation session first", responses.ErrCodeForbidden)
+		return
+	}
+	if req.AllowDestructive && !middleware.HasPermission(c, models.PermImpersonateUnsafe) {
+		responses.NewAPIResponse(c).Forbidden("You are not allowed to perform destructive actions while impersonating", responses.ErrCodeForbidden)
+		return
+	}
+
+	actorID, _ := middleware.GetUserID(c)
+	session, subject, err := services.NewImpersonationService().Start(services.StartImpersonationInput{
+		ActorID:          actorID,
+		SubjectID:        subjectID,
+		Reason:           req.Reason,
+		AllowDestructive: req.AllowDestructive,
+		Duration:         time.Duration(req.DurationMinutes) * time.Minute,
+		IPAddress:        c.ClientIP(),
+		UserAgent:        c.Request.UserAgent(),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	token, err := middleware.GenerateImpersonationToken(subject, session, services.GetConfig())
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to generate token", responses.ErrCodeInternalServer)
+		return
+	}
+
+	fmt.Printf("User %d started impersonating user %d (session %d): %s\n", actorID, subjectID, session.ID, req.Reason)
+	recordAudit(c, models.AuditActionImpersonationStart, models.AuditEntityImpersonation, session.ID, nil, session)
+
+	responses.NewAPIResponse(c).Created(ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		Session:   session,
+	})
+}
+
+// @Summary End impersonation
+// @Description Ends the impersonation session of the current token so it stops working immediately
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} gin.H "Session ended"
+// @Failure 400 {object} responses.APIErrorResponse "Not impersonating"
+// @Router /auth/impersonation/end [post]
+func EndImpersonationHandler(c *gin.Context) {
+	sessionID, ok := middleware.GetImpersonationID(c)
+	if !ok {
+		responses.NewAPIResponse(c).BadRequest("This token is not an impersonation token", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := services.NewImpersonationService().End(sessionID); err != nil {
+		if errors.Is(err, services.ErrImpersonationInactive) {
+			responses.NewAPIResponse(c).BadRequest("Impersonation session has already ended", responses.ErrCodeInvalidRequest)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to end impersonation session", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Impersonation session ended"})
+}
+
+// @Summary List impersonation sessions
+// @Description Returns impersonation sessions, newest first
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param actor_id query int false "Filter by the staff member who impersonated"
+// @Param subject_id query int false "Filter by the impersonated user"
+// @Success 200 {object} responses.ListResponse{data=[]models.ImpersonationSession}
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/impersonations [get]
+func GetImpersonationSessionsHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	actorID, _ := strconv.Atoi(c.Query("actor_id"))
+	subjectID, _ := strconv.Atoi(c.Query("subject_id"))
+
+	sessions, total, err := services.NewImpersonationService().GetSessions(actorID, subjectID, page, limit)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve impersonation sessions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+	responses.NewAPIResponse(c).Paginated(http.StatusOK, sessions, page, limit, int(total), totalPages)
+}
+
+// @Summary List requests made during an impersonation session
+// @Description Returns every request made with a session's token, including blocked ones, in order
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Session ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} responses.ListResponse{data=[]models.ImpersonationRequestLog}
+// @Failure 400 {object} responses.APIErrorResponse "Invalid session ID"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Router /admin/impersonations/{id}/requests [get]
+func GetImpersonationRequestsHandler(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid session ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	logs, total, err := services.NewImpersonationService().GetRequestLogs(sessionID, page, limit)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve impersonation requests", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+	responses.NewAPIResponse(c).Paginated(http.StatusOK, logs, page, limit, int(total), totalPages)
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// maxIntakeFileSize bounds files attached to intake answers to 20 MB
+const maxIntakeFileSize = 20 << 20
+
+// IntakeFormRequest defines an intake form for one of the lawyer's specialties
+type IntakeFormRequest struct {
+	Specialty string              `json:"specialty" binding:"required"`
+	Title     string              `json:"title" binding:"required"`
+	Schema    models.IntakeSchema `json:"schema"`
+}
+
+// @Summary List my intake forms
+// @Description Lists the intake forms the current lawyer asks clients to complete, one per specialty
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.IntakeForm
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/intake-forms [get]
+func GetMyIntakeFormsHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	forms, err := services.NewIntakeService().ListForms(lawyer.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve intake forms", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(forms)
+}
+
+// @Summary Create intake form
+// @Description Adds an intake form for one of the current lawyer's specialties. The schema is a JSON Schema object whose properties are string fields: free text (optional maxLength), choice (enum), date (format "date") or file (format "file"). Once a lawyer has a form, clients must complete one when booking.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param form body IntakeFormRequest true "Intake form"
+// @Success 201 {object} models.IntakeForm
+// @Failure 400 {object} responses.APIErrorResponse "Invalid intake form"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 409 {object} responses.APIErrorResponse "Form already exists for this specialty"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/intake-forms [post]
+func CreateIntakeFormHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	var req IntakeFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	form := &models.IntakeForm{
+		Specialty: req.Specialty,
+		Title:     req.Title,
+		Schema:    req.Schema,
+	}
+	if err := services.NewIntakeService().CreateForm(lawyer, form); err != nil {
+		respondIntakeFormError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityIntakeForm, form.ID, nil, form)
+	responses.NewAPIResponse(c).Created(form)
+}
+
+// @Summary Update intake form
+// @Description Replaces the specialty, title and schema of one of the current lawyer's intake forms. Answers already given keep the form as it was.
+// @Tags lawyers
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param formId path int true "Intake form ID"
+// @Param form body IntakeFormRequest true "Intake form"
+// @Success 200 {object} models.IntakeForm
+// @Failure 400 {object} responses.APIErrorResponse "Invalid intake form"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Intake form not found"
+// @Failure 409 {object} responses.APIErrorResponse "Form already exists for this specialty"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/intake-forms/{formId} [put]
+func UpdateIntakeFormHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	formID, err := strconv.Atoi(c.Param("formId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid intake form ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req IntakeFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	intakeService := services.NewIntakeService()
+	form, err := intakeService.GetForm(lawyer.ID, formID)
+	if err != nil {
+		respondIntakeFormError(c, err)
+		return
+	}
+	before := services.AuditSnapshot(form)
+
+	form.Specialty = req.Specialty
+	form.Title = req.Title
+	form.Schema = req.Schema
+	if err := intakeService.UpdateForm(lawyer, form); err != nil {
+		respondIntakeFormError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityIntakeForm, form.ID, before, form)
+	responses.NewAPIResponse(c).OK(form)
+}
+
+// @Summary Delete intake form
+// @Description Removes one of the current lawyer's intake forms. Answers already given are kept with their appointments.
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Param formId path int true "Intake form ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid intake form ID"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Intake form not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/intake-forms/{formId} [delete]
+func DeleteIntakeFormHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	formID, err := strconv.Atoi(c.Param("formId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid intake form ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	intakeService := services.NewIntakeService()
+	form, err := intakeService.GetForm(lawyer.ID, formID)
+	if err != nil {
+		respondIntakeFormError(c, err)
+		return
+	}
+
+	if err := intakeService.DeleteForm(lawyer.ID, formID); err != nil {
+		respondIntakeFormError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityIntakeForm, formID, form, nil)
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Intake form deleted successfully"})
+}
+
+// @Summary Get a lawyer's intake forms
+// @Description Lists the intake forms a client must choose from and complete when booking the lawyer. An empty list means no form is required.
+// @Tags public
+// @Produce json
+// @Param id path int true "Lawyer ID"
+// @Success 200 {array} models.IntakeForm
+// @Failure 400 {object} responses.APIErrorResponse "Invalid lawyer ID"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /public/lawyers/{id}/intake-forms [get]
+func GetLawyerIntakeFormsHandler(c *gin.Context) {
+	lawyerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid lawyer ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	forms, err := services.NewIntakeService().ListForms(lawyerID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve intake forms", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(forms)
+}
+
+// @Summary Upload intake file
+// @Description Uploads a file to attach to a file field of an intake form. Send the returned ID as the field's answer when booking; the file can only be used once and only by the uploader.
+// @Tags appointments
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "File to attach"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} responses.APIErrorResponse "Missing or too large file"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/intake-files [post]
+func UploadIntakeFileHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("File is required: "+err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxIntakeFileSize {
+		responses.NewAPIResponse(c).BadRequest("File must be at most 20 MB", responses.ErrCodeValidationFailed)
+		return
+	}
+
+	key := fmt.Sprintf("attachments/intake/%d/%d%s", userID, time.Now().UnixNano(), filepath.Ext(header.Filename))
+	if _, err := services.NewUtilService().UploadFileToS3(c.Request.Context(), key, file, header.Header.Get("Content-Type")); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Upload failed", responses.ErrCodeOperationFailed)
+		return
+	}
+
+	attachment := &models.Attachment{
+		FileName:   header.Filename,
+		FileSize:   int(header.Size),
+		FileType:   header.Header.Get("Content-Type"),
+		FilePath:   key,
+		UploadedBy: userID,
+	}
+	if err := services.NewIntakeService().SaveUpload(attachment); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to save file", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).Created(attachment)
+}
+
+func respondIntakeFormError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidIntakeForm):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrIntakeFormNotFound):
+		responses.NewAPIResponse(c).NotFound("Intake form not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrIntakeFormExists):
+		responses.NewAPIResponse(c).Conflict("Intake form already exists for this specialty", responses.ErrCodeResourceAlreadyExists)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to save intake form", responses.ErrCodeDatabaseError)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -219,7 +220,7 @@ func CreateLawyerHandler(c *gin.Context) {
 
 	// Get the user role
 	userRole, _ := middleware.GetUserRole(c)
-	if userRole != "lawyer" && userRole != "admin" {
+	if userRole != "lawyer" && !middleware.HasPermission(c, models.PermLawyersManage) {
 		responses.NewAPIResponse(c).Forbidden("Only lawyers can create lawyer profiles", responses.ErrCodeForbidden)
 		return
 	}
@@ -243,6 +244,25 @@ func CreateLawyerHandler(c *gin.Context) {
 		lawyer.Languages = models.StringArray{}
 	}
 
+	if lawyer.Availability != nil {
+		if err := lawyer.Availability.Validate(); err != nil {
+			responses.NewAPIResponse(c).BadRequest("invalid availability: "+err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
+	}
+	if lawyer.Timezone != "" {
+		if err := models.ValidateTimezone(lawyer.Timezone); err != nil {
+			responses.NewAPIResponse(c).BadRequest("invalid timezone: "+err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
+	}
+	if lawyer.BookingSettings != nil {
+		if err := lawyer.BookingSettings.Validate(); err != nil {
+			responses.NewAPIResponse(c).BadRequest("invalid booking settings: "+err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
+	}
+
 	// Get the lawyer service
 	lawyerService := services.NewLawyerService()
 
@@ -288,9 +308,6 @@ func UpdateLawyerHandler(c *gin.Context) {
 		return
 	}
 
-	// Get the user role
-	userRole, _ := middleware.GetUserRole(c)
-
 	// Get the lawyer ID from the URL
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -309,8 +326,8 @@ func UpdateLawyerHandler(c *gin.Context) {
 		return
 	}
 
-	// If not admin and not updating own lawyer profile, return error
-	if userRole != "admin" && currentLawyer.UserID != userID {
+	// Only the owner or staff who manage lawyer profiles may update it
+	if currentLawyer.UserID != userID && !middleware.HasPermission(c, models.PermLawyersManage) {
 		responses.NewAPIResponse(c).Forbidden("You can only update your own lawyer profile", responses.ErrCodeForbidden)
 		return
 	}
@@ -328,6 +345,11 @@ func UpdateLawyerHandler(c *gin.Context) {
 	// Update lawyer
 	err = lawyerService.UpdateLawyer(id, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAvailability) || errors.Is(err, services.ErrInvalidBookingSettings) ||
+			errors.Is(err, services.ErrInvalidTimezone) {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
 		responses.NewAPIResponse(c).InternalServerError("Failed to update lawyer profile: "+err.Error(), responses.ErrCodeDatabaseError)
 		return
 	}
@@ -344,11 +366,12 @@ func UpdateLawyerHandler(c *gin.Context) {
 		// Log the error but don't fail the request
 		fmt.Printf("Failed to record lawyer history: %v\n", err)
 	}
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityLawyer, id, &originalLawyer, updatedLawyer)
 
 	// Check if verification notification email should be sent
 	// Only send the notification if the lawyer is NOT verified, is updating their own profile (not admin update),
 	// and has completed their required profile fields
-	if !updatedLawyer.IsVerified && userRole != "admin" && isProfileComplete(updatedLawyer) {
+	if !updatedLawyer.IsVerified && currentLawyer.UserID == userID && isProfileComplete(updatedLawyer) {
 		// Send verification notification email to admins
 		// The email service will handle admin email retrieval and error handling internally
 		if err := services.GetEmailService().SendLawyerVerificationNotificationEmail(*updatedLawyer); err != nil {
@@ -385,9 +408,6 @@ func DeleteLawyerHandler(c *gin.Context) {
 		return
 	}
 
-	// Get the user role
-	userRole, _ := middleware.GetUserRole(c)
-
 	// Get the lawyer ID from the URL
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -407,7 +427,7 @@ func DeleteLawyerHandler(c *gin.Context) {
 	}
 
 	// Check if the user has permission to delete this lawyer profile
-	if userRole != "admin" && existingLawyer.UserID != userID {
+	if existingLawyer.UserID != userID && !middleware.HasPermission(c, models.PermLawyersManage) {
 		responses.NewAPIResponse(c).Forbidden("You can only delete your own lawyer profile", responses.ErrCodeForbidden)
 		return
 	}
@@ -419,6 +439,8 @@ func DeleteLawyerHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityLawyer, id, existingLawyer, nil)
+
 	// Return success
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Lawyer profile deleted successfully"})
 }
@@ -697,15 +719,12 @@ func UploadLawyerCertificationHandler(c *gin.Context) {
 		return
 	}
 
-	// get role
-	userRole, _ := middleware.GetUserRole(c)
-
 	// determine which lawyer to attach to
 	var targetLawyerID int
 	lawyerSvc := services.NewLawyerService()
 
-	if userRole == "admin" {
-		// admin must supply lawyer_id
+	if middleware.HasPermission(c, models.PermLawyersManage) {
+		// staff must supply lawyer_id
 		idStr := c.PostForm("lawyer_id")
 		if idStr == "" {
 			responses.NewAPIResponse(c).
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
 	"github.com/kotolino/lawyer/internal/models"
 	"github.com/kotolino/lawyer/internal/repository"
 	"github.com/kotolino/lawyer/internal/services"
@@ -51,6 +53,62 @@ func GetLawyerHistoryHandler(c *gin.Context) {
 	)
 }
 
+// @Summary Restore lawyer profile version
+// @Description Rolls a lawyer profile back to how it looked right after the given history entry. The restore is recorded as a new history entry.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Lawyer ID"
+// @Param historyId path int true "History entry ID"
+// @Success 200 {object} models.Lawyer
+// @Failure 400 {object} responses.APIErrorResponse "Invalid ID or profile already matches this version"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer or history entry not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/lawyers/{id}/history/{historyId}/restore [post]
+func RestoreLawyerVersionHandler(c *gin.Context) {
+	lawyerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid lawyer ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+	historyID, err := strconv.Atoi(c.Param("historyId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid history ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	lawyerService := services.NewLawyerService()
+	previous, err := lawyerService.GetLawyerByID(lawyerID)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("Lawyer not found", responses.ErrCodeResourceNotFound)
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if _, err := services.RestoreLawyerVersion(repository.DB, lawyerID, historyID, userID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrHistoryEntryNotFound), errors.Is(err, services.ErrLawyerNotFound):
+			responses.NewAPIResponse(c).NotFound("History entry not found", responses.ErrCodeResourceNotFound)
+		case errors.Is(err, services.ErrLawyerVersionUnchanged):
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		default:
+			responses.NewAPIResponse(c).InternalServerError("Failed to restore lawyer profile", responses.ErrCodeDatabaseError)
+		}
+		return
+	}
+
+	restored, err := lawyerService.GetLawyerByID(lawyerID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve restored lawyer profile", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionRestore, models.AuditEntityLawyer, lawyerID, previous, restored)
+
+	responses.NewAPIResponse(c).OK(restored)
+}
+
 // LogLawyerChange adds an entry to lawyer history
 func LogLawyerChange(lawyerID, userID int, changes []models.FieldChange) error {
 	return services.LogLawyerChange(repository.DB, lawyerID, userID, changes)
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// CreateMatterRequest opens a matter with one of the lawyer's clients
+type CreateMatterRequest struct {
+	ClientID    int     `json:"client_id" binding:"required"`
+	Title       string  `json:"title" binding:"required"`
+	Description *string `json:"description,omitempty"`
+	// PracticeArea is one of the lawyer's specialties
+	PracticeArea    string                 `json:"practice_area" binding:"required"`
+	OpposingParties []OpposingPartyRequest `json:"opposing_parties,omitempty"`
+	// AppointmentIDs are existing appointments with the client to group under the matter
+	AppointmentIDs []int `json:"appointment_ids,omitempty"`
+}
+
+// UpdateMatterRequest changes a matter. Omitted fields are left as they are; given
+// opposing parties replace the matter's current ones.
+type UpdateMatterRequest struct {
+	Title           *string                 `json:"title,omitempty"`
+	Description     *string                 `json:"description,omitempty"`
+	PracticeArea    *string                 `json:"practice_area,omitempty"`
+	Status          *string                 `json:"status,omitempty" binding:"omitempty,oneof=open on_hold closed"`
+	OpposingParties *[]OpposingPartyRequest `json:"opposing_parties,omitempty"`
+}
+
+// LinkMatterAppointmentRequest groups an appointment under a matter
+type LinkMatterAppointmentRequest struct {
+	AppointmentID int `json:"appointment_id" binding:"required"`
+}
+
+func opposingPartiesFromRequest(req []OpposingPartyRequest) []models.OpposingParty {
+	parties := make([]models.OpposingParty, 0, len(req))
+	for _, party := range req {
+		parties = append(parties, models.OpposingParty{Name: party.Name, NameKana: party.NameKana, Relationship: party.Relationship})
+	}
+	return parties
+}
+
+// loadMatterForParty loads the matter in the path for its lawyer, its client or
+// staff who manage appointments, and says which of the first two the caller is
+func loadMatterForParty(c *gin.Context) (*models.Matter, string, bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return nil, "", false
+	}
+	userRole, _ := middleware.GetUserRole(c)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid matter ID", responses.ErrCodeInvalidRequest)
+		return nil, "", false
+	}
+
+	matter, err := services.NewMatterService().GetMatter(id)
+	if err != nil {
+		respondMatterError(c, err)
+		return nil, "", false
+	}
+
+	if userRole == string(models.RoleLawyer) {
+		lawyer, err := services.NewLawyerService().GetLawyerByUserID(userID)
+		if err != nil {
+			responses.NewAPIResponse(c).NotFound("Lawyer profile not found", responses.ErrCodeResourceNotFound)
+			return nil, "", false
+		}
+		if matter.LawyerID == lawyer.ID {
+			return matter, string(models.RoleLawyer), true
+		}
+	} else if matter.ClientID == userID {
+		return matter, string(models.RoleClient), true
+	}
+
+	if middleware.HasPermission(c, models.PermAppointmentsManage) {
+		return matter, "", true
+	}
+	responses.NewAPIResponse(c).Forbidden("You do not have access to this matter", responses.ErrCodeForbidden)
+	return nil, "", false
+}
+
+// loadMatterForLawyer loads the matter in the path for its own lawyer only
+func loadMatterForLawyer(c *gin.Context) (*models.Matter, *models.Lawyer, bool) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if party != string(models.RoleLawyer) {
+		responses.NewAPIResponse(c).Forbidden("Only the matter's lawyer can do this", responses.ErrCodeForbidden)
+		return nil, nil, false
+	}
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return nil, nil, false
+	}
+	return matter, lawyer, true
+}
+
+// @Summary List matters
+// @Description Lists the current lawyer's matters, or the current client's matters with their lawyers, most recently updated first
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "Only matters in this status (open, on_hold or closed)"
+// @Success 200 {array} models.Matter
+// @Failure 400 {object} responses.APIErrorResponse "Invalid status"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters [get]
+func GetMattersHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+	userRole, _ := middleware.GetUserRole(c)
+
+	status := c.Query("status")
+	if status != "" && !models.ValidMatterStatus(status) {
+		responses.NewAPIResponse(c).BadRequest("Status must be open, on_hold or closed", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var lawyerID, clientID int
+	if userRole == string(models.RoleLawyer) {
+		lawyer, ok := currentLawyerProfile(c)
+		if !ok {
+			return
+		}
+		lawyerID = lawyer.ID
+	} else {
+		clientID = userID
+	}
+
+	matters, err := services.NewMatterService().ListMatters(lawyerID, clientID, status)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve matters", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(matters)
+}
+
+// @Summary Create a matter
+// @Description Opens a matter with a client the lawyer has had appointments with, in one of the lawyer's practice areas, and groups the given appointments under it. Opposing parties declared on the matter are checked for conflicts with every appointment in it.
+// @Tags matters
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param matter body CreateMatterRequest true "Matter"
+// @Success 201 {object} models.Matter
+// @Failure 400 {object} responses.APIErrorResponse "Invalid matter"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile or appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters [post]
+func CreateMatterHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	var req CreateMatterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	matter := &models.Matter{
+		ClientID:        req.ClientID,
+		Title:           req.Title,
+		Description:     req.Description,
+		PracticeArea:    req.PracticeArea,
+		OpposingParties: opposingPartiesFromRequest(req.OpposingParties),
+	}
+	if err := services.NewMatterService().CreateMatter(lawyer, matter, req.AppointmentIDs); err != nil {
+		respondMatterError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityMatter, matter.ID, nil, matter)
+	responses.NewAPIResponse(c).Created(matter)
+}
+
+// @Summary Get a matter
+// @Description Returns a matter with its opposing parties, for its lawyer, its client or staff
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Success 200 {object} models.Matter
+// @Failure 400 {object} responses.APIErrorResponse "Invalid matter ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Router /matters/{id} [get]
+func GetMatterHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+	responses.NewAPIResponse(c).OK(matter)
+}
+
+// @Summary Update a matter
+// @Description Changes a matter's title, description, practice area, status or opposing parties. Closing a matter stops new appointments being booked under it.
+// @Tags matters
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param matter body UpdateMatterRequest true "Changes"
+// @Success 200 {object} models.Matter
+// @Failure 400 {object} responses.APIErrorResponse "Invalid matter"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id} [put]
+func UpdateMatterHandler(c *gin.Context) {
+	matter, lawyer, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMatterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	before := services.AuditSnapshot(matter)
+	if req.Title != nil {
+		matter.Title = *req.Title
+	}
+	if req.Description != nil {
+		matter.Description = req.Description
+	}
+	if req.PracticeArea != nil {
+		matter.PracticeArea = *req.PracticeArea
+	}
+	if req.Status != nil {
+		matter.Status = *req.Status
+	}
+	if req.OpposingParties != nil {
+		matter.OpposingParties = opposingPartiesFromRequest(*req.OpposingParties)
+	}
+
+	if err := services.NewMatterService().UpdateMatter(lawyer, matter, req.OpposingParties != nil); err != nil {
+		respondMatterError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityMatter, matter.ID, before, matter)
+	responses.NewAPIResponse(c).OK(matter)
+}
+
+// @Summary Add an appointment to a matter
+// @Description Groups one of the client's appointments with the lawyer under the matter. An appointment belongs to at most one matter.
+// @Tags matters
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param request body LinkMatterAppointmentRequest true "Appointment"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Appointment is with someone else or in another matter"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/appointments [post]
+func LinkMatterAppointmentHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+
+	var req LinkMatterAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := services.NewMatterService().LinkAppointment(matter, req.AppointmentID); err != nil {
+		respondMatterError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityAppointment, req.AppointmentID, nil, gin.H{"matter_id": matter.ID})
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Appointment added to matter"})
+}
+
+// @Summary Remove an appointment from a matter
+// @Description Takes an appointment out of the matter. The appointment itself is kept.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Param appointmentId path int true "Appointment ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid appointment ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter or appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/appointments/{appointmentId} [delete]
+func UnlinkMatterAppointmentHandler(c *gin.Context) {
+	matter, _, ok := loadMatterForLawyer(c)
+	if !ok {
+		return
+	}
+
+	appointmentID, err := strconv.Atoi(c.Param("appointmentId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid appointment ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := services.NewMatterService().UnlinkAppointment(matter, appointmentID); err != nil {
+		respondMatterError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityAppointment, appointmentID, gin.H{"matter_id": matter.ID}, gin.H{"matter_id": nil})
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Appointment removed from matter"})
+}
+
+// @Summary Get a matter's timeline
+// @Description Lists everything that happened in the matter's appointments in time order: the appointments, their status changes, chat messages and files, intake files and consultation records. The client only sees consultation records shared with them, without private notes.
+// @Tags matters
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Matter ID"
+// @Success 200 {array} responses.MatterTimelineItem
+// @Failure 400 {object} responses.APIErrorResponse "Invalid matter ID"
+// @Failure 403 {object} responses.APIErrorResponse "Forbidden"
+// @Failure 404 {object} responses.APIErrorResponse "Matter not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /matters/{id}/timeline [get]
+func GetMatterTimelineHandler(c *gin.Context) {
+	matter, party, ok := loadMatterForParty(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := services.NewMatterService().Timeline(matter, party == string(models.RoleClient))
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve matter timeline", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(timeline)
+}
+
+func respondMatterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMatter), errors.Is(err, services.ErrInvalidOpposingParties):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrMatterNotFound):
+		responses.NewAPIResponse(c).NotFound("Matter not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrAppointmentNotFound):
+		responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to save matter", responses.ErrCodeDatabaseError)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// MeetingLinkSettingsRequest sets how early before its start an appointment's
+// meeting link is shown
+type MeetingLinkSettingsRequest struct {
+	WindowMinutes int `json:"window_minutes"`
+}
+
+// hideMeetingLinkUntilAvailable removes the meeting link from an appointment shown
+// to its client or lawyer outside the meeting link window. Staff who manage
+// appointments always see it.
+func hideMeetingLinkUntilAvailable(c *gin.Context, appointment *responses.AppointmentResponse) {
+	if appointment.MeetingLink == nil || appointment.MeetingLinkAvailableAt == nil {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	isParty := appointment.UserID == userID || appointment.Lawyer.UserID == userID
+	if !isParty && middleware.HasPermission(c, models.PermAppointmentsManage) {
+		return
+	}
+
+	now := time.Now()
+	if now.Before(*appointment.MeetingLinkAvailableAt) || !now.Before(appointment.EndTime) {
+		appointment.MeetingLink = nil
+	}
+}
+
+// @Summary Get meeting link window
+// @Description Returns how many minutes before its start an appointment's meeting link is shown to the client and the lawyer (admin only)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} MeetingLinkSettingsRequest
+// @Router /admin/settings/meeting-links [get]
+func GetMeetingLinkSettingsHandler(c *gin.Context) {
+	minutes, err := services.NewPlatformSettingService().GetMeetingLinkWindowMinutes()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load meeting link settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(MeetingLinkSettingsRequest{WindowMinutes: minutes})
+}
+
+// @Summary Update meeting link window
+// @Description Sets how many minutes before its start an appointment's meeting link is shown to the client and the lawyer (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MeetingLinkSettingsRequest true "Meeting link window"
+// @Success 200 {object} MeetingLinkSettingsRequest
+// @Failure 400 {object} responses.APIErrorResponse "Invalid window"
+// @Router /admin/settings/meeting-links [put]
+func UpdateMeetingLinkSettingsHandler(c *gin.Context) {
+	var req MeetingLinkSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	settingService := services.NewPlatformSettingService()
+	previous, _ := settingService.GetMeetingLinkWindowMinutes()
+	if err := settingService.SetMeetingLinkWindowMinutes(req.WindowMinutes, adminID); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	recordAuditByKey(c, models.AuditActionSettingsChange, models.AuditEntityPlatformSetting, models.SettingMeetingLinkWindowMins,
+		map[string]interface{}{"value": previous},
+		map[string]interface{}{"value": req.WindowMinutes})
+
+	responses.NewAPIResponse(c).OK(req)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func TestHideMeetingLinkUntilAvailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const clientID, lawyerUserID, staffID = 10, 20, 30
+	tests := []struct {
+		name        string
+		userID      int
+		canManage   bool
+		opensIn     time.Duration
+		wantVisible bool
+	}{
+		{name: "client before the window", userID: clientID, opensIn: time.Hour},
+		{name: "client in the window", userID: clientID, opensIn: -time.Minute, wantVisible: true},
+		{name: "appointment's lawyer before the window", userID: lawyerUserID, opensIn: time.Hour},
+		// Lawyers who manage appointments see links of appointments that are not theirs
+		{name: "other lawyer with manage permission", userID: staffID, canManage: true, opensIn: time.Hour, wantVisible: true},
+		{name: "appointment's lawyer with manage permission", userID: lawyerUserID, canManage: true, opensIn: time.Hour},
+		{name: "stranger", userID: staffID, opensIn: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("userID", tt.userID)
+			c.Set("userRole", string(models.RoleLawyer))
+			c.Set("permissions", map[string]bool{models.PermAppointmentsManage: tt.canManage})
+
+			link := "https://meet.example.com/room"
+			availableAt := time.Now().Add(tt.opensIn)
+			appointment := &responses.AppointmentResponse{
+				UserID:                 clientID,
+				EndTime:                time.Now().Add(3 * time.Hour),
+				MeetingLink:            &link,
+				MeetingLinkAvailableAt: &availableAt,
+				Lawyer:                 responses.LawyerBrief{UserID: lawyerUserID},
+			}
+
+			hideMeetingLinkUntilAvailable(c, appointment)
+			if visible := appointment.MeetingLink != nil; visible != tt.wantVisible {
+				t.Errorf("link visible = %v, want %v", visible, tt.wantVisible)
+			}
+		})
+	}
+}
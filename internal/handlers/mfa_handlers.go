@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// MFAVerifyRequest completes a login with a TOTP or recovery code
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFASetupRequest starts a forced enrollment during login
+type MFASetupRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code for an authenticated user
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFADisableRequest represents the request to turn off two-factor authentication
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFASettingsRequest represents the admin request to change which roles require two-factor authentication
+type MFASettingsRequest struct {
+	RequiredRoles []string `json:"required_roles"`
+}
+
+// MFAStatusResponse describes a user's two-factor authentication state
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// RecoveryCodesResponse returns freshly generated recovery codes. They are only shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFASetupCompleteResponse is returned when a forced enrollment finishes the login
+type MFASetupCompleteResponse struct {
+	AuthResponse
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// @Summary Complete login with a second factor
+// @Description Exchanges an MFA challenge token and a TOTP or recovery code for a session token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFAVerifyRequest true "Challenge token and code"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} responses.APIErrorResponse "Invalid request"
+// @Failure 401 {object} responses.APIErrorResponse "Invalid token or code"
+// @Failure 429 {object} responses.APIErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Router /auth/mfa/verify [post]
+func VerifyMFALoginHandler(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	claims, err := middleware.ParseMFAToken(req.MFAToken, middleware.ScopeMFAChallenge, services.GetConfig())
+	if err != nil {
+		responses.NewAPIResponse(c).Unauthorized("Invalid or expired MFA token", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	user, err := services.NewUserService().GetUserByID(claims.UserID)
+	if err != nil || !user.IsActive {
+		responses.NewAPIResponse(c).Unauthorized("Invalid or expired MFA token", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	// Second-factor guesses count towards the same lockout as password guesses
+	if !checkLoginThrottle(c, models.LoginActionMFA, user.Email) {
+		return
+	}
+
+	if err := services.NewMFAService().Verify(user, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) || errors.Is(err, services.ErrMFANotEnrolled) {
+			recordLoginFailure(c, models.LoginActionMFA, user.Email, &user.ID, models.LoginFailureInvalidMFACode)
+			responses.NewAPIResponse(c).Unauthorized("Invalid authentication code", responses.ErrCodeInvalidMFACode)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to verify authentication code", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordLoginAttempt(c, models.LoginActionMFA, user.Email, &user.ID, true, "")
+	respondWithSessionToken(c, user)
+}
+
+// @Summary Start required two-factor enrollment
+// @Description Returns a TOTP secret, otpauth URI and QR code for a user whose role requires two-factor authentication but who has not enrolled yet
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFASetupRequest true "Setup token from login"
+// @Success 200 {object} services.MFAEnrollment
+// @Failure 401 {object} responses.APIErrorResponse "Invalid token"
+// @Router /auth/mfa/setup [post]
+func StartMFASetupHandler(c *gin.Context) {
+	var req MFASetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	user, ok := userFromMFASetupToken(c, req.MFAToken)
+	if !ok {
+		return
+	}
+
+	enrollment, err := services.NewMFAService().BeginEnrollment(user)
+	if err != nil {
+		respondMFAEnrollmentError(c, err)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(enrollment)
+}
+
+// @Summary Finish required two-factor enrollment
+// @Description Confirms the first TOTP code, enables two-factor authentication and completes the login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFAVerifyRequest true "Setup token and first code"
+// @Success 200 {object} MFASetupCompleteResponse
+// @Failure 401 {object} responses.APIErrorResponse "Invalid token or code"
+// @Router /auth/mfa/setup/confirm [post]
+func ConfirmMFASetupHandler(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	user, ok := userFromMFASetupToken(c, req.MFAToken)
+	if !ok {
+		return
+	}
+
+	codes, err := services.NewMFAService().ConfirmEnrollment(user, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			responses.NewAPIResponse(c).Unauthorized("Invalid authentication code", responses.ErrCodeInvalidMFACode)
+			return
+		}
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeOperationFailed)
+		return
+	}
+
+	token, err := middleware.GenerateToken(user, services.GetConfig())
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to generate token", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	user.Password = ""
+	responses.NewAPIResponse(c).OK(MFASetupCompleteResponse{
+		AuthResponse:  AuthResponse{Token: token, User: user},
+		RecoveryCodes: codes,
+	})
+}
+
+// userFromMFASetupToken resolves the user behind a setup token, writing the error response on failure
+func userFromMFASetupToken(c *gin.Context, token string) (*models.User, bool) {
+	claims, err := middleware.ParseMFAToken(token, middleware.ScopeMFASetup, services.GetConfig())
+	if err != nil {
+		responses.NewAPIResponse(c).Unauthorized("Invalid or expired MFA token", responses.ErrCodeUnauthorized)
+		return nil, false
+	}
+
+	user, err := services.NewUserService().GetUserByID(claims.UserID)
+	if err != nil || !user.IsActive {
+		responses.NewAPIResponse(c).Unauthorized("Invalid or expired MFA token", responses.ErrCodeUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+// @Summary Get two-factor status
+// @Description Returns whether two-factor authentication is enabled or required for the current user
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} MFAStatusResponse
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Router /auth/mfa [get]
+func GetMFAStatusHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	required, err := services.NewPlatformSettingService().IsMFARequiredForRole(user.Role)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load security settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	remaining, err := services.NewMFAService().CountRemainingRecoveryCodes(user.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to count recovery codes", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(MFAStatusResponse{
+		Enabled:                user.TOTPEnabled,
+		Required:               required,
+		RecoveryCodesRemaining: remaining,
+	})
+}
+
+// @Summary Start two-factor enrollment
+// @Description Generates a TOTP secret for the current user, with its otpauth URI and the URI as a QR code PNG data URI to show to the authenticator app
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} services.MFAEnrollment
+// @Failure 400 {object} responses.APIErrorResponse "Already enabled"
+// @Router /auth/mfa/enroll [post]
+func BeginMFAEnrollmentHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := services.NewMFAService().BeginEnrollment(user)
+	if err != nil {
+		respondMFAEnrollmentError(c, err)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(enrollment)
+}
+
+// @Summary Confirm two-factor enrollment
+// @Description Verifies the first TOTP code, enables two-factor authentication and returns recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MFACodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} responses.APIErrorResponse "Invalid code"
+// @Router /auth/mfa/enroll/confirm [post]
+func ConfirmMFAEnrollmentHandler(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := services.NewMFAService().ConfirmEnrollment(user, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			responses.NewAPIResponse(c).BadRequest("Invalid authentication code", responses.ErrCodeInvalidMFACode)
+			return
+		}
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeOperationFailed)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Regenerate recovery codes
+// @Description Invalidates all recovery codes and returns a new set
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MFACodeRequest true "Current TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} responses.APIErrorResponse "Invalid code"
+// @Router /auth/mfa/recovery-codes [post]
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := services.NewMFAService().RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) || errors.Is(err, services.ErrMFANotEnrolled) {
+			responses.NewAPIResponse(c).BadRequest("Invalid authentication code", responses.ErrCodeInvalidMFACode)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to regenerate recovery codes", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Disable two-factor authentication
+// @Description Turns off two-factor authentication after checking the password and a current code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MFADisableRequest true "Password and code"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid password or code"
+// @Failure 403 {object} responses.APIErrorResponse "Required for this role"
+// @Router /auth/mfa/disable [post]
+func DisableMFAHandler(c *gin.Context) {
+	var req MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := utilService.ComparePassword(user.Password, req.Password); err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid password", responses.ErrCodeInvalidCredentials)
+		return
+	}
+
+	mfaService := services.NewMFAService()
+	if err := mfaService.Verify(user, req.Code); err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid authentication code", responses.ErrCodeInvalidMFACode)
+		return
+	}
+
+	if err := mfaService.Disable(user); err != nil {
+		if errors.Is(err, services.ErrMFARequiredForRole) {
+			responses.NewAPIResponse(c).Forbidden(err.Error(), responses.ErrCodeMFARequired)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to disable two-factor authentication", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// @Summary Reset a user's two-factor authentication
+// @Description Removes a user's TOTP secret and recovery codes so they can enroll again (admin only)
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} gin.H "Success message"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid user ID"
+// @Failure 404 {object} responses.APIErrorResponse "User not found"
+// @Router /users/{id}/mfa [delete]
+func ResetUserMFAHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid user ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	if _, err := services.NewUserService().GetUserByID(id); err != nil {
+		responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+		return
+	}
+
+	if err := services.NewMFAService().Reset(id); err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to reset two-factor authentication", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionMFAReset, models.AuditEntityUser, id, nil, nil)
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Two-factor authentication reset"})
+}
+
+// @Summary Get two-factor policy
+// @Description Returns the roles that must use two-factor authentication (admin only)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} MFASettingsRequest
+// @Router /admin/settings/mfa [get]
+func GetMFASettingsHandler(c *gin.Context) {
+	roles, err := services.NewPlatformSettingService().GetMFARequiredRoles()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load security settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(MFASettingsRequest{RequiredRoles: roles})
+}
+
+// @Summary Update two-factor policy
+// @Description Sets the roles (admin, moderator, lawyer) that must use two-factor authentication (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MFASettingsRequest true "Roles requiring two-factor authentication"
+// @Success 200 {object} MFASettingsRequest
+// @Failure 400 {object} responses.APIErrorResponse "Invalid role"
+// @Router /admin/settings/mfa [put]
+func UpdateMFASettingsHandler(c *gin.Context) {
+	var req MFASettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	settingService := services.NewPlatformSettingService()
+	previous, _ := settingService.GetMFARequiredRoles()
+	if err := settingService.SetMFARequiredRoles(req.RequiredRoles, adminID); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	roles, err := settingService.GetMFARequiredRoles()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load security settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAuditByKey(c, models.AuditActionSettingsChange, models.AuditEntityPlatformSetting, models.SettingMFARequiredRoles,
+		map[string]interface{}{"value": previous},
+		map[string]interface{}{"value": roles})
+
+	responses.NewAPIResponse(c).OK(MFASettingsRequest{RequiredRoles: roles})
+}
+
+// currentUser loads the authenticated user, writing the error response on failure
+func currentUser(c *gin.Context) (*models.User, bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Unauthorized", responses.ErrCodeUnauthorized)
+		return nil, false
+	}
+
+	user, err := services.NewUserService().GetUserByID(userID)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// respondMFAEnrollmentError maps BeginEnrollment errors to responses
+func respondMFAEnrollmentError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrCredentialsKeyMissing) {
+		responses.NewAPIResponse(c).InternalServerError("Two-factor authentication is not available: credential encryption is not configured", responses.ErrCodeOperationFailed)
+		return
+	}
+	responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeOperationFailed)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// PermissionsResponse lists every permission and the permissions of each role
+type PermissionsResponse struct {
+	Permissions     []models.Permission `json:"permissions"`
+	RolePermissions map[string][]string `json:"role_permissions"`
+}
+
+// UpdatePermissionsRequest replaces a set of granted permissions
+type UpdatePermissionsRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// @Summary List permissions
+// @Description Returns every permission and which roles hold it
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} PermissionsResponse
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /admin/permissions [get]
+func GetPermissionsHandler(c *gin.Context) {
+	permissionService := services.NewPermissionService()
+
+	permissions, err := permissionService.ListPermissions()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve permissions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	rolePermissions, err := permissionService.GetRolePermissionMap()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve role permissions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(PermissionsResponse{
+		Permissions:     permissions,
+		RolePermissions: rolePermissions,
+	})
+}
+
+// @Summary Update role permissions
+// @Description Replaces the permissions granted to every user with a role
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param role path string true "Role" Enums(client, lawyer, admin, moderator)
+// @Param request body UpdatePermissionsRequest true "Permissions"
+// @Success 200 {object} gin.H "Role and its permissions"
+// @Failure 400 {object} responses.APIErrorResponse "Invalid role or permission"
+// @Failure 403 {object} responses.APIErrorResponse "Insufficient permissions"
+// @Router /admin/roles/{role}/permissions [put]
+func UpdateRolePermissionsHandler(c *gin.Context) {
+	var req UpdatePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	role := c.Param("role")
+	permissionService := services.NewPermissionService()
+	previous, _ := permissionService.GetRolePermissions(role)
+	if err := permissionService.SetRolePermissions(role, req.Permissions); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	permissions, err := permissionService.GetRolePermissions(role)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve role permissions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAuditByKey(c, models.AuditActionPermissionsChange, models.AuditEntityRole, role,
+		map[string]interface{}{"permissions": previous},
+		map[string]interface{}{"permissions": permissions})
+
+	responses.NewAPIResponse(c).OK(gin.H{
+		"role":        role,
+		"permissions": permissions,
+	})
+}
+
+// @Summary Get user permissions
+// @Description Returns a user's role permissions, directly granted permissions and the effective set
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} services.UserPermissionSummary
+// @Failure 400 {object} responses.APIErrorResponse "Invalid user ID"
+// @Failure 404 {object} responses.APIErrorResponse "User not found"
+// @Router /admin/users/{id}/permissions [get]
+func GetUserPermissionsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid user ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	summary, err := services.NewPermissionService().GetUserPermissionSummary(id)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve user permissions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(summary)
+}
+
+// @Summary Grant permissions to a user
+// @Description Replaces the permissions granted directly to a user, on top of their role's permissions
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param request body UpdatePermissionsRequest true "Permissions"
+// @Success 200 {object} services.UserPermissionSummary
+// @Failure 400 {object} responses.APIErrorResponse "Invalid permission"
+// @Failure 404 {object} responses.APIErrorResponse "User not found"
+// @Router /admin/users/{id}/permissions [put]
+func UpdateUserPermissionsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid user ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req UpdatePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	permissionService := services.NewPermissionService()
+	previous, _ := permissionService.GetUserPermissionSummary(id)
+	if err := permissionService.SetUserPermissions(id, req.Permissions, adminID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+			return
+		}
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	summary, err := permissionService.GetUserPermissionSummary(id)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve user permissions", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	recordAudit(c, models.AuditActionPermissionsChange, models.AuditEntityUser, id, previous, summary)
+
+	responses.NewAPIResponse(c).OK(summary)
+}
@@ -294,12 +294,16 @@ func UpdateQuestionHandler(c *gin.Context) {
 	}
 
 	// Update question
-	updatedQuestion, err := services.GetQuestionService().UpdateQuestion(questionID, userID, questionUpdate)
+	questionService := services.GetQuestionService()
+	previous, _ := questionService.GetQuestionByID(questionID)
+	updatedQuestion, err := questionService.UpdateQuestion(questionID, userID, questionUpdate)
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to update question", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityQuestion, questionID, previous, updatedQuestion)
+
 	responses.NewAPIResponse(c).OK(updatedQuestion)
 }
 
@@ -338,11 +342,16 @@ func UpdateQuestionStatusHandler(c *gin.Context) {
 	}
 
 	// Update status
-	if err := services.GetQuestionService().UpdateQuestionStatus(questionID, statusUpdate.Status); err != nil {
+	questionService := services.GetQuestionService()
+	previous, _ := questionService.GetQuestionByID(questionID)
+	if err := questionService.UpdateQuestionStatus(questionID, statusUpdate.Status); err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to update question status", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	updated, _ := questionService.GetQuestionByID(questionID)
+	recordAudit(c, models.AuditActionStatusChange, models.AuditEntityQuestion, questionID, previous, updated)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Question status updated"})
 }
 
@@ -374,11 +383,15 @@ func DeleteQuestionHandler(c *gin.Context) {
 	}
 
 	// Delete question
-	if err := services.GetQuestionService().DeleteQuestion(questionID, userID); err != nil {
+	questionService := services.GetQuestionService()
+	previous, _ := questionService.GetQuestionByID(questionID)
+	if err := questionService.DeleteQuestion(questionID, userID); err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to delete question", responses.ErrCodeDatabaseError)
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityQuestion, questionID, previous, nil)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Question deleted"})
 }
 
@@ -477,10 +490,8 @@ type UpdateQuestionStatusRequest struct {
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /questions/{id}/visibility [put]
 func UpdateQuestionHiddenHandler(c *gin.Context) {
-	// admin only
-	role, ok := middleware.GetUserRole(c)
-	if !ok || role != "admin" {
-		responses.NewAPIResponse(c).Forbidden("Admin access required", responses.ErrCodeForbidden)
+	if !middleware.HasPermission(c, models.PermQuestionsModerate) {
+		responses.NewAPIResponse(c).Forbidden("Moderator access required", responses.ErrCodeForbidden)
 		return
 	}
 
@@ -511,6 +522,8 @@ func UpdateQuestionHiddenHandler(c *gin.Context) {
 		return
 	}
 
+	before := services.AuditSnapshot(q)
+
 	// only flip the hidden flag
 	q.IsHidden = *req.IsHidden
 
@@ -519,5 +532,7 @@ func UpdateQuestionHiddenHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionHide, models.AuditEntityQuestion, qID, before, q)
+
 	responses.NewAPIResponse(c).OK(q)
 }
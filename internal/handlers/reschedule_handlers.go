@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// RescheduleOptionRequest is one alternative time in a reschedule proposal
+type RescheduleOptionRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+}
+
+// ProposeRescheduleRequest offers alternative times for an appointment
+type ProposeRescheduleRequest struct {
+	Options []RescheduleOptionRequest `json:"options" binding:"required,min=1,dive"`
+	Reason  *string                   `json:"reason,omitempty"`
+}
+
+// AcceptRescheduleRequest picks one option of a proposal
+type AcceptRescheduleRequest struct {
+	OptionID int `json:"option_id" binding:"required"`
+}
+
+// DeclineRescheduleRequest turns down a proposal
+type DeclineRescheduleRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+// RescheduleSettingsRequest sets how close to its start an appointment can be rescheduled
+type RescheduleSettingsRequest struct {
+	WindowHours int `json:"window_hours"`
+}
+
+// loadAppointmentForParty loads the appointment in the id path parameter and
+// returns the current user's side of it, "client" or "lawyer". Staff who may manage
+// appointments get an empty side. It writes the error response itself on failure.
+func loadAppointmentForParty(c *gin.Context) (*models.Appointment, string, bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return nil, "", false
+	}
+	userRole, _ := middleware.GetUserRole(c)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid appointment ID", responses.ErrCodeInvalidRequest)
+		return nil, "", false
+	}
+
+	appointment, err := services.NewAppointmentService().GetAppointmentByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
+		return nil, "", false
+	}
+
+	if userRole == string(models.RoleLawyer) {
+		lawyer, err := services.NewLawyerService().GetLawyerByUserID(userID)
+		if err != nil {
+			responses.NewAPIResponse(c).NotFound("Lawyer profile not found", responses.ErrCodeResourceNotFound)
+			return nil, "", false
+		}
+		if appointment.LawyerID == lawyer.ID {
+			return appointment, string(models.RoleLawyer), true
+		}
+	} else if appointment.UserID == userID {
+		return appointment, string(models.RoleClient), true
+	}
+
+	if middleware.HasPermission(c, models.PermAppointmentsManage) {
+		return appointment, "", true
+	}
+	responses.NewAPIResponse(c).Forbidden("You do not have access to this appointment", responses.ErrCodeForbidden)
+	return nil, "", false
+}
+
+// respondRescheduleError maps reschedule service errors to responses
+func respondRescheduleError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, services.ErrProposalNotFound), errors.Is(err, services.ErrRescheduleOptionNotFound):
+		responses.NewAPIResponse(c).NotFound(msg, responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrOwnProposal):
+		responses.NewAPIResponse(c).Forbidden(msg, responses.ErrCodeForbidden)
+	case errors.Is(err, services.ErrSlotUnavailable):
+		responses.NewAPIResponse(c).Conflict("Lawyer is not available at the requested time", responses.ErrCodeTimeSlotUnavailable)
+	case errors.Is(err, services.ErrProposalNotPending), errors.Is(err, services.ErrProposalSuperseded):
+		responses.NewAPIResponse(c).Conflict(msg, responses.ErrCodeConflict)
+	case errors.As(err, new(*services.BookingPolicyError)),
+		errors.Is(err, services.ErrInvalidReschedule),
+		errors.Is(err, services.ErrNotReschedulable):
+		responses.NewAPIResponse(c).BadRequest(msg, responses.ErrCodeInvalidRequest)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to process reschedule", responses.ErrCodeDatabaseError)
+	}
+}
+
+// @Summary Propose a reschedule
+// @Description The client or the lawyer offers one to five alternative times. Each must be bookable under the lawyer's booking settings, and the appointment must start later than the reschedule window. A pending proposal is superseded.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param proposal body ProposeRescheduleRequest true "Alternative times"
+// @Success 201 {object} models.RescheduleProposal
+// @Failure 400 {object} responses.APIErrorResponse "Invalid options or reschedule window closed"
+// @Failure 403 {object} responses.APIErrorResponse "Not a party to the appointment"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 409 {object} responses.APIErrorResponse "Time slot not available"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/reschedule-proposals [post]
+func ProposeRescheduleHandler(c *gin.Context) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+	if party == "" {
+		responses.NewAPIResponse(c).Forbidden("Only the client or the lawyer can propose a reschedule", responses.ErrCodeForbidden)
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	var req ProposeRescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	options := make([]models.RescheduleOption, 0, len(req.Options))
+	for _, option := range req.Options {
+		options = append(options, models.RescheduleOption{StartTime: option.StartTime, EndTime: option.EndTime})
+	}
+
+	proposal, err := services.NewRescheduleService().ProposeReschedule(appointment, userID, party, options, req.Reason)
+	if err != nil {
+		respondRescheduleError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityRescheduleProposal, proposal.ID, nil, proposal)
+	responses.NewAPIResponse(c).Created(proposal)
+}
+
+// @Summary List reschedule proposals
+// @Description Returns every reschedule proposal of an appointment with its options, newest first
+// @Tags appointments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Success 200 {array} models.RescheduleProposal
+// @Failure 403 {object} responses.APIErrorResponse "No access to this appointment"
+// @Failure 404 {object} responses.APIErrorResponse "Appointment not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/reschedule-proposals [get]
+func GetRescheduleProposalsHandler(c *gin.Context) {
+	appointment, _, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+
+	proposals, err := services.NewRescheduleService().GetProposals(appointment.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve reschedule proposals", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(proposals)
+}
+
+// @Summary Accept a reschedule proposal
+// @Description The party that did not make the proposal moves the appointment to one of its options. The appointment keeps its ID and chat and reminders are sent again for the new time.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param proposalId path int true "Proposal ID"
+// @Param request body AcceptRescheduleRequest true "Chosen option"
+// @Success 200 {object} models.Appointment
+// @Failure 400 {object} responses.APIErrorResponse "Reschedule window closed or option no longer bookable"
+// @Failure 403 {object} responses.APIErrorResponse "Not the other party"
+// @Failure 404 {object} responses.APIErrorResponse "Proposal or option not found"
+// @Failure 409 {object} responses.APIErrorResponse "Proposal no longer pending or time slot taken"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/reschedule-proposals/{proposalId}/accept [post]
+func AcceptRescheduleHandler(c *gin.Context) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+	if party == "" {
+		responses.NewAPIResponse(c).Forbidden("Only the client or the lawyer can respond to a reschedule", responses.ErrCodeForbidden)
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	proposalID, err := strconv.Atoi(c.Param("proposalId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid proposal ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req AcceptRescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	before := services.AuditSnapshot(appointment)
+	updated, err := services.NewRescheduleService().AcceptReschedule(appointment.ID, proposalID, req.OptionID, userID, party)
+	if err != nil {
+		respondRescheduleError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionReschedule, models.AuditEntityAppointment, appointment.ID, before, updated)
+	// Only the parties respond to proposals, and the new link follows the window
+	if !services.MeetingLinkVisible(updated.StartTime, updated.EndTime, services.NewMeetingService().MeetingLinkWindow(), time.Now()) {
+		updated.MeetingLink = nil
+	}
+	responses.NewAPIResponse(c).OK(updated)
+}
+
+// @Summary Decline a reschedule proposal
+// @Description The party that did not make the proposal turns down all of its options. The appointment keeps its current time.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Appointment ID"
+// @Param proposalId path int true "Proposal ID"
+// @Param request body DeclineRescheduleRequest false "Reason"
+// @Success 200 {object} models.RescheduleProposal
+// @Failure 403 {object} responses.APIErrorResponse "Not the other party"
+// @Failure 404 {object} responses.APIErrorResponse "Proposal not found"
+// @Failure 409 {object} responses.APIErrorResponse "Proposal no longer pending"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /appointments/{id}/reschedule-proposals/{proposalId}/decline [post]
+func DeclineRescheduleHandler(c *gin.Context) {
+	appointment, party, ok := loadAppointmentForParty(c)
+	if !ok {
+		return
+	}
+	if party == "" {
+		responses.NewAPIResponse(c).Forbidden("Only the client or the lawyer can respond to a reschedule", responses.ErrCodeForbidden)
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	proposalID, err := strconv.Atoi(c.Param("proposalId"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid proposal ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	var req DeclineRescheduleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+			return
+		}
+	}
+
+	proposal, err := services.NewRescheduleService().DeclineReschedule(appointment.ID, proposalID, userID, party, req.Reason)
+	if err != nil {
+		respondRescheduleError(c, err)
+		return
+	}
+
+	recordAudit(c, models.AuditActionReject, models.AuditEntityRescheduleProposal, proposal.ID, nil, proposal)
+	responses.NewAPIResponse(c).OK(proposal)
+}
+
+// @Summary Get reschedule window
+// @Description Returns how many hours before its start an appointment can still be rescheduled (admin only)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} RescheduleSettingsRequest
+// @Router /admin/settings/reschedule [get]
+func GetRescheduleSettingsHandler(c *gin.Context) {
+	hours, err := services.NewPlatformSettingService().GetRescheduleWindowHours()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load reschedule settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(RescheduleSettingsRequest{WindowHours: hours})
+}
+
+// @Summary Update reschedule window
+// @Description Sets how many hours before its start an appointment can still be rescheduled (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RescheduleSettingsRequest true "Reschedule window"
+// @Success 200 {object} RescheduleSettingsRequest
+// @Failure 400 {object} responses.APIErrorResponse "Invalid window"
+// @Router /admin/settings/reschedule [put]
+func UpdateRescheduleSettingsHandler(c *gin.Context) {
+	var req RescheduleSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	settingService := services.NewPlatformSettingService()
+	previous, _ := settingService.GetRescheduleWindowHours()
+	if err := settingService.SetRescheduleWindowHours(req.WindowHours, adminID); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	recordAuditByKey(c, models.AuditActionSettingsChange, models.AuditEntityPlatformSetting, models.SettingRescheduleWindowHours,
+		map[string]interface{}{"value": previous},
+		map[string]interface{}{"value": req.WindowHours})
+
+	responses.NewAPIResponse(c).OK(req)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+func TestRespondRescheduleError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{services.ErrProposalNotFound, http.StatusNotFound},
+		{services.ErrRescheduleOptionNotFound, http.StatusNotFound},
+		{services.ErrOwnProposal, http.StatusForbidden},
+		{services.ErrSlotUnavailable, http.StatusConflict},
+		{fmt.Errorf("%w: it was accepted", services.ErrProposalNotPending), http.StatusConflict},
+		{services.ErrProposalSuperseded, http.StatusConflict},
+		{&services.BookingPolicyError{Reason: "outside the lawyer's available hours"}, http.StatusBadRequest},
+		{fmt.Errorf("%w: options must not repeat", services.ErrInvalidReschedule), http.StatusBadRequest},
+		{fmt.Errorf("%w: it is cancelled", services.ErrNotReschedulable), http.StatusBadRequest},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		respondRescheduleError(c, tt.err)
+		if w.Code != tt.want {
+			t.Errorf("%v: status = %d, want %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package responses
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,10 +31,15 @@ const (
 	ErrCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
 	ErrCodeForbidden          ErrorCode = "FORBIDDEN"
 	ErrCodeEmailNotVerified   ErrorCode = "EMAIL_NOT_VERIFIED"
+	ErrCodeInvalidMFACode     ErrorCode = "INVALID_MFA_CODE"
+	ErrCodeMFARequired        ErrorCode = "MFA_REQUIRED"
+	ErrCodeAccountLocked      ErrorCode = "ACCOUNT_LOCKED"
+	ErrCodeTooManyRequests    ErrorCode = "TOO_MANY_REQUESTS"
 
 	// Resource errors
 	ErrCodeResourceNotFound      ErrorCode = "RESOURCE_NOT_FOUND"
 	ErrCodeResourceAlreadyExists ErrorCode = "RESOURCE_ALREADY_EXISTS"
+	ErrCodeConflict              ErrorCode = "CONFLICT"
 
 	// Validation errors
 	ErrCodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
@@ -46,6 +54,9 @@ const (
 	ErrCodeOperationFailed        ErrorCode = "OPERATION_FAILED"
 	ErrCodeEmailIsAlreadyInUse    ErrorCode = "EMAIL_IS_ALREADY_IN_USE"
 	ErrCodeEmailIsAlreadyVerified ErrorCode = "EMAIL_IS_ALREADY_VERIFIED"
+	ErrCodeTimeSlotUnavailable    ErrorCode = "TIME_SLOT_UNAVAILABLE"
+	ErrCodeInvalidTransition      ErrorCode = "INVALID_STATUS_TRANSITION"
+	ErrCodeConflictOfInterest     ErrorCode = "CONFLICT_OF_INTEREST"
 )
 
 // Success returns a successful response with data wrapped in a data field
@@ -124,6 +135,31 @@ func (r *APIResponse) NotFound(message string, code ErrorCode) {
 	r.Error(http.StatusNotFound, message, code)
 }
 
+// Conflict returns a 409 Conflict response with an error message and code
+func (r *APIResponse) Conflict(message string, code ErrorCode) {
+	if code == "" {
+		code = ErrCodeConflict
+	}
+	r.Error(http.StatusConflict, message, code)
+}
+
+// TooManyRequests returns a 429 Too Many Requests response with a Retry-After header
+func (r *APIResponse) TooManyRequests(message string, code ErrorCode, retryAfter time.Duration) {
+	if code == "" {
+		code = ErrCodeTooManyRequests
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	r.ctx.Header("Retry-After", strconv.Itoa(seconds))
+	r.ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"code":        code,
+		"retry_after": seconds,
+	})
+}
+
 // InternalServerError returns a 500 Internal Server Error response with an error message and code
 func (r *APIResponse) InternalServerError(message string, code ErrorCode) {
 	if code == "" {
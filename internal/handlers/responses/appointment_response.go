@@ -2,17 +2,23 @@ package responses
 
 import (
 	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
 )
 
 // AppointmentResponse represents the API response structure for an appointment
 type AppointmentResponse struct {
 	ID           int       `json:"id"`
 	UserID       int       `json:"user_id"`
+	MatterID     *int      `json:"matter_id,omitempty"`
 	Description  *string   `json:"description,omitempty"`
 	StartTime    time.Time `json:"start_time"`
 	EndTime      time.Time `json:"end_time"`
 	Status       string    `json:"status"`
 	Notes        *string   `json:"notes,omitempty"`
+	// MeetingLink is shown to the parties from MeetingLinkAvailableAt until the end
+	MeetingLink            *string    `json:"meeting_link,omitempty"`
+	MeetingLinkAvailableAt *time.Time `json:"meeting_link_available_at,omitempty"`
 	ChatEnabled  bool      `json:"chat_enabled"`
 	IsLawyerViewed bool      `json:"is_lawyer_viewed"`
 	IsClientViewed bool      `json:"is_client_viewed"`
@@ -25,4 +31,10 @@ type AppointmentResponse struct {
 	// Lawyer information
 	Lawyer LawyerBrief `json:"lawyer"`
 	Client UserProfile `json:"client"`
+
+	// Intake holds the client's answers to the lawyer's intake form, if one was required
+	Intake *models.IntakeResponse `json:"intake,omitempty"`
+
+	// OpposingParties are the parties the client declared they are in dispute with
+	OpposingParties []models.OpposingParty `json:"opposing_parties,omitempty"`
 }
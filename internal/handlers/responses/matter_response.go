@@ -0,0 +1,38 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// Kinds of matter timeline entries
+const (
+	TimelineAppointment        = "appointment"
+	TimelineStatusChange       = "status_change"
+	TimelineChatMessage        = "chat_message"
+	TimelineAttachment         = "attachment"
+	TimelineConsultationRecord = "consultation_record"
+)
+
+// MatterAppointment is an appointment as shown in a matter's timeline
+type MatterAppointment struct {
+	ID          int       `json:"id"`
+	Status      string    `json:"status"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Description *string   `json:"description,omitempty"`
+}
+
+// MatterTimelineItem is one event in a matter's history. Type says which of the
+// detail fields is set; every event belongs to one of the matter's appointments.
+type MatterTimelineItem struct {
+	Type               string                           `json:"type"`
+	OccurredAt         time.Time                        `json:"occurred_at"`
+	AppointmentID      int                              `json:"appointment_id"`
+	Appointment        *MatterAppointment               `json:"appointment,omitempty"`
+	StatusChange       *models.AppointmentStatusHistory `json:"status_change,omitempty"`
+	ChatMessage        *models.ChatMessage              `json:"chat_message,omitempty"`
+	Attachment         *models.Attachment               `json:"attachment,omitempty"`
+	ConsultationRecord *models.ConsultationRecord       `json:"consultation_record,omitempty"`
+}
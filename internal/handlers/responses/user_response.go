@@ -9,9 +9,13 @@ type UserResponse struct {
 	Nickname          *string   `json:"nickname"`
 	FirstName         *string   `json:"first_name"`
 	LastName          *string   `json:"last_name"`
+	FirstNameKana     *string   `json:"first_name_kana"`
+	LastNameKana      *string   `json:"last_name_kana"`
 	ProfileImage      *string   `json:"profile_image,omitempty"`
 	Role              string    `json:"role"`
 	HasNewAppointment bool      `json:"has_new_appointment"`
+	MFAEnabled        bool      `json:"mfa_enabled"`
+	ImpersonatorID    *int      `json:"impersonator_id,omitempty"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
@@ -14,7 +14,7 @@ import (
 // CreateReviewRequest represents the request to create a review
 type CreateReviewRequest struct {
 	LawyerID      int     `json:"lawyer_id" binding:"required"`
-	AppointmentID *int    `json:"appointment_id,omitempty"`
+	AppointmentID *int    `json:"appointment_id"` // The attended appointment being reviewed
 	Rating        int     `json:"rating" binding:"required,min=1,max=5"`
 	Comment       *string `json:"comment,omitempty"`
 }
@@ -330,7 +330,7 @@ func GetUserReviewsHandler(c *gin.Context) {
 }
 
 // @Summary Create review
-// @Description Creates a new review
+// @Description Creates a review of an appointment the client attended. Appointments that were not completed, including no-shows, cannot be reviewed.
 // @Tags reviews
 // @Accept json
 // @Produce json
@@ -357,26 +357,28 @@ func CreateReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// If an appointment ID is provided, verify that it belongs to the user and lawyer
-	if req.AppointmentID != nil {
-		appointmentService := services.NewAppointmentService()
-		appointment, err := appointmentService.GetAppointmentByID(*req.AppointmentID)
-		if err != nil {
-			responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
-			return
-		}
+	// Reviews unlock only for appointments that took place
+	if req.AppointmentID == nil {
+		responses.NewAPIResponse(c).BadRequest("appointment_id is required: you can only review appointments you have attended", responses.ErrCodeInvalidRequest)
+		return
+	}
+	appointmentService := services.NewAppointmentService()
+	appointment, err := appointmentService.GetAppointmentByID(*req.AppointmentID)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("Appointment not found", responses.ErrCodeResourceNotFound)
+		return
+	}
 
-		// Check if the appointment belongs to the user and lawyer
-		if appointment.UserID != userID || appointment.LawyerID != req.LawyerID {
-			responses.NewAPIResponse(c).Forbidden("You can only review appointments you have attended", responses.ErrCodeForbidden)
-			return
-		}
+	// Check if the appointment belongs to the user and lawyer
+	if appointment.UserID != userID || appointment.LawyerID != req.LawyerID {
+		responses.NewAPIResponse(c).Forbidden("You can only review appointments you have attended", responses.ErrCodeForbidden)
+		return
+	}
 
-		// Check if the appointment is completed
-		if appointment.Status != "completed" {
-			responses.NewAPIResponse(c).BadRequest("You can only review completed appointments", responses.ErrCodeInvalidRequest)
-			return
-		}
+	// Completed appointments are the attended ones; no-shows cannot be reviewed
+	if appointment.Status != models.AppointmentStatusCompleted {
+		responses.NewAPIResponse(c).BadRequest("You can only review appointments you have attended", responses.ErrCodeInvalidRequest)
+		return
 	}
 
 	// Create the review
@@ -392,7 +394,7 @@ func CreateReviewHandler(c *gin.Context) {
 	reviewService := services.NewReviewService()
 
 	// Create the review
-	err := reviewService.CreateReview(&review)
+	err = reviewService.CreateReview(&review)
 	if err != nil {
 		responses.NewAPIResponse(c).InternalServerError("Failed to create review", responses.ErrCodeDatabaseError)
 		return
@@ -443,14 +445,10 @@ func UpdateReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if the user owns this review
-	if existingReview.UserID != userID {
-		// Check if the user is an admin
-		userRole, _ := middleware.GetUserRole(c)
-		if userRole != "admin" {
-			responses.NewAPIResponse(c).Forbidden("You can only update your own reviews", responses.ErrCodeForbidden)
-			return
-		}
+	// Check if the user owns this review or moderates reviews
+	if existingReview.UserID != userID && !middleware.HasPermission(c, models.PermReviewsModerate) {
+		responses.NewAPIResponse(c).Forbidden("You can only update your own reviews", responses.ErrCodeForbidden)
+		return
 	}
 
 	// Parse the request body
@@ -460,6 +458,8 @@ func UpdateReviewHandler(c *gin.Context) {
 		return
 	}
 
+	before := services.AuditSnapshot(existingReview)
+
 	// Update the review
 	existingReview.Rating = req.Rating
 	existingReview.Comment = req.Comment
@@ -471,6 +471,8 @@ func UpdateReviewHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityReview, id, before, existingReview)
+
 	responses.NewAPIResponse(c).OK(existingReview)
 }
 
@@ -514,14 +516,10 @@ func DeleteReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if the user owns this review
-	if review.UserID != userID {
-		// Check if the user is an admin
-		userRole, _ := middleware.GetUserRole(c)
-		if userRole != "admin" {
-			responses.NewAPIResponse(c).Forbidden("You can only delete your own reviews", responses.ErrCodeForbidden)
-			return
-		}
+	// Check if the user owns this review or moderates reviews
+	if review.UserID != userID && !middleware.HasPermission(c, models.PermReviewsModerate) {
+		responses.NewAPIResponse(c).Forbidden("You can only delete your own reviews", responses.ErrCodeForbidden)
+		return
 	}
 
 	// Delete the review
@@ -531,6 +529,8 @@ func DeleteReviewHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityReview, id, review, nil)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Review deleted successfully"})
 }
 
@@ -551,10 +551,9 @@ func DeleteReviewHandler(c *gin.Context) {
 // @Router /reviews/{id}/status [put]
 // UpdateReviewStatusHandler updates a review's approval status (admin only)
 func UpdateReviewStatusHandler(c *gin.Context) {
-	// Ensure the user is an admin
-	userRole, ok := middleware.GetUserRole(c)
-	if !ok || userRole != "admin" {
-		responses.NewAPIResponse(c).Forbidden("Admin access required", responses.ErrCodeForbidden)
+	// Ensure the user moderates reviews
+	if !middleware.HasPermission(c, models.PermReviewsModerate) {
+		responses.NewAPIResponse(c).Forbidden("Moderator access required", responses.ErrCodeForbidden)
 		return
 	}
 
@@ -583,6 +582,8 @@ func UpdateReviewStatusHandler(c *gin.Context) {
 		return
 	}
 
+	before := services.AuditSnapshot(existingReview)
+
 	// Update the review status
 	existingReview.ApprovedStatus = &req.ApprovedStatus
 
@@ -602,6 +603,8 @@ func UpdateReviewStatusHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionStatusChange, models.AuditEntityReview, id, before, existingReview)
+
 	responses.NewAPIResponse(c).OK(existingReview)
 }
 
@@ -625,10 +628,8 @@ type PinReviewRequest struct {
 // @Failure 500 {object} responses.APIErrorResponse "Internal server error"
 // @Router /reviews/{id}/pin [put]
 func PinReviewHandler(c *gin.Context) {
-	// admin only
-	role, ok := middleware.GetUserRole(c)
-	if !ok || role != "admin" {
-		responses.NewAPIResponse(c).Forbidden("Admin access required", responses.ErrCodeForbidden)
+	if !middleware.HasPermission(c, models.PermReviewsModerate) {
+		responses.NewAPIResponse(c).Forbidden("Moderator access required", responses.ErrCodeForbidden)
 		return
 	}
 
@@ -648,6 +649,7 @@ func PinReviewHandler(c *gin.Context) {
 	}
 
 	svc := services.NewReviewService()
+	previous, _ := svc.GetReviewByID(id)
 	updated, err := svc.PinReview(id, *req.IsPin)
 	if err != nil {
 		// if not found
@@ -662,6 +664,8 @@ func PinReviewHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionPin, models.AuditEntityReview, id, previous, updated)
+
 	responses.NewAPIResponse(c).OK(updated)
 }
 
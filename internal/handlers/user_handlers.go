@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/kotolino/lawyer/internal/models"
 	"net/http"
@@ -21,7 +22,7 @@ type UpdateUserStatusRequest struct {
 
 // UpdateUserRoleRequest represents the request to update a user's role
 type UpdateUserRoleRequest struct {
-	Role string `json:"role" binding:"required,oneof=client admin"`
+	Role string `json:"role" binding:"required,oneof=client admin moderator"`
 }
 
 // PasswordUpdateRequest represents the request to update a user's password
@@ -47,9 +48,8 @@ type PasswordUpdateRequest struct {
 // @Router /users [get]
 // GetUsersHandler returns a list of users
 func GetUsersHandler(c *gin.Context) {
-	// Get the user role from the context
-	userRole, ok := middleware.GetUserRole(c)
-	if !ok || userRole != "admin" {
+	// Check the user may list users
+	if !middleware.HasPermission(c, models.PermUsersView) {
 		responses.NewAPIResponse(c).Forbidden("Only administrators can view user list", responses.ErrCodeForbidden)
 		return
 	}
@@ -112,16 +112,13 @@ func GetUsersHandler(c *gin.Context) {
 // @Router /users/{id} [get]
 // GetUserByIDHandler returns a user by ID
 func GetUserByIDHandler(c *gin.Context) {
-	// Get the user role from the context
-	userRole, ok := middleware.GetUserRole(c)
+	// Get the current user ID from the context
+	currentUserID, ok := middleware.GetUserID(c)
 	if !ok {
 		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
 		return
 	}
 
-	// Get the current user ID from the context
-	currentUserID, _ := middleware.GetUserID(c)
-
 	// Get the user ID from the URL
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -131,7 +128,7 @@ func GetUserByIDHandler(c *gin.Context) {
 	}
 
 	// Check if the user has access to view this user
-	if userRole != "admin" && currentUserID != id {
+	if currentUserID != id && !middleware.HasPermission(c, models.PermUsersView) {
 		responses.NewAPIResponse(c).Forbidden("You do not have access to view this user", responses.ErrCodeForbidden)
 		return
 	}
@@ -170,15 +167,15 @@ func GetUserByIDHandler(c *gin.Context) {
 // @Router /users/{id} [put]
 // UpdateUserHandler updates a user
 func UpdateUserHandler(c *gin.Context) {
-	// Get the user role from the context
-	userRole, ok := middleware.GetUserRole(c)
+	// Get the current user ID from the context
+	currentUserID, ok := middleware.GetUserID(c)
 	if !ok {
 		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
 		return
 	}
 
-	// Get the current user ID from the context
-	currentUserID, _ := middleware.GetUserID(c)
+	// Staff who manage users may edit anyone, including role and active status
+	canManageUsers := middleware.HasPermission(c, models.PermUsersManage)
 
 	// Get the user ID from the URL
 	idStr := c.Param("id")
@@ -189,7 +186,7 @@ func UpdateUserHandler(c *gin.Context) {
 	}
 
 	// Check if the user has access to update this user
-	if userRole != "admin" && currentUserID != id {
+	if currentUserID != id && !canManageUsers {
 		responses.NewAPIResponse(c).Forbidden("You do not have access to update this user", responses.ErrCodeForbidden)
 		return
 	}
@@ -198,7 +195,7 @@ func UpdateUserHandler(c *gin.Context) {
 	userService := services.NewUserService()
 
 	// Check if the user exists
-	_, err = userService.GetUserByID(id)
+	existingUser, err := userService.GetUserByID(id)
 	if err != nil {
 		responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
 		return
@@ -212,7 +209,7 @@ func UpdateUserHandler(c *gin.Context) {
 	}
 
 	// Restrict updating of some fields
-	if userRole != "admin" {
+	if !canManageUsers {
 		delete(req, "role")
 		delete(req, "is_active")
 	}
@@ -229,6 +226,10 @@ func UpdateUserHandler(c *gin.Context) {
 	// Update the user
 	err = userService.UpdateUser(id, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidTimezone) {
+			responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+			return
+		}
 		responses.NewAPIResponse(c).InternalServerError("Failed to update user", responses.ErrCodeDatabaseError)
 		return
 	}
@@ -240,6 +241,8 @@ func UpdateUserHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionUpdate, models.AuditEntityUser, id, existingUser, updatedUser)
+
 	// Hide the password
 	updatedUser.Password = ""
 
@@ -262,9 +265,8 @@ func UpdateUserHandler(c *gin.Context) {
 // @Router /users/{id} [delete]
 // DeleteUserHandler deletes a user
 func DeleteUserHandler(c *gin.Context) {
-	// Get the user role from the context
-	userRole, ok := middleware.GetUserRole(c)
-	if !ok || userRole != "admin" {
+	// Check the user may manage users
+	if !middleware.HasPermission(c, models.PermUsersManage) {
 		responses.NewAPIResponse(c).Forbidden("Only administrators can delete users", responses.ErrCodeForbidden)
 		return
 	}
@@ -280,6 +282,13 @@ func DeleteUserHandler(c *gin.Context) {
 	// Get the user service
 	userService := services.NewUserService()
 
+	// Check if the user exists
+	user, err := userService.GetUserByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+		return
+	}
+
 	// Delete the user
 	err = userService.DeleteUser(id)
 	if err != nil {
@@ -287,6 +296,8 @@ func DeleteUserHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionDelete, models.AuditEntityUser, id, user, nil)
+
 	// Return success
 	responses.NewAPIResponse(c).OK(gin.H{"message": "User deleted successfully"})
 }
@@ -323,11 +334,11 @@ func UpdatePasswordHandler(c *gin.Context) {
 		return
 	}
 
-	// Get the user role from the context
-	userRole, _ := middleware.GetUserRole(c)
+	// Staff who manage users may reset passwords without knowing the current one
+	canManageUsers := middleware.HasPermission(c, models.PermUsersManage)
 
 	// Check if the user has access to update this user's password
-	if userRole != "admin" && currentUserID != id {
+	if currentUserID != id && !canManageUsers {
 		responses.NewAPIResponse(c).Forbidden("You do not have access to update this user's password", responses.ErrCodeForbidden)
 		return
 	}
@@ -343,7 +354,7 @@ func UpdatePasswordHandler(c *gin.Context) {
 	userService := services.NewUserService()
 
 	// Update the password
-	err = userService.UpdatePassword(id, req.CurrentPassword, req.NewPassword, userRole == "admin")
+	err = userService.UpdatePassword(id, req.CurrentPassword, req.NewPassword, canManageUsers)
 	if err != nil {
 		if err.Error() == "current password is incorrect" {
 			responses.NewAPIResponse(c).BadRequest("Current password is incorrect", responses.ErrCodeInvalidRequest)
@@ -353,6 +364,10 @@ func UpdatePasswordHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionPasswordChange, models.AuditEntityUser, id, nil, map[string]interface{}{
+		"reset_by_staff": currentUserID != id,
+	})
+
 	// Return success
 	responses.NewAPIResponse(c).OK(gin.H{"message": "Password updated successfully"})
 }
@@ -374,9 +389,8 @@ func UpdatePasswordHandler(c *gin.Context) {
 // @Router /users/{id}/status [put]
 // UpdateUserStatusHandler updates a user's active status (admin only)
 func UpdateUserStatusHandler(c *gin.Context) {
-	// Check if the user is an admin
-	userRole, ok := middleware.GetUserRole(c)
-	if !ok || userRole != "admin" {
+	// Check the user may manage users
+	if !middleware.HasPermission(c, models.PermUsersManage) {
 		responses.NewAPIResponse(c).Forbidden("Only administrators can update user status", responses.ErrCodeForbidden)
 		return
 	}
@@ -399,6 +413,14 @@ func UpdateUserStatusHandler(c *gin.Context) {
 	// Get the user service
 	userService := services.NewUserService()
 
+	// Get the existing user
+	user, err := userService.GetUserByID(id)
+	if err != nil {
+		responses.NewAPIResponse(c).NotFound("User not found", responses.ErrCodeResourceNotFound)
+		return
+	}
+	before := services.AuditSnapshot(user)
+
 	// Update the user status
 	err = userService.UpdateUserStatus(id, req.IsActive)
 	if err != nil {
@@ -406,6 +428,9 @@ func UpdateUserStatusHandler(c *gin.Context) {
 		return
 	}
 
+	user.IsActive = req.IsActive
+	recordAudit(c, models.AuditActionStatusChange, models.AuditEntityUser, id, before, user)
+
 	// Send notification email based on the account status (locked or unlocked)
 	// !req.IsActive means the account is locked, req.IsActive means it's unlocked
 	err = userService.SendAccountStatusNotificationEmail(id, !req.IsActive)
@@ -434,9 +459,8 @@ func UpdateUserStatusHandler(c *gin.Context) {
 // @Router /users/{id}/role [put]
 // UpdateUserRoleHandler updates a user's role (admin only)
 func UpdateUserRoleHandler(c *gin.Context) {
-	// Check if the user is an admin
-	userRole, ok := middleware.GetUserRole(c)
-	if !ok || userRole != "admin" {
+	// Check the user may manage users
+	if !middleware.HasPermission(c, models.PermUsersManage) {
 		responses.NewAPIResponse(c).Forbidden("Only administrators can update user roles", responses.ErrCodeForbidden)
 		return
 	}
@@ -466,6 +490,8 @@ func UpdateUserRoleHandler(c *gin.Context) {
 		return
 	}
 
+	before := services.AuditSnapshot(user)
+
 	// Update the user role
 	user.Role = req.Role
 
@@ -476,6 +502,8 @@ func UpdateUserRoleHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditActionRoleChange, models.AuditEntityUser, id, before, user)
+
 	responses.NewAPIResponse(c).OK(gin.H{"message": "User role updated successfully"})
 }
 
@@ -485,6 +513,9 @@ type CreateUserRequest struct {
 	Role      string  `json:"role" binding:"required,oneof=client lawyer"`
 	FirstName *string `json:"first_name,omitempty"`
 	LastName  *string `json:"last_name,omitempty"`
+	// FirstNameKana and LastNameKana are the reading of the name in kana
+	FirstNameKana *string `json:"first_name_kana,omitempty"`
+	LastNameKana  *string `json:"last_name_kana,omitempty"`
 }
 
 // @Summary Create user
@@ -502,8 +533,8 @@ type CreateUserRequest struct {
 // @Router /users [post]
 // CreateUserHandler allows an admin to spin up a new user
 func CreateUserHandler(c *gin.Context) {
-	// 1) check admin
-	if role, ok := middleware.GetUserRole(c); !ok || role != "admin" {
+	// 1) check permission
+	if !middleware.HasPermission(c, models.PermUsersManage) {
 		responses.NewAPIResponse(c).
 			Forbidden("Only administrators can create users", responses.ErrCodeForbidden)
 		return
@@ -517,12 +548,14 @@ func CreateUserHandler(c *gin.Context) {
 	}
 
 	u := &models.User{
-		Email:     req.Email,
-		Password:  req.Password,
-		Role:      req.Role,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		IsActive:  true,
+		Email:         req.Email,
+		Password:      req.Password,
+		Role:          req.Role,
+		FirstName:     req.FirstName,
+		LastName:      req.LastName,
+		FirstNameKana: req.FirstNameKana,
+		LastNameKana:  req.LastNameKana,
+		IsActive:      true,
 	}
 
 	userSvc := services.NewUserService()
@@ -533,6 +566,7 @@ func CreateUserHandler(c *gin.Context) {
 	}
 
 	u.Password = ""
+	recordAudit(c, models.AuditActionCreate, models.AuditEntityUser, u.ID, nil, u)
 
 	// 6) return 201
 	responses.NewAPIResponse(c).
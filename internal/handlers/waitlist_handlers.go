@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/middleware"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// JoinWaitlistRequest registers interest in a fully booked lawyer
+type JoinWaitlistRequest struct {
+	LawyerID int `json:"lawyer_id" binding:"required"`
+	// DateRanges and TimesOfDay are in the client's time zone. Leave TimesOfDay
+	// empty to accept any time.
+	DateRanges models.DateRanges    `json:"date_ranges" binding:"required"`
+	TimesOfDay models.TimeIntervals `json:"times_of_day"`
+	Note       *string              `json:"note,omitempty"`
+}
+
+// WaitlistSettingsRequest sets how long a slot offered to a waitlisted client is held
+type WaitlistSettingsRequest struct {
+	HoldMinutes int `json:"hold_minutes"`
+}
+
+// @Summary List my waitlist entries
+// @Description Lists the current user's waitlist entries, newest first
+// @Tags waitlist
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.WaitlistEntry
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /waitlist [get]
+func GetMyWaitlistEntriesHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	entries, err := services.NewWaitlistService().GetEntriesByUser(userID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve waitlist entries", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(entries)
+}
+
+// @Summary Join a lawyer's waitlist
+// @Description Registers interest in a lawyer with preferred date ranges and times of day. When a matching slot opens it is held for the client for a limited time and they are notified; clients are served in the order they joined.
+// @Tags waitlist
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param entry body JoinWaitlistRequest true "Waitlist entry"
+// @Success 201 {object} models.WaitlistEntry
+// @Failure 400 {object} responses.APIErrorResponse "Invalid waitlist entry"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer not found"
+// @Failure 409 {object} responses.APIErrorResponse "Already on this lawyer's waitlist"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /waitlist [post]
+func JoinWaitlistHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	var req JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	entry := &models.WaitlistEntry{
+		UserID:     userID,
+		LawyerID:   req.LawyerID,
+		DateRanges: req.DateRanges,
+		TimesOfDay: req.TimesOfDay,
+		Note:       req.Note,
+	}
+	if err := services.NewWaitlistService().JoinWaitlist(entry); err != nil {
+		respondWaitlistError(c, err)
+		return
+	}
+
+	responses.NewAPIResponse(c).Created(entry)
+}
+
+// @Summary Leave a waitlist
+// @Description Cancels one of the current user's active waitlist entries. A slot held for it is released to the next client.
+// @Tags waitlist
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Waitlist entry ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} responses.APIErrorResponse "Invalid ID or entry not active"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Waitlist entry not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /waitlist/{id} [delete]
+func CancelWaitlistEntryHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	entryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid waitlist entry ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := services.NewWaitlistService().CancelEntry(userID, entryID); err != nil {
+		respondWaitlistError(c, err)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Waitlist entry cancelled"})
+}
+
+// @Summary List my held slots
+// @Description Lists the slots currently held for the current user. A held slot can only be booked by that user until expires_at.
+// @Tags waitlist
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.SlotHold
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /waitlist/holds [get]
+func GetMySlotHoldsHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	holds, err := services.NewWaitlistService().GetHoldsByUser(userID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve held slots", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(holds)
+}
+
+// @Summary Decline a held slot
+// @Description Gives up a slot held for the current user so it can be offered to the next client. The user stays on the waitlist.
+// @Tags waitlist
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Hold ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} responses.APIErrorResponse "Invalid ID or hold no longer active"
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Hold not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /waitlist/holds/{id}/decline [post]
+func DeclineSlotHoldHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		responses.NewAPIResponse(c).Unauthorized("Authentication required", responses.ErrCodeUnauthorized)
+		return
+	}
+
+	holdID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		responses.NewAPIResponse(c).BadRequest("Invalid hold ID", responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	if err := services.NewWaitlistService().DeclineHold(userID, holdID); err != nil {
+		respondWaitlistError(c, err)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(gin.H{"message": "Held slot declined"})
+}
+
+// @Summary Get my waitlist
+// @Description Lists the active entries on the current lawyer's waitlist in the order they are served
+// @Tags lawyers
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.WaitlistEntry
+// @Failure 401 {object} responses.APIErrorResponse "Unauthorized"
+// @Failure 404 {object} responses.APIErrorResponse "Lawyer profile not found"
+// @Failure 500 {object} responses.APIErrorResponse "Internal server error"
+// @Router /lawyers/profile/waitlist [get]
+func GetMyLawyerWaitlistHandler(c *gin.Context) {
+	lawyer, ok := currentLawyerProfile(c)
+	if !ok {
+		return
+	}
+
+	entries, err := services.NewWaitlistService().GetLawyerQueue(lawyer.ID)
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to retrieve waitlist", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(entries)
+}
+
+// @Summary Get waitlist hold length
+// @Description Returns how many minutes a slot offered to a waitlisted client stays reserved for them (admin only)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} WaitlistSettingsRequest
+// @Router /admin/settings/waitlist [get]
+func GetWaitlistSettingsHandler(c *gin.Context) {
+	minutes, err := services.NewPlatformSettingService().GetWaitlistHoldMinutes()
+	if err != nil {
+		responses.NewAPIResponse(c).InternalServerError("Failed to load waitlist settings", responses.ErrCodeDatabaseError)
+		return
+	}
+
+	responses.NewAPIResponse(c).OK(WaitlistSettingsRequest{HoldMinutes: minutes})
+}
+
+// @Summary Update waitlist hold length
+// @Description Sets how many minutes a slot offered to a waitlisted client stays reserved for them. Holds already made keep their expiry. (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body WaitlistSettingsRequest true "Hold length"
+// @Success 200 {object} WaitlistSettingsRequest
+// @Failure 400 {object} responses.APIErrorResponse "Invalid hold length"
+// @Router /admin/settings/waitlist [put]
+func UpdateWaitlistSettingsHandler(c *gin.Context) {
+	var req WaitlistSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidRequest)
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	settingService := services.NewPlatformSettingService()
+	previous, _ := settingService.GetWaitlistHoldMinutes()
+	if err := settingService.SetWaitlistHoldMinutes(req.HoldMinutes, adminID); err != nil {
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+		return
+	}
+
+	recordAuditByKey(c, models.AuditActionSettingsChange, models.AuditEntityPlatformSetting, models.SettingWaitlistHoldMinutes,
+		map[string]interface{}{"value": previous},
+		map[string]interface{}{"value": req.HoldMinutes})
+
+	responses.NewAPIResponse(c).OK(req)
+}
+
+func respondWaitlistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWaitlist):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeValidationFailed)
+	case errors.Is(err, services.ErrLawyerNotFound):
+		responses.NewAPIResponse(c).NotFound("Lawyer not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrWaitlistEntryNotFound):
+		responses.NewAPIResponse(c).NotFound("Waitlist entry not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrWaitlistHoldNotFound):
+		responses.NewAPIResponse(c).NotFound("Hold not found", responses.ErrCodeResourceNotFound)
+	case errors.Is(err, services.ErrAlreadyWaitlisted):
+		responses.NewAPIResponse(c).Conflict("Already on this lawyer's waitlist", responses.ErrCodeResourceAlreadyExists)
+	case errors.Is(err, services.ErrWaitlistEntryNotActive), errors.Is(err, services.ErrWaitlistHoldNotActive):
+		responses.NewAPIResponse(c).BadRequest(err.Error(), responses.ErrCodeInvalidTransition)
+	default:
+		responses.NewAPIResponse(c).InternalServerError("Failed to update waitlist", responses.ErrCodeDatabaseError)
+	}
+}
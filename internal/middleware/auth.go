@@ -12,10 +12,29 @@ import (
 	"github.com/kotolino/lawyer/internal/models"
 )
 
+// Token scopes for tokens that must not be accepted as regular session tokens
+const (
+	ScopeMFAChallenge = "mfa_challenge"
+	ScopeMFASetup     = "mfa_setup"
+	// ScopeImpersonation marks a token a staff member uses to act as another user
+	ScopeImpersonation = "impersonation"
+)
+
+// MFATokenAudience is the audience of MFA challenge and setup tokens. Session
+// tokens have none.
+const MFATokenAudience = "mfa"
+
+// MFAChallengeTTL is how long a user has to complete the second login step
+const MFAChallengeTTL = 5 * time.Minute
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID int    `json:"user_id"`
 	Role   string `json:"role"`
+	Scope  string `json:"scope,omitempty"`
+	// ActorID is the staff member behind an impersonation token; UserID is the impersonated subject
+	ActorID         int `json:"actor_id,omitempty"`
+	ImpersonationID int `json:"impersonation_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -38,14 +57,8 @@ func Auth(cfg *config.Config) gin.HandlerFunc {
 		// Extract the token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse the token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("invalid signing method")
-			}
-			return []byte(cfg.JWT.Secret), nil
-		})
+		// Parse the token, resolving the verification key from its alg and kid headers
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, getKeySet(cfg).Keyfunc)
 
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -65,10 +78,21 @@ func Auth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// MFA challenge and setup tokens only work on their own endpoints
+		if (claims.Scope != "" && claims.Scope != ScopeImpersonation) || len(claims.Audience) > 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token scope"})
+			return
+		}
+
 		// Set the user ID and role in the context
 		c.Set("userID", claims.UserID)
 		c.Set("userRole", claims.Role)
 
+		if claims.Scope == ScopeImpersonation {
+			handleImpersonatedRequest(c, claims)
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -116,11 +140,8 @@ func GenerateToken(user *models.User, cfg *config.Config) (string, error) {
 		},
 	}
 
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with the secret key
-	tokenString, err := token.SignedString([]byte(cfg.JWT.Secret))
+	// Sign the token with the active key
+	tokenString, err := getKeySet(cfg).Sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -128,6 +149,48 @@ func GenerateToken(user *models.User, cfg *config.Config) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateMFAToken generates a short-lived token that only proves the password step
+// of a login succeeded. scope must be ScopeMFAChallenge or ScopeMFASetup. It is
+// signed with a key of its own that is not published in the JWKS.
+func GenerateMFAToken(user *models.User, scope string, cfg *config.Config) (string, time.Time, error) {
+	expirationTime := time.Now().Add(MFAChallengeTTL)
+
+	claims := &Claims{
+		UserID: user.ID,
+		Role:   user.Role,
+		Scope:  scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   user.Email,
+			Audience:  jwt.ClaimStrings{MFATokenAudience},
+		},
+	}
+
+	tokenString, err := getKeySet(cfg).SignMFA(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// ParseMFAToken validates an MFA token and checks that it carries the expected scope
+func ParseMFAToken(tokenString, scope string, cfg *config.Config) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, getKeySet(cfg).MFAKeyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(MFATokenAudience))
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.Scope != scope {
+		return nil, errors.New("invalid MFA token scope")
+	}
+
+	return claims, nil
+}
+
 // GetUserID gets the user ID from the context
 func GetUserID(c *gin.Context) (int, bool) {
 	userID, exists := c.Get("userID")
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// useKeySet installs a key set for the duration of a test
+func useKeySet(t *testing.T, keySet *KeySet) {
+	t.Helper()
+
+	keySetMu.Lock()
+	previous := currentKeySet
+	currentKeySet = keySet
+	keySetMu.Unlock()
+	t.Cleanup(func() {
+		keySetMu.Lock()
+		currentKeySet = previous
+		keySetMu.Unlock()
+	})
+}
+
+// ed25519KeySet loads a key set with one freshly generated Ed25519 key, keeping the
+// shared secret accepted as during a migration
+func ed25519KeySet(t *testing.T, secret string) *KeySet {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "k1.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keySet, err := LoadKeySet(config.JWTConfig{Algorithm: "EdDSA", KeysDir: dir, Secret: secret, AcceptHS256: true})
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	return keySet
+}
+
+// authStatus runs Auth over a request carrying token
+func authStatus(cfg *config.Config, token string) int {
+	router := gin.New()
+	router.GET("/api/me", Auth(cfg), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestMFATokensAreNotSessionTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const secret = "test-secret"
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: secret, ExpirationHours: 1}}
+	user := &models.User{ID: 7, Role: string(models.RoleLawyer), Email: "lawyer@example.com"}
+
+	keySets := map[string]*KeySet{
+		"HS256": newHMACKeySet(secret),
+		"EdDSA": ed25519KeySet(t, secret),
+	}
+	for name, keySet := range keySets {
+		t.Run(name, func(t *testing.T) {
+			useKeySet(t, keySet)
+
+			mfaToken, _, err := GenerateMFAToken(user, ScopeMFAChallenge, cfg)
+			if err != nil {
+				t.Fatalf("GenerateMFAToken: %v", err)
+			}
+			sessionToken, err := GenerateToken(user, cfg)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+
+			if claims, err := ParseMFAToken(mfaToken, ScopeMFAChallenge, cfg); err != nil || claims.UserID != user.ID {
+				t.Errorf("ParseMFAToken = %+v, %v; want the user's claims", claims, err)
+			}
+			if _, err := ParseMFAToken(mfaToken, ScopeMFASetup, cfg); err == nil {
+				t.Error("challenge token accepted as a setup token")
+			}
+			if code := authStatus(cfg, mfaToken); code != http.StatusUnauthorized {
+				t.Errorf("Auth with an MFA token = %d, want %d", code, http.StatusUnauthorized)
+			}
+
+			if code := authStatus(cfg, sessionToken); code != http.StatusOK {
+				t.Errorf("Auth with a session token = %d, want %d", code, http.StatusOK)
+			}
+			if _, err := ParseMFAToken(sessionToken, ScopeMFAChallenge, cfg); err == nil {
+				t.Error("session token accepted as an MFA token")
+			}
+
+			// Not even the shared secret verifies an MFA token
+			if _, err := jwt.ParseWithClaims(mfaToken, &Claims{}, func(*jwt.Token) (interface{}, error) {
+				return []byte(secret), nil
+			}); err == nil {
+				t.Error("MFA token verifies with the session secret")
+			}
+		})
+	}
+}
+
+func TestParseMFATokenRejectsSessionKeySignatures(t *testing.T) {
+	const secret = "test-secret"
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: secret}}
+	useKeySet(t, newHMACKeySet(secret))
+
+	// A token shaped like an MFA token but signed with the session key
+	claims := &Claims{
+		UserID: 7,
+		Scope:  ScopeMFAChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Audience:  jwt.ClaimStrings{MFATokenAudience},
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["typ"] = mfaTokenType
+	forged, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseMFAToken(forged, ScopeMFAChallenge, cfg); err == nil {
+		t.Error("MFA token signed with the session secret was accepted")
+	}
+}
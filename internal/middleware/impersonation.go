@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// Context keys set on requests made with an impersonation token
+const (
+	actorIDKey         = "actorID"
+	impersonationIDKey = "impersonationID"
+)
+
+// impersonationSafeRoutes are the only writes an impersonation session may make without
+// allow_destructive: ending the session and marking things read. Every other request that
+// is not a GET, HEAD or OPTIONS is blocked, so new routes are covered without being listed.
+var impersonationSafeRoutes = map[string]bool{
+	"POST /api/auth/impersonation/end":  true,
+	"POST /api/auth/logout":             true,
+	"PATCH /api/notifications/:id/read": true,
+	"PATCH /api/notifications/read-all": true,
+	"PATCH /api/chats/:id/read":         true,
+}
+
+// GenerateImpersonationToken issues a token that authenticates as subject on behalf of the
+// session's actor. It expires with the session.
+func GenerateImpersonationToken(subject *models.User, session *models.ImpersonationSession, cfg *config.Config) (string, error) {
+	claims := &Claims{
+		UserID:          subject.ID,
+		Role:            subject.Role,
+		Scope:           ScopeImpersonation,
+		ActorID:         session.ActorID,
+		ImpersonationID: session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   subject.Email,
+		},
+	}
+
+	return getKeySet(cfg).Sign(claims)
+}
+
+// GetActorID returns the staff member behind the current request when it is impersonated
+func GetActorID(c *gin.Context) (int, bool) {
+	actorID, exists := c.Get(actorIDKey)
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := actorID.(int)
+	return id, ok
+}
+
+// GetImpersonationID returns the impersonation session of the current request, if any
+func GetImpersonationID(c *gin.Context) (int, bool) {
+	sessionID, exists := c.Get(impersonationIDKey)
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := sessionID.(int)
+	return id, ok
+}
+
+// IsImpersonating reports whether the current request uses an impersonation token
+func IsImpersonating(c *gin.Context) bool {
+	_, ok := GetImpersonationID(c)
+	return ok
+}
+
+// handleImpersonatedRequest checks that the session behind an impersonation token is still
+// open, blocks destructive actions the session does not allow, and records the request.
+func handleImpersonatedRequest(c *gin.Context, claims *Claims) {
+	impersonationService := services.NewImpersonationService()
+
+	session, err := impersonationService.GetActiveSession(claims.ImpersonationID)
+	if err != nil || session.ActorID != claims.ActorID || session.SubjectID != claims.UserID {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Impersonation session has ended"})
+		return
+	}
+
+	c.Set(actorIDKey, session.ActorID)
+	c.Set(impersonationIDKey, session.ID)
+
+	entry := &models.ImpersonationRequestLog{
+		SessionID: session.ID,
+		ActorID:   session.ActorID,
+		SubjectID: session.SubjectID,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+	}
+	if route := c.FullPath(); route != "" {
+		entry.Route = &route
+	}
+	if ip := c.ClientIP(); ip != "" {
+		entry.IPAddress = &ip
+	}
+
+	if isDestructiveRequest(c) && !session.AllowDestructive {
+		entry.Blocked = true
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This action is not allowed while impersonating"})
+	} else {
+		c.Next()
+	}
+
+	entry.StatusCode = c.Writer.Status()
+	impersonationService.LogRequest(entry)
+}
+
+// isDestructiveRequest reports whether a request may change data. Only reads and the
+// routes in impersonationSafeRoutes are considered harmless.
+func isDestructiveRequest(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !impersonationSafeRoutes[c.Request.Method+" "+c.FullPath()]
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIsDestructiveRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		method string
+		route  string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/api/matters/:id", "/api/matters/1", false},
+		{http.MethodHead, "/api/matters/:id", "/api/matters/1", false},
+		{http.MethodPost, "/api/auth/impersonation/end", "/api/auth/impersonation/end", false},
+		{http.MethodPatch, "/api/notifications/:id/read", "/api/notifications/3/read", false},
+		{http.MethodDelete, "/api/notifications/:id", "/api/notifications/3", true},
+		// Routes that were never listed anywhere are still blocked
+		{http.MethodPost, "/api/calendar/feed", "/api/calendar/feed", true},
+		{http.MethodPost, "/api/waitlist", "/api/waitlist", true},
+		{http.MethodPut, "/api/appointments/:id/consultation-record", "/api/appointments/5/consultation-record", true},
+		{http.MethodPost, "/api/matters/:id/documents/:documentId/checkout", "/api/matters/1/documents/2/checkout", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.route, func(t *testing.T) {
+			router := gin.New()
+			var got bool
+			router.Handle(tt.method, tt.route, func(c *gin.Context) {
+				got = isDestructiveRequest(c)
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if got != tt.want {
+				t.Errorf("isDestructiveRequest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kotolino/lawyer/config"
+)
+
+var (
+	keySetMu      sync.RWMutex
+	currentKeySet *KeySet
+)
+
+// signingKey is one entry of the key set. private is nil for verify-only (retired) keys.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet holds the keys used to sign and verify session tokens
+type KeySet struct {
+	method     jwt.SigningMethod
+	active     *signingKey
+	keys       map[string]*signingKey
+	hmacSecret []byte
+	// mfaSecret signs MFA challenge and setup tokens. It is derived from the secret
+	// or the active private key and never published, so nothing that verifies
+	// session tokens can accept an MFA token.
+	mfaSecret []byte
+}
+
+// mfaTokenType is the typ header of MFA tokens
+const mfaTokenType = "mfa+jwt"
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// InitSigningKeys loads the key set described by cfg. It must be called once at startup
+// before any token is issued or verified.
+func InitSigningKeys(cfg *config.Config) error {
+	keySet, err := LoadKeySet(cfg.JWT)
+	if err != nil {
+		return err
+	}
+
+	keySetMu.Lock()
+	currentKeySet = keySet
+	keySetMu.Unlock()
+	return nil
+}
+
+// getKeySet returns the loaded key set, falling back to the shared secret if
+// InitSigningKeys has not been called
+func getKeySet(cfg *config.Config) *KeySet {
+	keySetMu.RLock()
+	keySet := currentKeySet
+	keySetMu.RUnlock()
+	if keySet != nil {
+		return keySet
+	}
+	return newHMACKeySet(cfg.JWT.Secret)
+}
+
+func newHMACKeySet(secret string) *KeySet {
+	return &KeySet{
+		method:     jwt.SigningMethodHS256,
+		hmacSecret: []byte(secret),
+		mfaSecret:  deriveMFASecret([]byte(secret)),
+	}
+}
+
+// deriveMFASecret derives the MFA token key from secret key material
+func deriveMFASecret(material []byte) []byte {
+	mac := hmac.New(sha256.New, material)
+	mac.Write([]byte("lawyer mfa token"))
+	return mac.Sum(nil)
+}
+
+// LoadKeySet builds a key set from JWT configuration. For RS256 and EdDSA every
+// <kid>.pem file in KeysDir is loaded; ActiveKeyID selects the signing key.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	if cfg.Algorithm == "" || cfg.Algorithm == "HS256" {
+		return newHMACKeySet(cfg.Secret), nil
+	}
+
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	keySet := &KeySet{
+		method: method,
+		keys:   make(map[string]*signingKey),
+	}
+	if cfg.AcceptHS256 {
+		keySet.hmacSecret = []byte(cfg.Secret)
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.KeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := loadPEMKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %s: %v", file, err)
+		}
+		key.id = kid
+		keySet.keys[kid] = key
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" {
+		// Without an explicit choice, a directory with exactly one private key is unambiguous
+		for _, key := range keySet.keys {
+			if key.private == nil {
+				continue
+			}
+			if activeID != "" {
+				return nil, errors.New("JWT_ACTIVE_KID is required when JWT_KEYS_DIR contains several private keys")
+			}
+			activeID = key.id
+		}
+	}
+
+	active, ok := keySet.keys[activeID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("no private key found for active kid %q in %s", activeID, cfg.KeysDir)
+	}
+	if active.method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("active key %q is a %s key but JWT_ALGORITHM is %s", activeID, active.method.Alg(), method.Alg())
+	}
+	keySet.active = active
+
+	// The default secret may be in use alongside asymmetric keys, so the MFA key
+	// comes from the private key instead
+	switch private := active.private.(type) {
+	case *rsa.PrivateKey:
+		keySet.mfaSecret = deriveMFASecret(private.D.Bytes())
+	case ed25519.PrivateKey:
+		keySet.mfaSecret = deriveMFASecret(private.Seed())
+	}
+
+	return keySet, nil
+}
+
+// loadPEMKey reads an RSA or Ed25519 key. Private keys may be PKCS#8 or PKCS#1;
+// public keys must be PKIX.
+func loadPEMKey(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &signingKey{method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &signingKey{method: jwt.SigningMethodEdDSA, public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// Sign signs claims with the active key, setting the kid header for asymmetric keys
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if k.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.hmacSecret)
+	}
+
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.id
+	return token.SignedString(k.active.private)
+}
+
+// SignMFA signs the claims of an MFA token with the MFA key
+func (k *KeySet) SignMFA(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["typ"] = mfaTokenType
+	return token.SignedString(k.mfaSecret)
+}
+
+// MFAKeyfunc resolves the verification key of an MFA token
+func (k *KeySet) MFAKeyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwt.SigningMethodHS256.Alg() || token.Header["typ"] != mfaTokenType {
+		return nil, errors.New("not an MFA token")
+	}
+	return k.mfaSecret, nil
+}
+
+// Keyfunc resolves the verification key for a token from its alg and kid headers
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Header["typ"] == mfaTokenType {
+		return nil, errors.New("MFA tokens are not session tokens")
+	}
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if k.hmacSecret == nil || token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, errors.New("invalid signing method")
+		}
+		return k.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public half of every asymmetric key, including retired ones,
+// so tokens they signed can still be verified elsewhere
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// GetJWKS returns the public keys of the loaded key set
+func GetJWKS(cfg *config.Config) JWKS {
+	return getKeySet(cfg).JWKS()
+}
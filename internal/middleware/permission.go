@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// permissionsKey caches the current user's effective permissions on the request context
+const permissionsKey = "permissions"
+
+// RequirePermission middleware allows the request only if the user holds every listed permission
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetUserID(c); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		granted, err := loadPermissions(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+			return
+		}
+
+		for _, p := range permissions {
+			if !granted[p] {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the current user holds a permission. Use it for checks
+// that depend on the resource, such as "owner or moderator".
+func HasPermission(c *gin.Context, permission string) bool {
+	granted, err := loadPermissions(c)
+	if err != nil {
+		return false
+	}
+	return granted[permission]
+}
+
+// loadPermissions returns the user's effective permissions, querying them at most once per request
+func loadPermissions(c *gin.Context) (map[string]bool, error) {
+	if cached, exists := c.Get(permissionsKey); exists {
+		if granted, ok := cached.(map[string]bool); ok {
+			return granted, nil
+		}
+	}
+
+	granted := make(map[string]bool)
+	userID, ok := GetUserID(c)
+	if !ok {
+		return granted, nil
+	}
+	role, _ := GetUserRole(c)
+
+	permissions, err := services.NewPermissionService().GetEffectivePermissions(userID, role)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range permissions {
+		granted[p] = true
+	}
+
+	c.Set(permissionsKey, granted)
+	return granted, nil
+}
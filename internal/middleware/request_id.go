@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kotolino/lawyer/internal/services"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey = "requestID"
+
+// validRequestID limits client-supplied IDs to something safe to log and store
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID middleware tags every request with an ID, reusing the caller's X-Request-ID
+// when it looks sane, and echoes it in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the current request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// GetAuditContext describes the current request for the audit trail
+func GetAuditContext(c *gin.Context) services.AuditContext {
+	userID, _ := GetUserID(c)
+	role, _ := GetUserRole(c)
+	actorID, _ := GetActorID(c)
+
+	return services.AuditContext{
+		ActorID:        userID,
+		ActorRole:      role,
+		ImpersonatorID: actorID,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		RequestID:      GetRequestID(c),
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
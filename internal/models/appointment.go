@@ -8,29 +8,35 @@ import (
 
 // Appointment represents a scheduled meeting between a user and a lawyer
 type Appointment struct {
-	ID               int            `json:"id" gorm:"primaryKey"`
-	UserID           int            `json:"user_id" gorm:"not null;index"`
-	User             User           `gorm:"foreignKey:UserID"`
-	LawyerID         int            `json:"lawyer_id" gorm:"not null;index"`
-	Lawyer           Lawyer         `gorm:"foreignKey:LawyerID"`
-	Description      *string        `json:"description,omitempty"`
-	StartTime        time.Time      `json:"start_time" gorm:"not null;index"`
-	EndTime          time.Time      `json:"end_time" gorm:"not null"`
-	Status           string         `json:"status" gorm:"not null;default:pending"`
-	MeetingLink      *string        `json:"meeting_link,omitempty"`
-	Notes            *string        `json:"notes,omitempty"`
-	ChatEnabled      bool           `json:"chat_enabled" gorm:"default:false"`
-	RejectReason     *string        `json:"reject_reason,omitempty"`
-	CancelReason     *string        `json:"cancel_reason,omitempty"`
-	AdminReason      *string        `json:"admin_reason,omitempty"`
-	DayReminderSent  bool           `json:"day_reminder_sent" gorm:"default:false"`
-	HourReminderSent bool           `json:"hour_reminder_sent" gorm:"default:false"`
-	IsLawyerViewed   bool           `json:"is_lawyer_viewed" gorm:"default:false"`
-	IsClientViewed   bool           `json:"is_client_viewed" gorm:"default:false"`
-	CreatedAt        time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt        time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
-	LawyerName       string         `json:"lawyer_name" gorm:"-"`
+	ID               int               `json:"id" gorm:"primaryKey"`
+	UserID           int               `json:"user_id" gorm:"not null;index"`
+	User             User              `gorm:"foreignKey:UserID"`
+	LawyerID         int               `json:"lawyer_id" gorm:"not null;index"`
+	Lawyer           Lawyer            `gorm:"foreignKey:LawyerID"`
+	MatterID         *int              `json:"matter_id,omitempty" gorm:"index"`
+	Description      *string           `json:"description,omitempty"`
+	StartTime        time.Time         `json:"start_time" gorm:"not null;index"`
+	EndTime          time.Time         `json:"end_time" gorm:"not null"`
+	Status           AppointmentStatus `json:"status" gorm:"not null;default:pending"`
+	MeetingLink      *string           `json:"meeting_link,omitempty"`
+	MeetingProvider  *string           `json:"-"`
+	MeetingID        *string           `json:"-"`
+	Notes            *string           `json:"notes,omitempty"`
+	ChatEnabled      bool              `json:"chat_enabled" gorm:"default:false"`
+	RejectReason     *string           `json:"reject_reason,omitempty"`
+	CancelReason     *string           `json:"cancel_reason,omitempty"`
+	AdminReason      *string           `json:"admin_reason,omitempty"`
+	DayReminderSent  bool              `json:"day_reminder_sent" gorm:"default:false"`
+	HourReminderSent bool              `json:"hour_reminder_sent" gorm:"default:false"`
+	IsLawyerViewed   bool              `json:"is_lawyer_viewed" gorm:"default:false"`
+	IsClientViewed   bool              `json:"is_client_viewed" gorm:"default:false"`
+	CalendarSequence int               `json:"-" gorm:"not null;default:0"`
+	CreatedAt        time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt    `json:"-" gorm:"index"`
+	LawyerName       string            `json:"lawyer_name" gorm:"-"`
+	Intake           *IntakeResponse   `json:"intake,omitempty" gorm:"-"`
+	OpposingParties  []OpposingParty   `json:"opposing_parties,omitempty" gorm:"-"`
 }
 
 // TableName specifies the table name for the Appointment model
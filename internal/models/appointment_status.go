@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// AppointmentStatus is the lifecycle state of an appointment
+type AppointmentStatus string
+
+const (
+	AppointmentStatusPending   AppointmentStatus = "pending"
+	AppointmentStatusConfirmed AppointmentStatus = "confirmed"
+	AppointmentStatusRejected  AppointmentStatus = "rejected"
+	AppointmentStatusCancelled AppointmentStatus = "cancelled"
+	AppointmentStatusCompleted AppointmentStatus = "completed"
+	// AppointmentStatusNoShowClient and AppointmentStatusNoShowLawyer record which
+	// party did not attend
+	AppointmentStatusNoShowClient AppointmentStatus = "no_show_client"
+	AppointmentStatusNoShowLawyer AppointmentStatus = "no_show_lawyer"
+)
+
+// appointmentTransitions lists the statuses each status may move to. Statuses
+// without an entry are terminal.
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	AppointmentStatusPending: {
+		AppointmentStatusConfirmed,
+		AppointmentStatusRejected,
+		AppointmentStatusCancelled,
+	},
+	AppointmentStatusConfirmed: {
+		AppointmentStatusCompleted,
+		AppointmentStatusCancelled,
+		AppointmentStatusRejected,
+		AppointmentStatusNoShowClient,
+		AppointmentStatusNoShowLawyer,
+	},
+}
+
+// IsValid checks if the status is valid
+func (s AppointmentStatus) IsValid() bool {
+	switch s {
+	case AppointmentStatusPending, AppointmentStatusConfirmed, AppointmentStatusRejected,
+		AppointmentStatusCancelled, AppointmentStatusCompleted, AppointmentStatusNoShowClient,
+		AppointmentStatusNoShowLawyer:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the string representation of the status
+func (s AppointmentStatus) String() string {
+	return string(s)
+}
+
+// IsTerminal reports whether no further transitions are possible
+func (s AppointmentStatus) IsTerminal() bool {
+	return len(appointmentTransitions[s]) == 0
+}
+
+// CanTransitionTo reports whether the transition table allows moving to next
+func (s AppointmentStatus) CanTransitionTo(next AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// InactiveAppointmentStatuses are statuses whose appointments no longer hold a time slot
+func InactiveAppointmentStatuses() []AppointmentStatus {
+	return []AppointmentStatus{AppointmentStatusRejected, AppointmentStatusCancelled}
+}
+
+// TerminalAppointmentStatuses are statuses that cannot change any more
+func TerminalAppointmentStatuses() []AppointmentStatus {
+	return []AppointmentStatus{
+		AppointmentStatusRejected,
+		AppointmentStatusCancelled,
+		AppointmentStatusCompleted,
+		AppointmentStatusNoShowClient,
+		AppointmentStatusNoShowLawyer,
+	}
+}
+
+// IsNoShow reports whether the status records that a party did not attend
+func (s AppointmentStatus) IsNoShow() bool {
+	return s == AppointmentStatusNoShowClient || s == AppointmentStatusNoShowLawyer
+}
+
+// IsAttendanceOutcome reports whether the status records whether an appointment took
+// place. Such statuses are only set once the appointment has ended.
+func (s AppointmentStatus) IsAttendanceOutcome() bool {
+	return s == AppointmentStatusCompleted || s.IsNoShow()
+}
+
+// AppointmentStatusHistory records one status transition of an appointment.
+// FromStatus is nil for the entry written when the appointment is created and
+// ChangedBy is nil for automatic transitions.
+type AppointmentStatusHistory struct {
+	ID            int                `json:"id" gorm:"primaryKey"`
+	AppointmentID int                `json:"appointment_id" gorm:"not null;index"`
+	FromStatus    *AppointmentStatus `json:"from_status"`
+	ToStatus      AppointmentStatus  `json:"to_status" gorm:"not null"`
+	ChangedBy     *int               `json:"changed_by"`
+	ChangedByRole *string            `json:"changed_by_role"`
+	Reason        *string            `json:"reason,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the AppointmentStatusHistory model
+func (AppointmentStatusHistory) TableName() string {
+	return "appointment_status_history"
+}
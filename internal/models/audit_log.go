@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Audit actions
+const (
+	AuditActionCreate             = "create"
+	AuditActionUpdate             = "update"
+	AuditActionDelete             = "delete"
+	AuditActionRestore            = "restore"
+	AuditActionStatusChange       = "status_change"
+	AuditActionRoleChange         = "role_change"
+	AuditActionReject             = "reject"
+	AuditActionVerify             = "verify"
+	AuditActionPin                = "pin"
+	AuditActionHide               = "hide"
+	AuditActionAccept             = "accept"
+	AuditActionPasswordChange     = "password_change"
+	AuditActionMFAReset           = "mfa_reset"
+	AuditActionPermissionsChange  = "permissions_change"
+	AuditActionSettingsChange     = "settings_change"
+	AuditActionImpersonationStart = "impersonation_start"
+	AuditActionReschedule         = "reschedule"
+	AuditActionAttendance         = "attendance"
+	AuditActionNoShowReset        = "no_show_reset"
+	AuditActionAcknowledge        = "acknowledge"
+	AuditActionCheckOut           = "check_out"
+	AuditActionCheckIn            = "check_in"
+)
+
+// Audited entity types
+const (
+	AuditEntityUser                  = "user"
+	AuditEntityLawyer                = "lawyer"
+	AuditEntityAppointment           = "appointment"
+	AuditEntityReview                = "review"
+	AuditEntityQuestion              = "question"
+	AuditEntityAnswer                = "answer"
+	AuditEntityChatMessage           = "chat_message"
+	AuditEntityArticle               = "article"
+	AuditEntityRole                  = "role"
+	AuditEntityPlatformSetting       = "platform_setting"
+	AuditEntityImpersonation         = "impersonation_session"
+	AuditEntityAvailabilityException = "availability_exception"
+	AuditEntityPlatformClosure       = "platform_closure"
+	AuditEntityRescheduleProposal    = "reschedule_proposal"
+	AuditEntityExternalCalendar      = "external_calendar"
+	AuditEntityIntakeForm            = "intake_form"
+	AuditEntityConflictCheck         = "conflict_check"
+	AuditEntityConsultationRecord    = "consultation_record"
+	AuditEntityFollowUpTask          = "follow_up_task"
+	AuditEntityMatter                = "matter"
+	AuditEntityDocument              = "document"
+	AuditEntityDocumentFolder        = "document_folder"
+)
+
+// AuditLog is one entry in the append-only audit trail. Each entry's Hash covers its
+// content and the previous entry's hash, so removing or editing a row breaks the chain.
+type AuditLog struct {
+	ID             int64        `json:"id" gorm:"primaryKey"`
+	ActorID        *int         `json:"actor_id,omitempty"`
+	ActorRole      *string      `json:"actor_role,omitempty"`
+	ImpersonatorID *int         `json:"impersonator_id,omitempty"`
+	Action         string       `json:"action" gorm:"not null"`
+	EntityType     string       `json:"entity_type" gorm:"not null"`
+	EntityID       *string      `json:"entity_id,omitempty"`
+	Changes        FieldChanges `json:"changes" gorm:"type:jsonb;not null"`
+	IPAddress      *string      `json:"ip_address,omitempty"`
+	UserAgent      *string      `json:"user_agent,omitempty"`
+	RequestID      *string      `json:"request_id,omitempty"`
+	PrevHash       string       `json:"prev_hash" gorm:"not null"`
+	Hash           string       `json:"hash" gorm:"not null"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// TableName specifies the table name for AuditLog
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
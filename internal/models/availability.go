@@ -0,0 +1,265 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeInterval is a span of wall-clock time within one day, written as "HH:MM".
+// End may be "24:00" to run until midnight.
+type TimeInterval struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Minutes returns the start and end of the interval as minutes after midnight
+func (i TimeInterval) Minutes() (start, end int, err error) {
+	start, err = parseClock(i.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err = parseClock(i.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start >= 24*60 {
+		return 0, 0, fmt.Errorf("interval cannot start at %s", i.Start)
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("interval %s-%s must start before it ends", i.Start, i.End)
+	}
+	return start, end, nil
+}
+
+// parseClock reads "HH:MM" into minutes after midnight, allowing "24:00"
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || len(parts[0]) < 1 || len(parts[0]) > 2 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	hour, errH := strconv.Atoi(parts[0])
+	minute, errM := strconv.Atoi(parts[1])
+	if errH != nil || errM != nil || hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// TimeIntervals is a list of intervals within one day
+type TimeIntervals []TimeInterval
+
+// Validate checks that every interval is well formed and that none overlap
+func (intervals TimeIntervals) Validate() error {
+	type span struct{ start, end int }
+	spans := make([]span, 0, len(intervals))
+	for _, interval := range intervals {
+		start, end, err := interval.Minutes()
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span{start, end})
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start < spans[i-1].end {
+			return errors.New("intervals must not overlap")
+		}
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface for TimeIntervals
+func (intervals TimeIntervals) Value() (driver.Value, error) {
+	if intervals == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(intervals)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface for TimeIntervals
+func (intervals *TimeIntervals) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*intervals = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, intervals)
+	case string:
+		return json.Unmarshal([]byte(v), intervals)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// Availability is a lawyer's recurring weekly schedule. A day without intervals is
+// a day off.
+type Availability struct {
+	Monday    TimeIntervals `json:"monday,omitempty"`
+	Tuesday   TimeIntervals `json:"tuesday,omitempty"`
+	Wednesday TimeIntervals `json:"wednesday,omitempty"`
+	Thursday  TimeIntervals `json:"thursday,omitempty"`
+	Friday    TimeIntervals `json:"friday,omitempty"`
+	Saturday  TimeIntervals `json:"saturday,omitempty"`
+	Sunday    TimeIntervals `json:"sunday,omitempty"`
+}
+
+// ForWeekday returns the intervals scheduled on a day of the week
+func (a Availability) ForWeekday(day time.Weekday) TimeIntervals {
+	return *a.weekday(day)
+}
+
+// weekday returns the field holding the intervals of a day of the week
+func (a *Availability) weekday(day time.Weekday) *TimeIntervals {
+	switch day {
+	case time.Monday:
+		return &a.Monday
+	case time.Tuesday:
+		return &a.Tuesday
+	case time.Wednesday:
+		return &a.Wednesday
+	case time.Thursday:
+		return &a.Thursday
+	case time.Friday:
+		return &a.Friday
+	case time.Saturday:
+		return &a.Saturday
+	default:
+		return &a.Sunday
+	}
+}
+
+// Validate checks every day of the schedule
+func (a Availability) Validate() error {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if err := a.ForWeekday(day).Validate(); err != nil {
+			return fmt.Errorf("%s: %w", strings.ToLower(day.String()), err)
+		}
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface for Availability
+func (a Availability) Value() (driver.Value, error) {
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface for Availability. Schedules saved before
+// the format was typed may hold entries that do not parse or validate. Those entries
+// are logged and dropped rather than making the whole lawyer unreadable.
+func (a *Availability) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+
+	*a = Availability{}
+	var days map[string]json.RawMessage
+	if err := json.Unmarshal(data, &days); err != nil {
+		fmt.Printf("Ignoring unreadable availability %s: %v\n", data, err)
+		return nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		raw, ok := days[name]
+		if !ok {
+			continue
+		}
+		intervals, dropped := readIntervals(raw)
+		for _, reason := range dropped {
+			fmt.Printf("Ignoring availability entry on %s: %s\n", name, reason)
+		}
+		*a.weekday(day) = intervals
+	}
+	return nil
+}
+
+// readIntervals keeps the valid, non-overlapping intervals of one day of a stored
+// schedule, sorted by start, and describes each entry it left out
+func readIntervals(raw json.RawMessage) (TimeIntervals, []string) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, []string{fmt.Sprintf("%s is not a list of intervals", raw)}
+	}
+
+	type span struct {
+		interval   TimeInterval
+		start, end int
+	}
+	var dropped []string
+	spans := make([]span, 0, len(entries))
+	for _, entry := range entries {
+		var interval TimeInterval
+		if err := json.Unmarshal(entry, &interval); err != nil {
+			dropped = append(dropped, fmt.Sprintf("%s is not an interval", entry))
+			continue
+		}
+		start, end, err := interval.Minutes()
+		if err != nil {
+			dropped = append(dropped, err.Error())
+			continue
+		}
+		spans = append(spans, span{interval, start, end})
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var intervals TimeIntervals
+	lastEnd := -1
+	for _, s := range spans {
+		if s.start < lastEnd {
+			dropped = append(dropped, fmt.Sprintf("interval %s-%s overlaps an earlier one", s.interval.Start, s.interval.End))
+			continue
+		}
+		intervals = append(intervals, s.interval)
+		lastEnd = s.end
+	}
+	return intervals, dropped
+}
+
+// AvailabilityException overrides a lawyer's weekly schedule on one date. The
+// lawyer is either closed all day or works only the given intervals.
+type AvailabilityException struct {
+	ID        int           `json:"id" gorm:"primaryKey"`
+	LawyerID  int           `json:"lawyer_id" gorm:"not null;index"`
+	Date      Date          `json:"date" gorm:"type:date;not null"`
+	Closed    bool          `json:"closed" gorm:"not null;default:false"`
+	Intervals TimeIntervals `json:"intervals" gorm:"type:jsonb;not null"`
+	Reason    *string       `json:"reason,omitempty"`
+	CreatedAt time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the AvailabilityException model
+func (AvailabilityException) TableName() string {
+	return "availability_exceptions"
+}
+
+// Validate checks that the exception either closes the day or lists valid hours
+func (e AvailabilityException) Validate() error {
+	if e.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if e.Closed {
+		if len(e.Intervals) > 0 {
+			return errors.New("a closed day cannot have intervals")
+		}
+		return nil
+	}
+	if len(e.Intervals) == 0 {
+		return errors.New("intervals are required unless the day is closed")
+	}
+	return e.Intervals.Validate()
+}
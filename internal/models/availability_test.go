@@ -0,0 +1,64 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAvailabilityScan(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  Availability
+	}{
+		{
+			name:  "typed schedule",
+			value: []byte(`{"monday":[{"start":"09:00","end":"12:00"},{"start":"13:00","end":"17:00"}]}`),
+			want: Availability{Monday: TimeIntervals{
+				{Start: "09:00", End: "12:00"},
+				{Start: "13:00", End: "17:00"},
+			}},
+		},
+		{
+			name: "legacy entries are dropped",
+			value: `{
+				"monday": [{"start":"9:00","end":"12:00"}, {"start":"25:00","end":"26:00"}, "09:00-17:00", {"start":"14:00","end":"13:00"}],
+				"tuesday": "09:00-17:00",
+				"wednesday": null,
+				"holidays": [{"start":"10:00","end":"11:00"}]
+			}`,
+			want: Availability{Monday: TimeIntervals{{Start: "9:00", End: "12:00"}}},
+		},
+		{
+			name:  "overlaps keep the earliest interval",
+			value: `{"friday":[{"start":"10:00","end":"12:00"},{"start":"09:00","end":"11:00"},{"start":"12:00","end":"24:00"}]}`,
+			want: Availability{Friday: TimeIntervals{
+				{Start: "09:00", End: "11:00"},
+				{Start: "12:00", End: "24:00"},
+			}},
+		},
+		{
+			name:  "not an object",
+			value: `["monday"]`,
+			want:  Availability{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Availability{Sunday: TimeIntervals{{Start: "01:00", End: "02:00"}}}
+			if err := got.Scan(tt.value); err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan = %+v, want %+v", got, tt.want)
+			}
+			if err := got.Validate(); err != nil {
+				t.Errorf("scanned schedule does not validate: %v", err)
+			}
+		})
+	}
+
+	if err := new(Availability).Scan(42); err == nil {
+		t.Error("Scan(42) succeeded, want a type error")
+	}
+}
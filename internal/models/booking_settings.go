@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Slot lengths a lawyer can offer, in minutes
+var AllowedSlotMinutes = []int{30, 45, 60, 90}
+
+// Defaults used for lawyers who have not configured their booking settings
+const (
+	DefaultSlotMinutes      = 30
+	DefaultMinNoticeHours   = 48
+	DefaultMaxAdvanceDays   = 90
+	maxBookingBufferMinutes = 120
+	maxBookingAdvanceDays   = 365
+)
+
+// BookingSettings controls how clients can book a lawyer. A zero DailyCap means
+// there is no daily limit.
+type BookingSettings struct {
+	SlotMinutes         int `json:"slot_minutes"`
+	BufferBeforeMinutes int `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int `json:"buffer_after_minutes"`
+	MinNoticeHours      int `json:"min_notice_hours"`
+	MaxAdvanceDays      int `json:"max_advance_days"`
+	DailyCap            int `json:"daily_cap"`
+}
+
+// DefaultBookingSettings returns the settings of a lawyer who has not set any
+func DefaultBookingSettings() BookingSettings {
+	return BookingSettings{
+		SlotMinutes:    DefaultSlotMinutes,
+		MinNoticeHours: DefaultMinNoticeHours,
+		MaxAdvanceDays: DefaultMaxAdvanceDays,
+	}
+}
+
+// Validate checks that every setting is within its allowed range
+func (b BookingSettings) Validate() error {
+	allowed := false
+	for _, minutes := range AllowedSlotMinutes {
+		if b.SlotMinutes == minutes {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("slot_minutes must be one of %v", AllowedSlotMinutes)
+	}
+	if b.BufferBeforeMinutes < 0 || b.BufferBeforeMinutes > maxBookingBufferMinutes {
+		return fmt.Errorf("buffer_before_minutes must be between 0 and %d", maxBookingBufferMinutes)
+	}
+	if b.BufferAfterMinutes < 0 || b.BufferAfterMinutes > maxBookingBufferMinutes {
+		return fmt.Errorf("buffer_after_minutes must be between 0 and %d", maxBookingBufferMinutes)
+	}
+	if b.MinNoticeHours < 0 {
+		return errors.New("min_notice_hours must not be negative")
+	}
+	if b.MaxAdvanceDays < 1 || b.MaxAdvanceDays > maxBookingAdvanceDays {
+		return fmt.Errorf("max_advance_days must be between 1 and %d", maxBookingAdvanceDays)
+	}
+	if b.MinNoticeHours > b.MaxAdvanceDays*24 {
+		return errors.New("min_notice_hours must not exceed max_advance_days")
+	}
+	if b.DailyCap < 0 {
+		return errors.New("daily_cap must not be negative")
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface for BookingSettings
+func (b BookingSettings) Value() (driver.Value, error) {
+	bytes, err := json.Marshal(b)
+	return string(bytes), err
+}
+
+// Scan implements the sql.Scanner interface for BookingSettings
+func (b *BookingSettings) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, b)
+	case string:
+		return json.Unmarshal([]byte(v), b)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CalendarFeedToken is a user's secret iCalendar subscription token. Only its
+// SHA-256 hash is stored; the token itself is shown once when it is created.
+type CalendarFeedToken struct {
+	ID             int        `json:"id" gorm:"primaryKey"`
+	UserID         int        `json:"user_id" gorm:"not null;uniqueIndex"`
+	TokenHash      string     `json:"-" gorm:"not null;uniqueIndex"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// TableName specifies the table name for the CalendarFeedToken model
+func (CalendarFeedToken) TableName() string {
+	return "calendar_feed_tokens"
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Conflict check statuses
+const (
+	ConflictCheckClear     = "clear"
+	ConflictCheckPotential = "potential_conflict"
+)
+
+// What a conflict match compares: a party declared against the new booking, or the
+// booking client themself
+const (
+	ConflictSubjectOpposingParty = "opposing_party"
+	ConflictSubjectClient        = "client"
+)
+
+// Where a conflict match was found: one of the lawyer's past clients, or a party
+// declared as opposing in another of the lawyer's appointments or matters
+const (
+	ConflictSourcePastClient    = "past_client"
+	ConflictSourceOpposingParty = "opposing_party"
+)
+
+// MaxOpposingParties bounds how many opposing parties a booking can declare
+const MaxOpposingParties = 20
+
+// OpposingParty is a person or organization on the other side of the client's
+// matter, declared when booking or on a Matter so the lawyer can check for
+// conflicts of interest. It belongs to exactly one appointment or matter.
+type OpposingParty struct {
+	ID            int       `json:"id" gorm:"primaryKey"`
+	AppointmentID *int      `json:"appointment_id,omitempty" gorm:"index"`
+	MatterID      *int      `json:"matter_id,omitempty" gorm:"index"`
+	Name          string    `json:"name" gorm:"not null"`
+	NameKana      *string   `json:"name_kana,omitempty"`
+	Relationship  *string   `json:"relationship,omitempty"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the OpposingParty model
+func (OpposingParty) TableName() string {
+	return "opposing_parties"
+}
+
+// ConflictMatch is a name from the booking that resembles someone the lawyer has
+// acted for or against. Score is the similarity of the normalized names, 1 for an
+// exact match.
+type ConflictMatch struct {
+	Subject       string  `json:"subject"`
+	SubjectName   string  `json:"subject_name"`
+	Source        string  `json:"source"`
+	MatchedName   string  `json:"matched_name"`
+	AppointmentID *int    `json:"appointment_id,omitempty"`
+	MatterID      *int    `json:"matter_id,omitempty"`
+	UserID        *int    `json:"user_id,omitempty"`
+	Score         float64 `json:"score"`
+	Exact         bool    `json:"exact"`
+}
+
+// ConflictMatches is a list of conflict matches stored as JSON
+type ConflictMatches []ConflictMatch
+
+// Value implements the driver.Valuer interface for ConflictMatches
+func (m ConflictMatches) Value() (driver.Value, error) {
+	if m == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface for ConflictMatches
+func (m *ConflictMatches) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// ConflictCheck records one conflict-of-interest check of an appointment, kept for
+// compliance. A check with potential conflicts must be acknowledged by the lawyer,
+// with a note on why they may act, before the appointment can be confirmed.
+type ConflictCheck struct {
+	ID                  int             `json:"id" gorm:"primaryKey"`
+	AppointmentID       int             `json:"appointment_id" gorm:"not null;index"`
+	LawyerID            int             `json:"lawyer_id" gorm:"not null;index"`
+	CheckedBy           int             `json:"checked_by" gorm:"not null"`
+	Status              string          `json:"status" gorm:"not null"`
+	CheckedNames        StringArray     `json:"checked_names" gorm:"type:text[];not null"`
+	Matches             ConflictMatches `json:"matches" gorm:"type:jsonb;not null"`
+	AcknowledgedBy      *int            `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt      *time.Time      `json:"acknowledged_at,omitempty"`
+	AcknowledgementNote *string         `json:"acknowledgement_note,omitempty"`
+	CreatedAt           time.Time       `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the ConflictCheck model
+func (ConflictCheck) TableName() string {
+	return "conflict_checks"
+}
+
+// Cleared reports whether the check allows the appointment to be confirmed
+func (c ConflictCheck) Cleared() bool {
+	return c.Status == ConflictCheckClear || c.AcknowledgedAt != nil
+}
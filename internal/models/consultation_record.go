@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Follow-up task statuses
+const (
+	FollowUpTaskOpen = "open"
+	FollowUpTaskDone = "done"
+)
+
+// Who a follow-up task is for. Tasks for the lawyer are never shown to the client.
+const (
+	FollowUpAssigneeLawyer = "lawyer"
+	FollowUpAssigneeClient = "client"
+)
+
+// Consultation record limits
+const (
+	MaxConsultationTextLength = 10000
+	MaxFollowUpTasks          = 50
+)
+
+// ConsultationRecord is the lawyer's record of a completed consultation. Private
+// notes stay with the lawyer; the summary and next steps are shown to the client
+// once the lawyer shares the record.
+type ConsultationRecord struct {
+	ID            int            `json:"id" gorm:"primaryKey"`
+	AppointmentID int            `json:"appointment_id" gorm:"not null;uniqueIndex"`
+	LawyerID      int            `json:"lawyer_id" gorm:"not null;index"`
+	PrivateNotes  *string        `json:"private_notes,omitempty"`
+	Summary       *string        `json:"summary,omitempty"`
+	NextSteps     *string        `json:"next_steps,omitempty"`
+	SharedAt      *time.Time     `json:"shared_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	Tasks         []FollowUpTask `json:"tasks" gorm:"foreignKey:RecordID"`
+}
+
+// TableName specifies the table name for the ConsultationRecord model
+func (ConsultationRecord) TableName() string {
+	return "consultation_records"
+}
+
+// ForClient returns the record as the client sees it: without the private notes
+// and with only the tasks assigned to them
+func (r ConsultationRecord) ForClient() ConsultationRecord {
+	r.PrivateNotes = nil
+	tasks := make([]FollowUpTask, 0, len(r.Tasks))
+	for _, task := range r.Tasks {
+		if task.Assignee == FollowUpAssigneeClient {
+			tasks = append(tasks, task)
+		}
+	}
+	r.Tasks = tasks
+	return r
+}
+
+// FollowUpTask is something the lawyer or the client agreed to do after a
+// consultation, by a due date. The assignee is reminded once when it becomes overdue.
+type FollowUpTask struct {
+	ID             int        `json:"id" gorm:"primaryKey"`
+	RecordID       int        `json:"record_id" gorm:"not null;index"`
+	LawyerID       int        `json:"lawyer_id" gorm:"not null;index"`
+	Assignee       string     `json:"assignee" gorm:"not null"`
+	Title          string     `json:"title" gorm:"not null"`
+	Description    *string    `json:"description,omitempty"`
+	DueDate        Date       `json:"due_date" gorm:"type:date;not null"`
+	Status         string     `json:"status" gorm:"not null;default:open"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the FollowUpTask model
+func (FollowUpTask) TableName() string {
+	return "follow_up_tasks"
+}
+
+// Overdue reports whether an open task's due date has passed in loc
+func (t FollowUpTask) Overdue(now time.Time, loc *time.Location) bool {
+	return t.Status == FollowUpTaskOpen && !now.Before(t.DueDate.In(loc).AddDate(0, 0, 1))
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Document visibility
+const (
+	DocumentVisibilityLawyerOnly = "lawyer_only"
+	DocumentVisibilityShared     = "shared"
+)
+
+// DocumentFolder organizes a matter's documents. Folders nest; a nil ParentID is
+// the top of the vault.
+type DocumentFolder struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	MatterID  int       `json:"matter_id" gorm:"not null;index"`
+	ParentID  *int      `json:"parent_id,omitempty"`
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedBy int       `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the DocumentFolder model
+func (DocumentFolder) TableName() string {
+	return "document_folders"
+}
+
+// Document is a file in a matter's vault, kept in every version uploaded. While
+// someone has it checked out, only they can upload a new version.
+type Document struct {
+	ID             int            `json:"id" gorm:"primaryKey"`
+	MatterID       int            `json:"matter_id" gorm:"not null;index"`
+	FolderID       *int           `json:"folder_id,omitempty" gorm:"index"`
+	Title          string         `json:"title" gorm:"not null"`
+	Visibility     string         `json:"visibility" gorm:"not null;default:lawyer_only"`
+	CurrentVersion int            `json:"current_version" gorm:"not null;default:1"`
+	CheckedOutBy   *int           `json:"checked_out_by,omitempty"`
+	CheckedOutAt   *time.Time     `json:"checked_out_at,omitempty"`
+	CreatedBy      int            `json:"created_by" gorm:"not null"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Versions []DocumentVersion `json:"versions,omitempty" gorm:"foreignKey:DocumentID"`
+}
+
+// TableName specifies the table name for the Document model
+func (Document) TableName() string {
+	return "documents"
+}
+
+// Shared reports whether the matter's client can see the document
+func (d *Document) Shared() bool {
+	return d.Visibility == DocumentVisibilityShared
+}
+
+// DocumentVersion is one upload of a document. Its file is stored as an attachment.
+type DocumentVersion struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	DocumentID int       `json:"document_id" gorm:"not null;index"`
+	Version    int       `json:"version" gorm:"not null"`
+	Note       *string   `json:"note,omitempty"`
+	UploadedBy int       `json:"uploaded_by" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	Attachment *Attachment `json:"attachment,omitempty" gorm:"polymorphic:Attachmentable;polymorphicValue:DocumentVersion"`
+}
+
+// TableName specifies the table name for the DocumentVersion model
+func (DocumentVersion) TableName() string {
+	return "document_versions"
+}
+
+// DocumentDownload records who downloaded which version of a document
+type DocumentDownload struct {
+	ID             int       `json:"id" gorm:"primaryKey"`
+	DocumentID     int       `json:"document_id" gorm:"not null;index"`
+	VersionID      int       `json:"version_id" gorm:"not null"`
+	Version        int       `json:"version" gorm:"->;-:migration"`
+	UserID         int       `json:"user_id" gorm:"not null"`
+	ImpersonatorID *int      `json:"impersonator_id,omitempty"`
+	IPAddress      *string   `json:"ip_address,omitempty"`
+	UserAgent      *string   `json:"user_agent,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the DocumentDownload model
+func (DocumentDownload) TableName() string {
+	return "document_downloads"
+}
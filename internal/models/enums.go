@@ -7,12 +7,14 @@ const (
 	RoleClient UserRole = "client"
 	RoleLawyer UserRole = "lawyer"
 	RoleAdmin  UserRole = "admin"
+	// RoleModerator is staff with a limited set of moderation permissions
+	RoleModerator UserRole = "moderator"
 )
 
 // IsValid checks if the role is valid
 func (r UserRole) IsValid() bool {
 	switch r {
-	case RoleClient, RoleLawyer, RoleAdmin:
+	case RoleClient, RoleLawyer, RoleAdmin, RoleModerator:
 		return true
 	default:
 		return false
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// External calendar kinds
+const (
+	// ExternalCalendarICS is a plain iCalendar file fetched with GET
+	ExternalCalendarICS = "ics"
+	// ExternalCalendarCalDAV is a CalDAV collection queried for free/busy time
+	ExternalCalendarCalDAV = "caldav"
+)
+
+// ExternalCalendar is a calendar outside the platform, such as the lawyer's court
+// schedule, whose busy time blocks bookings. The password is encrypted at rest and
+// never returned by the API.
+type ExternalCalendar struct {
+	ID                int        `json:"id" gorm:"primaryKey"`
+	LawyerID          int        `json:"lawyer_id" gorm:"not null;index"`
+	Name              string     `json:"name" gorm:"not null"`
+	Kind              string     `json:"kind" gorm:"not null"`
+	URL               string     `json:"url" gorm:"not null"`
+	Username          *string    `json:"username,omitempty"`
+	EncryptedPassword *string    `json:"-"`
+	HasPassword       bool       `json:"has_password" gorm:"-"`
+	Enabled           bool       `json:"enabled" gorm:"not null;default:true"`
+	LastSyncedAt      *time.Time `json:"last_synced_at,omitempty"`
+	LastAttemptAt     *time.Time `json:"last_attempt_at,omitempty"`
+	LastSyncError     *string    `json:"last_sync_error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the ExternalCalendar model
+func (ExternalCalendar) TableName() string {
+	return "external_calendars"
+}
+
+// BusyBlock is a period imported from an external calendar during which the lawyer
+// cannot take appointments. Only the times are kept, not what the event was.
+type BusyBlock struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	CalendarID int       `json:"calendar_id" gorm:"not null;index"`
+	LawyerID   int       `json:"lawyer_id" gorm:"not null;index"`
+	StartTime  time.Time `json:"start_time" gorm:"not null"`
+	EndTime    time.Time `json:"end_time" gorm:"not null"`
+}
+
+// TableName specifies the table name for the BusyBlock model
+func (BusyBlock) TableName() string {
+	return "busy_blocks"
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// ImpersonationSession is a period in which a staff member acts as another user
+type ImpersonationSession struct {
+	ID               int        `json:"id" gorm:"primaryKey"`
+	ActorID          int        `json:"actor_id" gorm:"not null"`
+	Actor            *User      `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+	SubjectID        int        `json:"subject_id" gorm:"not null"`
+	Subject          *User      `json:"subject,omitempty" gorm:"foreignKey:SubjectID"`
+	Reason           string     `json:"reason" gorm:"not null"`
+	AllowDestructive bool       `json:"allow_destructive" gorm:"not null;default:false"`
+	IPAddress        *string    `json:"ip_address,omitempty"`
+	UserAgent        *string    `json:"user_agent,omitempty"`
+	StartedAt        time.Time  `json:"started_at" gorm:"autoCreateTime"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null"`
+	EndedAt          *time.Time `json:"ended_at,omitempty"`
+}
+
+// TableName specifies the table name for ImpersonationSession
+func (ImpersonationSession) TableName() string {
+	return "impersonation_sessions"
+}
+
+// IsActive reports whether the session can still be used
+func (s *ImpersonationSession) IsActive(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ImpersonationRequestLog records one request made under impersonation
+type ImpersonationRequestLog struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	SessionID  int       `json:"session_id" gorm:"not null"`
+	ActorID    int       `json:"actor_id" gorm:"not null"`
+	SubjectID  int       `json:"subject_id" gorm:"not null"`
+	Method     string    `json:"method" gorm:"not null"`
+	Path       string    `json:"path" gorm:"not null"`
+	Route      *string   `json:"route,omitempty"`
+	StatusCode int       `json:"status_code" gorm:"not null"`
+	Blocked    bool      `json:"blocked" gorm:"not null;default:false"`
+	IPAddress  *string   `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for ImpersonationRequestLog
+func (ImpersonationRequestLog) TableName() string {
+	return "impersonation_request_logs"
+}
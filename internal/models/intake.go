@@ -0,0 +1,349 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Intake field kinds
+const (
+	IntakeFieldText   = "text"
+	IntakeFieldChoice = "choice"
+	IntakeFieldDate   = "date"
+	IntakeFieldFile   = "file"
+)
+
+// Intake form limits
+const (
+	MaxIntakeFields        = 50
+	MaxIntakeChoices       = 50
+	MaxIntakeTextLength    = 10000
+	DefaultIntakeMaxLength = 2000
+)
+
+var intakeFieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// IntakeField is one question of an intake form, written as a JSON Schema property
+// of type "string". Choice fields list their options in enum, date fields use
+// format "date" and file fields use format "file"; anything else is free text.
+type IntakeField struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	MaxLength   int      `json:"maxLength,omitempty"`
+}
+
+// Kind returns which of the intake field kinds the field is
+func (f IntakeField) Kind() string {
+	switch {
+	case len(f.Enum) > 0:
+		return IntakeFieldChoice
+	case f.Format == "date":
+		return IntakeFieldDate
+	case f.Format == "file":
+		return IntakeFieldFile
+	default:
+		return IntakeFieldText
+	}
+}
+
+func (f IntakeField) validate(name string) error {
+	if f.Type != "string" {
+		return fmt.Errorf("field %s must have type \"string\"", name)
+	}
+	if strings.TrimSpace(f.Title) == "" {
+		return fmt.Errorf("field %s needs a title", name)
+	}
+	if f.Format != "" && f.Format != "date" && f.Format != "file" {
+		return fmt.Errorf("field %s has unsupported format %q", name, f.Format)
+	}
+	if len(f.Enum) > 0 {
+		if f.Format != "" {
+			return fmt.Errorf("field %s cannot have both enum and format", name)
+		}
+		if len(f.Enum) > MaxIntakeChoices {
+			return fmt.Errorf("field %s can have at most %d choices", name, MaxIntakeChoices)
+		}
+		seen := make(map[string]bool, len(f.Enum))
+		for _, option := range f.Enum {
+			if strings.TrimSpace(option) == "" {
+				return fmt.Errorf("field %s has an empty choice", name)
+			}
+			if seen[option] {
+				return fmt.Errorf("field %s lists choice %q twice", name, option)
+			}
+			seen[option] = true
+		}
+	}
+	if f.MaxLength != 0 {
+		if f.Kind() != IntakeFieldText {
+			return fmt.Errorf("field %s: maxLength only applies to text fields", name)
+		}
+		if f.MaxLength < 0 || f.MaxLength > MaxIntakeTextLength {
+			return fmt.Errorf("field %s: maxLength must be between 1 and %d", name, MaxIntakeTextLength)
+		}
+	}
+	return nil
+}
+
+// IntakeSchema is the JSON Schema of an intake form: an object whose properties are
+// the form's fields. Order lists the field names in the order they are shown; fields
+// not listed follow in name order.
+type IntakeSchema struct {
+	Type       string                 `json:"type"`
+	Properties map[string]IntakeField `json:"properties"`
+	Required   []string               `json:"required,omitempty"`
+	Order      []string               `json:"x-order,omitempty"`
+}
+
+// Validate checks that the schema only uses the supported field kinds
+func (s IntakeSchema) Validate() error {
+	if s.Type != "object" {
+		return errors.New("schema must have type \"object\"")
+	}
+	if len(s.Properties) == 0 {
+		return errors.New("schema needs at least one field")
+	}
+	if len(s.Properties) > MaxIntakeFields {
+		return fmt.Errorf("schema can have at most %d fields", MaxIntakeFields)
+	}
+	for name, field := range s.Properties {
+		if !intakeFieldNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid field name %q, use lowercase letters, digits and underscores", name)
+		}
+		if err := field.validate(name); err != nil {
+			return err
+		}
+	}
+	if err := s.checkNames(s.Required, "required"); err != nil {
+		return err
+	}
+	return s.checkNames(s.Order, "x-order")
+}
+
+func (s IntakeSchema) checkNames(names []string, list string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := s.Properties[name]; !ok {
+			return fmt.Errorf("%s lists unknown field %q", list, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s lists field %q twice", list, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// FieldNames returns the field names in display order
+func (s IntakeSchema) FieldNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	listed := make(map[string]bool, len(s.Order))
+	for _, name := range s.Order {
+		names = append(names, name)
+		listed[name] = true
+	}
+	var rest []string
+	for name := range s.Properties {
+		if !listed[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// IsRequired reports whether a field must be answered
+func (s IntakeSchema) IsRequired(name string) bool {
+	for _, required := range s.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAnswers validates answers against the schema and returns them normalized:
+// text is trimmed, unanswered optional fields are dropped and file fields hold the
+// uploaded file's ID as an int
+func (s IntakeSchema) CheckAnswers(answers IntakeAnswers) (IntakeAnswers, error) {
+	for name := range answers {
+		if _, ok := s.Properties[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+
+	normalized := IntakeAnswers{}
+	for _, name := range s.FieldNames() {
+		field := s.Properties[name]
+		value, answered := answers[name]
+		if text, ok := value.(string); ok && strings.TrimSpace(text) == "" {
+			answered = false
+		}
+		if !answered || value == nil {
+			if s.IsRequired(name) {
+				return nil, fmt.Errorf("%s is required", field.Title)
+			}
+			continue
+		}
+
+		if field.Kind() == IntakeFieldFile {
+			number, ok := value.(float64)
+			if !ok || number < 1 || number != math.Trunc(number) || number > math.MaxInt32 {
+				return nil, fmt.Errorf("%s must be the ID of an uploaded file", field.Title)
+			}
+			normalized[name] = int(number)
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", field.Title)
+		}
+		text = strings.TrimSpace(text)
+		switch field.Kind() {
+		case IntakeFieldChoice:
+			valid := false
+			for _, option := range field.Enum {
+				if option == text {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("%s must be one of the listed choices", field.Title)
+			}
+		case IntakeFieldDate:
+			if _, err := ParseDate(text); err != nil {
+				return nil, fmt.Errorf("%s: %v", field.Title, err)
+			}
+		default:
+			limit := field.MaxLength
+			if limit == 0 {
+				limit = DefaultIntakeMaxLength
+			}
+			if utf8.RuneCountInString(text) > limit {
+				return nil, fmt.Errorf("%s must be at most %d characters", field.Title, limit)
+			}
+		}
+		normalized[name] = text
+	}
+	return normalized, nil
+}
+
+// Value implements the driver.Valuer interface for IntakeSchema
+func (s IntakeSchema) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface for IntakeSchema
+func (s *IntakeSchema) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// IntakeAnswers holds a client's answers keyed by field name
+type IntakeAnswers map[string]interface{}
+
+// Value implements the driver.Valuer interface for IntakeAnswers
+func (a IntakeAnswers) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface for IntakeAnswers
+func (a *IntakeAnswers) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// IntakeForm is a questionnaire a lawyer asks clients to complete when booking a
+// consultation in one of their specialties
+type IntakeForm struct {
+	ID        int          `json:"id" gorm:"primaryKey"`
+	LawyerID  int          `json:"lawyer_id" gorm:"not null;index"`
+	Specialty string       `json:"specialty" gorm:"not null"`
+	Title     string       `json:"title" gorm:"not null"`
+	Schema    IntakeSchema `json:"schema" gorm:"type:jsonb;not null"`
+	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the IntakeForm model
+func (IntakeForm) TableName() string {
+	return "intake_forms"
+}
+
+// IntakeResponse is a completed intake form stored with an appointment. The form's
+// title, specialty and schema are copied so later edits to the form do not change
+// what the client answered.
+type IntakeResponse struct {
+	ID            int           `json:"id" gorm:"primaryKey"`
+	AppointmentID int           `json:"appointment_id" gorm:"not null;uniqueIndex"`
+	FormID        *int          `json:"form_id,omitempty"`
+	Specialty     string        `json:"specialty" gorm:"not null"`
+	Title         string        `json:"title" gorm:"not null"`
+	Schema        IntakeSchema  `json:"schema" gorm:"type:jsonb;not null"`
+	Answers       IntakeAnswers `json:"answers" gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time     `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the IntakeResponse model
+func (IntakeResponse) TableName() string {
+	return "intake_responses"
+}
+
+// FileIDs returns the IDs of the files attached in answers to file fields
+func (r IntakeResponse) FileIDs() []int {
+	seen := map[int]bool{}
+	var ids []int
+	for name, field := range r.Schema.Properties {
+		if field.Kind() != IntakeFieldFile {
+			continue
+		}
+		var id int
+		switch v := r.Answers[name].(type) {
+		case int:
+			id = v
+		case float64:
+			id = int(v)
+		default:
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntakeSchemaCheckAnswers(t *testing.T) {
+	schema := IntakeSchema{
+		Type: "object",
+		Properties: map[string]IntakeField{
+			"summary":  {Type: "string", Title: "Summary", MaxLength: 10},
+			"notes":    {Type: "string", Title: "Notes"},
+			"category": {Type: "string", Title: "Category", Enum: []string{"divorce", "custody"}},
+			"since":    {Type: "string", Title: "Since", Format: "date"},
+			"contract": {Type: "string", Title: "Contract", Format: "file"},
+		},
+		Required: []string{"summary", "category"},
+	}
+
+	tests := []struct {
+		name    string
+		answers IntakeAnswers
+		want    IntakeAnswers
+		wantErr string
+	}{
+		{
+			name:    "required fields only",
+			answers: IntakeAnswers{"summary": "  Dispute  ", "category": "custody"},
+			want:    IntakeAnswers{"summary": "Dispute", "category": "custody"},
+		},
+		{
+			name: "every field",
+			answers: IntakeAnswers{
+				"summary": "Dispute", "notes": "日本語のメモ", "category": "divorce",
+				"since": "2026-01-31", "contract": float64(12),
+			},
+			want: IntakeAnswers{
+				"summary": "Dispute", "notes": "日本語のメモ", "category": "divorce",
+				"since": "2026-01-31", "contract": 12,
+			},
+		},
+		{
+			name:    "blank optional fields are dropped",
+			answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "notes": "   ", "since": nil},
+			want:    IntakeAnswers{"summary": "Dispute", "category": "divorce"},
+		},
+		{name: "unknown field", answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "age": "40"}, wantErr: `unknown field "age"`},
+		{name: "missing required", answers: IntakeAnswers{"category": "divorce"}, wantErr: "Summary is required"},
+		{name: "blank required", answers: IntakeAnswers{"summary": " ", "category": "divorce"}, wantErr: "Summary is required"},
+		{name: "not a string", answers: IntakeAnswers{"summary": float64(3), "category": "divorce"}, wantErr: "Summary must be a string"},
+		// The limit counts characters, not bytes
+		{name: "text at its limit", answers: IntakeAnswers{"summary": strings.Repeat("相", 10), "category": "divorce"}, want: IntakeAnswers{"summary": strings.Repeat("相", 10), "category": "divorce"}},
+		{name: "text over its limit", answers: IntakeAnswers{"summary": strings.Repeat("a", 11), "category": "divorce"}, wantErr: "Summary must be at most 10 characters"},
+		{name: "text over the default limit", answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "notes": strings.Repeat("a", DefaultIntakeMaxLength+1)}, wantErr: "Notes must be at most 2000 characters"},
+		{name: "unlisted choice", answers: IntakeAnswers{"summary": "Dispute", "category": "Divorce"}, wantErr: "Category must be one of the listed choices"},
+		{name: "invalid date", answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "since": "2026-02-30"}, wantErr: `Since: invalid date "2026-02-30", expected YYYY-MM-DD`},
+		{name: "file as text", answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "contract": "12"}, wantErr: "Contract must be the ID of an uploaded file"},
+		{name: "fractional file ID", answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "contract": 1.5}, wantErr: "Contract must be the ID of an uploaded file"},
+		{name: "zero file ID", answers: IntakeAnswers{"summary": "Dispute", "category": "divorce", "contract": float64(0)}, wantErr: "Contract must be the ID of an uploaded file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.CheckAnswers(tt.answers)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckAnswers: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("answers = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"database/sql/driver"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,23 +10,6 @@ import (
 	"gorm.io/gorm"
 )
 
-// Availability represents a lawyer's availability schedule
-type Availability map[string]interface{}
-
-// Value implements the driver.Valuer interface for Availability
-func (a Availability) Value() (driver.Value, error) {
-	return json.Marshal(a)
-}
-
-// Scan implements the sql.Scanner interface for Availability
-func (a *Availability) Scan(value interface{}) error {
-	b, ok := value.([]byte)
-	if !ok {
-		return errors.New("type assertion to []byte failed")
-	}
-	return json.Unmarshal(b, &a)
-}
-
 // StringArray represents a string array type for PostgreSQL
 type StringArray []string
 
@@ -82,9 +64,9 @@ func (s *StringArray) Scan(value interface{}) error {
 
 // Lawyer represents a lawyer in the system
 type Lawyer struct {
-	ID              int           `json:"id" gorm:"primaryKey"`
-	UserID          int           `json:"user_id" gorm:"uniqueIndex;not null"`
-	User            User          `json:"user"   gorm:"foreignKey:UserID"`
+	ID              int           `json:"id" gorm:"primaryKey" history:"-"`
+	UserID          int           `json:"user_id" gorm:"uniqueIndex;not null" history:"-"`
+	User            User          `json:"user"   gorm:"foreignKey:UserID" history:"-"`
 	FullName        string        `json:"full_name" gorm:"not null"`
 	Phone           *string       `json:"phone,omitempty"`
 	Email           string        `json:"email" gorm:"uniqueIndex;not null"`
@@ -110,10 +92,20 @@ type Lawyer struct {
 	AreasOfExpertise          *string `json:"areas_of_expertise,omitempty" gorm:"type:text;column:areas_of_expertise"`
 	Notes                     *string `json:"notes,omitempty" gorm:"type:text;column:notes"`
 
-	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime" history:"-"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime" history:"-"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
-	IsVerified bool          `json:"is_verified" gorm:"default:false"`
+	// Verification is granted by staff and is never rolled back by a restore
+	IsVerified bool `json:"is_verified" gorm:"default:false" history:"norestore"`
+
+	// Closes the lawyer's calendar on Japanese national holidays
+	ClosedOnHolidays bool `json:"closed_on_holidays" gorm:"not null;default:false"`
+
+	// IANA zone the weekly schedule and exceptions are written in
+	Timezone string `json:"timezone" gorm:"not null;default:'Asia/Tokyo'"`
+
+	// Slot length, buffers and booking horizon; nil means the defaults apply
+	BookingSettings *BookingSettings `json:"booking_settings,omitempty" gorm:"type:jsonb"`
 
 	// Additional calculated fields that don't exist in the database
 	ReviewCount   *int     `json:"review_count,omitempty" gorm:"-"`
@@ -124,3 +116,17 @@ type Lawyer struct {
 func (Lawyer) TableName() string {
 	return "lawyers"
 }
+
+// Location returns the time zone of the lawyer's calendar
+func (l Lawyer) Location() *time.Location {
+	return LoadTimezone(l.Timezone)
+}
+
+// EffectiveBookingSettings returns the lawyer's booking settings, or the defaults
+// if none are set
+func (l Lawyer) EffectiveBookingSettings() BookingSettings {
+	if l.BookingSettings == nil {
+		return DefaultBookingSettings()
+	}
+	return *l.BookingSettings
+}
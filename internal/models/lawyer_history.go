@@ -15,7 +15,7 @@ type Pagination struct {
 	TotalPages  int `json:"total_pages"`
 }
 
-// FieldChange represents a change in a lawyer field
+// FieldChange represents a change in a single field
 type FieldChange struct {
 	Field string      `json:"field"`
 	Old   interface{} `json:"old"`
@@ -41,12 +41,13 @@ func (f *FieldChanges) Scan(value interface{}) error {
 
 // LawyerHistory stores the history of changes made to a lawyer
 type LawyerHistory struct {
-	ID        int          `json:"id" gorm:"primaryKey"`
-	LawyerID  int          `json:"lawyer_id" gorm:"not null"`
-	UserID    int          `json:"user_id" gorm:"not null"`
-	User      User         `json:"user" gorm:"foreignKey:UserID"`
-	Changes   FieldChanges `json:"changes" gorm:"type:jsonb;not null"`
-	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	ID           int          `json:"id" gorm:"primaryKey"`
+	LawyerID     int          `json:"lawyer_id" gorm:"not null"`
+	UserID       int          `json:"user_id" gorm:"not null"`
+	User         User         `json:"user" gorm:"foreignKey:UserID"`
+	Changes      FieldChanges `json:"changes" gorm:"type:jsonb;not null"`
+	RestoredFrom *int         `json:"restored_from,omitempty"`
+	CreatedAt    time.Time    `json:"created_at" gorm:"autoCreateTime"`
 }
 
 // TableName specifies the table name for LawyerHistory
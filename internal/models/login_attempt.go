@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Login attempt actions
+const (
+	LoginActionLogin              = "login"
+	LoginActionMFA                = "mfa"
+	LoginActionForgotPassword     = "forgot_password"
+	LoginActionResendVerification = "resend_verification"
+)
+
+// Login attempt failure reasons
+const (
+	LoginFailureUnknownEmail      = "unknown_email"
+	LoginFailureInvalidPassword   = "invalid_password"
+	LoginFailureInvalidMFACode    = "invalid_mfa_code"
+	LoginFailureInactive          = "inactive"
+	LoginFailureEmailNotVerified  = "email_not_verified"
+	LoginFailureLocked            = "locked"
+	LoginFailureRateLimited       = "rate_limited"
+	LoginFailureDelayNotRespected = "delay_not_respected"
+)
+
+// LoginAttempt records an authentication or account-email request for auditing
+type LoginAttempt struct {
+	ID            int64     `json:"id" gorm:"primaryKey"`
+	Email         string    `json:"email" gorm:"not null"`
+	UserID        *int      `json:"user_id,omitempty"`
+	IPAddress     string    `json:"ip_address" gorm:"not null"`
+	UserAgent     *string   `json:"user_agent,omitempty"`
+	Action        string    `json:"action" gorm:"not null"`
+	Success       bool      `json:"success" gorm:"not null;default:false"`
+	FailureReason *string   `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for LoginAttempt
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// RateLimitHit is a single event in a Postgres-backed sliding window
+type RateLimitHit struct {
+	ID    int64     `gorm:"primaryKey"`
+	Key   string    `gorm:"not null"`
+	HitAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for RateLimitHit
+func (RateLimitHit) TableName() string {
+	return "rate_limit_hits"
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Matter statuses
+const (
+	MatterStatusOpen   = "open"
+	MatterStatusOnHold = "on_hold"
+	MatterStatusClosed = "closed"
+)
+
+// Matter is a client's case with a lawyer. Appointments are grouped under it, and
+// with them their chat messages, attachments and consultation records, so the
+// whole history of the case can be followed in one timeline.
+type Matter struct {
+	ID              int             `json:"id" gorm:"primaryKey"`
+	LawyerID        int             `json:"lawyer_id" gorm:"not null;index"`
+	ClientID        int             `json:"client_id" gorm:"not null;index"`
+	Title           string          `json:"title" gorm:"not null"`
+	Description     *string         `json:"description,omitempty"`
+	PracticeArea    string          `json:"practice_area" gorm:"not null"`
+	Status          string          `json:"status" gorm:"not null;default:open"`
+	ClosedAt        *time.Time      `json:"closed_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+	OpposingParties []OpposingParty `json:"opposing_parties" gorm:"-"`
+}
+
+// TableName specifies the table name for the Matter model
+func (Matter) TableName() string {
+	return "matters"
+}
+
+// ValidMatterStatus reports whether status is one of the matter statuses
+func ValidMatterStatus(status string) bool {
+	switch status {
+	case MatterStatusOpen, MatterStatusOnHold, MatterStatusClosed:
+		return true
+	}
+	return false
+}
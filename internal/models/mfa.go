@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a hashed one-time code a user can use instead of a TOTP code
+type RecoveryCode struct {
+	ID        int        `json:"id" gorm:"primaryKey"`
+	UserID    int        `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the RecoveryCode model
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
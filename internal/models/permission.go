@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Named permissions. Roles are mapped to permissions in the role_permissions table and
+// individual users can be granted extra permissions in user_permissions.
+const (
+	PermAdminDashboard     = "admin.dashboard"
+	PermUsersView          = "users.view"
+	PermUsersManage        = "users.manage"
+	PermImpersonate        = "users.impersonate"
+	PermImpersonateUnsafe  = "users.impersonate_destructive"
+	PermPermissionsManage  = "permissions.manage"
+	PermSettingsManage     = "settings.manage"
+	PermSecurityAudit      = "security.audit"
+	PermAppointmentsManage = "appointments.manage"
+	PermAppointmentsReject = "appointments.reject"
+	PermReviewsModerate    = "reviews.moderate"
+	PermQuestionsModerate  = "questions.moderate"
+	PermLawyersVerify      = "lawyers.verify"
+	PermLawyersManage      = "lawyers.manage"
+	PermArticlesWrite      = "articles.write"
+	PermArticlesPublish    = "articles.publish"
+	PermArticlesManage     = "articles.manage"
+)
+
+// Permission is a named capability that can be granted to roles and users
+type Permission struct {
+	Name        string    `json:"name" gorm:"primaryKey"`
+	Description string    `json:"description" gorm:"not null;default:''"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for Permission
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// RolePermission grants a permission to every user with a role
+type RolePermission struct {
+	Role       string    `json:"role" gorm:"primaryKey"`
+	Permission string    `json:"permission" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for RolePermission
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// UserPermission grants a permission to a single user in addition to their role's permissions
+type UserPermission struct {
+	UserID     int       `json:"user_id" gorm:"primaryKey"`
+	Permission string    `json:"permission" gorm:"primaryKey"`
+	GrantedBy  *int      `json:"granted_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for UserPermission
+func (UserPermission) TableName() string {
+	return "user_permissions"
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PlatformClosure is a date on which no lawyer on the platform takes appointments,
+// such as the office's お盆 or year-end break
+type PlatformClosure struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Date      Date      `json:"date" gorm:"type:date;uniqueIndex;not null"`
+	Reason    string    `json:"reason" gorm:"not null"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the PlatformClosure model
+func (PlatformClosure) TableName() string {
+	return "platform_closures"
+}
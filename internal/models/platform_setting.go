@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Platform setting keys
+const (
+	SettingMFARequiredRoles      = "mfa_required_roles"
+	SettingRescheduleWindowHours = "reschedule_window_hours"
+	SettingMeetingLinkWindowMins = "meeting_link_window_minutes"
+	SettingAttendanceGraceHours  = "attendance_grace_hours"
+	SettingNoShowLimit           = "no_show_booking_limit"
+	SettingNoShowRestrictionDays = "no_show_restriction_days"
+	SettingWaitlistHoldMinutes   = "waitlist_hold_minutes"
+)
+
+// PlatformSetting is a key/value setting managed by admins
+type PlatformSetting struct {
+	Key       string    `json:"key" gorm:"primaryKey"`
+	Value     string    `json:"value" gorm:"not null;default:''"`
+	UpdatedBy *int      `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the PlatformSetting model
+func (PlatformSetting) TableName() string {
+	return "platform_settings"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Reschedule proposal statuses
+const (
+	RescheduleStatusPending    = "pending"
+	RescheduleStatusAccepted   = "accepted"
+	RescheduleStatusDeclined   = "declined"
+	RescheduleStatusSuperseded = "superseded"
+)
+
+// RescheduleProposal is one party's offer of alternative times for an appointment.
+// The other party accepts one option or declines them all. Proposals are never
+// deleted, so they form the appointment's reschedule history.
+type RescheduleProposal struct {
+	ID               int                `json:"id" gorm:"primaryKey"`
+	AppointmentID    int                `json:"appointment_id" gorm:"not null;index"`
+	ProposedBy       int                `json:"proposed_by" gorm:"not null"`
+	ProposedByRole   string             `json:"proposed_by_role" gorm:"not null"`
+	Reason           *string            `json:"reason,omitempty"`
+	Status           string             `json:"status" gorm:"not null;default:pending"`
+	OriginalStart    time.Time          `json:"original_start_time" gorm:"not null"`
+	OriginalEnd      time.Time          `json:"original_end_time" gorm:"not null"`
+	AcceptedOptionID *int               `json:"accepted_option_id,omitempty"`
+	RespondedBy      *int               `json:"responded_by,omitempty"`
+	RespondedAt      *time.Time         `json:"responded_at,omitempty"`
+	DeclineReason    *string            `json:"decline_reason,omitempty"`
+	Options          []RescheduleOption `json:"options" gorm:"foreignKey:ProposalID"`
+	CreatedAt        time.Time          `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time          `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the RescheduleProposal model
+func (RescheduleProposal) TableName() string {
+	return "reschedule_proposals"
+}
+
+// RescheduleOption is one alternative time offered in a proposal
+type RescheduleOption struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	ProposalID int       `json:"proposal_id" gorm:"not null;index"`
+	StartTime  time.Time `json:"start_time" gorm:"not null"`
+	EndTime    time.Time `json:"end_time" gorm:"not null"`
+}
+
+// TableName specifies the table name for the RescheduleOption model
+func (RescheduleOption) TableName() string {
+	return "reschedule_options"
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	_ "time/tzdata" // embeds the zone database for images that ship without one
+)
+
+// DefaultTimezone is the IANA zone of users and lawyers who have not chosen one
+const DefaultTimezone = "Asia/Tokyo"
+
+// locations caches loaded zones by name, since time.LoadLocation reads and parses
+// the zone file on every call
+var locations sync.Map
+
+// loadLocation returns the named IANA zone, loading it at most once
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// ValidateTimezone checks that name is an IANA zone such as "Europe/London"
+func ValidateTimezone(name string) error {
+	if name == "" || name == "Local" {
+		return fmt.Errorf("%q is not an IANA time zone", name)
+	}
+	if _, err := loadLocation(name); err != nil {
+		return fmt.Errorf("%q is not an IANA time zone", name)
+	}
+	return nil
+}
+
+// LoadTimezone returns the location of an IANA zone, falling back to the default
+// zone when the name is empty or unknown
+func LoadTimezone(name string) *time.Location {
+	if name != "" && name != "Local" {
+		if loc, err := loadLocation(name); err == nil {
+			return loc
+		}
+	}
+	if loc, err := loadLocation(DefaultTimezone); err == nil {
+		return loc
+	}
+	// Fallback to fixed offset if LoadLocation fails (e.g. timezone data missing)
+	return time.FixedZone("JST", 9*60*60)
+}
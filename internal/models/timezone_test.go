@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTimezone(t *testing.T) {
+	for _, name := range []string{"Asia/Tokyo", "Europe/London", "America/New_York", "UTC"} {
+		if err := ValidateTimezone(name); err != nil {
+			t.Errorf("ValidateTimezone(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons", "../etc/passwd"} {
+		if err := ValidateTimezone(name); err == nil {
+			t.Errorf("ValidateTimezone(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	if got := LoadTimezone("Europe/London").String(); got != "Europe/London" {
+		t.Errorf("LoadTimezone(Europe/London) = %s", got)
+	}
+	for _, name := range []string{"", "Local", "Not/A_Zone"} {
+		if got := LoadTimezone(name).String(); got != DefaultTimezone {
+			t.Errorf("LoadTimezone(%q) = %s, want %s", name, got, DefaultTimezone)
+		}
+	}
+	if LoadTimezone("America/New_York") != LoadTimezone("America/New_York") {
+		t.Error("LoadTimezone did not reuse the cached location")
+	}
+}
+
+func TestDateDayBoundary(t *testing.T) {
+	tokyo := LoadTimezone("Asia/Tokyo")
+	newYork := LoadTimezone("America/New_York")
+
+	// 15:30 UTC on 1 March is already 2 March in Tokyo but still 1 March in New York
+	instant := time.Date(2026, 3, 1, 15, 30, 0, 0, time.UTC)
+	if got := NewDate(instant.In(tokyo)).String(); got != "2026-03-02" {
+		t.Errorf("Tokyo date = %s, want 2026-03-02", got)
+	}
+	if got := NewDate(instant.In(newYork)).String(); got != "2026-03-01" {
+		t.Errorf("New York date = %s, want 2026-03-01", got)
+	}
+
+	day := NewDate(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+	if got, want := day.In(tokyo), time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("2026-03-02 starts at %s in Tokyo, want %s", got.UTC(), want)
+	}
+}
+
+func TestDateAcrossDST(t *testing.T) {
+	newYork := LoadTimezone("America/New_York")
+
+	tests := []struct {
+		date  string
+		hours float64
+	}{
+		{"2026-03-08", 23}, // clocks go forward
+		{"2026-11-01", 25}, // clocks go back
+		{"2026-06-15", 24},
+	}
+	for _, tt := range tests {
+		day, err := ParseDate(tt.date)
+		if err != nil {
+			t.Fatalf("ParseDate(%s): %v", tt.date, err)
+		}
+		start := day.In(newYork)
+		end := NewDate(day.AddDate(0, 0, 1)).In(newYork)
+		if got := end.Sub(start).Hours(); got != tt.hours {
+			t.Errorf("%s lasts %v hours in New York, want %v", tt.date, got, tt.hours)
+		}
+	}
+}
@@ -15,6 +15,8 @@ type User struct {
 	Nickname            *string        `json:"nickname,omitempty"`
 	FirstName           *string        `json:"first_name,omitempty"`
 	LastName            *string        `json:"last_name,omitempty"`
+	FirstNameKana       *string        `json:"first_name_kana,omitempty"`
+	LastNameKana        *string        `json:"last_name_kana,omitempty"`
 	ProfileImage        *string        `json:"profile_image,omitempty"`
 	BirthDate           *time.Time     `json:"birth_date,omitempty"`
 	PostalCode          *string        `json:"postal_code,omitempty"`
@@ -26,6 +28,7 @@ type User struct {
 	Address             *string        `json:"address,omitempty"`
 	Phone               *string        `json:"phone,omitempty"`
 	Notes               *string        `json:"notes,omitempty"`
+	Timezone            string         `json:"timezone" gorm:"not null;default:'Asia/Tokyo'"`
 	IsActive            bool           `json:"is_active" gorm:"not null;default:true"`
 	EmailVerified       bool           `json:"email_verified" gorm:"not null;default:false"`
 	VerificationToken   *string        `json:"-"`
@@ -33,6 +36,12 @@ type User struct {
 	GoogleID            *string        `json:"-"`
 	ResetPasswordToken  *string        `json:"-"`
 	ResetPasswordExpiry *time.Time     `json:"-"`
+	TOTPSecret          *string        `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabled         bool           `json:"mfa_enabled" gorm:"column:totp_enabled;not null;default:false"`
+	TOTPEnabledAt       *time.Time     `json:"-" gorm:"column:totp_enabled_at"`
+	TOTPLastUsedStep    int64          `json:"-" gorm:"column:totp_last_used_step;not null;default:0"`
+	NoShowCount         int            `json:"no_show_count" gorm:"not null;default:0"`
+	LastNoShowAt        *time.Time     `json:"last_no_show_at,omitempty"`
 	CreatedAt           time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt           time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
@@ -42,3 +51,8 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+// Location returns the user's time zone
+func (u User) Location() *time.Location {
+	return LoadTimezone(u.Timezone)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Waitlist entry statuses
+const (
+	WaitlistStatusActive    = "active"
+	WaitlistStatusBooked    = "booked"
+	WaitlistStatusCancelled = "cancelled"
+	WaitlistStatusExpired   = "expired"
+)
+
+// Slot hold statuses
+const (
+	SlotHoldStatusHeld     = "held"
+	SlotHoldStatusBooked   = "booked"
+	SlotHoldStatusDeclined = "declined"
+	SlotHoldStatusExpired  = "expired"
+	SlotHoldStatusReleased = "released"
+)
+
+// DateRange is a span of calendar dates, inclusive at both ends
+type DateRange struct {
+	From Date `json:"from"`
+	To   Date `json:"to"`
+}
+
+// Contains reports whether a date falls within the range
+func (r DateRange) Contains(d Date) bool {
+	return !d.Before(r.From.Time) && !d.After(r.To.Time)
+}
+
+// DateRanges is a list of date ranges stored as JSON
+type DateRanges []DateRange
+
+// Value implements the driver.Valuer interface for DateRanges
+func (ranges DateRanges) Value() (driver.Value, error) {
+	if ranges == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(ranges)
+	return string(b), err
+}
+
+// Scan implements the sql.Scanner interface for DateRanges
+func (ranges *DateRanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*ranges = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, ranges)
+	case string:
+		return json.Unmarshal([]byte(v), ranges)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// WaitlistEntry is a client's request to be offered a slot with a fully booked
+// lawyer. Dates and times of day are in the client's time zone; no times of day
+// means any time.
+type WaitlistEntry struct {
+	ID         int           `json:"id" gorm:"primaryKey"`
+	UserID     int           `json:"user_id" gorm:"not null;index"`
+	LawyerID   int           `json:"lawyer_id" gorm:"not null;index"`
+	DateRanges DateRanges    `json:"date_ranges" gorm:"type:jsonb;not null"`
+	TimesOfDay TimeIntervals `json:"times_of_day" gorm:"type:jsonb;not null"`
+	Note       *string       `json:"note,omitempty"`
+	Status     string        `json:"status" gorm:"not null;default:active"`
+	CreatedAt  time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the WaitlistEntry model
+func (WaitlistEntry) TableName() string {
+	return "waitlist_entries"
+}
+
+// SlotHold reserves an open slot for one waitlisted client until it expires. Only
+// that client can book the slot while the hold lasts.
+type SlotHold struct {
+	ID            int       `json:"id" gorm:"primaryKey"`
+	EntryID       int       `json:"entry_id" gorm:"not null;index"`
+	UserID        int       `json:"user_id" gorm:"not null"`
+	LawyerID      int       `json:"lawyer_id" gorm:"not null"`
+	StartTime     time.Time `json:"start_time" gorm:"not null"`
+	EndTime       time.Time `json:"end_time" gorm:"not null"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"not null"`
+	Status        string    `json:"status" gorm:"not null;default:held"`
+	AppointmentID *int      `json:"appointment_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the SlotHold model
+func (SlotHold) TableName() string {
+	return "slot_holds"
+}
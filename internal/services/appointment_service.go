@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"gorm.io/gorm/clause"
 	"net/smtp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -35,7 +35,7 @@ func (s *AppointmentService) GetAppointmentByID(id int) (*models.Appointment, er
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("appointment not found")
+			return nil, ErrAppointmentNotFound
 		}
 		return nil, result.Error
 	}
@@ -62,10 +62,11 @@ func (s *AppointmentService) GetAppointmentResponseByID(id int) (*responses.Appo
 	response := &responses.AppointmentResponse{
 		ID:           appointment.ID,
 		UserID:       appointment.UserID,
+		MatterID:     appointment.MatterID,
 		Description:  appointment.Description,
 		StartTime:    appointment.StartTime,
 		EndTime:      appointment.EndTime,
-		Status:       appointment.Status,
+		Status:       appointment.Status.String(),
 		Notes:        appointment.Notes,
 		ChatEnabled:  appointment.ChatEnabled,
 		RejectReason: appointment.RejectReason,
@@ -91,6 +92,23 @@ func (s *AppointmentService) GetAppointmentResponseByID(id int) (*responses.Appo
 			IsActive:     client.IsActive,
 		},
 	}
+	if appointment.MeetingLink != nil && *appointment.MeetingLink != "" {
+		availableAt := MeetingLinkAvailableAt(appointment.StartTime, (&MeetingService{DB: s.DB}).MeetingLinkWindow())
+		response.MeetingLink = appointment.MeetingLink
+		response.MeetingLinkAvailableAt = &availableAt
+	}
+
+	intake, err := (&IntakeService{DB: s.DB}).GetResponse(appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	response.Intake = intake
+
+	parties, err := (&ConflictCheckService{DB: s.DB}).GetOpposingParties(appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	response.OpposingParties = parties
 
 	return response, nil
 }
@@ -166,7 +184,7 @@ func (s *AppointmentService) GetAppointmentsByUserID(userID int, statusFilter st
 			Description:    appointment.Description,
 			StartTime:      appointment.StartTime,
 			EndTime:        appointment.EndTime,
-			Status:         appointment.Status,
+			Status:         appointment.Status.String(),
 			Notes:          appointment.Notes,
 			ChatEnabled:    appointment.ChatEnabled,
 			IsLawyerViewed: appointment.IsLawyerViewed,
@@ -257,7 +275,7 @@ func (s *AppointmentService) GetAppointmentsByLawyerID(lawyerID int, statusFilte
 			Description:    appointment.Description,
 			StartTime:      appointment.StartTime,
 			EndTime:        appointment.EndTime,
-			Status:         appointment.Status,
+			Status:         appointment.Status.String(),
 			Notes:          appointment.Notes,
 			ChatEnabled:    appointment.ChatEnabled,
 			IsLawyerViewed: appointment.IsLawyerViewed,
@@ -391,7 +409,7 @@ func (s *AppointmentService) GetAllAppointments(
 			Description:    a.Description,
 			StartTime:      a.StartTime,
 			EndTime:        a.EndTime,
-			Status:         a.Status,
+			Status:         a.Status.String(),
 			Notes:          a.Notes,
 			ChatEnabled:    a.ChatEnabled,
 			AdminReason:    a.AdminReason,
@@ -418,38 +436,163 @@ func (s *AppointmentService) GetAllAppointments(
 	return resp, total, nil
 }
 
+var (
+	// ErrSlotUnavailable is returned when a booking overlaps another live appointment
+	// of the same lawyer, busy time, a hold for another client, or their buffers
+	ErrSlotUnavailable     = errors.New("time slot not available")
+	ErrAppointmentNotFound = errors.New("appointment not found")
+)
+
+// CreateAppointment books an appointment and records its initial status. New
+// appointments always start as pending. The overlap check is done by the database's
+// exclusion constraint in the same statement as the insert, so concurrent bookings of
+// one slot cannot both succeed. A slot held for a waitlisted client can only be
+// booked by that client, which uses up the hold. Intake answers prepared by
+// IntakeService.PrepareIntake are saved in the same transaction.
 func (s *AppointmentService) CreateAppointment(appointment *models.Appointment) error {
+	appointment.Status = models.AppointmentStatusPending
 
-	result := s.DB.Create(appointment)
-	return result.Error
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		holds, err := (&WaitlistService{DB: tx}).lockHolds(appointment.LawyerID, appointment.StartTime, appointment.EndTime)
+		if err != nil {
+			return err
+		}
+		for _, hold := range holds {
+			if hold.UserID != appointment.UserID {
+				return ErrSlotUnavailable
+			}
+		}
+
+		if err := tx.Create(appointment).Error; err != nil {
+			return err
+		}
+		initial := AppointmentTransition{To: appointment.Status, ActorID: appointment.UserID, ActorRole: string(models.RoleClient)}
+		if err := tx.Create(newStatusHistory(appointment.ID, nil, initial)).Error; err != nil {
+			return err
+		}
+		if appointment.Intake != nil {
+			if err := (&IntakeService{DB: tx}).saveResponse(appointment.ID, appointment.UserID, appointment.Intake); err != nil {
+				return err
+			}
+		}
+		if err := (&ConflictCheckService{DB: tx}).saveOpposingParties(appointment.ID, appointment.OpposingParties); err != nil {
+			return err
+		}
+		return (&WaitlistService{DB: tx}).markBooked(appointment)
+	})
+	if pgErrorCode(err) == pgExclusionViolation {
+		return ErrSlotUnavailable
+	}
+	return err
 }
 
-func (s *AppointmentService) UpdateAppointment(appointment *models.Appointment) error {
+// UpdateAppointment saves an appointment's details and, when transition is not nil,
+// changes its status in the same transaction, so a refused transition leaves the
+// details unchanged. Status and the reasons that go with it only change through a
+// transition, as in TransitionStatus. A new time must be bookable under the
+// lawyer's booking policy, as with a reschedule: policy violations are a
+// *BookingPolicyError and a clash returns ErrSlotUnavailable.
+func (s *AppointmentService) UpdateAppointment(appointment *models.Appointment, transition *AppointmentTransition) error {
 	if appointment.ID <= 0 {
 		return errors.New("invalid appointment ID")
 	}
+	if transition != nil {
+		if err := s.clearTransition(appointment.ID, *transition); err != nil {
+			return err
+		}
+	}
 
 	var existingAppointment models.Appointment
-	if err := s.DB.First(&existingAppointment, appointment.ID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("appointment not found")
+	var from models.AppointmentStatus
+	var timeChanged, linkReplaced bool
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&existingAppointment, appointment.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAppointmentNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"description":  appointment.Description,
+			"start_time":   appointment.StartTime,
+			"end_time":     appointment.EndTime,
+			"meeting_link": appointment.MeetingLink,
+			"notes":        appointment.Notes,
+			"chat_enabled": appointment.ChatEnabled,
+		}
+		timeChanged = !appointment.StartTime.Equal(existingAppointment.StartTime) || !appointment.EndTime.Equal(existingAppointment.EndTime)
+		if timeChanged {
+			var lawyer models.Lawyer
+			if err := tx.First(&lawyer, existingAppointment.LawyerID).Error; err != nil {
+				return err
+			}
+			if err := (&AvailabilityService{DB: tx}).CheckBooking(&lawyer, appointment.StartTime, appointment.EndTime, existingAppointment.ID, existingAppointment.UserID); err != nil {
+				return err
+			}
+			updates["day_reminder_sent"] = false
+			updates["hour_reminder_sent"] = false
+			updates["calendar_sequence"] = gorm.Expr("calendar_sequence + 1")
 		}
+		// A link entered by hand replaces an automatically created meeting
+		linkReplaced = isManagedMeeting(&existingAppointment) && !sameMeetingLink(appointment.MeetingLink, existingAppointment.MeetingLink)
+		if linkReplaced {
+			updates["meeting_provider"] = nil
+			updates["meeting_id"] = nil
+		}
+
+		if err := tx.Model(&models.Appointment{}).Where("id = ?", appointment.ID).Updates(updates).Error; err != nil {
+			if pgErrorCode(err) == pgExclusionViolation {
+				return ErrSlotUnavailable
+			}
+			return err
+		}
+		if transition == nil {
+			return nil
+		}
+
+		var updated models.Appointment
+		if err := tx.First(&updated, appointment.ID).Error; err != nil {
+			return err
+		}
+		var err error
+		from, err = applyTransition(tx, &updated, *transition)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
-	result := s.DB.Model(&appointment).Updates(map[string]interface{}{
-		"description":   appointment.Description,
-		"start_time":    appointment.StartTime,
-		"end_time":      appointment.EndTime,
-		"status":        appointment.Status,
-		"meeting_link":  appointment.MeetingLink,
-		"notes":         appointment.Notes,
-		"chat_enabled":  appointment.ChatEnabled,
-		"cancel_reason": appointment.CancelReason,
-		"admin_reason":  appointment.AdminReason,
-	})
+	meetingService := NewMeetingService()
+	switch {
+	case linkReplaced:
+		appointment.MeetingProvider = nil
+		appointment.MeetingID = nil
+		if existingAppointment.MeetingID != nil {
+			meetingService.ReleaseMeeting(*existingAppointment.MeetingProvider, *existingAppointment.MeetingID)
+		}
+	case timeChanged:
+		if err := meetingService.RegenerateMeeting(appointment); err != nil {
+			fmt.Printf("Failed to regenerate meeting for appointment %d: %v\n", appointment.ID, err)
+		}
+	}
 
-	return result.Error
+	if transition != nil {
+		var updated models.Appointment
+		if err := s.DB.First(&updated, appointment.ID).Error; err != nil {
+			return err
+		}
+		s.runTransitionHooks(&updated, from, *transition)
+	}
+
+	return nil
+}
+
+func sameMeetingLink(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 // UpdateAppointmentViewedStatus updates the viewed status of an appointment based on user role
@@ -484,34 +627,6 @@ func (s *AppointmentService) DeleteAppointment(appointmentID int) error {
 	return result.Error
 }
 
-func (s *AppointmentService) CheckAvailability(
-	lawyerID int,
-	startTime, endTime time.Time,
-) (bool, error) {
-	if lawyerID <= 0 {
-		return false, errors.New("invalid lawyer ID")
-	}
-
-	startUTC := startTime.UTC()
-	endUTC := endTime.UTC()
-
-	var count int64
-	err := s.DB.
-		Model(&models.Appointment{}).
-		Where("lawyer_id = ? AND status NOT IN ('cancelled', 'rejected') "+
-			"AND start_time < ? AND end_time > ?",
-			lawyerID,
-			endUTC,
-			startUTC,
-		).
-		Count(&count).Error
-	if err != nil {
-		return false, err
-	}
-
-	return count == 0, nil
-}
-
 func (s *AppointmentService) GetUpcomingAppointments(userID int, isLawyer bool) ([]responses.AppointmentResponse, error) {
 	if userID <= 0 {
 		return nil, errors.New("invalid user ID")
@@ -545,7 +660,7 @@ func (s *AppointmentService) GetUpcomingAppointments(userID int, isLawyer bool)
 				Description: appointment.Description,
 				StartTime:   appointment.StartTime,
 				EndTime:     appointment.EndTime,
-				Status:      appointment.Status,
+				Status:      appointment.Status.String(),
 				Notes:       appointment.Notes,
 				ChatEnabled: appointment.ChatEnabled,
 				CreatedAt:   appointment.CreatedAt,
@@ -594,7 +709,7 @@ func (s *AppointmentService) appointmentsToResponses(appointments []models.Appoi
 			Description: appointment.Description,
 			StartTime:   appointment.StartTime,
 			EndTime:     appointment.EndTime,
-			Status:      appointment.Status,
+			Status:      appointment.Status.String(),
 			Notes:       appointment.Notes,
 			ChatEnabled: appointment.ChatEnabled,
 			CreatedAt:   appointment.CreatedAt,
@@ -613,60 +728,20 @@ func (s *AppointmentService) appointmentsToResponses(appointments []models.Appoi
 	return responseList, nil
 }
 
+// TimeSlot is one bookable slot. Time and End are wall-clock times in the lawyer's
+// time zone; StartAt and EndAt carry the offset so clients elsewhere can convert.
 type TimeSlot struct {
-	Time      string `json:"time"`
-	Available bool   `json:"available"`
+	Time      string    `json:"time"`
+	End       string    `json:"end"`
+	StartAt   time.Time `json:"start_at"`
+	EndAt     time.Time `json:"end_at"`
+	Available bool      `json:"available"`
 }
 
-func parseTimeSlotStr(date time.Time, timeStr string, loc *time.Location) (time.Time, error) {
-	parts := strings.Split(timeStr, ":")
-	if len(parts) != 2 {
-		return time.Time{}, fmt.Errorf("invalid time format: %s, expected HH:MM", timeStr)
-	}
-	hour, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid hour in time string '%s': %w", timeStr, err)
-	}
-	minute, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid minute in time string '%s': %w", timeStr, err)
-	}
-
-	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
-		return time.Time{}, fmt.Errorf("hour (%d) or minute (%d) out of range in time string '%s'", hour, minute, timeStr)
-	}
-
-	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc), nil
-}
-
-func (s *AppointmentService) GetAvailableTimeSlots(lawyerID int, date time.Time) ([]TimeSlot, error) {
-	var slots []TimeSlot
-	loc := date.Location() // Location is passed from the handler (e.g., Asia/Tokyo)
-	now := time.Now().In(loc)
-
-	// dayStart is the beginning of the selected date in the specified location.
-	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-
-	// General working hours for generating candidate slots
-	generalWorkDayStartHour := 9 // 9 AM
-	generalWorkDayEndHour := 17  // 5 PM (slots generated up to 16:30)
-
-	// If the selected date is in the past, all slots are unavailable.
-	if dayStart.Before(todayStart) {
-		for h := generalWorkDayStartHour; h < generalWorkDayEndHour; h++ {
-			for m := 0; m < 60; m += 30 {
-				slotTime := time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, loc)
-				slots = append(slots, TimeSlot{
-					Time:      slotTime.Format("15:04"),
-					Available: false,
-				})
-			}
-		}
-		return slots, nil
-	}
-
-	// Fetch lawyer details, including availability
+// GetAvailableTimeSlots lists the slots a lawyer offers on a date in the lawyer's
+// time zone, marking each one available or not by the lawyer's booking policy
+func (s *AppointmentService) GetAvailableTimeSlots(lawyerID int, date models.Date) ([]TimeSlot, error) {
+	// Fetch lawyer details, including availability and booking settings
 	var lawyer models.Lawyer
 	if err := s.DB.First(&lawyer, lawyerID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -675,175 +750,90 @@ func (s *AppointmentService) GetAvailableTimeSlots(lawyerID int, date time.Time)
 		return nil, fmt.Errorf("failed to fetch lawyer: %w", err)
 	}
 
-	// Parse lawyer's defined work periods for the given day
-	var parsedLawyerWorkPeriods []struct{ Start, End time.Time }
-	if lawyer.Availability != nil {
-		availabilityData := *lawyer.Availability // Dereference pointer to get the map
-		if availabilityData != nil {             // Check if the map itself is not nil
-			dayOfWeek := strings.ToLower(date.Weekday().String())
-			dayScheduleUntyped, entryExists := availabilityData[dayOfWeek]
-
-			if entryExists && dayScheduleUntyped != nil {
-				if scheduleIntervals, ok := dayScheduleUntyped.([]interface{}); ok {
-					for _, intervalEntry := range scheduleIntervals {
-						if intervalMap, isMap := intervalEntry.(map[string]interface{}); isMap {
-							startStr, startOk := intervalMap["start"].(string)
-							endStr, endOk := intervalMap["end"].(string)
-
-							if startOk && endOk {
-								periodStart, errS := parseTimeSlotStr(date, startStr, loc)
-								periodEnd, errE := parseTimeSlotStr(date, endStr, loc)
-
-								// Validate: start must be before end, and times must be parsed correctly.
-								if errS == nil && errE == nil && periodStart.Before(periodEnd) {
-									parsedLawyerWorkPeriods = append(parsedLawyerWorkPeriods, struct{ Start, End time.Time }{periodStart, periodEnd})
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+	availabilityService := &AvailabilityService{DB: s.DB}
+	day, err := availabilityService.loadBookingDay(&lawyer, date, 0)
+	if err != nil {
+		return nil, err
 	}
-
-	// If no valid work periods were parsed from the lawyer's schedule for this day,
-	// all standard 09:00-17:00 slots are considered unavailable.
-	if len(parsedLawyerWorkPeriods) == 0 {
-		for h := generalWorkDayStartHour; h < generalWorkDayEndHour; h++ {
-			for m := 0; m < 60; m += 30 {
-				slotT := time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, loc)
-				slots = append(slots, TimeSlot{Time: slotT.Format("15:04"), Available: false})
-			}
+	slotDuration := time.Duration(day.settings.SlotMinutes) * time.Minute
+
+	// If the lawyer does not work on this day, the standard 09:00-17:00 slots are
+	// listed as unavailable
+	if len(day.slotStarts()) == 0 {
+		slots := []TimeSlot{}
+		closingTime := day.dayStart.Add(17 * time.Hour)
+		for slotStart := day.dayStart.Add(9 * time.Hour); !slotStart.Add(slotDuration).After(closingTime); slotStart = slotStart.Add(slotDuration) {
+			slots = append(slots, TimeSlot{
+				Time:      slotStart.Format("15:04"),
+				End:       slotStart.Add(slotDuration).Format("15:04"),
+				StartAt:   slotStart,
+				EndAt:     slotStart.Add(slotDuration),
+				Available: false,
+			})
 		}
 		return slots, nil
 	}
 
-	// Fetch existing appointments for the lawyer on the given date to check for booked slots.
-	// dayEnd is the start of the next day, effectively covering the entire selected date.
-	dayEnd := dayStart.Add(24 * time.Hour)
-	var existingAppointments []models.Appointment
-	if err := s.DB.
-		Where(
-			"lawyer_id = ? AND status NOT IN ? AND start_time < ? AND end_time > ?",
-			lawyerID,
-			[]string{"cancelled", "rejected"},
-			dayEnd, dayStart,
-		).
-		Find(&existingAppointments).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch existing appointments: %w", err)
-	}
-
-	// Generate candidate slots (e.g., 09:00, 09:30 ... 16:30) and check availability
-	slotDuration := 30 * time.Minute
-	for h := generalWorkDayStartHour; h < generalWorkDayEndHour; h++ { // h from 9 up to 16
-		for m := 0; m < 60; m += 30 { // m is 0 or 30
-			slotStart := time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, loc)
-			slotEnd := slotStart.Add(slotDuration)
-
-			// Check 1: Is this slot within any of the lawyer's parsed work periods?
-			isWithinLawyerDefinedTime := false
-			for _, lawyerPeriod := range parsedLawyerWorkPeriods {
-				// A slot is within a lawyer's period if:
-				// lawyerPeriod.Start <= slotStart AND slotEnd <= lawyerPeriod.End
-				if (lawyerPeriod.Start.Before(slotStart) || lawyerPeriod.Start.Equal(slotStart)) &&
-					(slotEnd.Before(lawyerPeriod.End) || slotEnd.Equal(lawyerPeriod.End)) {
-					isWithinLawyerDefinedTime = true
-					break
-				}
-			}
-
-			if !isWithinLawyerDefinedTime {
-				slots = append(slots, TimeSlot{Time: slotStart.Format("15:04"), Available: false})
-				continue
-			}
-
-			// Check 2: If the selected date is today, is the slot in the future?
-			if dayStart.Equal(todayStart) && slotStart.Before(now) {
-				slots = append(slots, TimeSlot{Time: slotStart.Format("15:04"), Available: false})
-				continue
-			}
-
-			// Check 3: Is the slot within the next 48 hours from now?
-			fortyEightHoursFromNow := now.Add(48 * time.Hour)
-			if slotStart.Before(fortyEightHoursFromNow) {
-				slots = append(slots, TimeSlot{Time: slotStart.Format("15:04"), Available: false})
-				continue
-			}
-
-			// Check 4: Is the slot already booked (overlaps with an existing appointment)?
-			isBooked := false
-			for _, appt := range existingAppointments {
-				// Check for overlap: (SlotStart < ApptEnd) AND (SlotEnd > ApptStart)
-				if slotStart.Before(appt.EndTime) && slotEnd.After(appt.StartTime) {
-					isBooked = true
-					break
-				}
-			}
-			if isBooked {
-				slots = append(slots, TimeSlot{Time: slotStart.Format("15:04"), Available: false})
-				continue
-			}
-
-			// If all checks pass, the slot is available
-			slots = append(slots, TimeSlot{Time: slotStart.Format("15:04"), Available: true})
-		}
-	}
-
-	return slots, nil
+	return day.timeSlots(time.Now()), nil
 }
 
-func (s *AppointmentService) RejectAppointment(appointmentID int, reason string) error {
-	if appointmentID <= 0 {
-		return errors.New("invalid appointment ID")
-	}
+// RejectAppointment rejects a pending or confirmed appointment with a reason
+func (s *AppointmentService) RejectAppointment(appointmentID int, reason string, actorID int, actorRole string) (*models.Appointment, error) {
 	reason = strings.TrimSpace(reason)
 	if reason == "" {
-		return errors.New("reason is required")
-	}
-
-	var appointment models.Appointment
-	if err := s.DB.First(&appointment, appointmentID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("appointment not found")
-		}
-		return err
-	}
-
-	// Only allow rejecting pending/confirmed (not already completed/cancelled)
-	if appointment.Status == "cancelled" || appointment.Status == "rejected" || appointment.Status == "completed" {
-		return errors.New("cannot reject appointment with this status")
+		return nil, errors.New("reason is required")
 	}
 
-	// Update status and reject_reason
-	return s.DB.Model(&appointment).Updates(map[string]interface{}{
-		"status":        "rejected",
-		"reject_reason": reason,
-		"updated_at":    time.Now(),
-	}).Error
+	return s.TransitionStatus(appointmentID, AppointmentTransition{
+		To:        models.AppointmentStatusRejected,
+		ActorID:   actorID,
+		ActorRole: actorRole,
+		Reason:    &reason,
+	})
 }
 
+// AutoCancelPendingAppointments cancels appointments the lawyer never confirmed
 func (s *AppointmentService) AutoCancelPendingAppointments() error {
 	now := time.Now()
-
-	result := s.DB.Model(&models.Appointment{}).
-		Where("status = ? AND start_time <= ?", "pending", now.Add(-5*time.Minute)).
-		Updates(map[string]interface{}{
-			"status": "cancelled",
-		})
-
-	return result.Error
+	return s.autoTransition(
+		models.AppointmentStatusPending,
+		"start_time",
+		now.Add(-5*time.Minute),
+		models.AppointmentStatusCancelled,
+	)
 }
 
+// AutoCompleteConfirmedAppointments completes confirmed appointments whose
+// attendance was not reported within the grace period after they ended
 func (s *AppointmentService) AutoCompleteConfirmedAppointments() error {
-	now := time.Now()
+	settings, err := (&PlatformSettingService{DB: s.DB}).GetAttendanceSettings()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-time.Duration(settings.GraceHours) * time.Hour)
+	return s.autoTransition(models.AppointmentStatusConfirmed, "end_time", cutoff, models.AppointmentStatusCompleted)
+}
 
-	result := s.DB.Model(&models.Appointment{}).
-		Where("status = ? AND start_time <= ?", "confirmed", now).
-		Updates(map[string]interface{}{
-			"status": "completed",
-		})
+// autoTransition moves every appointment in one status whose start_time or
+// end_time column is at or before the cutoff to another status, one at a time so
+// each change gets its history and hooks
+func (s *AppointmentService) autoTransition(from models.AppointmentStatus, column string, cutoff time.Time, to models.AppointmentStatus) error {
+	var ids []int
+	if err := s.DB.Model(&models.Appointment{}).
+		Where("status = ? AND "+column+" <= ?", from, cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
 
-	return result.Error
+	for _, id := range ids {
+		// Appointments that changed since they were listed are skipped
+		_, err := s.TransitionStatus(id, AppointmentTransition{To: to})
+		if err != nil && !errors.Is(err, ErrInvalidStatusTransition) {
+			fmt.Printf("Failed to move appointment %d from %s to %s: %v\n", id, from, to, err)
+		}
+	}
+
+	return nil
 }
 
 // SendAppointmentReminders sends email reminders to lawyers for upcoming appointments
@@ -854,7 +844,7 @@ func (s *AppointmentService) SendAppointmentReminders() error {
 
 	// Get all active appointments (not rejected, cancelled, or finished)
 	var appointments []models.Appointment
-	if err := s.DB.Where("status NOT IN (?, ?, ?)", "rejected", "cancelled", "completed").
+	if err := s.DB.Where("status NOT IN ?", models.TerminalAppointmentStatuses()).
 		Find(&appointments).Error; err != nil {
 		return fmt.Errorf("failed to fetch appointments: %w", err)
 	}
@@ -893,9 +883,8 @@ func (s *AppointmentService) SendAppointmentReminders() error {
 			continue
 		}
 
-		// Format appointment date and time for display
-		appointmentDate := appointment.StartTime.Format("2006年01月02日")
-		appointmentTime := appointment.StartTime.Format("15:04")
+		// Format appointment date and time in the lawyer's time zone
+		appointmentDate, appointmentTime := appointmentDisplayTime(appointment.StartTime, lawyer.Location())
 
 		// Get client display name
 		var clientName string
@@ -984,7 +973,7 @@ func (s *AppointmentService) SendAppointmentReminders() error {
 
 // SendLawyerAppointmentStatusUpdateEmail sends email notification to the client
 // when a lawyer updates the status of an appointment
-func (s *AppointmentService) SendLawyerAppointmentStatusUpdateEmail(appointment *models.Appointment, updatedStatus string) error {
+func (s *AppointmentService) SendLawyerAppointmentStatusUpdateEmail(appointment *models.Appointment, updatedStatus models.AppointmentStatus) error {
 	// Run asynchronously to avoid blocking API response
 	go func() {
 		// Get required services
@@ -1006,17 +995,8 @@ func (s *AppointmentService) SendLawyerAppointmentStatusUpdateEmail(appointment
 			return
 		}
 
-		// Status names mapping for Japanese
-		statusNamesJa := map[string]string{
-			"pending":   "保留中",
-			"confirmed": "確認済み",
-			"rejected":  "拒否",
-			"cancelled": "キャンセル",
-			"completed": "完了",
-		}
-
 		// Get status name in Japanese
-		statusName := statusNamesJa[updatedStatus]
+		statusName := appointmentStatusNamesJa[updatedStatus]
 
 		// Send email to client
 		err = emailService.SendLawyerAppointmentStatusUpdateEmail(
@@ -1089,7 +1069,7 @@ func (s *AppointmentService) SendAppointmentCancelledEmail(appointment *models.A
 
 // SendAppointmentStatusUpdateEmails sends email notifications to both lawyer and client
 // when an admin updates the status of an appointment
-func (s *AppointmentService) SendAppointmentStatusUpdateEmails(appointment *models.Appointment, updatedStatus string) error {
+func (s *AppointmentService) SendAppointmentStatusUpdateEmails(appointment *models.Appointment, updatedStatus models.AppointmentStatus) error {
 	// Run asynchronously to avoid blocking API response
 	go func() {
 		// Get required services
@@ -1117,15 +1097,6 @@ func (s *AppointmentService) SendAppointmentStatusUpdateEmails(appointment *mode
 			return
 		}
 
-		// Status names mapping for Japanese
-		statusNamesJa := map[string]string{
-			"pending":   "保留中",
-			"confirmed": "確認済み",
-			"rejected":  "拒否",
-			"cancelled": "キャンセル",
-			"completed": "完了",
-		}
-
 		// Send email to client
 		var clientDisplayName string
 		if lawyer.FullName != "" {
@@ -1135,7 +1106,7 @@ func (s *AppointmentService) SendAppointmentStatusUpdateEmails(appointment *mode
 		}
 
 		// Get status name in Japanese
-		statusNameForClient := statusNamesJa[updatedStatus]
+		statusNameForClient := appointmentStatusNamesJa[updatedStatus]
 
 		err = emailService.SendAppointmentStatusUpdateEmail(
 			*client,
@@ -1158,7 +1129,7 @@ func (s *AppointmentService) SendAppointmentStatusUpdateEmails(appointment *mode
 		}
 
 		// Get status name in Japanese
-		statusNameForLawyer := statusNamesJa[updatedStatus]
+		statusNameForLawyer := appointmentStatusNamesJa[updatedStatus]
 
 		err = emailService.SendAppointmentStatusUpdateEmail(
 			*lawyerUser,
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func TestCreateAppointmentConcurrentBookings(t *testing.T) {
+	db := openTestDB(t)
+	lawyer := createTestLawyer(t, db)
+	clients := []*models.User{
+		createTestUser(t, db, models.RoleClient),
+		createTestUser(t, db, models.RoleClient),
+	}
+
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Hour).UTC()
+	end := start.Add(time.Hour)
+	service := &AppointmentService{DB: db}
+
+	var (
+		wg      sync.WaitGroup
+		release = make(chan struct{})
+		errs    = make([]error, len(clients))
+	)
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-release
+			errs[i] = service.CreateAppointment(&models.Appointment{
+				UserID:    client.ID,
+				LawyerID:  lawyer.ID,
+				StartTime: start,
+				EndTime:   end,
+			})
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	succeeded, conflicted := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrSlotUnavailable):
+			conflicted++
+		default:
+			t.Fatalf("CreateAppointment: %v", err)
+		}
+	}
+	if succeeded != 1 || conflicted != 1 {
+		t.Fatalf("got %d bookings and %d ErrSlotUnavailable, want exactly one of each", succeeded, conflicted)
+	}
+
+	var count int64
+	if err := db.Model(&models.Appointment{}).Where("lawyer_id = ?", lawyer.ID).Count(&count).Error; err != nil {
+		t.Fatalf("counting appointments: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("lawyer has %d appointments, want 1", count)
+	}
+}
+
+func TestBookingPolicyError(t *testing.T) {
+	var err error = &BookingPolicyError{Reason: "outside the lawyer's available hours"}
+	if got, want := err.Error(), "booking not allowed: outside the lawyer's available hours"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	wrapped := errors.Join(errors.New("checking option 1"), err)
+	var policyErr *BookingPolicyError
+	if !errors.As(wrapped, &policyErr) || errors.Is(wrapped, ErrSlotUnavailable) {
+		t.Errorf("wrapped policy error not recognised: %v", wrapped)
+	}
+}
+
+func TestUpdateAppointmentChecksBooking(t *testing.T) {
+	db := openTestDB(t)
+	lawyer := createTestLawyer(t, db)
+	settings := models.BookingSettings{SlotMinutes: 60, MaxAdvanceDays: 30}
+	if err := db.Model(lawyer).Updates(map[string]interface{}{
+		"availability":     allDayLawyer(settings).Availability,
+		"booking_settings": &settings,
+	}).Error; err != nil {
+		t.Fatalf("updating test lawyer: %v", err)
+	}
+	client := createTestUser(t, db, models.RoleClient)
+
+	day := time.Now().In(lawyer.Location()).AddDate(0, 0, 3)
+	at := func(hour int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, lawyer.Location())
+	}
+	appointment := &models.Appointment{
+		UserID:    client.ID,
+		LawyerID:  lawyer.ID,
+		StartTime: at(10),
+		EndTime:   at(11),
+		Status:    models.AppointmentStatusConfirmed,
+	}
+	if err := db.Omit("User", "Lawyer").Create(appointment).Error; err != nil {
+		t.Fatalf("creating test appointment: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&models.Appointment{}, appointment.ID)
+	})
+
+	calendar := &models.ExternalCalendar{LawyerID: lawyer.ID, Name: "Office", Kind: models.ExternalCalendarICS, URL: "https://calendar.example.com/office.ics"}
+	if err := db.Create(calendar).Error; err != nil {
+		t.Fatalf("creating test calendar: %v", err)
+	}
+	if err := db.Create(&models.BusyBlock{CalendarID: calendar.ID, LawyerID: lawyer.ID, StartTime: at(13), EndTime: at(14)}).Error; err != nil {
+		t.Fatalf("creating busy block: %v", err)
+	}
+
+	service := &AppointmentService{DB: db}
+	move := func(start, end time.Time) error {
+		moved := *appointment
+		moved.StartTime, moved.EndTime = start, end
+		return service.UpdateAppointment(&moved, nil)
+	}
+
+	if err := move(at(13), at(14)); !errors.Is(err, ErrSlotUnavailable) {
+		t.Errorf("moving onto busy time: error = %v, want ErrSlotUnavailable", err)
+	}
+	var policyErr *BookingPolicyError
+	if err := move(at(20), at(21)); !errors.As(err, &policyErr) {
+		t.Errorf("moving outside working hours: error = %v, want *BookingPolicyError", err)
+	}
+	if err := move(at(15), at(16)); err != nil {
+		t.Fatalf("moving to free time: %v", err)
+	}
+
+	var stored models.Appointment
+	if err := db.First(&stored, appointment.ID).Error; err != nil {
+		t.Fatalf("loading appointment: %v", err)
+	}
+	if !stored.StartTime.Equal(at(15)) || stored.CalendarSequence != appointment.CalendarSequence+1 {
+		t.Errorf("stored start %v sequence %d, want %v and %d", stored.StartTime, stored.CalendarSequence, at(15), appointment.CalendarSequence+1)
+	}
+}
+
+func TestUpdateAppointmentRefusedTransitionKeepsDetails(t *testing.T) {
+	db := openTestDB(t)
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	pastClient := createTestUser(t, db, models.RoleClient)
+	if err := db.Model(pastClient).Updates(map[string]interface{}{"last_name": "山田", "first_name": "太郎"}).Error; err != nil {
+		t.Fatalf("naming past client: %v", err)
+	}
+
+	start := time.Now().Add(96 * time.Hour).Truncate(time.Hour)
+	newAppointment := func(userID int, status models.AppointmentStatus, offset time.Duration) *models.Appointment {
+		appointment := &models.Appointment{
+			UserID:    userID,
+			LawyerID:  lawyer.ID,
+			StartTime: start.Add(offset),
+			EndTime:   start.Add(offset + time.Hour),
+			Status:    status,
+		}
+		if err := db.Omit("User", "Lawyer").Create(appointment).Error; err != nil {
+			t.Fatalf("creating test appointment: %v", err)
+		}
+		t.Cleanup(func() {
+			db.Unscoped().Delete(&models.Appointment{}, appointment.ID)
+		})
+		return appointment
+	}
+	newAppointment(pastClient.ID, models.AppointmentStatusCompleted, -240*time.Hour)
+	pending := newAppointment(client.ID, models.AppointmentStatusPending, 0)
+	if err := db.Create(&models.OpposingParty{AppointmentID: &pending.ID, Name: "山田 太郎"}).Error; err != nil {
+		t.Fatalf("creating opposing party: %v", err)
+	}
+	completed := newAppointment(client.ID, models.AppointmentStatusCompleted, -48*time.Hour)
+
+	service := &AppointmentService{DB: db}
+	description := "edited"
+
+	edited := *pending
+	edited.Description = &description
+	err := service.UpdateAppointment(&edited, &AppointmentTransition{To: models.AppointmentStatusConfirmed, ActorID: lawyer.UserID, ActorRole: string(models.RoleLawyer)})
+	var reviewErr *ConflictReviewError
+	if !errors.As(err, &reviewErr) || !errors.Is(err, ErrConflictUnreviewed) {
+		t.Fatalf("confirming with a conflict: error = %v, want *ConflictReviewError", err)
+	}
+	if reviewErr.Check == nil || reviewErr.Check.ID == 0 || reviewErr.Check.AppointmentID != pending.ID {
+		t.Errorf("refused confirmation returned check %+v, want the recorded check of appointment %d", reviewErr.Check, pending.ID)
+	}
+
+	edited = *completed
+	edited.Description = &description
+	err = service.UpdateAppointment(&edited, &AppointmentTransition{To: models.AppointmentStatusRejected, ActorID: lawyer.UserID, ActorRole: string(models.RoleLawyer)})
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("rejecting a completed appointment: error = %v, want ErrInvalidStatusTransition", err)
+	}
+
+	for _, appointment := range []*models.Appointment{pending, completed} {
+		var stored models.Appointment
+		if err := db.First(&stored, appointment.ID).Error; err != nil {
+			t.Fatalf("loading appointment: %v", err)
+		}
+		if stored.Description != nil || stored.Status != appointment.Status {
+			t.Errorf("appointment %d stored with description %v and status %s after a refused transition, want it unchanged",
+				appointment.ID, stored.Description, stored.Status)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationTypeAppointmentStatus is the in-app notification sent on status changes
+const NotificationTypeAppointmentStatus = "appointment_status"
+
+// ErrInvalidStatusTransition is returned when an appointment cannot move from its
+// current status to the requested one, usually because someone changed it first
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// appointmentStatusNamesJa are the status names shown to users
+var appointmentStatusNamesJa = map[models.AppointmentStatus]string{
+	models.AppointmentStatusPending:      "保留中",
+	models.AppointmentStatusConfirmed:    "確認済み",
+	models.AppointmentStatusRejected:     "拒否",
+	models.AppointmentStatusCancelled:    "キャンセル",
+	models.AppointmentStatusCompleted:    "完了",
+	models.AppointmentStatusNoShowClient: "欠席（相談者）",
+	models.AppointmentStatusNoShowLawyer: "欠席（弁護士）",
+}
+
+// AppointmentTransition is a request to move an appointment to another status
+type AppointmentTransition struct {
+	To models.AppointmentStatus
+	// ActorID and ActorRole identify who asked for the change; ActorID is zero
+	// for automatic transitions made by scheduled jobs
+	ActorID   int
+	ActorRole string
+	// Reason is stored as the cancel or reject reason
+	Reason      *string
+	AdminReason *string
+}
+
+func (t AppointmentTransition) isAutomatic() bool {
+	return t.ActorID == 0
+}
+
+// TransitionStatus moves an appointment to a new status if the transition table
+// allows it, refusing attendance outcomes before the appointment has ended with
+// ErrInvalidAttendance and confirmations that need a conflict-of-interest review
+// with a *ConflictReviewError. It records the change in the status history and runs
+// the transition's hooks. It returns the updated appointment.
+func (s *AppointmentService) TransitionStatus(appointmentID int, transition AppointmentTransition) (*models.Appointment, error) {
+	if appointmentID <= 0 {
+		return nil, errors.New("invalid appointment ID")
+	}
+	if err := s.clearTransition(appointmentID, transition); err != nil {
+		return nil, err
+	}
+
+	var appointment models.Appointment
+	var from models.AppointmentStatus
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&appointment, appointmentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAppointmentNotFound
+			}
+			return err
+		}
+
+		var err error
+		from, err = applyTransition(tx, &appointment, transition)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.First(&appointment, appointmentID).Error; err != nil {
+		return nil, err
+	}
+
+	s.runTransitionHooks(&appointment, from, transition)
+
+	return &appointment, nil
+}
+
+// clearTransition makes the checks a transition needs before its transaction.
+// Confirming needs a conflict-of-interest check that is clear or acknowledged; the
+// check it runs is kept even when the confirmation is refused, so the lawyer can
+// review and acknowledge it.
+func (s *AppointmentService) clearTransition(appointmentID int, transition AppointmentTransition) error {
+	if !transition.To.IsValid() {
+		return errors.New("invalid status")
+	}
+	if transition.To != models.AppointmentStatusConfirmed {
+		return nil
+	}
+
+	var appointment models.Appointment
+	if err := s.DB.First(&appointment, appointmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAppointmentNotFound
+		}
+		return err
+	}
+	if !appointment.Status.CanTransitionTo(transition.To) {
+		return ErrInvalidStatusTransition
+	}
+
+	check, err := (&ConflictCheckService{DB: s.DB}).ClearForConfirmation(&appointment, transition.ActorID)
+	if errors.Is(err, ErrConflictUnreviewed) {
+		return &ConflictReviewError{Check: check}
+	}
+	return err
+}
+
+// applyTransition changes the status of an appointment locked by tx and records the
+// change, returning the status it had before
+func applyTransition(tx *gorm.DB, appointment *models.Appointment, transition AppointmentTransition) (models.AppointmentStatus, error) {
+	from := appointment.Status
+	if !from.CanTransitionTo(transition.To) {
+		return from, ErrInvalidStatusTransition
+	}
+	if transition.To.IsAttendanceOutcome() && time.Now().Before(appointment.EndTime) {
+		return from, fmt.Errorf("%w: attendance can be confirmed once the appointment has ended", ErrInvalidAttendance)
+	}
+
+	updates := map[string]interface{}{
+		"status":     transition.To,
+		"updated_at": time.Now(),
+		// Calendars only apply an update to an event with a higher SEQUENCE
+		"calendar_sequence": gorm.Expr("calendar_sequence + 1"),
+	}
+	switch transition.To {
+	case models.AppointmentStatusConfirmed:
+		updates["chat_enabled"] = true
+	case models.AppointmentStatusRejected:
+		updates["chat_enabled"] = false
+		updates["reject_reason"] = transition.Reason
+	case models.AppointmentStatusCancelled:
+		updates["chat_enabled"] = false
+		updates["cancel_reason"] = transition.Reason
+	case models.AppointmentStatusNoShowClient, models.AppointmentStatusNoShowLawyer:
+		updates["chat_enabled"] = false
+	}
+	if transition.AdminReason != nil {
+		updates["admin_reason"] = transition.AdminReason
+	}
+
+	if err := tx.Model(appointment).Updates(updates).Error; err != nil {
+		return from, err
+	}
+	if transition.To.IsNoShow() {
+		if err := recordNoShow(tx, appointment, transition.To); err != nil {
+			return from, err
+		}
+	}
+
+	return from, tx.Create(newStatusHistory(appointment.ID, &from, transition)).Error
+}
+
+// GetStatusHistory returns an appointment's status changes, oldest first
+func (s *AppointmentService) GetStatusHistory(appointmentID int) ([]models.AppointmentStatusHistory, error) {
+	var history []models.AppointmentStatusHistory
+	err := s.DB.Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC, id ASC").
+		Find(&history).Error
+	return history, err
+}
+
+func newStatusHistory(appointmentID int, from *models.AppointmentStatus, transition AppointmentTransition) *models.AppointmentStatusHistory {
+	entry := &models.AppointmentStatusHistory{
+		AppointmentID: appointmentID,
+		FromStatus:    from,
+		ToStatus:      transition.To,
+		Reason:        transition.Reason,
+	}
+	if !transition.isAutomatic() {
+		entry.ChangedBy = &transition.ActorID
+		entry.ChangedByRole = &transition.ActorRole
+	}
+	return entry
+}
+
+// runTransitionHooks creates or revokes the meeting link, offers a freed slot to the
+// lawyer's waitlist and sends the emails and in-app notifications for a status
+// change. Failures are logged; the transition
+// itself has already been committed.
+func (s *AppointmentService) runTransitionHooks(appointment *models.Appointment, from models.AppointmentStatus, transition AppointmentTransition) {
+	NewMeetingService().SyncMeetingForStatus(appointment)
+	s.notifyStatusChange(appointment, transition)
+	if transition.To == models.AppointmentStatusCancelled || transition.To == models.AppointmentStatusRejected {
+		(&WaitlistService{DB: s.DB}).offerLogged(appointment.LawyerID)
+	}
+
+	// Scheduled jobs only leave in-app notifications
+	if transition.isAutomatic() {
+		return
+	}
+
+	var err error
+	switch {
+	case transition.ActorRole == string(models.RoleLawyer):
+		err = s.SendLawyerAppointmentStatusUpdateEmail(appointment, transition.To)
+	case transition.To == models.AppointmentStatusCancelled:
+		err = s.SendAppointmentCancelledEmail(appointment)
+	default:
+		err = s.SendAppointmentStatusUpdateEmails(appointment, transition.To)
+	}
+	if err != nil {
+		fmt.Printf("Failed to send status emails for appointment %d (%s -> %s): %v\n", appointment.ID, from, transition.To, err)
+	}
+}
+
+// notifyStatusChange leaves an in-app notification for every party other than the
+// actor, with the appointment time in each recipient's time zone
+func (s *AppointmentService) notifyStatusChange(appointment *models.Appointment, transition AppointmentTransition) {
+	var lawyer models.Lawyer
+	if err := s.DB.Select("id", "user_id", "timezone").First(&lawyer, appointment.LawyerID).Error; err != nil {
+		fmt.Printf("Failed to load lawyer for appointment %d notification: %v\n", appointment.ID, err)
+		return
+	}
+	var client models.User
+	if err := s.DB.Select("id", "timezone").First(&client, appointment.UserID).Error; err != nil {
+		fmt.Printf("Failed to load client for appointment %d notification: %v\n", appointment.ID, err)
+		return
+	}
+
+	recipients := map[int]*time.Location{
+		appointment.UserID: client.Location(),
+		lawyer.UserID:      lawyer.Location(),
+	}
+
+	notificationService := NewNotificationService()
+	for userID, loc := range recipients {
+		if userID == transition.ActorID {
+			continue
+		}
+		appointmentDate, appointmentTime := appointmentDisplayTime(appointment.StartTime, loc)
+		notification := &models.Notification{
+			UserID: userID,
+			Type:   NotificationTypeAppointmentStatus,
+			Content: fmt.Sprintf("%s %sの予約のステータスが「%s」に変更されました",
+				appointmentDate, appointmentTime, appointmentStatusNamesJa[transition.To]),
+		}
+		if err := notificationService.CreateNotification(notification); err != nil {
+			fmt.Printf("Failed to create status notification for user %d: %v\n", userID, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Attendance outcomes reported after an appointment
+const (
+	AttendanceAttended     = "attended"
+	AttendanceNoShowClient = "no_show_client"
+	AttendanceNoShowLawyer = "no_show_lawyer"
+)
+
+// ErrInvalidAttendance is wrapped with the reason an attendance report was refused
+var ErrInvalidAttendance = errors.New("invalid attendance")
+
+// attendanceStatuses maps each attendance outcome to the status it records
+var attendanceStatuses = map[string]models.AppointmentStatus{
+	AttendanceAttended:     models.AppointmentStatusCompleted,
+	AttendanceNoShowClient: models.AppointmentStatusNoShowClient,
+	AttendanceNoShowLawyer: models.AppointmentStatusNoShowLawyer,
+}
+
+// AttendanceService handles attendance confirmation and no-show tracking
+type AttendanceService struct {
+	DB *gorm.DB
+}
+
+// NewAttendanceService creates a new attendance service
+func NewAttendanceService() *AttendanceService {
+	return &AttendanceService{
+		DB: repository.DB,
+	}
+}
+
+// ConfirmAttendance records whether a confirmed appointment took place once it has
+// ended. Lawyers and staff report any outcome; clients can only report that the
+// lawyer did not attend.
+func (s *AttendanceService) ConfirmAttendance(appointment *models.Appointment, outcome string, note *string, actorID int, actorRole string) (*models.Appointment, error) {
+	status, ok := attendanceStatuses[outcome]
+	if !ok {
+		return nil, fmt.Errorf("%w: outcome must be attended, no_show_client or no_show_lawyer", ErrInvalidAttendance)
+	}
+	if actorRole == string(models.RoleClient) && status != models.AppointmentStatusNoShowLawyer {
+		return nil, fmt.Errorf("%w: clients can only report that the lawyer did not attend", ErrInvalidAttendance)
+	}
+
+	return (&AppointmentService{DB: s.DB}).TransitionStatus(appointment.ID, AppointmentTransition{
+		To:        status,
+		ActorID:   actorID,
+		ActorRole: actorRole,
+		Reason:    note,
+	})
+}
+
+// recordNoShow counts a no-show against the party who missed the appointment
+func recordNoShow(tx *gorm.DB, appointment *models.Appointment, status models.AppointmentStatus) error {
+	userID := appointment.UserID
+	if status == models.AppointmentStatusNoShowLawyer {
+		var lawyer models.Lawyer
+		if err := tx.Select("id", "user_id").First(&lawyer, appointment.LawyerID).Error; err != nil {
+			return err
+		}
+		userID = lawyer.UserID
+	}
+
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"no_show_count": gorm.Expr("no_show_count + 1"),
+		// No-shows may be reported out of order
+		"last_no_show_at": gorm.Expr("GREATEST(COALESCE(last_no_show_at, ?), ?)", appointment.EndTime, appointment.EndTime),
+	}).Error
+}
+
+// CheckBookingAllowed refuses new bookings from a client who has reached the
+// no-show limit, until the restriction period after their last no-show has passed
+func (s *AttendanceService) CheckBookingAllowed(userID int) error {
+	settings, err := (&PlatformSettingService{DB: s.DB}).GetAttendanceSettings()
+	if err != nil {
+		return err
+	}
+	if settings.NoShowLimit == 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := s.DB.Select("id", "no_show_count", "last_no_show_at", "timezone").First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.NoShowCount < settings.NoShowLimit {
+		return nil
+	}
+
+	if settings.RestrictionDays == 0 || user.LastNoShowAt == nil {
+		return &BookingPolicyError{Reason: "too many missed appointments, please contact support"}
+	}
+	reopensAt := user.LastNoShowAt.AddDate(0, 0, settings.RestrictionDays)
+	if !time.Now().Before(reopensAt) {
+		return nil
+	}
+	return &BookingPolicyError{Reason: "too many missed appointments, booking reopens on " +
+		reopensAt.In(user.Location()).Format("2006-01-02")}
+}
+
+// ResetNoShows clears a user's no-show counter, lifting any booking restriction
+func (s *AttendanceService) ResetNoShows(userID int) error {
+	result := s.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"no_show_count":   0,
+		"last_no_show_at": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
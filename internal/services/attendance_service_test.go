@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// useAttendanceSettings stores attendance settings for the test and puts the
+// previous ones back when it ends
+func useAttendanceSettings(t *testing.T, db *gorm.DB, settings AttendanceSettings) {
+	t.Helper()
+
+	service := &PlatformSettingService{DB: db}
+	admin := createTestUser(t, db, models.RoleAdmin)
+	keys := []string{models.SettingAttendanceGraceHours, models.SettingNoShowLimit, models.SettingNoShowRestrictionDays}
+	previous := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := service.Get(key)
+		if err != nil {
+			t.Fatalf("reading setting %s: %v", key, err)
+		}
+		previous[key] = value
+	}
+	if err := service.SetAttendanceSettings(settings, admin.ID); err != nil {
+		t.Fatalf("SetAttendanceSettings: %v", err)
+	}
+	t.Cleanup(func() {
+		for key, value := range previous {
+			if err := service.Set(key, value, admin.ID); err != nil {
+				t.Errorf("restoring setting %s: %v", key, err)
+			}
+		}
+	})
+}
+
+func loadNoShows(t *testing.T, db *gorm.DB, userID int) models.User {
+	t.Helper()
+
+	var user models.User
+	if err := db.Select("id", "no_show_count", "last_no_show_at").First(&user, userID).Error; err != nil {
+		t.Fatalf("loading user: %v", err)
+	}
+	return user
+}
+
+func TestRecordNoShow(t *testing.T) {
+	db := openTestDB(t)
+	useRepositoryDB(t, db)
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	service := &AttendanceService{DB: db}
+
+	ended := time.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	missed := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusConfirmed, ended, time.Hour)
+	if _, err := service.ConfirmAttendance(missed, AttendanceNoShowClient, nil, lawyer.UserID, string(models.RoleLawyer)); err != nil {
+		t.Fatalf("ConfirmAttendance: %v", err)
+	}
+	got := loadNoShows(t, db, client.ID)
+	if got.NoShowCount != 1 || got.LastNoShowAt == nil || !got.LastNoShowAt.Equal(missed.EndTime) {
+		t.Errorf("client no-shows = %d, last %v, want 1 at %v", got.NoShowCount, got.LastNoShowAt, missed.EndTime)
+	}
+
+	// A no-show reported late for an earlier appointment keeps the latest date
+	earlier := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusConfirmed, ended.Add(-7*24*time.Hour), time.Hour)
+	if _, err := service.ConfirmAttendance(earlier, AttendanceNoShowClient, nil, lawyer.UserID, string(models.RoleLawyer)); err != nil {
+		t.Fatalf("ConfirmAttendance: %v", err)
+	}
+	got = loadNoShows(t, db, client.ID)
+	if got.NoShowCount != 2 || got.LastNoShowAt == nil || !got.LastNoShowAt.Equal(missed.EndTime) {
+		t.Errorf("client no-shows = %d, last %v, want 2 at %v", got.NoShowCount, got.LastNoShowAt, missed.EndTime)
+	}
+
+	// The client reports the lawyer, which counts against the lawyer's user
+	absent := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusConfirmed, ended.Add(-24*time.Hour), time.Hour)
+	if _, err := service.ConfirmAttendance(absent, AttendanceNoShowLawyer, nil, client.ID, string(models.RoleClient)); err != nil {
+		t.Fatalf("ConfirmAttendance: %v", err)
+	}
+	if got := loadNoShows(t, db, lawyer.UserID); got.NoShowCount != 1 {
+		t.Errorf("lawyer no-shows = %d, want 1", got.NoShowCount)
+	}
+	if got := loadNoShows(t, db, client.ID); got.NoShowCount != 2 {
+		t.Errorf("client no-shows = %d after the lawyer's no-show, want 2", got.NoShowCount)
+	}
+
+	// Attendance counts nothing
+	attended := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusConfirmed, ended.Add(-48*time.Hour), time.Hour)
+	if _, err := service.ConfirmAttendance(attended, AttendanceAttended, nil, lawyer.UserID, string(models.RoleLawyer)); err != nil {
+		t.Fatalf("ConfirmAttendance: %v", err)
+	}
+	if got := loadNoShows(t, db, client.ID); got.NoShowCount != 2 {
+		t.Errorf("client no-shows = %d after attending, want 2", got.NoShowCount)
+	}
+}
+
+func TestTransitionStatusRefusesAttendanceBeforeEnd(t *testing.T) {
+	db := openTestDB(t)
+	service := &AppointmentService{DB: db}
+	appointment := createTestAppointment(t, db)
+
+	for _, status := range []models.AppointmentStatus{
+		models.AppointmentStatusCompleted,
+		models.AppointmentStatusNoShowClient,
+		models.AppointmentStatusNoShowLawyer,
+	} {
+		_, err := service.TransitionStatus(appointment.ID, AppointmentTransition{To: status, ActorID: 1, ActorRole: string(models.RoleLawyer)})
+		if !errors.Is(err, ErrInvalidAttendance) {
+			t.Errorf("TransitionStatus(%s) before the end error = %v, want ErrInvalidAttendance", status, err)
+		}
+	}
+
+	if got := loadNoShows(t, db, appointment.UserID); got.NoShowCount != 0 {
+		t.Errorf("no-show count = %d, want 0", got.NoShowCount)
+	}
+}
+
+func TestCheckBookingAllowed(t *testing.T) {
+	db := openTestDB(t)
+	service := &AttendanceService{DB: db}
+	client := createTestUser(t, db, models.RoleClient)
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		settings    AttendanceSettings
+		count       int
+		lastNoShow  time.Time
+		wantAllowed bool
+	}{
+		{name: "restriction off", settings: AttendanceSettings{GraceHours: 24, NoShowLimit: 0, RestrictionDays: 30}, count: 5, lastNoShow: now.Add(-time.Hour), wantAllowed: true},
+		{name: "under the limit", settings: AttendanceSettings{GraceHours: 24, NoShowLimit: 2, RestrictionDays: 30}, count: 1, lastNoShow: now.Add(-time.Hour), wantAllowed: true},
+		{name: "at the limit", settings: AttendanceSettings{GraceHours: 24, NoShowLimit: 2, RestrictionDays: 30}, count: 2, lastNoShow: now.AddDate(0, 0, -10), wantAllowed: false},
+		{name: "restriction over", settings: AttendanceSettings{GraceHours: 24, NoShowLimit: 2, RestrictionDays: 30}, count: 2, lastNoShow: now.AddDate(0, 0, -31), wantAllowed: true},
+		{name: "restricted until reset", settings: AttendanceSettings{GraceHours: 24, NoShowLimit: 2, RestrictionDays: 0}, count: 3, lastNoShow: now.AddDate(0, -6, 0), wantAllowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useAttendanceSettings(t, db, tt.settings)
+			if err := db.Model(client).Updates(map[string]interface{}{
+				"no_show_count":   tt.count,
+				"last_no_show_at": tt.lastNoShow,
+			}).Error; err != nil {
+				t.Fatalf("updating client: %v", err)
+			}
+
+			err := service.CheckBookingAllowed(client.ID)
+			var policyErr *BookingPolicyError
+			switch {
+			case tt.wantAllowed && err != nil:
+				t.Errorf("CheckBookingAllowed: %v, want the booking allowed", err)
+			case !tt.wantAllowed && !errors.As(err, &policyErr):
+				t.Errorf("CheckBookingAllowed error = %v, want *BookingPolicyError", err)
+			}
+		})
+	}
+
+	// Resetting the counter lifts the restriction
+	useAttendanceSettings(t, db, AttendanceSettings{GraceHours: 24, NoShowLimit: 2, RestrictionDays: 0})
+	if err := service.ResetNoShows(client.ID); err != nil {
+		t.Fatalf("ResetNoShows: %v", err)
+	}
+	if err := service.CheckBookingAllowed(client.ID); err != nil {
+		t.Errorf("CheckBookingAllowed after a reset: %v", err)
+	}
+}
+
+func TestAutoCompleteConfirmedAppointmentsGraceCutoff(t *testing.T) {
+	db := openTestDB(t)
+	useRepositoryDB(t, db)
+	useAttendanceSettings(t, db, AttendanceSettings{GraceHours: 24, NoShowLimit: 0, RestrictionDays: 90})
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+
+	now := time.Now()
+	pastGrace := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusConfirmed, now.Add(-26*time.Hour), time.Hour)
+	withinGrace := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusConfirmed, now.Add(-24*time.Hour), time.Hour)
+	pending := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusPending, now.Add(-30*time.Hour), time.Hour)
+
+	if err := (&AppointmentService{DB: db}).AutoCompleteConfirmedAppointments(); err != nil {
+		t.Fatalf("AutoCompleteConfirmedAppointments: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		appointment *models.Appointment
+		want        models.AppointmentStatus
+	}{
+		{name: "ended before the grace period", appointment: pastGrace, want: models.AppointmentStatusCompleted},
+		{name: "within the grace period", appointment: withinGrace, want: models.AppointmentStatusConfirmed},
+		{name: "never confirmed", appointment: pending, want: models.AppointmentStatusPending},
+	} {
+		var stored models.Appointment
+		if err := db.Select("id", "status").First(&stored, tt.appointment.ID).Error; err != nil {
+			t.Fatalf("loading appointment: %v", err)
+		}
+		if stored.Status != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, stored.Status, tt.want)
+		}
+	}
+	if got := loadNoShows(t, db, client.ID); got.NoShowCount != 0 {
+		t.Errorf("automatic completion counted %d no-shows", got.NoShowCount)
+	}
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// auditGenesisHash is the previous hash of the first entry in the chain
+var auditGenesisHash = strings.Repeat("0", 64)
+
+// errAuditChainBroken stops VerifyChain at the first mismatch
+var errAuditChainBroken = errors.New("audit chain broken")
+
+// auditLockKey serializes writers so each entry links to the one before it
+const auditLockKey = 31031
+
+// auditIgnoredFields are bookkeeping columns that change on every write
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+// AuditContext identifies who made a change and from where
+type AuditContext struct {
+	ActorID        int
+	ActorRole      string
+	ImpersonatorID int
+	IPAddress      string
+	UserAgent      string
+	RequestID      string
+}
+
+// AuditEvent describes a change to one entity. Before and After are snapshots of the
+// entity (structs or maps); either may be nil for creations and deletions.
+type AuditEvent struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+}
+
+// AuditLogFilter narrows the audit log list and export
+type AuditLogFilter struct {
+	ActorID    int
+	Action     string
+	EntityType string
+	EntityID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditChainStatus is the result of checking the audit hash chain
+type AuditChainStatus struct {
+	Checked  int    `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+}
+
+// AuditService records and queries the system-wide audit trail
+type AuditService struct {
+	DB *gorm.DB
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService() *AuditService {
+	return &AuditService{
+		DB: repository.DB,
+	}
+}
+
+// AuditID formats a numeric entity ID for an audit event
+func AuditID(id int) string {
+	return strconv.Itoa(id)
+}
+
+// AuditSnapshot captures an entity's current JSON representation. Take it before
+// mutating an entity in place so the change can be diffed afterwards.
+func AuditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+// Record appends an entry to the audit trail. Failures are logged rather than returned
+// so auditing never breaks the operation being audited.
+func (s *AuditService) Record(ctx AuditContext, event AuditEvent) {
+	changes := auditDiff(AuditSnapshot(event.Before), AuditSnapshot(event.After))
+	if event.Action == models.AuditActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	entry := &models.AuditLog{
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		Changes:    changes,
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if ctx.ActorID != 0 {
+		entry.ActorID = &ctx.ActorID
+	}
+	if ctx.ActorRole != "" {
+		entry.ActorRole = &ctx.ActorRole
+	}
+	if ctx.ImpersonatorID != 0 {
+		entry.ImpersonatorID = &ctx.ImpersonatorID
+	}
+	if event.EntityID != "" {
+		entry.EntityID = &event.EntityID
+	}
+	if ctx.IPAddress != "" {
+		entry.IPAddress = &ctx.IPAddress
+	}
+	if ctx.UserAgent != "" {
+		entry.UserAgent = &ctx.UserAgent
+	}
+	if ctx.RequestID != "" {
+		entry.RequestID = &ctx.RequestID
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLockKey).Error; err != nil {
+			return err
+		}
+
+		var last models.AuditLog
+		result := tx.Select("hash").Order("id DESC").Limit(1).Find(&last)
+		if result.Error != nil {
+			return result.Error
+		}
+		entry.PrevHash = auditGenesisHash
+		if result.RowsAffected > 0 {
+			entry.PrevHash = last.Hash
+		}
+		entry.Hash = auditHash(entry)
+
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		fmt.Printf("Failed to record audit entry %s %s/%s: %v\n", event.Action, event.EntityType, event.EntityID, err)
+	}
+}
+
+// GetAuditLogs returns entries matching the filter, newest first
+func (s *AuditService) GetAuditLogs(filter AuditLogFilter, page, limit int) ([]models.AuditLog, int64, error) {
+	var logs []models.AuditLog
+	var total int64
+
+	query := s.filteredQuery(filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// ExportAuditLogs streams entries with an ID greater than afterID in insertion order.
+// Entries are never changed, so callers can resume from the last exported ID and append
+// to a previous export.
+func (s *AuditService) ExportAuditLogs(filter AuditLogFilter, afterID int64, fn func(models.AuditLog) error) error {
+	const batchSize = 500
+
+	cursor := afterID
+	for {
+		var batch []models.AuditLog
+		err := s.filteredQuery(filter).
+			Where("id > ?", cursor).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&batch).Error
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range batch {
+			if err := fn(entry); err != nil {
+				return err
+			}
+			cursor = entry.ID
+		}
+
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
+
+// VerifyChain recomputes every entry's hash and checks that it links to the entry before
+// it. BrokenAt is the first entry that does not match.
+func (s *AuditService) VerifyChain() (*AuditChainStatus, error) {
+	status := &AuditChainStatus{Valid: true}
+	prevHash := auditGenesisHash
+
+	err := s.ExportAuditLogs(AuditLogFilter{}, 0, func(entry models.AuditLog) error {
+		status.Checked++
+		if entry.PrevHash != prevHash || auditHash(&entry) != entry.Hash {
+			id := entry.ID
+			status.Valid = false
+			status.BrokenAt = &id
+			return errAuditChainBroken
+		}
+		prevHash = entry.Hash
+		return nil
+	})
+	if err != nil && err != errAuditChainBroken {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (s *AuditService) filteredQuery(filter AuditLogFilter) *gorm.DB {
+	query := s.DB.Model(&models.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// auditDiff lists the top-level fields that differ between two snapshots. Nested
+// objects such as preloaded relations are skipped; they are audited on their own.
+func auditDiff(before, after map[string]interface{}) models.FieldChanges {
+	keys := make([]string, 0, len(before)+len(after))
+	seen := make(map[string]bool)
+	for k := range before {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for k := range after {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := models.FieldChanges{}
+	for _, k := range keys {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		oldValue, newValue := before[k], after[k]
+		if isNestedObject(oldValue) || isNestedObject(newValue) {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, models.FieldChange{Field: k, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+func isNestedObject(v interface{}) bool {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		for _, item := range value {
+			if _, ok := item.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// auditHash covers every recorded field and the previous hash
+func auditHash(entry *models.AuditLog) string {
+	payload, _ := json.Marshal(struct {
+		PrevHash       string              `json:"prev_hash"`
+		ActorID        *int                `json:"actor_id"`
+		ActorRole      *string             `json:"actor_role"`
+		ImpersonatorID *int                `json:"impersonator_id"`
+		Action         string              `json:"action"`
+		EntityType     string              `json:"entity_type"`
+		EntityID       *string             `json:"entity_id"`
+		Changes        models.FieldChanges `json:"changes"`
+		IPAddress      *string             `json:"ip_address"`
+		UserAgent      *string             `json:"user_agent"`
+		RequestID      *string             `json:"request_id"`
+		CreatedAt      string              `json:"created_at"`
+	}{
+		PrevHash:       entry.PrevHash,
+		ActorID:        entry.ActorID,
+		ActorRole:      entry.ActorRole,
+		ImpersonatorID: entry.ImpersonatorID,
+		Action:         entry.Action,
+		EntityType:     entry.EntityType,
+		EntityID:       entry.EntityID,
+		Changes:        entry.Changes,
+		IPAddress:      entry.IPAddress,
+		UserAgent:      entry.UserAgent,
+		RequestID:      entry.RequestID,
+		CreatedAt:      entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
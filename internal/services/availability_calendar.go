@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxCalendarDays is the longest date range the availability calendar returns at once
+const MaxCalendarDays = 62
+
+// ErrInvalidCalendarRange is wrapped with the reason a calendar range was refused
+var ErrInvalidCalendarRange = errors.New("invalid range")
+
+// CalendarDay lists the slots of one date in the availability calendar
+type CalendarDay struct {
+	Date      models.Date `json:"date"`
+	Available bool        `json:"available"`
+	Slots     []TimeSlot  `json:"slots"`
+}
+
+// NextAvailableSlot is the earliest bookable slot
+type NextAvailableSlot struct {
+	Date    models.Date `json:"date"`
+	Time    string      `json:"time"`
+	End     string      `json:"end"`
+	StartAt time.Time   `json:"start_at"`
+	EndAt   time.Time   `json:"end_at"`
+}
+
+// AvailabilityCalendar is a lawyer's slots over a range of dates
+type AvailabilityCalendar struct {
+	LawyerID      int                `json:"lawyer_id"`
+	From          models.Date        `json:"from"`
+	To            models.Date        `json:"to"`
+	Timezone      string             `json:"timezone"`
+	SlotMinutes   int                `json:"slot_minutes"`
+	Days          []CalendarDay      `json:"days"`
+	NextAvailable *NextAvailableSlot `json:"next_available"`
+}
+
+// GetAvailabilityCalendar lists a lawyer's slots for every date from from to to,
+// inclusive, in the lawyer's time zone. A zero from means today there and a zero to
+// means 30 days after from. The lawyer is loaded once and closures, exceptions and
+// appointments are fetched once for the whole range. NextAvailable is the first
+// bookable slot on or after from, looking past to up to the lawyer's booking
+// horizon, or nil if there is none.
+func (s *AvailabilityService) GetAvailabilityCalendar(lawyerID int, from, to models.Date) (*AvailabilityCalendar, error) {
+	var lawyer models.Lawyer
+	if err := s.DB.First(&lawyer, lawyerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLawyerNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch lawyer: %w", err)
+	}
+	loc := lawyer.Location()
+
+	if from.IsZero() {
+		from = models.NewDate(time.Now().In(loc))
+	}
+	if to.IsZero() {
+		to = models.NewDate(from.AddDate(0, 0, 30))
+	}
+	if to.Before(from.Time) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidCalendarRange)
+	}
+	if int(to.Sub(from.Time).Hours()/24)+1 > MaxCalendarDays {
+		return nil, fmt.Errorf("%w: at most %d days can be requested", ErrInvalidCalendarRange, MaxCalendarDays)
+	}
+
+	days, err := s.loadBookingRange(&lawyer, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	calendar := &AvailabilityCalendar{
+		LawyerID:    lawyer.ID,
+		From:        from,
+		To:          to,
+		Timezone:    loc.String(),
+		SlotMinutes: lawyer.EffectiveBookingSettings().SlotMinutes,
+		Days:        make([]CalendarDay, 0, len(days)),
+	}
+	for _, day := range days {
+		entry := CalendarDay{Date: models.NewDate(day.dayStart), Slots: day.timeSlots(now)}
+		for _, slot := range entry.Slots {
+			if slot.Available {
+				entry.Available = true
+				if calendar.NextAvailable == nil {
+					calendar.NextAvailable = newNextAvailableSlot(entry.Date, slot)
+				}
+				break
+			}
+		}
+		calendar.Days = append(calendar.Days, entry)
+	}
+
+	if calendar.NextAvailable == nil {
+		next, err := s.nextAvailableAfter(&lawyer, to, now)
+		if err != nil {
+			return nil, err
+		}
+		calendar.NextAvailable = next
+	}
+	return calendar, nil
+}
+
+// nextAvailableAfter finds the first bookable slot after a date, up to the lawyer's
+// booking horizon
+func (s *AvailabilityService) nextAvailableAfter(lawyer *models.Lawyer, after models.Date, now time.Time) (*NextAvailableSlot, error) {
+	settings := lawyer.EffectiveBookingSettings()
+	from := models.NewDate(after.AddDate(0, 0, 1))
+	horizon := models.NewDate(now.In(lawyer.Location()).AddDate(0, 0, settings.MaxAdvanceDays))
+	if from.After(horizon.Time) {
+		return nil, nil
+	}
+
+	days, err := s.loadBookingRange(lawyer, from, horizon, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range days {
+		for _, slot := range day.timeSlots(now) {
+			if slot.Available {
+				return newNextAvailableSlot(models.NewDate(day.dayStart), slot), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func newNextAvailableSlot(date models.Date, slot TimeSlot) *NextAvailableSlot {
+	return &NextAvailableSlot{
+		Date:    date,
+		Time:    slot.Time,
+		End:     slot.End,
+		StartAt: slot.StartAt,
+		EndAt:   slot.EndAt,
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidAvailability and ErrInvalidBookingSettings are wrapped with the
+	// validation error
+	ErrInvalidAvailability           = errors.New("invalid availability")
+	ErrInvalidBookingSettings        = errors.New("invalid booking settings")
+	ErrAvailabilityExceptionNotFound = errors.New("exception not found")
+	ErrAvailabilityExceptionExists   = errors.New("exception already exists for this date")
+)
+
+// WorkPeriod is a span of time a lawyer accepts appointments on a given date
+type WorkPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// AvailabilityService manages lawyers' weekly schedules and date exceptions
+type AvailabilityService struct {
+	DB *gorm.DB
+}
+
+// NewAvailabilityService creates a new availability service
+func NewAvailabilityService() *AvailabilityService {
+	return &AvailabilityService{
+		DB: repository.DB,
+	}
+}
+
+// UpdateWeeklySchedule validates and replaces a lawyer's weekly schedule
+func (s *AvailabilityService) UpdateWeeklySchedule(lawyerID int, schedule models.Availability) error {
+	if err := schedule.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAvailability, err)
+	}
+
+	result := s.DB.Model(&models.Lawyer{}).Where("id = ?", lawyerID).Update("availability", schedule)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLawyerNotFound
+	}
+	s.offerToWaitlist(lawyerID)
+	return nil
+}
+
+// UpdateBookingSettings validates and replaces a lawyer's booking settings
+func (s *AvailabilityService) UpdateBookingSettings(lawyerID int, settings models.BookingSettings) error {
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBookingSettings, err)
+	}
+
+	result := s.DB.Model(&models.Lawyer{}).Where("id = ?", lawyerID).Update("booking_settings", settings)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLawyerNotFound
+	}
+	s.offerToWaitlist(lawyerID)
+	return nil
+}
+
+// GetExceptions lists a lawyer's exceptions between two dates, inclusive
+func (s *AvailabilityService) GetExceptions(lawyerID int, from, to models.Date) ([]models.AvailabilityException, error) {
+	var exceptions []models.AvailabilityException
+	err := s.DB.Where("lawyer_id = ? AND date >= ? AND date <= ?", lawyerID, from, to).
+		Order("date ASC").
+		Find(&exceptions).Error
+	return exceptions, err
+}
+
+// GetException returns one of a lawyer's exceptions
+func (s *AvailabilityService) GetException(lawyerID, exceptionID int) (*models.AvailabilityException, error) {
+	var exception models.AvailabilityException
+	if err := s.DB.Where("id = ? AND lawyer_id = ?", exceptionID, lawyerID).First(&exception).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAvailabilityExceptionNotFound
+		}
+		return nil, err
+	}
+	return &exception, nil
+}
+
+// CreateException adds an exception for a date that has none yet
+func (s *AvailabilityService) CreateException(exception *models.AvailabilityException) error {
+	if err := exception.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAvailability, err)
+	}
+	if exception.Intervals == nil {
+		exception.Intervals = models.TimeIntervals{}
+	}
+
+	err := s.DB.Create(exception).Error
+	if pgErrorCode(err) == pgUniqueViolation {
+		return ErrAvailabilityExceptionExists
+	}
+	if err != nil {
+		return err
+	}
+	s.offerToWaitlist(exception.LawyerID)
+	return nil
+}
+
+// UpdateException replaces the date, hours and reason of an existing exception
+func (s *AvailabilityService) UpdateException(exception *models.AvailabilityException) error {
+	if err := exception.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAvailability, err)
+	}
+	if exception.Intervals == nil {
+		exception.Intervals = models.TimeIntervals{}
+	}
+
+	err := s.DB.Model(exception).Updates(map[string]interface{}{
+		"date":      exception.Date,
+		"closed":    exception.Closed,
+		"intervals": exception.Intervals,
+		"reason":    exception.Reason,
+	}).Error
+	if pgErrorCode(err) == pgUniqueViolation {
+		return ErrAvailabilityExceptionExists
+	}
+	if err != nil {
+		return err
+	}
+	s.offerToWaitlist(exception.LawyerID)
+	return nil
+}
+
+// DeleteException removes one of a lawyer's exceptions
+func (s *AvailabilityService) DeleteException(lawyerID, exceptionID int) error {
+	result := s.DB.Where("id = ? AND lawyer_id = ?", exceptionID, lawyerID).Delete(&models.AvailabilityException{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAvailabilityExceptionNotFound
+	}
+	s.offerToWaitlist(lawyerID)
+	return nil
+}
+
+// offerToWaitlist offers slots opened by a change to a lawyer's availability to
+// their waitlist
+func (s *AvailabilityService) offerToWaitlist(lawyerID int) {
+	(&WaitlistService{DB: s.DB}).offerLogged(lawyerID)
+}
+
+// workPeriodsOn returns the periods a lawyer works on a date in loc. A platform
+// closure shuts every lawyer. Otherwise an exception for the date replaces the
+// weekly schedule, and lawyers closed on national holidays do not work on one unless
+// an exception says so. Malformed stored intervals are skipped.
+func workPeriodsOn(lawyer *models.Lawyer, day models.Date, loc *time.Location, platformClosed bool, exception *models.AvailabilityException) []WorkPeriod {
+	var intervals models.TimeIntervals
+	switch {
+	case platformClosed:
+		return nil
+	case exception != nil && exception.Closed:
+		return nil
+	case exception != nil:
+		intervals = exception.Intervals
+	case lawyer.ClosedOnHolidays && JapaneseHolidayName(day) != "":
+		return nil
+	case lawyer.Availability != nil:
+		intervals = lawyer.Availability.ForWeekday(day.Weekday())
+	}
+
+	periods := make([]WorkPeriod, 0, len(intervals))
+	for _, interval := range intervals {
+		start, end, err := interval.Minutes()
+		if err != nil {
+			continue
+		}
+		periods = append(periods, WorkPeriod{
+			Start: time.Date(day.Year(), day.Month(), day.Day(), 0, start, 0, 0, loc),
+			End:   time.Date(day.Year(), day.Month(), day.Day(), 0, end, 0, 0, loc),
+		})
+	}
+	return periods
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func TestWorkPeriodsAcrossDST(t *testing.T) {
+	allDay := models.TimeIntervals{{Start: "00:00", End: "24:00"}}
+	lawyer := &models.Lawyer{
+		Timezone:     "America/New_York",
+		Availability: &models.Availability{Sunday: allDay},
+	}
+	loc := lawyer.Location()
+
+	tests := []struct {
+		date  string
+		slots int
+		last  string
+	}{
+		{"2026-03-08", 23, "23:00"}, // 02:00 does not exist
+		{"2026-11-01", 25, "23:00"}, // 01:00 happens twice
+		{"2026-06-14", 24, "23:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			day, err := models.ParseDate(tt.date)
+			if err != nil {
+				t.Fatalf("ParseDate: %v", err)
+			}
+			periods := workPeriodsOn(lawyer, day, loc, false, nil)
+			starts := layoutSlotStarts(periods, 60)
+			if len(starts) != tt.slots {
+				t.Fatalf("got %d hourly slots, want %d", len(starts), tt.slots)
+			}
+			for i := 1; i < len(starts); i++ {
+				if gap := starts[i].Sub(starts[i-1]); gap != time.Hour {
+					t.Errorf("slots %d and %d are %s apart", i-1, i, gap)
+				}
+			}
+			if got := starts[len(starts)-1].In(loc).Format("15:04"); got != tt.last {
+				t.Errorf("last slot starts at %s, want %s", got, tt.last)
+			}
+		})
+	}
+}
+
+func TestWorkPeriodsUseLawyerZone(t *testing.T) {
+	hours := models.TimeIntervals{{Start: "09:00", End: "17:00"}}
+	lawyer := &models.Lawyer{
+		Timezone:     "Asia/Tokyo",
+		Availability: &models.Availability{Monday: hours},
+	}
+	day, _ := models.ParseDate("2026-11-02")
+
+	periods := workPeriodsOn(lawyer, day, lawyer.Location(), false, nil)
+	if len(periods) != 1 {
+		t.Fatalf("got %d periods, want 1", len(periods))
+	}
+	// 09:00 in Tokyo is midnight UTC
+	if want := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC); !periods[0].Start.Equal(want) {
+		t.Errorf("period starts at %s, want %s", periods[0].Start.UTC(), want)
+	}
+}
+
+func TestAvailabilityValidationErrors(t *testing.T) {
+	// Validation fails before the database is touched
+	service := &AvailabilityService{}
+
+	schedule := models.Availability{Monday: models.TimeIntervals{{Start: "17:00", End: "09:00"}}}
+	err := service.UpdateWeeklySchedule(1, schedule)
+	if !errors.Is(err, ErrInvalidAvailability) || !strings.HasPrefix(err.Error(), "invalid availability: monday: ") {
+		t.Errorf("UpdateWeeklySchedule error = %v, want ErrInvalidAvailability with the day's reason", err)
+	}
+
+	err = service.UpdateBookingSettings(1, models.BookingSettings{SlotMinutes: 7})
+	if !errors.Is(err, ErrInvalidBookingSettings) {
+		t.Errorf("UpdateBookingSettings error = %v, want ErrInvalidBookingSettings", err)
+	}
+
+	err = service.CreateException(&models.AvailabilityException{LawyerID: 1})
+	if !errors.Is(err, ErrInvalidAvailability) || err.Error() != "invalid availability: date is required" {
+		t.Errorf("CreateException error = %v, want ErrInvalidAvailability", err)
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// BookingPolicyError is returned when a booking breaks the lawyer's booking policy
+// or the client is barred from booking. Its message is meant for the client.
+type BookingPolicyError struct {
+	Reason string
+}
+
+func (e *BookingPolicyError) Error() string {
+	return "booking not allowed: " + e.Reason
+}
+
+// bookingDay holds everything the booking policy needs to judge slots for one
+// lawyer on one date, so slot listings can load it once and check every slot
+type bookingDay struct {
+	settings models.BookingSettings
+	dayStart time.Time
+	periods  []WorkPeriod
+	// starts is the sorted start of every slot in periods, laid out once when the
+	// day is loaded since every slot check needs it
+	starts       []time.Time
+	appointments []models.Appointment
+	// busy is time taken on the lawyer's external calendars
+	busy []models.BusyBlock
+	// holds are slots reserved for waitlisted clients. They block everyone but
+	// clientID, the client the check is made for.
+	holds    []models.SlotHold
+	clientID int
+}
+
+// loadBookingDay loads the lawyer's work periods and active appointments for a date
+// in the lawyer's time zone. excludeAppointmentID skips an appointment being moved.
+func (s *AvailabilityService) loadBookingDay(lawyer *models.Lawyer, day models.Date, excludeAppointmentID int) (*bookingDay, error) {
+	days, err := s.loadBookingRange(lawyer, day, day, excludeAppointmentID)
+	if err != nil {
+		return nil, err
+	}
+	return days[0], nil
+}
+
+// loadBookingRange loads one bookingDay per date from from to to, inclusive, with
+// dates taken in the lawyer's time zone. Platform closures, exceptions,
+// appointments and imported busy time are each fetched with a single query for the
+// whole range.
+func (s *AvailabilityService) loadBookingRange(lawyer *models.Lawyer, from, to models.Date, excludeAppointmentID int) ([]*bookingDay, error) {
+	settings := lawyer.EffectiveBookingSettings()
+	loc := lawyer.Location()
+
+	holidayService := &HolidayService{DB: s.DB}
+	closures, err := holidayService.GetPlatformClosures(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch platform closures: %w", err)
+	}
+	closed := make(map[models.Date]bool, len(closures))
+	for _, closure := range closures {
+		closed[models.NewDate(closure.Date.Time)] = true
+	}
+
+	exceptionList, err := s.GetExceptions(lawyer.ID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch availability exceptions: %w", err)
+	}
+	exceptions := make(map[models.Date]*models.AvailabilityException, len(exceptionList))
+	for i := range exceptionList {
+		exceptions[models.NewDate(exceptionList[i].Date.Time)] = &exceptionList[i]
+	}
+
+	// Neighbouring days are included so buffers across midnight are respected
+	margin := time.Duration(settings.BufferBeforeMinutes+settings.BufferAfterMinutes) * time.Minute
+	rangeStart := from.In(loc)
+	rangeEnd := to.In(loc).AddDate(0, 0, 1)
+	query := s.DB.Where(
+		"lawyer_id = ? AND status NOT IN ? AND start_time < ? AND end_time > ?",
+		lawyer.ID,
+		models.InactiveAppointmentStatuses(),
+		rangeEnd.Add(margin),
+		rangeStart.Add(-margin),
+	)
+	if excludeAppointmentID > 0 {
+		query = query.Where("id <> ?", excludeAppointmentID)
+	}
+	var appointments []models.Appointment
+	if err := query.Order("start_time ASC").Find(&appointments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch existing appointments: %w", err)
+	}
+
+	var busyBlocks []models.BusyBlock
+	if err := s.DB.Where(
+		"lawyer_id = ? AND start_time < ? AND end_time > ?",
+		lawyer.ID,
+		rangeEnd.Add(margin),
+		rangeStart.Add(-margin),
+	).Order("start_time ASC").Find(&busyBlocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch external calendar busy time: %w", err)
+	}
+
+	var holds []models.SlotHold
+	if err := s.DB.Where(
+		"lawyer_id = ? AND status = ? AND expires_at > ? AND start_time < ? AND end_time > ?",
+		lawyer.ID,
+		models.SlotHoldStatusHeld,
+		time.Now(),
+		rangeEnd.Add(margin),
+		rangeStart.Add(-margin),
+	).Order("start_time ASC").Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch waitlist holds: %w", err)
+	}
+
+	var days []*bookingDay
+	for date := from; !date.After(to.Time); date = models.NewDate(date.AddDate(0, 0, 1)) {
+		dayStart := date.In(loc)
+		windowStart := dayStart.Add(-margin)
+		windowEnd := dayStart.AddDate(0, 0, 1).Add(margin)
+
+		var dayAppointments []models.Appointment
+		for _, appt := range appointments {
+			if appt.StartTime.Before(windowEnd) && appt.EndTime.After(windowStart) {
+				dayAppointments = append(dayAppointments, appt)
+			}
+		}
+		var dayBusy []models.BusyBlock
+		for _, block := range busyBlocks {
+			if block.StartTime.Before(windowEnd) && block.EndTime.After(windowStart) {
+				dayBusy = append(dayBusy, block)
+			}
+		}
+		var dayHolds []models.SlotHold
+		for _, hold := range holds {
+			if hold.StartTime.Before(windowEnd) && hold.EndTime.After(windowStart) {
+				dayHolds = append(dayHolds, hold)
+			}
+		}
+
+		periods := workPeriodsOn(lawyer, date, loc, closed[date], exceptions[date])
+		days = append(days, &bookingDay{
+			settings:     settings,
+			dayStart:     dayStart,
+			periods:      periods,
+			starts:       layoutSlotStarts(periods, settings.SlotMinutes),
+			appointments: dayAppointments,
+			busy:         dayBusy,
+			holds:        dayHolds,
+		})
+	}
+	return days, nil
+}
+
+// slotStarts lists the start of every slot the lawyer offers on the day
+func (d *bookingDay) slotStarts() []time.Time {
+	return d.starts
+}
+
+// layoutSlotStarts lays slots out from the start of each work period at the
+// lawyer's slot length and returns their starts in order
+func layoutSlotStarts(periods []WorkPeriod, slotMinutes int) []time.Time {
+	length := time.Duration(slotMinutes) * time.Minute
+	var starts []time.Time
+	for _, period := range periods {
+		for start := period.Start; !start.Add(length).After(period.End); start = start.Add(length) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// timeSlots lists every slot the lawyer offers on the day with its availability
+func (d *bookingDay) timeSlots(now time.Time) []TimeSlot {
+	length := time.Duration(d.settings.SlotMinutes) * time.Minute
+	starts := d.slotStarts()
+	slots := make([]TimeSlot, 0, len(starts))
+	for _, slotStart := range starts {
+		slotEnd := slotStart.Add(length)
+		slots = append(slots, TimeSlot{
+			Time:      slotStart.Format("15:04"),
+			End:       slotEnd.Format("15:04"),
+			StartAt:   slotStart,
+			EndAt:     slotEnd,
+			Available: d.check(slotStart, slotEnd, now) == nil,
+		})
+	}
+	return slots
+}
+
+// check applies the booking policy to one slot. It is the single rule used both
+// when listing slots and when accepting a booking.
+func (d *bookingDay) check(start, end, now time.Time) error {
+	settings := d.settings
+
+	if end.Sub(start) != time.Duration(settings.SlotMinutes)*time.Minute {
+		return &BookingPolicyError{Reason: fmt.Sprintf("appointments with this lawyer last %d minutes", settings.SlotMinutes)}
+	}
+
+	i := sort.Search(len(d.starts), func(i int) bool { return !d.starts[i].Before(start) })
+	if i == len(d.starts) || !d.starts[i].Equal(start) {
+		return &BookingPolicyError{Reason: "outside the lawyer's available hours"}
+	}
+
+	if start.Before(now.Add(time.Duration(settings.MinNoticeHours) * time.Hour)) {
+		return &BookingPolicyError{Reason: fmt.Sprintf("appointments must be booked at least %d hours in advance", settings.MinNoticeHours)}
+	}
+
+	nowLocal := now.In(d.dayStart.Location())
+	lastDay := time.Date(nowLocal.Year(), nowLocal.Month(), nowLocal.Day(), 0, 0, 0, 0, d.dayStart.Location()).
+		AddDate(0, 0, settings.MaxAdvanceDays)
+	if d.dayStart.After(lastDay) {
+		return &BookingPolicyError{Reason: fmt.Sprintf("appointments can be booked at most %d days in advance", settings.MaxAdvanceDays)}
+	}
+
+	before := time.Duration(settings.BufferBeforeMinutes) * time.Minute
+	after := time.Duration(settings.BufferAfterMinutes) * time.Minute
+	dayEnd := d.dayStart.AddDate(0, 0, 1)
+	booked := 0
+	for _, appt := range d.appointments {
+		// Both the new slot and the existing appointment keep their buffers clear
+		if start.Add(-before).Before(appt.EndTime) && end.Add(after).After(appt.StartTime) {
+			return ErrSlotUnavailable
+		}
+		if start.Before(appt.EndTime.Add(after)) && end.After(appt.StartTime.Add(-before)) {
+			return ErrSlotUnavailable
+		}
+		if !appt.StartTime.Before(d.dayStart) && appt.StartTime.Before(dayEnd) {
+			booked++
+		}
+	}
+	// Busy time from external calendars must stay clear of the new slot's buffers,
+	// but it does not count toward the daily cap
+	for _, block := range d.busy {
+		if start.Add(-before).Before(block.EndTime) && end.Add(after).After(block.StartTime) {
+			return ErrSlotUnavailable
+		}
+	}
+	// Another client's hold is treated like the appointment it may become
+	for _, hold := range d.holds {
+		if hold.UserID == d.clientID {
+			continue
+		}
+		if start.Add(-before).Before(hold.EndTime) && end.Add(after).After(hold.StartTime) {
+			return ErrSlotUnavailable
+		}
+		if start.Before(hold.EndTime.Add(after)) && end.After(hold.StartTime.Add(-before)) {
+			return ErrSlotUnavailable
+		}
+		if !hold.StartTime.Before(d.dayStart) && hold.StartTime.Before(dayEnd) {
+			booked++
+		}
+	}
+	if settings.DailyCap > 0 && booked >= settings.DailyCap {
+		return &BookingPolicyError{Reason: "the lawyer's daily appointment limit is reached"}
+	}
+
+	return nil
+}
+
+// CheckBooking applies the lawyer's booking policy to a requested appointment time
+// for a client, judged in the lawyer's time zone. Policy violations are a
+// *BookingPolicyError; a clash with another appointment, busy time imported from
+// the lawyer's calendars, a slot held for another waitlisted client, or their
+// buffers returns ErrSlotUnavailable.
+func (s *AvailabilityService) CheckBooking(lawyer *models.Lawyer, start, end time.Time, excludeAppointmentID, clientID int) error {
+	loc := lawyer.Location()
+	start = start.In(loc)
+	end = end.In(loc)
+
+	day, err := s.loadBookingDay(lawyer, models.NewDate(start), excludeAppointmentID)
+	if err != nil {
+		return err
+	}
+	day.clientID = clientID
+	return day.check(start, end, time.Now())
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// allDayLawyer works 09:00-17:00 in Tokyo on every day of the week
+func allDayLawyer(settings models.BookingSettings) *models.Lawyer {
+	hours := models.TimeIntervals{{Start: "09:00", End: "17:00"}}
+	return &models.Lawyer{
+		ID:       1,
+		Timezone: "Asia/Tokyo",
+		Availability: &models.Availability{
+			Monday: hours, Tuesday: hours, Wednesday: hours, Thursday: hours,
+			Friday: hours, Saturday: hours, Sunday: hours,
+		},
+		BookingSettings: &settings,
+	}
+}
+
+// testBookingDay lays out a day the way loadBookingRange does, without the database
+func testBookingDay(lawyer *models.Lawyer, date models.Date, appointments []models.Appointment) *bookingDay {
+	settings := lawyer.EffectiveBookingSettings()
+	periods := workPeriodsOn(lawyer, date, lawyer.Location(), false, nil)
+	return &bookingDay{
+		settings:     settings,
+		dayStart:     date.In(lawyer.Location()),
+		periods:      periods,
+		starts:       layoutSlotStarts(periods, settings.SlotMinutes),
+		appointments: appointments,
+	}
+}
+
+func TestBookingDayCheck(t *testing.T) {
+	settings := models.BookingSettings{SlotMinutes: 60, BufferAfterMinutes: 30, MinNoticeHours: 24, MaxAdvanceDays: 30, DailyCap: 2}
+	lawyer := allDayLawyer(settings)
+	loc := lawyer.Location()
+	date := models.NewDate(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC))
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 11, 2, hour, minute, 0, 0, loc)
+	}
+	now := at(9, 0).AddDate(0, 0, -7)
+	booked := []models.Appointment{{StartTime: at(12, 0), EndTime: at(13, 0)}}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		now        time.Time
+		wantPolicy bool
+		wantTaken  bool
+	}{
+		{name: "free slot", start: at(9, 0), end: at(10, 0), now: now},
+		{name: "off the grid", start: at(9, 30), end: at(10, 30), now: now, wantPolicy: true},
+		{name: "wrong length", start: at(9, 0), end: at(9, 30), now: now, wantPolicy: true},
+		{name: "outside hours", start: at(17, 0), end: at(18, 0), now: now, wantPolicy: true},
+		{name: "too soon", start: at(9, 0), end: at(10, 0), now: at(9, 0).Add(-time.Hour), wantPolicy: true},
+		{name: "too far ahead", start: at(9, 0), end: at(10, 0), now: now.AddDate(0, 0, -60), wantPolicy: true},
+		{name: "booked", start: at(12, 0), end: at(13, 0), now: now, wantTaken: true},
+		{name: "inside the buffer after", start: at(13, 0), end: at(14, 0), now: now, wantTaken: true},
+		{name: "after the buffer", start: at(14, 0), end: at(15, 0), now: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testBookingDay(lawyer, date, booked).check(tt.start, tt.end, tt.now)
+			var policyErr *BookingPolicyError
+			switch {
+			case tt.wantPolicy && !errors.As(err, &policyErr):
+				t.Errorf("check = %v, want a BookingPolicyError", err)
+			case tt.wantTaken && !errors.Is(err, ErrSlotUnavailable):
+				t.Errorf("check = %v, want ErrSlotUnavailable", err)
+			case !tt.wantPolicy && !tt.wantTaken && err != nil:
+				t.Errorf("check = %v, want the slot bookable", err)
+			}
+		})
+	}
+
+	t.Run("daily cap", func(t *testing.T) {
+		full := append(booked, models.Appointment{StartTime: at(15, 0), EndTime: at(16, 0)})
+		var policyErr *BookingPolicyError
+		if err := testBookingDay(lawyer, date, full).check(at(9, 0), at(10, 0), now); !errors.As(err, &policyErr) {
+			t.Errorf("check = %v, want the daily limit reached", err)
+		}
+	})
+}
+
+func TestBookingDayTimeSlots(t *testing.T) {
+	lawyer := allDayLawyer(models.BookingSettings{SlotMinutes: 90, MinNoticeHours: 0, MaxAdvanceDays: 30})
+	date := models.NewDate(time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC))
+	now := date.In(lawyer.Location()).AddDate(0, 0, -1)
+
+	slots := testBookingDay(lawyer, date, nil).timeSlots(now)
+	// 09:00-17:00 holds five 90-minute slots; the last would end at 17:00 + 30m
+	want := []string{"09:00", "10:30", "12:00", "13:30", "15:00"}
+	if len(slots) != len(want) {
+		t.Fatalf("got %d slots, want %d", len(slots), len(want))
+	}
+	for i, slot := range slots {
+		if slot.Time != want[i] || !slot.Available {
+			t.Errorf("slot %d = %s available %v, want %s available", i, slot.Time, slot.Available, want[i])
+		}
+	}
+}
+
+// BenchmarkBookingDayTimeSlots evaluates every slot of the longest calendar range,
+// the work GetAvailabilityCalendar does once the range is loaded
+func BenchmarkBookingDayTimeSlots(b *testing.B) {
+	lawyer := allDayLawyer(models.BookingSettings{SlotMinutes: 30, BufferBeforeMinutes: 15, BufferAfterMinutes: 15, MaxAdvanceDays: 90})
+	allDay := models.TimeIntervals{{Start: "00:00", End: "24:00"}}
+	lawyer.Availability = &models.Availability{
+		Monday: allDay, Tuesday: allDay, Wednesday: allDay, Thursday: allDay,
+		Friday: allDay, Saturday: allDay, Sunday: allDay,
+	}
+
+	from := models.NewDate(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+	now := from.In(lawyer.Location()).AddDate(0, 0, -1)
+	days := make([]*bookingDay, 0, MaxCalendarDays)
+	for i := 0; i < MaxCalendarDays; i++ {
+		date := models.NewDate(from.AddDate(0, 0, i))
+		dayStart := date.In(lawyer.Location())
+		var appointments []models.Appointment
+		for hour := 1; hour < 24; hour += 3 {
+			start := dayStart.Add(time.Duration(hour) * time.Hour)
+			appointments = append(appointments, models.Appointment{StartTime: start, EndTime: start.Add(30 * time.Minute)})
+		}
+		days = append(days, testBookingDay(lawyer, date, appointments))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, day := range days {
+			day.timeSlots(now)
+		}
+	}
+}
+
+// calendarBenchmarkLawyer is a lawyer who works all day every day with 30-minute
+// slots, so every day of the calendar has slots to evaluate
+func calendarBenchmarkLawyer(b *testing.B, db *gorm.DB) *models.Lawyer {
+	b.Helper()
+
+	lawyer := createTestLawyer(b, db)
+	settings := models.BookingSettings{SlotMinutes: 30, MinNoticeHours: 0, MaxAdvanceDays: 90}
+	if err := db.Model(lawyer).Updates(map[string]interface{}{
+		"availability":     allDayLawyer(settings).Availability,
+		"booking_settings": &settings,
+	}).Error; err != nil {
+		b.Fatalf("updating test lawyer: %v", err)
+	}
+	return lawyer
+}
+
+// BenchmarkGetAvailabilityCalendar times the largest calendar request end to end,
+// including the database queries. It needs TEST_DATABASE_URL.
+func BenchmarkGetAvailabilityCalendar(b *testing.B) {
+	db := openTestDB(b)
+	lawyer := calendarBenchmarkLawyer(b, db)
+
+	service := &AvailabilityService{DB: db}
+	from := models.NewDate(time.Now().AddDate(0, 0, 1))
+	to := models.NewDate(from.AddDate(0, 0, MaxCalendarDays-1))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := service.GetAvailabilityCalendar(lawyer.ID, from, to); err != nil {
+			b.Fatalf("GetAvailabilityCalendar: %v", err)
+		}
+	}
+}
+
+// BenchmarkGetAvailableTimeSlotsPerDay times the same range fetched the way clients
+// did before the calendar endpoint, one GetAvailableTimeSlots call per day, for
+// comparison with BenchmarkGetAvailabilityCalendar. It needs TEST_DATABASE_URL.
+func BenchmarkGetAvailableTimeSlotsPerDay(b *testing.B) {
+	db := openTestDB(b)
+	lawyer := calendarBenchmarkLawyer(b, db)
+
+	service := &AppointmentService{DB: db}
+	from := models.NewDate(time.Now().AddDate(0, 0, 1))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for day := 0; day < MaxCalendarDays; day++ {
+			if _, err := service.GetAvailableTimeSlots(lawyer.ID, models.NewDate(from.AddDate(0, 0, day))); err != nil {
+				b.Fatalf("GetAvailableTimeSlots: %v", err)
+			}
+		}
+	}
+}
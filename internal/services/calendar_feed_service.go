@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// calendarFeedPastDays is how far back a calendar feed lists appointments
+const calendarFeedPastDays = 90
+
+// ErrCalendarFeedNotFound is returned for unknown or revoked feed tokens
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+// CalendarFeed describes a user's subscription feed. URL is only set right after
+// the token is created, since the token itself is not stored.
+type CalendarFeed struct {
+	Enabled        bool       `json:"enabled"`
+	URL            string     `json:"url,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// CalendarFeedService manages iCalendar subscription feeds of appointments
+type CalendarFeedService struct {
+	DB *gorm.DB
+}
+
+// NewCalendarFeedService creates a new calendar feed service
+func NewCalendarFeedService() *CalendarFeedService {
+	return &CalendarFeedService{
+		DB: repository.DB,
+	}
+}
+
+// hashFeedToken returns the hex SHA-256 of a feed token
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// calendarFeedURL is the public address calendar apps subscribe to
+func calendarFeedURL(token string) string {
+	base := ""
+	if cfg := GetConfig(); cfg != nil {
+		base = cfg.Server.PublicURL
+	}
+	return fmt.Sprintf("%s/api/public/calendar/%s.ics", base, token)
+}
+
+// GetFeed returns the state of a user's calendar feed
+func (s *CalendarFeedService) GetFeed(userID int) (*CalendarFeed, error) {
+	var feedToken models.CalendarFeedToken
+	err := s.DB.Where("user_id = ?", userID).First(&feedToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &CalendarFeed{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &CalendarFeed{
+		Enabled:        true,
+		CreatedAt:      &feedToken.CreatedAt,
+		LastAccessedAt: feedToken.LastAccessedAt,
+	}, nil
+}
+
+// RotateFeedToken issues a new secret feed token for a user, replacing any previous
+// one so old subscription URLs stop working. The returned feed holds the URL.
+func (s *CalendarFeedService) RotateFeedToken(userID int) (*CalendarFeed, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	feedToken := models.CalendarFeedToken{
+		UserID:    userID,
+		TokenHash: hashFeedToken(token),
+		CreatedAt: time.Now(),
+	}
+	err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"token_hash": feedToken.TokenHash, "created_at": feedToken.CreatedAt, "last_accessed_at": nil}),
+	}).Create(&feedToken).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &CalendarFeed{
+		Enabled:   true,
+		URL:       calendarFeedURL(token),
+		CreatedAt: &feedToken.CreatedAt,
+	}, nil
+}
+
+// RevokeFeedToken disables a user's calendar feed
+func (s *CalendarFeedService) RevokeFeedToken(userID int) error {
+	return s.DB.Where("user_id = ?", userID).Delete(&models.CalendarFeedToken{}).Error
+}
+
+// RenderFeed returns the iCalendar document for a feed token. It lists the owner's
+// appointments as a client and, for lawyers, the appointments booked with them,
+// from 90 days ago onward, in the owner's time zone. Cancelled appointments stay in
+// the feed marked as cancelled so subscribed calendars drop them; rejected requests
+// never reached the calendar and are left out.
+func (s *CalendarFeedService) RenderFeed(token string) ([]byte, error) {
+	var feedToken models.CalendarFeedToken
+	if err := s.DB.Where("token_hash = ?", hashFeedToken(token)).First(&feedToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCalendarFeedNotFound
+		}
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, feedToken.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCalendarFeedNotFound
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrCalendarFeedNotFound
+	}
+
+	query := s.DB.Preload("User").Preload("Lawyer").
+		Where("status <> ? AND end_time >= ?", models.AppointmentStatusRejected, time.Now().AddDate(0, 0, -calendarFeedPastDays))
+	var lawyer models.Lawyer
+	err := s.DB.Select("id").Where("user_id = ?", user.ID).First(&lawyer).Error
+	switch {
+	case err == nil:
+		query = query.Where("user_id = ? OR lawyer_id = ?", user.ID, lawyer.ID)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		query = query.Where("user_id = ?", user.ID)
+	default:
+		return nil, err
+	}
+
+	var appointments []models.Appointment
+	if err := query.Order("start_time ASC").Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	linkWindow := (&MeetingService{DB: s.DB}).MeetingLinkWindow()
+	events := make([]icsEvent, 0, len(appointments))
+	for _, appointment := range appointments {
+		otherParty := appointment.Lawyer.FullName
+		if appointment.UserID != user.ID {
+			otherParty = clientDisplayName(appointment.User)
+		}
+		// Calendar apps poll the feed, so the link appears once its window opens
+		if !MeetingLinkVisible(appointment.StartTime, appointment.EndTime, linkWindow, now) {
+			appointment.MeetingLink = nil
+		}
+		events = append(events, newAppointmentEvent(appointment, appointmentEventSummary(otherParty)))
+	}
+
+	if err := s.DB.Model(&feedToken).Update("last_accessed_at", now).Error; err != nil {
+		fmt.Printf("Failed to record access to calendar feed %d: %v\n", feedToken.ID, err)
+	}
+
+	return buildICalendar("", "べんごしっち 予約", user.Location(), events), nil
+}
+
+// clientDisplayName is the name shown to a lawyer for a client
+func clientDisplayName(client models.User) string {
+	if client.Nickname != nil && *client.Nickname != "" {
+		return *client.Nickname
+	}
+	if client.FirstName != nil && client.LastName != nil {
+		return fmt.Sprintf("%s %s", *client.FirstName, *client.LastName)
+	}
+	return client.Email
+}
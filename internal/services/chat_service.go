@@ -26,7 +26,7 @@ func (s *ChatService) NewChatMessage(message models.ChatMessage) (*models.ChatMe
 	// Check if appointment exists
 	var appointment models.Appointment
 	if err := s.db.First(&appointment, message.AppointmentID).Error; err != nil {
-		return nil, errors.New("appointment not found")
+		return nil, ErrAppointmentNotFound
 	}
 
 	// Check if sender and receiver exist
@@ -54,7 +54,7 @@ func (s *ChatService) GetMessagesByAppointment(appointmentID, userID int) ([]mod
 	// Check if appointment exists and user has access
 	var appointment models.Appointment
 	if err := s.db.Preload("Lawyer").First(&appointment, appointmentID).Error; err != nil {
-		return nil, errors.New("appointment not found")
+		return nil, ErrAppointmentNotFound
 	}
 
 	if appointment.UserID != userID && appointment.Lawyer.UserID != userID {
@@ -0,0 +1,632 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// Names at least this similar after normalization are reported as potential
+// conflicts. Names shorter than minFuzzyNameLength only match exactly, since one
+// differing character in a two-character surname says little.
+const (
+	conflictMatchThreshold = 0.75
+	minFuzzyNameLength     = 3
+)
+
+var (
+	// ErrConflictUnreviewed is returned when an appointment is confirmed while its
+	// latest conflict check has unacknowledged potential conflicts
+	ErrConflictUnreviewed    = errors.New("conflict of interest: potential conflicts must be acknowledged before confirming")
+	ErrConflictCheckNotFound = errors.New("conflict check not found")
+	// ErrInvalidOpposingParties and ErrInvalidAcknowledgement are wrapped with the
+	// reason the input was refused
+	ErrInvalidOpposingParties = errors.New("invalid opposing parties")
+	ErrInvalidAcknowledgement = errors.New("invalid acknowledgement")
+)
+
+// ConflictReviewError is returned when an appointment is confirmed while its latest
+// conflict check has unacknowledged potential conflicts. Check is that check, which
+// the lawyer has to acknowledge. It wraps ErrConflictUnreviewed.
+type ConflictReviewError struct {
+	Check *models.ConflictCheck
+}
+
+func (e *ConflictReviewError) Error() string {
+	return ErrConflictUnreviewed.Error()
+}
+
+func (e *ConflictReviewError) Unwrap() error {
+	return ErrConflictUnreviewed
+}
+
+// corporateDesignators are dropped from names before comparing, so "株式会社山田商事"
+// and "山田商事(株)" are the same party. They are in NFKC form.
+var corporateDesignators = []string{
+	"特定非営利活動法人", "一般社団法人", "一般財団法人", "公益社団法人", "公益財団法人",
+	"社会福祉法人", "弁護士法人", "税理士法人", "株式会社", "有限会社", "合同会社",
+	"合資会社", "合名会社", "医療法人", "学校法人", "宗教法人", "npo法人",
+	"(株)", "(有)", "(同)", "(資)", "(名)", "(社)", "(財)", "(医)",
+}
+
+// latinDesignators are company suffixes dropped when they stand as separate words
+var latinDesignators = map[string]bool{
+	"inc": true, "ltd": true, "llc": true, "corp": true, "co": true,
+	"kk": true, "gk": true, "plc": true, "gmbh": true, "company": true, "corporation": true,
+}
+
+// kanjiVariants folds old and variant forms of kanji common in names onto the form
+// usually typed, so 髙橋 matches 高橋 and 渡邉 matches 渡辺
+var kanjiVariants = map[rune]rune{
+	'髙': '高', '﨑': '崎', '嵜': '崎', '邊': '辺', '邉': '辺', '齋': '斎', '齊': '斉',
+	'澤': '沢', '濱': '浜', '廣': '広', '國': '国', '櫻': '桜', '嶋': '島', '嶌': '島',
+	'冨': '富', '德': '徳', '惠': '恵', '榮': '栄', '藏': '蔵', '龍': '竜', '瀧': '滝',
+	'槇': '槙', '眞': '真', '實': '実', '壽': '寿', '傳': '伝', '淺': '浅', '驛': '駅',
+	'圓': '円', '會': '会', '與': '与', '萬': '万', '豐': '豊', '黑': '黒', '戶': '戸',
+	'兒': '児', '關': '関', '絲': '糸', '彌': '弥', '禮': '礼', '曾': '曽',
+}
+
+// smallKana maps small katakana to their full-size forms, since company names often
+// write them full size: キャノン matches キヤノン
+var smallKana = map[rune]rune{
+	'ァ': 'ア', 'ィ': 'イ', 'ゥ': 'ウ', 'ェ': 'エ', 'ォ': 'オ', 'ッ': 'ツ',
+	'ャ': 'ヤ', 'ュ': 'ユ', 'ョ': 'ヨ', 'ヮ': 'ワ', 'ヵ': 'カ', 'ヶ': 'ケ',
+}
+
+// ConflictCheckService checks bookings for conflicts of interest: parties the client
+// is in dispute with who the lawyer has acted for, and clients the lawyer has
+// previously been asked to act against
+type ConflictCheckService struct {
+	DB *gorm.DB
+}
+
+// NewConflictCheckService creates a new conflict check service
+func NewConflictCheckService() *ConflictCheckService {
+	return &ConflictCheckService{
+		DB: repository.DB,
+	}
+}
+
+// PrepareOpposingParties trims and validates the opposing parties declared with a
+// booking. Validation errors wrap ErrInvalidOpposingParties. The parties are
+// saved with the appointment by CreateAppointment.
+func (s *ConflictCheckService) PrepareOpposingParties(parties []models.OpposingParty) ([]models.OpposingParty, error) {
+	if len(parties) > models.MaxOpposingParties {
+		return nil, fmt.Errorf("%w: at most %d can be declared", ErrInvalidOpposingParties, models.MaxOpposingParties)
+	}
+
+	prepared := make([]models.OpposingParty, 0, len(parties))
+	for _, party := range parties {
+		party.Name = strings.TrimSpace(party.Name)
+		party.NameKana = trimOptional(party.NameKana)
+		party.Relationship = trimOptional(party.Relationship)
+		if party.Name == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidOpposingParties)
+		}
+		if utf8.RuneCountInString(party.Name) > 255 || (party.NameKana != nil && utf8.RuneCountInString(*party.NameKana) > 255) ||
+			(party.Relationship != nil && utf8.RuneCountInString(*party.Relationship) > 255) {
+			return nil, fmt.Errorf("%w: names and relationships must be at most 255 characters", ErrInvalidOpposingParties)
+		}
+		if normalizePartyName(party.Name) == "" {
+			return nil, fmt.Errorf("%w: %q is not a name", ErrInvalidOpposingParties, party.Name)
+		}
+		prepared = append(prepared, party)
+	}
+	return prepared, nil
+}
+
+func trimOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// saveOpposingParties stores the opposing parties declared with a new appointment
+func (s *ConflictCheckService) saveOpposingParties(appointmentID int, parties []models.OpposingParty) error {
+	if len(parties) == 0 {
+		return nil
+	}
+	for i := range parties {
+		parties[i].ID = 0
+		parties[i].AppointmentID = &appointmentID
+		parties[i].MatterID = nil
+	}
+	return s.DB.Create(&parties).Error
+}
+
+// replaceMatterParties replaces the opposing parties of a matter
+func (s *ConflictCheckService) replaceMatterParties(matterID int, parties []models.OpposingParty) error {
+	if err := s.DB.Where("matter_id = ?", matterID).Delete(&models.OpposingParty{}).Error; err != nil {
+		return err
+	}
+	if len(parties) == 0 {
+		return nil
+	}
+	for i := range parties {
+		parties[i].ID = 0
+		parties[i].AppointmentID = nil
+		parties[i].MatterID = &matterID
+	}
+	return s.DB.Create(&parties).Error
+}
+
+// GetOpposingParties lists the opposing parties declared with an appointment
+func (s *ConflictCheckService) GetOpposingParties(appointmentID int) ([]models.OpposingParty, error) {
+	var parties []models.OpposingParty
+	err := s.DB.Where("appointment_id = ?", appointmentID).Order("id ASC").Find(&parties).Error
+	return parties, err
+}
+
+// GetMatterParties lists the opposing parties of a matter
+func (s *ConflictCheckService) GetMatterParties(matterID int) ([]models.OpposingParty, error) {
+	var parties []models.OpposingParty
+	err := s.DB.Where("matter_id = ?", matterID).Order("id ASC").Find(&parties).Error
+	return parties, err
+}
+
+// ListChecks lists the conflict checks run for an appointment, newest first
+func (s *ConflictCheckService) ListChecks(appointmentID int) ([]models.ConflictCheck, error) {
+	var checks []models.ConflictCheck
+	err := s.DB.Where("appointment_id = ?", appointmentID).Order("created_at DESC, id DESC").Find(&checks).Error
+	return checks, err
+}
+
+// latestCheck returns the most recent conflict check of an appointment, or nil if it
+// has never been checked
+func (s *ConflictCheckService) latestCheck(appointmentID int) (*models.ConflictCheck, error) {
+	var check models.ConflictCheck
+	err := s.DB.Where("appointment_id = ?", appointmentID).Order("created_at DESC, id DESC").First(&check).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// RunCheck compares the opposing parties of the appointment and of its matter with
+// the lawyer's other clients, and its client with parties declared against the
+// lawyer's other clients, and records the result
+func (s *ConflictCheckService) RunCheck(appointment *models.Appointment, actorID int) (*models.ConflictCheck, error) {
+	client, parties, err := s.checkSubjects(appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	pastClients, err := s.pastClients(appointment)
+	if err != nil {
+		return nil, err
+	}
+	pastParties, err := s.pastOpposingParties(appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches models.ConflictMatches
+	for _, party := range parties {
+		subject := partyNames(party)
+		for _, past := range pastClients {
+			userID, appointmentID := past.UserID, past.AppointmentID
+			if score, exact, ok := bestNameMatch(subject, clientNames(past.FirstName, past.LastName, past.FirstNameKana, past.LastNameKana)); ok {
+				matches = append(matches, models.ConflictMatch{
+					Subject:       models.ConflictSubjectOpposingParty,
+					SubjectName:   party.Name,
+					Source:        models.ConflictSourcePastClient,
+					MatchedName:   displayClientName(past.FirstName, past.LastName),
+					AppointmentID: &appointmentID,
+					UserID:        &userID,
+					Score:         score,
+					Exact:         exact,
+				})
+			}
+		}
+	}
+
+	if subject := clientNames(client.FirstName, client.LastName, client.FirstNameKana, client.LastNameKana); len(subject) > 0 {
+		clientName := checkedClientName(client)
+		for _, past := range pastParties {
+			if score, exact, ok := bestNameMatch(subject, partyNames(past)); ok {
+				matches = append(matches, models.ConflictMatch{
+					Subject:       models.ConflictSubjectClient,
+					SubjectName:   clientName,
+					Source:        models.ConflictSourceOpposingParty,
+					MatchedName:   past.Name,
+					AppointmentID: past.AppointmentID,
+					MatterID:      past.MatterID,
+					Score:         score,
+					Exact:         exact,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	check := &models.ConflictCheck{
+		AppointmentID: appointment.ID,
+		LawyerID:      appointment.LawyerID,
+		CheckedBy:     actorID,
+		Status:        models.ConflictCheckClear,
+		CheckedNames:  checkedNames(client, parties),
+		Matches:       matches,
+	}
+	if len(matches) > 0 {
+		check.Status = models.ConflictCheckPotential
+	}
+	if err := s.DB.Create(check).Error; err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// checkSubjects loads an appointment's client and the opposing parties declared with
+// it and with its matter
+func (s *ConflictCheckService) checkSubjects(appointment *models.Appointment) (*models.User, []models.OpposingParty, error) {
+	var client models.User
+	if err := s.DB.First(&client, appointment.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	parties, err := s.GetOpposingParties(appointment.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if appointment.MatterID != nil {
+		matterParties, err := s.GetMatterParties(*appointment.MatterID)
+		if err != nil {
+			return nil, nil, err
+		}
+		parties = append(parties, matterParties...)
+	}
+	return &client, parties, nil
+}
+
+// checkedNames lists the names a check of the parties and the client compares, as
+// recorded in ConflictCheck.CheckedNames
+func checkedNames(client *models.User, parties []models.OpposingParty) []string {
+	var names []string
+	for _, party := range parties {
+		names = append(names, party.Name)
+	}
+	if len(clientNames(client.FirstName, client.LastName, client.FirstNameKana, client.LastNameKana)) > 0 {
+		names = append(names, checkedClientName(client))
+	}
+	return names
+}
+
+// checksCurrentNames reports whether a check compared the names the appointment's
+// parties and client have now. A check of other names says nothing about them.
+func (s *ConflictCheckService) checksCurrentNames(check *models.ConflictCheck, appointment *models.Appointment) (bool, error) {
+	client, parties, err := s.checkSubjects(appointment)
+	if err != nil {
+		return false, err
+	}
+	current := checkedNames(client, parties)
+	checked := append([]string(nil), check.CheckedNames...)
+	if len(current) != len(checked) {
+		return false, nil
+	}
+	sort.Strings(current)
+	sort.Strings(checked)
+	for i := range current {
+		if current[i] != checked[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RecheckMatter runs a fresh check, recorded as run by the lawyer, for each pending
+// or confirmed appointment of a matter whose latest check compared other names than
+// it has now, after the matter's parties changed or an appointment joined it. An
+// acknowledgement of the old check no longer counts for confirming.
+func (s *ConflictCheckService) RecheckMatter(matter *models.Matter) error {
+	var appointments []models.Appointment
+	if err := s.DB.Where("matter_id = ? AND status IN ?", matter.ID,
+		[]models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}).
+		Find(&appointments).Error; err != nil {
+		return err
+	}
+
+	var lawyer models.Lawyer
+	for i := range appointments {
+		latest, err := s.latestCheck(appointments[i].ID)
+		if err != nil {
+			return err
+		}
+		// Appointments never checked are checked when they are confirmed
+		if latest == nil {
+			continue
+		}
+		current, err := s.checksCurrentNames(latest, &appointments[i])
+		if err != nil {
+			return err
+		}
+		if current {
+			continue
+		}
+		if lawyer.ID == 0 {
+			if err := s.DB.Select("id", "user_id").First(&lawyer, matter.LawyerID).Error; err != nil {
+				return err
+			}
+		}
+		if _, err := s.RunCheck(&appointments[i], lawyer.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearForConfirmation decides whether an appointment may be confirmed. An
+// acknowledged latest check stands while the appointment's parties and client are
+// the ones it checked; otherwise a new check is run and recorded. When it finds
+// potential conflicts the check is returned with ErrConflictUnreviewed.
+func (s *ConflictCheckService) ClearForConfirmation(appointment *models.Appointment, actorID int) (*models.ConflictCheck, error) {
+	latest, err := s.latestCheck(appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.AcknowledgedAt != nil {
+		current, err := s.checksCurrentNames(latest, appointment)
+		if err != nil {
+			return nil, err
+		}
+		if current {
+			return latest, nil
+		}
+	}
+
+	check, err := s.RunCheck(appointment, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !check.Cleared() {
+		return check, ErrConflictUnreviewed
+	}
+	return check, nil
+}
+
+// Acknowledge records that the lawyer reviewed the potential conflicts found by an
+// appointment's latest check and may act, with a note saying why. Validation errors
+// wrap ErrInvalidAcknowledgement.
+func (s *ConflictCheckService) Acknowledge(appointmentID, checkID, actorID int, note string) (*models.ConflictCheck, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: a note explaining the review is required", ErrInvalidAcknowledgement)
+	}
+
+	var check models.ConflictCheck
+	if err := s.DB.Where("id = ? AND appointment_id = ?", checkID, appointmentID).First(&check).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConflictCheckNotFound
+		}
+		return nil, err
+	}
+	if check.Status != models.ConflictCheckPotential {
+		return nil, fmt.Errorf("%w: the check found no conflicts", ErrInvalidAcknowledgement)
+	}
+	if check.AcknowledgedAt != nil {
+		return nil, fmt.Errorf("%w: the check is already acknowledged", ErrInvalidAcknowledgement)
+	}
+	latest, err := s.latestCheck(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if latest.ID != check.ID {
+		return nil, fmt.Errorf("%w: only the latest check can be acknowledged", ErrInvalidAcknowledgement)
+	}
+
+	now := time.Now().UTC()
+	result := s.DB.Model(&check).Where("acknowledged_at IS NULL").Updates(map[string]interface{}{
+		"acknowledged_by":      actorID,
+		"acknowledged_at":      now,
+		"acknowledgement_note": note,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: the check is already acknowledged", ErrInvalidAcknowledgement)
+	}
+	check.AcknowledgedBy = &actorID
+	check.AcknowledgedAt = &now
+	check.AcknowledgementNote = &note
+	return &check, nil
+}
+
+// pastClient is the latest appointment of one of the lawyer's other clients
+type pastClient struct {
+	AppointmentID int
+	UserID        int
+	FirstName     *string
+	LastName      *string
+	FirstNameKana *string
+	LastNameKana  *string
+}
+
+func (s *ConflictCheckService) pastClients(appointment *models.Appointment) ([]pastClient, error) {
+	var clients []pastClient
+	err := s.DB.Table("appointments").
+		Select("DISTINCT ON (appointments.user_id) appointments.id AS appointment_id, appointments.user_id, users.first_name, users.last_name, users.first_name_kana, users.last_name_kana").
+		Joins("JOIN users ON users.id = appointments.user_id").
+		Where("appointments.lawyer_id = ? AND appointments.user_id <> ? AND appointments.id <> ?",
+			appointment.LawyerID, appointment.UserID, appointment.ID).
+		Order("appointments.user_id, appointments.start_time DESC").
+		Scan(&clients).Error
+	return clients, err
+}
+
+// pastOpposingParties lists the parties other clients declared against them when
+// booking the lawyer or on their matters with the lawyer
+func (s *ConflictCheckService) pastOpposingParties(appointment *models.Appointment) ([]models.OpposingParty, error) {
+	var parties []models.OpposingParty
+	err := s.DB.Where("appointment_id IN (?) OR matter_id IN (?)",
+		s.DB.Table("appointments").Select("id").Where("lawyer_id = ? AND user_id <> ?", appointment.LawyerID, appointment.UserID),
+		s.DB.Table("matters").Select("id").Where("lawyer_id = ? AND client_id <> ?", appointment.LawyerID, appointment.UserID)).
+		Order("id ASC").
+		Find(&parties).Error
+	return parties, err
+}
+
+// partyNames returns the normalized forms a party may be known by
+func partyNames(party models.OpposingParty) []string {
+	names := []string{normalizePartyName(party.Name)}
+	if party.NameKana != nil {
+		names = append(names, normalizePartyName(*party.NameKana))
+	}
+	return nonEmpty(names)
+}
+
+// clientNames returns the normalized forms of a user's name and of its reading in
+// kana, family name first and given name first. The reading lets a party declared
+// in kana match a client whose name is written in kanji.
+func clientNames(firstName, lastName, firstNameKana, lastNameKana *string) []string {
+	var names []string
+	for _, name := range [][2]*string{{firstName, lastName}, {firstNameKana, lastNameKana}} {
+		first, last := "", ""
+		if name[0] != nil {
+			first = *name[0]
+		}
+		if name[1] != nil {
+			last = *name[1]
+		}
+		if strings.TrimSpace(first+last) == "" {
+			continue
+		}
+		names = append(names, normalizePartyName(last+first), normalizePartyName(first+last))
+	}
+	return nonEmpty(names)
+}
+
+func displayClientName(firstName, lastName *string) string {
+	var parts []string
+	if lastName != nil && strings.TrimSpace(*lastName) != "" {
+		parts = append(parts, strings.TrimSpace(*lastName))
+	}
+	if firstName != nil && strings.TrimSpace(*firstName) != "" {
+		parts = append(parts, strings.TrimSpace(*firstName))
+	}
+	return strings.Join(parts, " ")
+}
+
+// checkedClientName is a client's name as a check records it, with its reading
+func checkedClientName(client *models.User) string {
+	name := displayClientName(client.FirstName, client.LastName)
+	reading := displayClientName(client.FirstNameKana, client.LastNameKana)
+	switch {
+	case reading == "" || reading == name:
+		return name
+	case name == "":
+		return reading
+	}
+	return name + " (" + reading + ")"
+}
+
+func nonEmpty(names []string) []string {
+	kept := names[:0]
+	for _, name := range names {
+		if name != "" {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// normalizePartyName reduces a name to a form in which spelling differences that do
+// not change who is meant disappear: full-width and half-width characters, hiragana
+// and katakana, small and full-size kana, variant kanji, case, spacing, punctuation
+// and corporate designators such as 株式会社
+func normalizePartyName(name string) string {
+	name = strings.ToLower(norm.NFKC.String(name))
+	for _, designator := range corporateDesignators {
+		name = strings.ReplaceAll(name, designator, " ")
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '々'
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if latinDesignators[word] {
+			continue
+		}
+		for _, r := range word {
+			if r >= 'ぁ' && r <= 'ゖ' {
+				r += 'ァ' - 'ぁ'
+			}
+			if large, ok := smallKana[r]; ok {
+				r = large
+			}
+			if folded, ok := kanjiVariants[r]; ok {
+				r = folded
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// bestNameMatch compares every form of two names and reports the best similarity
+// if it is high enough to be a potential conflict
+func bestNameMatch(subject, candidate []string) (score float64, exact bool, ok bool) {
+	for _, a := range subject {
+		for _, b := range candidate {
+			if a == b {
+				return 1, true, true
+			}
+			if utf8.RuneCountInString(a) < minFuzzyNameLength || utf8.RuneCountInString(b) < minFuzzyNameLength {
+				continue
+			}
+			if similarity := nameSimilarity(a, b); similarity > score {
+				score = similarity
+			}
+		}
+	}
+	return score, false, score >= conflictMatchThreshold
+}
+
+// nameSimilarity is one minus the edit distance between two names divided by the
+// length of the longer, so 1 means identical
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longer := len(ra)
+	if len(rb) > longer {
+		longer = len(rb)
+	}
+	if longer == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longer)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func TestPrepareOpposingParties(t *testing.T) {
+	service := &ConflictCheckService{}
+
+	prepared, err := service.PrepareOpposingParties([]models.OpposingParty{{Name: "  山田商事株式会社 "}})
+	if err != nil {
+		t.Fatalf("PrepareOpposingParties: %v", err)
+	}
+	if prepared[0].Name != "山田商事株式会社" {
+		t.Errorf("name = %q, want it trimmed", prepared[0].Name)
+	}
+
+	tests := []struct {
+		name    string
+		parties []models.OpposingParty
+	}{
+		{name: "too many", parties: make([]models.OpposingParty, models.MaxOpposingParties+1)},
+		{name: "no name", parties: []models.OpposingParty{{Name: " "}}},
+		{name: "long name", parties: []models.OpposingParty{{Name: strings.Repeat("山", 256)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.PrepareOpposingParties(tt.parties); !errors.Is(err, ErrInvalidOpposingParties) {
+				t.Errorf("error = %v, want ErrInvalidOpposingParties", err)
+			}
+		})
+	}
+}
+
+func TestAcknowledgeRequiresNote(t *testing.T) {
+	_, err := (&ConflictCheckService{}).Acknowledge(1, 1, 1, "  ")
+	if !errors.Is(err, ErrInvalidAcknowledgement) {
+		t.Errorf("error = %v, want ErrInvalidAcknowledgement", err)
+	}
+}
+
+func TestAcknowledgementLapsesWhenPartiesChange(t *testing.T) {
+	db := openTestDB(t)
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	pastClient := createTestUser(t, db, models.RoleClient)
+	if err := db.Model(pastClient).Updates(map[string]interface{}{"last_name": "山田", "first_name": "太郎"}).Error; err != nil {
+		t.Fatalf("naming past client: %v", err)
+	}
+
+	start := time.Now().Add(96 * time.Hour).Truncate(time.Hour)
+	newAppointment := func(userID int, status models.AppointmentStatus, offset time.Duration) *models.Appointment {
+		appointment := &models.Appointment{
+			UserID:    userID,
+			LawyerID:  lawyer.ID,
+			StartTime: start.Add(offset),
+			EndTime:   start.Add(offset + time.Hour),
+			Status:    status,
+		}
+		if err := db.Omit("User", "Lawyer").Create(appointment).Error; err != nil {
+			t.Fatalf("creating test appointment: %v", err)
+		}
+		t.Cleanup(func() {
+			db.Unscoped().Delete(&models.Appointment{}, appointment.ID)
+		})
+		return appointment
+	}
+	newAppointment(pastClient.ID, models.AppointmentStatusCompleted, -240*time.Hour)
+	appointment := newAppointment(client.ID, models.AppointmentStatusPending, 0)
+	if err := db.Create(&models.OpposingParty{AppointmentID: &appointment.ID, Name: "山田 太郎"}).Error; err != nil {
+		t.Fatalf("creating opposing party: %v", err)
+	}
+
+	service := &ConflictCheckService{DB: db}
+	check, err := service.ClearForConfirmation(appointment, lawyer.UserID)
+	if !errors.Is(err, ErrConflictUnreviewed) {
+		t.Fatalf("ClearForConfirmation error = %v, want ErrConflictUnreviewed", err)
+	}
+	acknowledged, err := service.Acknowledge(appointment.ID, check.ID, lawyer.UserID, "different 山田 太郎")
+	if err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	if cleared, err := service.ClearForConfirmation(appointment, lawyer.UserID); err != nil || cleared.ID != acknowledged.ID {
+		t.Fatalf("ClearForConfirmation with unchanged parties = %v, %v, want the acknowledged check %d", cleared, err, acknowledged.ID)
+	}
+
+	// Putting the appointment in a matter adds the matter's parties to the check
+	matter := &models.Matter{ClientID: client.ID, Title: "Dispute", PracticeArea: "civil",
+		OpposingParties: []models.OpposingParty{{Name: "佐藤商事株式会社"}}}
+	if err := (&MatterService{DB: db}).CreateMatter(lawyer, matter, []int{appointment.ID}); err != nil {
+		t.Fatalf("CreateMatter: %v", err)
+	}
+	checks, err := service.ListChecks(appointment.ID)
+	if err != nil {
+		t.Fatalf("ListChecks: %v", err)
+	}
+	if len(checks) != 2 || checks[0].AcknowledgedAt != nil || checks[0].CheckedBy != lawyer.UserID {
+		t.Fatalf("after linking the matter got %d checks, latest %+v, want a fresh unacknowledged check by the lawyer", len(checks), checks[0])
+	}
+
+	appointment.MatterID = &matter.ID
+	if _, err := service.ClearForConfirmation(appointment, lawyer.UserID); !errors.Is(err, ErrConflictUnreviewed) {
+		t.Errorf("ClearForConfirmation after the parties changed error = %v, want ErrConflictUnreviewed", err)
+	}
+}
+
+func TestClientNamesIncludeReading(t *testing.T) {
+	first, last := "太郎", "山田"
+	firstKana, lastKana := "たろう", "やまだ"
+	party := partyNames(models.OpposingParty{Name: "ヤマダ タロウ"})
+
+	if _, _, ok := bestNameMatch(party, clientNames(&first, &last, nil, nil)); ok {
+		t.Errorf("kana party matched a client with no reading")
+	}
+	if _, exact, ok := bestNameMatch(party, clientNames(&first, &last, &firstKana, &lastKana)); !ok || !exact {
+		t.Errorf("kana party match with the client's reading = %v (exact %v), want an exact match", ok, exact)
+	}
+}
+
+func TestNormalizePartyName(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+	}{
+		{name: "full-width latin", a: "ＡＢＣ", b: "ABC"},
+		{name: "half-width katakana", a: "ｶﾀｶﾅ", b: "カタカナ"},
+		{name: "hiragana", a: "かたかな", b: "カタカナ"},
+		{name: "small kana", a: "キャノン", b: "キヤノン"},
+		{name: "variant kanji", a: "髙橋", b: "高橋"},
+		{name: "variant kanji in a full name", a: "渡邉 一郎", b: "渡辺一郎"},
+		{name: "leading designator", a: "株式会社山田商事", b: "山田商事"},
+		{name: "abbreviated designator", a: "山田商事(株)", b: "山田商事"},
+		{name: "full-width abbreviated designator", a: "山田商事（株）", b: "山田商事"},
+		{name: "enclosed designator", a: "㈱山田商事", b: "山田商事"},
+		{name: "latin designators", a: "Yamada Trading Co., Ltd.", b: "yamada trading"},
+		{name: "spacing and punctuation", a: "山田・太郎", b: "山田 太郎"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := normalizePartyName(tt.a), normalizePartyName(tt.b)
+			if a != b {
+				t.Errorf("normalizePartyName(%q) = %q, normalizePartyName(%q) = %q, want them equal", tt.a, a, tt.b, b)
+			}
+			if a == "" {
+				t.Errorf("normalizePartyName(%q) is empty", tt.a)
+			}
+		})
+	}
+
+	if got := normalizePartyName("株式会社"); got != "" {
+		t.Errorf("normalizePartyName of a bare designator = %q, want empty", got)
+	}
+	if normalizePartyName("山田商事") == normalizePartyName("山口商事") {
+		t.Errorf("different names normalized to the same form")
+	}
+}
+
+func TestBestNameMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		subject   []string
+		candidate []string
+		wantOK    bool
+		wantExact bool
+	}{
+		{name: "exact", subject: []string{"山田商事"}, candidate: []string{"山田商事"}, wantOK: true, wantExact: true},
+		{name: "exact on any form", subject: []string{"山田太郎", "太郎山田"}, candidate: []string{"太郎山田"}, wantOK: true, wantExact: true},
+		{name: "similar", subject: []string{"山田太郎"}, candidate: []string{"山田次郎"}, wantOK: true},
+		{name: "dissimilar", subject: []string{"山田太郎"}, candidate: []string{"佐藤花子"}},
+		{name: "short names match exactly", subject: []string{"山田"}, candidate: []string{"山田"}, wantOK: true, wantExact: true},
+		{name: "short names are not fuzzy", subject: []string{"山田"}, candidate: []string{"山口"}},
+		{name: "short against long is not fuzzy", subject: []string{"山田"}, candidate: []string{"山田太"}},
+		{name: "no forms", subject: nil, candidate: []string{"山田"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, exact, ok := bestNameMatch(tt.subject, tt.candidate)
+			if ok != tt.wantOK || exact != tt.wantExact {
+				t.Errorf("bestNameMatch = %v, exact %v, ok %v, want exact %v, ok %v", score, exact, ok, tt.wantExact, tt.wantOK)
+			}
+			if ok && score < conflictMatchThreshold {
+				t.Errorf("matched with score %v below the threshold", score)
+			}
+		})
+	}
+
+	// Short names are never scored, so a near miss reports nothing
+	if score, _, _ := bestNameMatch([]string{"山田"}, []string{"山口"}); score != 0 {
+		t.Errorf("short names scored %v, want 0", score)
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "", b: "", want: 1},
+		{a: "山田商事", b: "山田商事", want: 1},
+		{a: "山田太郎", b: "山田次郎", want: 0.75},
+		{a: "山田商事", b: "山田", want: 0.5},
+		{a: "kitten", b: "sitting", want: 1 - 3.0/7},
+		{a: "abc", b: "xyz", want: 0},
+	}
+	for _, tt := range tests {
+		if got := nameSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := nameSimilarity(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
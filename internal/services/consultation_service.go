@@ -0,0 +1,321 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// In-app notifications about consultation records
+const (
+	NotificationTypeConsultationRecord = "consultation_record"
+	NotificationTypeFollowUpOverdue    = "follow_up_overdue"
+)
+
+var (
+	ErrConsultationRecordNotFound = errors.New("consultation record not found")
+	ErrConsultationRecordExists   = errors.New("consultation record already exists")
+	ErrFollowUpTaskNotFound       = errors.New("follow-up task not found")
+	// ErrInvalidConsultationRecord and ErrInvalidFollowUpTask are wrapped with the
+	// reason the input was refused
+	ErrInvalidConsultationRecord = errors.New("invalid consultation record")
+	ErrInvalidFollowUpTask       = errors.New("invalid follow-up task")
+)
+
+// ConsultationService manages lawyers' records of completed consultations and the
+// follow-up tasks agreed in them
+type ConsultationService struct {
+	DB *gorm.DB
+}
+
+// NewConsultationService creates a new consultation service
+func NewConsultationService() *ConsultationService {
+	return &ConsultationService{
+		DB: repository.DB,
+	}
+}
+
+// GetRecord returns the consultation record of an appointment with its tasks in due
+// date order
+func (s *ConsultationService) GetRecord(appointmentID int) (*models.ConsultationRecord, error) {
+	var record models.ConsultationRecord
+	err := s.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("due_date ASC, id ASC")
+	}).Where("appointment_id = ?", appointmentID).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsultationRecordNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SaveRecord writes the lawyer's private notes, summary and next steps for a
+// completed appointment, creating its record on first save. Empty values clear a
+// field. Validation errors wrap ErrInvalidConsultationRecord.
+func (s *ConsultationService) SaveRecord(appointment *models.Appointment, privateNotes, summary, nextSteps *string) (*models.ConsultationRecord, error) {
+	if appointment.Status != models.AppointmentStatusCompleted {
+		return nil, fmt.Errorf("%w: the appointment must be completed", ErrInvalidConsultationRecord)
+	}
+	privateNotes, summary, nextSteps = trimOptional(privateNotes), trimOptional(summary), trimOptional(nextSteps)
+	for _, text := range []*string{privateNotes, summary, nextSteps} {
+		if text != nil && utf8.RuneCountInString(*text) > models.MaxConsultationTextLength {
+			return nil, fmt.Errorf("%w: notes, summary and next steps must be at most %d characters", ErrInvalidConsultationRecord, models.MaxConsultationTextLength)
+		}
+	}
+
+	record, err := s.GetRecord(appointment.ID)
+	if err != nil && !errors.Is(err, ErrConsultationRecordNotFound) {
+		return nil, err
+	}
+	if record == nil {
+		record = &models.ConsultationRecord{
+			AppointmentID: appointment.ID,
+			LawyerID:      appointment.LawyerID,
+			PrivateNotes:  privateNotes,
+			Summary:       summary,
+			NextSteps:     nextSteps,
+			Tasks:         []models.FollowUpTask{},
+		}
+		err := s.DB.Create(record).Error
+		if pgErrorCode(err) == pgUniqueViolation {
+			return nil, ErrConsultationRecordExists
+		}
+		return record, err
+	}
+
+	if err := s.DB.Model(record).Updates(map[string]interface{}{
+		"private_notes": privateNotes,
+		"summary":       summary,
+		"next_steps":    nextSteps,
+	}).Error; err != nil {
+		return nil, err
+	}
+	record.PrivateNotes, record.Summary, record.NextSteps = privateNotes, summary, nextSteps
+	return record, nil
+}
+
+// ShareRecord shows the record's summary, next steps and client tasks to the client
+// and notifies them. Sharing again after edits notifies them again.
+func (s *ConsultationService) ShareRecord(appointment *models.Appointment) (*models.ConsultationRecord, error) {
+	record, err := s.GetRecord(appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Summary == nil {
+		return nil, fmt.Errorf("%w: write a summary before sharing", ErrInvalidConsultationRecord)
+	}
+
+	now := time.Now().UTC()
+	if err := s.DB.Model(record).Update("shared_at", now).Error; err != nil {
+		return nil, err
+	}
+	record.SharedAt = &now
+
+	s.notifyShared(appointment)
+	return record, nil
+}
+
+func (s *ConsultationService) notifyShared(appointment *models.Appointment) {
+	var client models.User
+	if err := s.DB.First(&client, appointment.UserID).Error; err != nil {
+		fmt.Printf("Failed to load client for consultation record of appointment %d: %v\n", appointment.ID, err)
+		return
+	}
+	date, clock := appointmentDisplayTime(appointment.StartTime, client.Location())
+	notification := &models.Notification{
+		UserID:  client.ID,
+		Type:    NotificationTypeConsultationRecord,
+		Content: fmt.Sprintf("%s %sのご相談について、弁護士から相談記録が共有されました", date, clock),
+	}
+	if err := NewNotificationService().CreateNotification(notification); err != nil {
+		fmt.Printf("Failed to create consultation record notification for user %d: %v\n", client.ID, err)
+	}
+}
+
+// GetTask returns one of a record's follow-up tasks
+func (s *ConsultationService) GetTask(recordID, taskID int) (*models.FollowUpTask, error) {
+	var task models.FollowUpTask
+	if err := s.DB.Where("id = ? AND record_id = ?", taskID, recordID).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFollowUpTaskNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// AddTask adds an open follow-up task to a record. Validation errors wrap
+// ErrInvalidFollowUpTask.
+func (s *ConsultationService) AddTask(record *models.ConsultationRecord, task *models.FollowUpTask) error {
+	task.RecordID = record.ID
+	task.LawyerID = record.LawyerID
+	task.Status = models.FollowUpTaskOpen
+	if err := validateFollowUpTask(task); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.FollowUpTask{}).Where("record_id = ?", record.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= models.MaxFollowUpTasks {
+		return fmt.Errorf("%w: a record can have at most %d tasks", ErrInvalidFollowUpTask, models.MaxFollowUpTasks)
+	}
+	return s.DB.Create(task).Error
+}
+
+// UpdateTask saves changes to a follow-up task. Completing it records when; moving
+// its due date or reopening it lets it be reminded about again.
+func (s *ConsultationService) UpdateTask(task *models.FollowUpTask) error {
+	if err := validateFollowUpTask(task); err != nil {
+		return err
+	}
+
+	var current models.FollowUpTask
+	if err := s.DB.First(&current, task.ID).Error; err != nil {
+		return err
+	}
+	if task.Status == models.FollowUpTaskDone && current.Status != models.FollowUpTaskDone {
+		now := time.Now().UTC()
+		task.CompletedAt = &now
+	} else if task.Status == models.FollowUpTaskOpen {
+		task.CompletedAt = nil
+		if current.Status != models.FollowUpTaskOpen || !task.DueDate.Equal(current.DueDate.Time) {
+			task.ReminderSentAt = nil
+		}
+	}
+
+	return s.DB.Model(task).Updates(map[string]interface{}{
+		"assignee":         task.Assignee,
+		"title":            task.Title,
+		"description":      task.Description,
+		"due_date":         task.DueDate,
+		"status":           task.Status,
+		"completed_at":     task.CompletedAt,
+		"reminder_sent_at": task.ReminderSentAt,
+	}).Error
+}
+
+// DeleteTask removes one of a record's follow-up tasks
+func (s *ConsultationService) DeleteTask(recordID, taskID int) error {
+	result := s.DB.Where("id = ? AND record_id = ?", taskID, recordID).Delete(&models.FollowUpTask{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFollowUpTaskNotFound
+	}
+	return nil
+}
+
+func validateFollowUpTask(task *models.FollowUpTask) error {
+	task.Title = strings.TrimSpace(task.Title)
+	task.Description = trimOptional(task.Description)
+	if task.Assignee == "" {
+		task.Assignee = models.FollowUpAssigneeLawyer
+	}
+	switch {
+	case task.Title == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidFollowUpTask)
+	case utf8.RuneCountInString(task.Title) > 255:
+		return fmt.Errorf("%w: title must be at most 255 characters", ErrInvalidFollowUpTask)
+	case task.Description != nil && utf8.RuneCountInString(*task.Description) > models.MaxConsultationTextLength:
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidFollowUpTask, models.MaxConsultationTextLength)
+	case task.DueDate.IsZero():
+		return fmt.Errorf("%w: due_date is required", ErrInvalidFollowUpTask)
+	case task.Assignee != models.FollowUpAssigneeLawyer && task.Assignee != models.FollowUpAssigneeClient:
+		return fmt.Errorf("%w: assignee must be lawyer or client", ErrInvalidFollowUpTask)
+	case task.Status != models.FollowUpTaskOpen && task.Status != models.FollowUpTaskDone:
+		return fmt.Errorf("%w: status must be open or done", ErrInvalidFollowUpTask)
+	}
+	return nil
+}
+
+// ListLawyerTasks lists the follow-up tasks across a lawyer's consultations in due
+// date order, optionally only those in one status
+func (s *ConsultationService) ListLawyerTasks(lawyerID int, status string) ([]models.FollowUpTask, error) {
+	query := s.DB.Where("lawyer_id = ?", lawyerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var tasks []models.FollowUpTask
+	err := query.Order("due_date ASC, id ASC").Find(&tasks).Error
+	return tasks, err
+}
+
+// overdueTask is an open follow-up task with what is needed to remind its assignee
+type overdueTask struct {
+	models.FollowUpTask
+	ClientID     int
+	LawyerUserID int
+	SharedAt     *time.Time
+}
+
+// SendOverdueTaskReminders reminds the assignee of each open follow-up task whose due
+// date has passed in their time zone, once per task. Client tasks are only reminded
+// about once the record is shared with the client. Run it periodically.
+func (s *ConsultationService) SendOverdueTaskReminders() error {
+	now := time.Now()
+
+	// Tasks due today in UTC may already be overdue in time zones ahead of it
+	var tasks []overdueTask
+	if err := s.DB.Table("follow_up_tasks").
+		Select("follow_up_tasks.*, appointments.user_id AS client_id, lawyers.user_id AS lawyer_user_id, consultation_records.shared_at").
+		Joins("JOIN consultation_records ON consultation_records.id = follow_up_tasks.record_id").
+		Joins("JOIN appointments ON appointments.id = consultation_records.appointment_id").
+		Joins("JOIN lawyers ON lawyers.id = follow_up_tasks.lawyer_id").
+		Where("follow_up_tasks.status = ? AND follow_up_tasks.reminder_sent_at IS NULL AND follow_up_tasks.due_date <= ?",
+			models.FollowUpTaskOpen, models.NewDate(now.UTC())).
+		Scan(&tasks).Error; err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		recipientID := task.LawyerUserID
+		if task.Assignee == models.FollowUpAssigneeClient {
+			if task.SharedAt == nil {
+				continue
+			}
+			recipientID = task.ClientID
+		}
+
+		var recipient models.User
+		if err := s.DB.Select("id", "timezone").First(&recipient, recipientID).Error; err != nil {
+			fmt.Printf("Failed to load user %d for follow-up task %d: %v\n", recipientID, task.ID, err)
+			continue
+		}
+		if !task.Overdue(now, recipient.Location()) {
+			continue
+		}
+
+		// Claim the reminder first so overlapping runs send it once
+		result := s.DB.Model(&models.FollowUpTask{}).
+			Where("id = ? AND reminder_sent_at IS NULL", task.ID).
+			Update("reminder_sent_at", now.UTC())
+		if result.Error != nil {
+			fmt.Printf("Failed to mark reminder for follow-up task %d: %v\n", task.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		notification := &models.Notification{
+			UserID:  recipientID,
+			Type:    NotificationTypeFollowUpOverdue,
+			Content: fmt.Sprintf("フォローアップタスク「%s」の期限（%s）を過ぎています", task.Title, task.DueDate),
+		}
+		if err := NewNotificationService().CreateNotification(notification); err != nil {
+			fmt.Printf("Failed to create overdue task notification for user %d: %v\n", recipientID, err)
+		}
+	}
+	return nil
+}
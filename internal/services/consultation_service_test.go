@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// createCompletedAppointment inserts an appointment that ended a day ago and was
+// completed
+func createCompletedAppointment(t *testing.T, db *gorm.DB) (*models.Appointment, *models.Lawyer) {
+	t.Helper()
+
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	start := time.Now().Add(-25 * time.Hour).Truncate(time.Minute)
+	return createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusCompleted, start, time.Hour), lawyer
+}
+
+func TestSaveRecord(t *testing.T) {
+	confirmed := &models.Appointment{ID: 1, Status: models.AppointmentStatusConfirmed}
+	if _, err := (&ConsultationService{}).SaveRecord(confirmed, nil, nil, nil); !errors.Is(err, ErrInvalidConsultationRecord) {
+		t.Errorf("saving a record of a confirmed appointment: error = %v, want ErrInvalidConsultationRecord", err)
+	}
+
+	db := openTestDB(t)
+	service := &ConsultationService{DB: db}
+	appointment, lawyer := createCompletedAppointment(t, db)
+	text := func(s string) *string { return &s }
+
+	record, err := service.SaveRecord(appointment, text(" Client seems unsure "), text("Reviewed the lease"), nil)
+	if err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+	if record.LawyerID != lawyer.ID || record.PrivateNotes == nil || *record.PrivateNotes != "Client seems unsure" {
+		t.Errorf("new record = %+v, want the lawyer's trimmed notes", record)
+	}
+
+	// Saving again updates the same record; an empty value clears its field
+	updated, err := service.SaveRecord(appointment, text(" "), text("Reviewed the lease"), text("Send a notice"))
+	if err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+	stored, err := service.GetRecord(appointment.ID)
+	if err != nil {
+		t.Fatalf("GetRecord: %v", err)
+	}
+	if updated.ID != record.ID || stored.PrivateNotes != nil || stored.NextSteps == nil || *stored.NextSteps != "Send a notice" {
+		t.Errorf("record after saving again = %+v, want notes cleared and next steps set", stored)
+	}
+}
+
+func TestUpdateTaskTracksCompletionAndReminders(t *testing.T) {
+	db := openTestDB(t)
+	service := &ConsultationService{DB: db}
+	appointment, _ := createCompletedAppointment(t, db)
+	summary := "Reviewed the lease"
+	record, err := service.SaveRecord(appointment, nil, &summary, nil)
+	if err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+
+	due := models.NewDate(time.Now().AddDate(0, 0, -3))
+	task := &models.FollowUpTask{Title: "Send the notice", DueDate: due}
+	if err := service.AddTask(record, task); err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	if task.Status != models.FollowUpTaskOpen || task.Assignee != models.FollowUpAssigneeLawyer {
+		t.Errorf("new task is %s for %s, want open for the lawyer", task.Status, task.Assignee)
+	}
+	if err := service.AddTask(record, &models.FollowUpTask{Title: " ", DueDate: due}); !errors.Is(err, ErrInvalidFollowUpTask) {
+		t.Errorf("adding a task without a title: error = %v, want ErrInvalidFollowUpTask", err)
+	}
+
+	load := func() models.FollowUpTask {
+		t.Helper()
+		stored, err := service.GetTask(record.ID, task.ID)
+		if err != nil {
+			t.Fatalf("GetTask: %v", err)
+		}
+		return *stored
+	}
+	sent := time.Now().UTC()
+	if err := db.Model(task).Update("reminder_sent_at", sent).Error; err != nil {
+		t.Fatalf("marking reminder: %v", err)
+	}
+
+	done := load()
+	done.Status = models.FollowUpTaskDone
+	if err := service.UpdateTask(&done); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got := load(); got.CompletedAt == nil || got.ReminderSentAt == nil {
+		t.Errorf("completed task: completed at %v, reminded at %v, want both set", got.CompletedAt, got.ReminderSentAt)
+	}
+
+	// Reopening lets the task be reminded about again
+	reopened := load()
+	reopened.Status = models.FollowUpTaskOpen
+	if err := service.UpdateTask(&reopened); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got := load(); got.CompletedAt != nil || got.ReminderSentAt != nil {
+		t.Errorf("reopened task: completed at %v, reminded at %v, want neither", got.CompletedAt, got.ReminderSentAt)
+	}
+
+	// So does moving its due date, but editing its title does not
+	if err := db.Model(task).Update("reminder_sent_at", sent).Error; err != nil {
+		t.Fatalf("marking reminder: %v", err)
+	}
+	renamed := load()
+	renamed.Title = "Send the notice by post"
+	if err := service.UpdateTask(&renamed); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got := load(); got.ReminderSentAt == nil {
+		t.Error("renaming a task cleared its reminder")
+	}
+	moved := load()
+	moved.DueDate = models.NewDate(time.Now().AddDate(0, 0, 7))
+	if err := service.UpdateTask(&moved); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got := load(); got.ReminderSentAt != nil {
+		t.Error("moving a task's due date kept its reminder")
+	}
+}
+
+func countNotifications(t *testing.T, db *gorm.DB, userID int, notificationType string) int64 {
+	t.Helper()
+
+	var count int64
+	if err := db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", userID, notificationType).Count(&count).Error; err != nil {
+		t.Fatalf("counting notifications: %v", err)
+	}
+	return count
+}
+
+func TestSendOverdueTaskReminders(t *testing.T) {
+	db := openTestDB(t)
+	useRepositoryDB(t, db)
+	service := &ConsultationService{DB: db}
+	appointment, lawyer := createCompletedAppointment(t, db)
+	t.Cleanup(func() {
+		db.Where("user_id IN ?", []int{appointment.UserID, lawyer.UserID}).Delete(&models.Notification{})
+	})
+	summary := "Reviewed the lease"
+	record, err := service.SaveRecord(appointment, nil, &summary, nil)
+	if err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+
+	overdue := models.NewDate(time.Now().AddDate(0, 0, -2))
+	for _, task := range []*models.FollowUpTask{
+		{Title: "Send the notice", DueDate: overdue},
+		{Title: "Collect the receipts", DueDate: overdue, Assignee: models.FollowUpAssigneeClient},
+		{Title: "File the claim", DueDate: models.NewDate(time.Now().AddDate(0, 0, 5))},
+	} {
+		if err := service.AddTask(record, task); err != nil {
+			t.Fatalf("AddTask: %v", err)
+		}
+	}
+
+	if err := service.SendOverdueTaskReminders(); err != nil {
+		t.Fatalf("SendOverdueTaskReminders: %v", err)
+	}
+	if got := countNotifications(t, db, lawyer.UserID, NotificationTypeFollowUpOverdue); got != 1 {
+		t.Errorf("lawyer got %d overdue reminders, want 1 for the overdue task", got)
+	}
+	// The client's task is theirs to see only once the record is shared
+	if got := countNotifications(t, db, appointment.UserID, NotificationTypeFollowUpOverdue); got != 0 {
+		t.Errorf("client got %d overdue reminders before the record was shared, want 0", got)
+	}
+
+	if _, err := service.ShareRecord(appointment); err != nil {
+		t.Fatalf("ShareRecord: %v", err)
+	}
+	if err := service.SendOverdueTaskReminders(); err != nil {
+		t.Fatalf("SendOverdueTaskReminders: %v", err)
+	}
+	if got := countNotifications(t, db, appointment.UserID, NotificationTypeFollowUpOverdue); got != 1 {
+		t.Errorf("client got %d overdue reminders after sharing, want 1", got)
+	}
+	if got := countNotifications(t, db, appointment.UserID, NotificationTypeConsultationRecord); got != 1 {
+		t.Errorf("client got %d shared record notifications, want 1", got)
+	}
+	if got := countNotifications(t, db, lawyer.UserID, NotificationTypeFollowUpOverdue); got != 1 {
+		t.Errorf("lawyer got %d overdue reminders after a second run, want still 1", got)
+	}
+}
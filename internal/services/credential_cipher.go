@@ -0,0 +1,73 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// credentialCipherVersion prefixes ciphertexts so the key or algorithm can be
+// changed later without guessing how old values were written
+const credentialCipherVersion = "v1:"
+
+// ErrCredentialsKeyMissing is returned when a credential must be encrypted or
+// decrypted but no key is configured
+var ErrCredentialsKeyMissing = errors.New("credential encryption key is not configured")
+
+// encryptCredential seals a secret with AES-256-GCM under key. The random nonce is
+// stored in front of the ciphertext.
+func encryptCredential(key []byte, plaintext string) (string, error) {
+	if len(key) == 0 {
+		return "", ErrCredentialsKeyMissing
+	}
+	gcm, err := newCredentialGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return credentialCipherVersion + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptCredential opens a value written by encryptCredential
+func decryptCredential(key []byte, ciphertext string) (string, error) {
+	if len(key) == 0 {
+		return "", ErrCredentialsKeyMissing
+	}
+	if !strings.HasPrefix(ciphertext, credentialCipherVersion) {
+		return "", errors.New("unknown credential format")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, credentialCipherVersion))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newCredentialGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("credential ciphertext is too short")
+	}
+	nonce, body := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, body, nil)
+	if err != nil {
+		return "", errors.New("credential cannot be decrypted with the configured key")
+	}
+	return string(plaintext), nil
+}
+
+func newCredentialGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
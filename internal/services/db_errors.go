@@ -0,0 +1,18 @@
+package services
+
+import "errors"
+
+// SQLSTATE codes the services map to their own errors
+const (
+	pgUniqueViolation    = "23505"
+	pgExclusionViolation = "23P01"
+)
+
+// pgErrorCode returns the SQLSTATE of a Postgres error, or "" for any other error
+func pgErrorCode(err error) string {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState()
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kotolino/lawyer/internal/repository"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // registers the postgres:// scheme
+	_ "github.com/golang-migrate/migrate/v4/source/file"       // required for file:// source
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	migrateTestDBOnce sync.Once
+	migrateTestDBErr  error
+)
+
+// openTestDB connects to the database named by TEST_DATABASE_URL, a postgres:// URL,
+// and brings its schema up to date. Tests that need it are skipped when it is unset.
+func openTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	migrateTestDBOnce.Do(func() {
+		m, err := migrate.New("file://../../db/migrations", dsn)
+		if err != nil {
+			migrateTestDBErr = err
+			return
+		}
+		defer m.Close()
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			migrateTestDBErr = err
+		}
+	})
+	if migrateTestDBErr != nil {
+		t.Fatalf("migrating test database: %v", migrateTestDBErr)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	return db
+}
+
+// createTestUser inserts a user that is removed, with everything that cascades from
+// it, when the test ends
+func createTestUser(t testing.TB, db *gorm.DB, role models.UserRole) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		Password:      "not-a-real-hash",
+		Role:          string(role),
+		Email:         fmt.Sprintf("%s-%d@example.com", role, time.Now().UnixNano()),
+		IsActive:      true,
+		EmailVerified: true,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("creating test user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&models.User{}, user.ID)
+	})
+	return user
+}
+
+// createTestLawyer inserts a lawyer profile with its own user
+func createTestLawyer(t testing.TB, db *gorm.DB) *models.Lawyer {
+	t.Helper()
+
+	user := createTestUser(t, db, models.RoleLawyer)
+	lawyer := &models.Lawyer{
+		UserID:         user.ID,
+		FullName:       "Test Lawyer",
+		Email:          user.Email,
+		OfficeName:     "Test Office",
+		Address:        "1-1 Chiyoda",
+		BarAssociation: "Tokyo",
+		Specialties:    models.StringArray{"civil"},
+		BarNumber:      fmt.Sprint(user.ID),
+		Languages:      models.StringArray{"ja"},
+		Timezone:       "Asia/Tokyo",
+	}
+	if err := db.Omit("User").Create(lawyer).Error; err != nil {
+		t.Fatalf("creating test lawyer: %v", err)
+	}
+	return lawyer
+}
+
+// createTestAppointmentAt inserts an appointment between a client and a lawyer that
+// is removed when the test ends
+func createTestAppointmentAt(t testing.TB, db *gorm.DB, userID, lawyerID int, status models.AppointmentStatus, start time.Time, length time.Duration) *models.Appointment {
+	t.Helper()
+
+	appointment := &models.Appointment{
+		UserID:    userID,
+		LawyerID:  lawyerID,
+		StartTime: start,
+		EndTime:   start.Add(length),
+		Status:    status,
+	}
+	if err := db.Omit("User", "Lawyer").Create(appointment).Error; err != nil {
+		t.Fatalf("creating test appointment: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&models.Appointment{}, appointment.ID)
+	})
+	return appointment
+}
+
+// useRepositoryDB points the services that connect through the repository, such as
+// the notifications sent by transition hooks, at the test database
+func useRepositoryDB(t testing.TB, db *gorm.DB) {
+	t.Helper()
+
+	previous := repository.DB
+	repository.DB = db
+	t.Cleanup(func() {
+		repository.DB = previous
+	})
+}
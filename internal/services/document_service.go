@@ -0,0 +1,428 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// AttachmentTypeDocumentVersion is the attachment type of files in a matter's
+// document vault
+const AttachmentTypeDocumentVersion = "DocumentVersion"
+
+// NotificationTypeDocument tells one side of a matter about a shared document the
+// other side added or revised
+const NotificationTypeDocument = "matter_document"
+
+var (
+	ErrFolderNotFound          = errors.New("folder not found")
+	ErrDocumentNotFound        = errors.New("document not found")
+	ErrDocumentVersionNotFound = errors.New("version not found")
+	ErrDocumentCheckedOut      = errors.New("document is checked out by someone else")
+	// ErrInvalidFolder and ErrInvalidDocument are wrapped with the reason the change
+	// was refused
+	ErrInvalidFolder   = errors.New("invalid folder")
+	ErrInvalidDocument = errors.New("invalid document")
+)
+
+// DocumentService manages the folders, documents and versions in matters' vaults
+type DocumentService struct {
+	DB *gorm.DB
+}
+
+// NewDocumentService creates a new document service
+func NewDocumentService() *DocumentService {
+	return &DocumentService{
+		DB: repository.DB,
+	}
+}
+
+// ListFolders lists a matter's folders by name
+func (s *DocumentService) ListFolders(matterID int) ([]models.DocumentFolder, error) {
+	var folders []models.DocumentFolder
+	if err := s.DB.Where("matter_id = ?", matterID).Order("name ASC, id ASC").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// GetFolder returns one of a matter's folders
+func (s *DocumentService) GetFolder(matterID, folderID int) (*models.DocumentFolder, error) {
+	var folder models.DocumentFolder
+	if err := s.DB.Where("id = ? AND matter_id = ?", folderID, matterID).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// SaveFolder creates or renames and moves a folder. Validation errors wrap
+// ErrInvalidFolder.
+func (s *DocumentService) SaveFolder(folder *models.DocumentFolder) error {
+	folder.Name = strings.TrimSpace(folder.Name)
+	switch {
+	case folder.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidFolder)
+	case utf8.RuneCountInString(folder.Name) > 255:
+		return fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidFolder)
+	case strings.ContainsAny(folder.Name, "/\\"):
+		return fmt.Errorf("%w: name cannot contain slashes", ErrInvalidFolder)
+	}
+
+	// Walk up from the new parent to make sure the folder is not moved inside itself
+	for parentID := folder.ParentID; parentID != nil; {
+		if folder.ID != 0 && *parentID == folder.ID {
+			return fmt.Errorf("%w: a folder cannot be moved inside itself", ErrInvalidFolder)
+		}
+		parent, err := s.GetFolder(folder.MatterID, *parentID)
+		if err != nil {
+			if errors.Is(err, ErrFolderNotFound) {
+				return fmt.Errorf("%w: parent folder not found", ErrInvalidFolder)
+			}
+			return err
+		}
+		parentID = parent.ParentID
+	}
+
+	var err error
+	if folder.ID == 0 {
+		err = s.DB.Create(folder).Error
+	} else {
+		err = s.DB.Model(folder).Updates(map[string]interface{}{
+			"name":      folder.Name,
+			"parent_id": folder.ParentID,
+		}).Error
+	}
+	if pgErrorCode(err) == pgUniqueViolation {
+		return fmt.Errorf("%w: a folder with this name already exists here", ErrInvalidFolder)
+	}
+	return err
+}
+
+// DeleteFolder removes an empty folder
+func (s *DocumentService) DeleteFolder(folder *models.DocumentFolder) error {
+	var children, documents int64
+	if err := s.DB.Model(&models.DocumentFolder{}).Where("parent_id = ?", folder.ID).Count(&children).Error; err != nil {
+		return err
+	}
+	if err := s.DB.Model(&models.Document{}).Where("folder_id = ?", folder.ID).Count(&documents).Error; err != nil {
+		return err
+	}
+	if children > 0 || documents > 0 {
+		return fmt.Errorf("%w: only empty folders can be deleted", ErrInvalidFolder)
+	}
+	return s.DB.Delete(folder).Error
+}
+
+// ListDocuments lists a matter's documents by title, only those in a folder when
+// folderID is set and only shared ones for the client
+func (s *DocumentService) ListDocuments(matterID int, folderID *int, forClient bool) ([]models.Document, error) {
+	query := s.DB.Where("matter_id = ?", matterID)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	}
+	if forClient {
+		query = query.Where("visibility = ?", models.DocumentVisibilityShared)
+	}
+	var documents []models.Document
+	if err := query.Order("title ASC, id ASC").Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// GetDocument returns one of a matter's documents with its versions, newest first.
+// Lawyer-only documents are not found for the client.
+func (s *DocumentService) GetDocument(matterID, documentID int, forClient bool) (*models.Document, error) {
+	var document models.Document
+	err := s.DB.Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version DESC")
+	}).Preload("Versions.Attachment").
+		Where("id = ? AND matter_id = ?", documentID, matterID).
+		First(&document).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && forClient && !document.Shared()) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// CreateDocument adds a document to a matter's vault with the uploaded file as its
+// first version. Validation errors wrap ErrInvalidDocument.
+func (s *DocumentService) CreateDocument(matter *models.Matter, document *models.Document, note *string, file *models.Attachment) error {
+	document.MatterID = matter.ID
+	document.CurrentVersion = 1
+	if document.Visibility == "" {
+		document.Visibility = models.DocumentVisibilityLawyerOnly
+	}
+	if err := s.ValidateDocument(matter, document); err != nil {
+		return err
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(document).Error; err != nil {
+			return err
+		}
+		version, err := addDocumentVersion(tx, document, 1, document.CreatedBy, note, file)
+		if err != nil {
+			return err
+		}
+		document.Versions = []models.DocumentVersion{*version}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if document.Shared() {
+		s.notifyOtherParty(matter, document, document.CreatedBy == matter.ClientID, false)
+	}
+	return nil
+}
+
+// UpdateDocument saves a document's title, folder and visibility. Making a document
+// lawyer-only releases the client's check-out of it.
+func (s *DocumentService) UpdateDocument(matter *models.Matter, document *models.Document, wasShared bool) error {
+	if err := s.ValidateDocument(matter, document); err != nil {
+		return err
+	}
+	if !document.Shared() && document.CheckedOutBy != nil && *document.CheckedOutBy == matter.ClientID {
+		document.CheckedOutBy = nil
+		document.CheckedOutAt = nil
+	}
+
+	if err := s.DB.Model(document).Updates(map[string]interface{}{
+		"title":          document.Title,
+		"folder_id":      document.FolderID,
+		"visibility":     document.Visibility,
+		"checked_out_by": document.CheckedOutBy,
+		"checked_out_at": document.CheckedOutAt,
+	}).Error; err != nil {
+		return err
+	}
+
+	if document.Shared() && !wasShared {
+		s.notifyOtherParty(matter, document, false, false)
+	}
+	return nil
+}
+
+// ValidateDocument checks a document's title, visibility and folder, and that its
+// matter is still open. Call it before storing an uploaded file.
+func (s *DocumentService) ValidateDocument(matter *models.Matter, document *models.Document) error {
+	document.Title = strings.TrimSpace(document.Title)
+	switch {
+	case matter.Status == models.MatterStatusClosed:
+		return fmt.Errorf("%w: the matter is closed", ErrInvalidDocument)
+	case document.Title == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidDocument)
+	case utf8.RuneCountInString(document.Title) > 255:
+		return fmt.Errorf("%w: title must be at most 255 characters", ErrInvalidDocument)
+	case document.Visibility != models.DocumentVisibilityLawyerOnly && document.Visibility != models.DocumentVisibilityShared:
+		return fmt.Errorf("%w: visibility must be lawyer_only or shared", ErrInvalidDocument)
+	}
+	if document.FolderID != nil {
+		if _, err := s.GetFolder(matter.ID, *document.FolderID); err != nil {
+			if errors.Is(err, ErrFolderNotFound) {
+				return fmt.Errorf("%w: folder not found", ErrInvalidDocument)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteDocument removes a document from the vault. Its versions are kept.
+func (s *DocumentService) DeleteDocument(document *models.Document) error {
+	return s.DB.Delete(document).Error
+}
+
+// CheckOut locks a document so only the user can upload its next version. Checking
+// out a document the user already holds is a no-op.
+func (s *DocumentService) CheckOut(matter *models.Matter, document *models.Document, userID int) error {
+	if matter.Status == models.MatterStatusClosed {
+		return fmt.Errorf("%w: the matter is closed", ErrInvalidDocument)
+	}
+	now := time.Now().UTC()
+	result := s.DB.Model(&models.Document{}).
+		Where("id = ? AND checked_out_by IS NULL", document.ID).
+		Updates(map[string]interface{}{"checked_out_by": userID, "checked_out_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var current models.Document
+		if err := s.DB.Select("id", "checked_out_by", "checked_out_at").First(&current, document.ID).Error; err != nil {
+			return err
+		}
+		if current.CheckedOutBy == nil || *current.CheckedOutBy != userID {
+			return ErrDocumentCheckedOut
+		}
+		document.CheckedOutBy, document.CheckedOutAt = current.CheckedOutBy, current.CheckedOutAt
+		return nil
+	}
+	document.CheckedOutBy, document.CheckedOutAt = &userID, &now
+	return nil
+}
+
+// CanCheckIn reports whether the user holds the document's check-out. Call it before
+// storing an uploaded file; CheckIn checks again.
+func (s *DocumentService) CanCheckIn(document *models.Document, userID int) error {
+	if document.CheckedOutBy == nil || *document.CheckedOutBy != userID {
+		return fmt.Errorf("%w: check the document out before uploading a new version", ErrInvalidDocument)
+	}
+	return nil
+}
+
+// CheckIn releases the user's check-out of a document. With a file, the file is
+// stored as the document's next version first; without one the check-out is just
+// abandoned.
+func (s *DocumentService) CheckIn(matter *models.Matter, document *models.Document, userID int, note *string, file *models.Attachment) error {
+	if err := s.CanCheckIn(document, userID); err != nil {
+		return err
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"checked_out_by": nil, "checked_out_at": nil}
+		if file != nil {
+			updates["current_version"] = gorm.Expr("current_version + 1")
+		}
+		result := tx.Model(&models.Document{}).
+			Where("id = ? AND checked_out_by = ?", document.ID, userID).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: check the document out before uploading a new version", ErrInvalidDocument)
+		}
+		if file == nil {
+			return nil
+		}
+
+		var number int
+		if err := tx.Model(&models.Document{}).Where("id = ?", document.ID).Pluck("current_version", &number).Error; err != nil {
+			return err
+		}
+		version, err := addDocumentVersion(tx, document, number, userID, note, file)
+		if err != nil {
+			return err
+		}
+		document.CurrentVersion = number
+		document.Versions = append([]models.DocumentVersion{*version}, document.Versions...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	document.CheckedOutBy, document.CheckedOutAt = nil, nil
+	if file != nil && document.Shared() {
+		s.notifyOtherParty(matter, document, userID == matter.ClientID, true)
+	}
+	return nil
+}
+
+// ReleaseCheckOut lets the matter's lawyer unlock a document someone else left
+// checked out
+func (s *DocumentService) ReleaseCheckOut(document *models.Document) error {
+	if err := s.DB.Model(document).Updates(map[string]interface{}{
+		"checked_out_by": nil,
+		"checked_out_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+	document.CheckedOutBy, document.CheckedOutAt = nil, nil
+	return nil
+}
+
+func addDocumentVersion(tx *gorm.DB, document *models.Document, number, userID int, note *string, file *models.Attachment) (*models.DocumentVersion, error) {
+	version := &models.DocumentVersion{
+		DocumentID: document.ID,
+		Version:    number,
+		Note:       trimOptional(note),
+		UploadedBy: userID,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return nil, err
+	}
+	file.AttachmentableType = AttachmentTypeDocumentVersion
+	file.AttachmentableID = version.ID
+	file.UploadedBy = userID
+	if err := tx.Create(file).Error; err != nil {
+		return nil, err
+	}
+	version.Attachment = file
+	return version, nil
+}
+
+// GetVersion returns a version of a document with its file; zero means the current
+// version
+func (s *DocumentService) GetVersion(document *models.Document, number int) (*models.DocumentVersion, error) {
+	if number == 0 {
+		number = document.CurrentVersion
+	}
+	for i := range document.Versions {
+		if document.Versions[i].Version == number && document.Versions[i].Attachment != nil {
+			return &document.Versions[i], nil
+		}
+	}
+	return nil, ErrDocumentVersionNotFound
+}
+
+// RecordDownload logs a download of a document version
+func (s *DocumentService) RecordDownload(download *models.DocumentDownload) error {
+	return s.DB.Create(download).Error
+}
+
+// ListDownloads lists who downloaded a document, newest first
+func (s *DocumentService) ListDownloads(documentID int) ([]models.DocumentDownload, error) {
+	var downloads []models.DocumentDownload
+	err := s.DB.Table("document_downloads").
+		Select("document_downloads.*, document_versions.version").
+		Joins("JOIN document_versions ON document_versions.id = document_downloads.version_id").
+		Where("document_downloads.document_id = ?", documentID).
+		Order("document_downloads.id DESC").
+		Find(&downloads).Error
+	if err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// notifyOtherParty tells the matter's client about a document the lawyer shared or
+// revised, and the lawyer about one from the client
+func (s *DocumentService) notifyOtherParty(matter *models.Matter, document *models.Document, fromClient, revised bool) {
+	recipientID := matter.ClientID
+	if fromClient {
+		var lawyer models.Lawyer
+		if err := s.DB.Select("id", "user_id").First(&lawyer, matter.LawyerID).Error; err != nil {
+			fmt.Printf("Failed to load lawyer for document %d: %v\n", document.ID, err)
+			return
+		}
+		recipientID = lawyer.UserID
+	}
+	content := fmt.Sprintf("案件「%s」に書類「%s」が共有されました", matter.Title, document.Title)
+	if revised {
+		content = fmt.Sprintf("案件「%s」の書類「%s」が更新されました（第%d版）", matter.Title, document.Title, document.CurrentVersion)
+	}
+
+	notification := &models.Notification{
+		UserID:  recipientID,
+		Type:    NotificationTypeDocument,
+		Content: content,
+	}
+	if err := NewNotificationService().CreateNotification(notification); err != nil {
+		fmt.Printf("Failed to create document notification for user %d: %v\n", recipientID, err)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// createTestDocument adds a document with one version to a matter's vault
+func createTestDocument(t *testing.T, db *gorm.DB, matter *models.Matter, title, visibility string, createdBy int) *models.Document {
+	t.Helper()
+
+	document := &models.Document{Title: title, Visibility: visibility, CreatedBy: createdBy}
+	if err := (&DocumentService{DB: db}).CreateDocument(matter, document, nil, testDocumentFile(t, db)); err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	return document
+}
+
+// testDocumentFile returns an unsaved file for a document version, removed when the
+// test ends
+func testDocumentFile(t *testing.T, db *gorm.DB) *models.Attachment {
+	t.Helper()
+
+	file := &models.Attachment{FileName: "lease.pdf", FileSize: 10, FileType: "application/pdf", FilePath: "documents/lease.pdf"}
+	t.Cleanup(func() {
+		if file.ID != 0 {
+			db.Unscoped().Delete(&models.Attachment{}, file.ID)
+		}
+	})
+	return file
+}
+
+func TestDocumentCheckOutAndCheckIn(t *testing.T) {
+	db := openTestDB(t)
+	useRepositoryDB(t, db)
+	matter, lawyer, client := createTestMatter(t, db)
+	t.Cleanup(func() {
+		db.Where("user_id IN ?", []int{client.ID, lawyer.UserID}).Delete(&models.Notification{})
+	})
+	service := &DocumentService{DB: db}
+	document := createTestDocument(t, db, matter, "Lease", models.DocumentVisibilityShared, lawyer.UserID)
+
+	// Each side works on its own copy of the document, as two requests would
+	load := func(forClient bool) *models.Document {
+		t.Helper()
+		loaded, err := service.GetDocument(matter.ID, document.ID, forClient)
+		if err != nil {
+			t.Fatalf("GetDocument: %v", err)
+		}
+		return loaded
+	}
+	lawyerCopy, clientCopy := load(false), load(true)
+
+	if err := service.CheckOut(matter, lawyerCopy, lawyer.UserID); err != nil {
+		t.Fatalf("CheckOut: %v", err)
+	}
+	if err := service.CheckOut(matter, lawyerCopy, lawyer.UserID); err != nil {
+		t.Errorf("checking out a document already held: %v", err)
+	}
+	if err := service.CheckOut(matter, clientCopy, client.ID); !errors.Is(err, ErrDocumentCheckedOut) {
+		t.Errorf("checking out a document held by the lawyer: error = %v, want ErrDocumentCheckedOut", err)
+	}
+	if err := service.CheckIn(matter, clientCopy, client.ID, nil, testDocumentFile(t, db)); !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("checking in without the check-out: error = %v, want ErrInvalidDocument", err)
+	}
+	// A copy that claims the check-out is still checked against the stored document
+	forged := *clientCopy
+	forged.CheckedOutBy = &client.ID
+	if err := service.CheckIn(matter, &forged, client.ID, nil, testDocumentFile(t, db)); !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("checking in a copy claiming the check-out: error = %v, want ErrInvalidDocument", err)
+	}
+
+	note := "Signed copy"
+	if err := service.CheckIn(matter, lawyerCopy, lawyer.UserID, &note, testDocumentFile(t, db)); err != nil {
+		t.Fatalf("CheckIn: %v", err)
+	}
+	stored := load(false)
+	if stored.CurrentVersion != 2 || stored.CheckedOutBy != nil {
+		t.Errorf("after check-in: version %d, checked out by %v, want version 2 and free", stored.CurrentVersion, stored.CheckedOutBy)
+	}
+	if len(stored.Versions) != 2 || stored.Versions[0].Version != 2 || stored.Versions[0].UploadedBy != lawyer.UserID {
+		t.Errorf("versions = %+v, want the lawyer's version 2 first", stored.Versions)
+	}
+	if got := countNotifications(t, db, client.ID, NotificationTypeDocument); got != 2 {
+		t.Errorf("client got %d document notifications, want 2 for sharing and revising", got)
+	}
+
+	// Once released the client can check it out; making it lawyer-only takes it back
+	clientCopy = load(true)
+	if err := service.CheckOut(matter, clientCopy, client.ID); err != nil {
+		t.Fatalf("CheckOut by the client: %v", err)
+	}
+	hidden := load(false)
+	hidden.Visibility = models.DocumentVisibilityLawyerOnly
+	if err := service.UpdateDocument(matter, hidden, true); err != nil {
+		t.Fatalf("UpdateDocument: %v", err)
+	}
+	if err := service.CheckIn(matter, clientCopy, client.ID, nil, testDocumentFile(t, db)); !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("client checking in a document made lawyer-only: error = %v, want ErrInvalidDocument", err)
+	}
+	if stored := load(false); stored.CheckedOutBy != nil || stored.CurrentVersion != 2 {
+		t.Errorf("after hiding: checked out by %v at version %d, want free at version 2", stored.CheckedOutBy, stored.CurrentVersion)
+	}
+
+	matter.Status = models.MatterStatusClosed
+	if err := service.CheckOut(matter, load(false), lawyer.UserID); !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("checking out in a closed matter: error = %v, want ErrInvalidDocument", err)
+	}
+}
+
+func TestDocumentVisibility(t *testing.T) {
+	db := openTestDB(t)
+	useRepositoryDB(t, db)
+	matter, lawyer, client := createTestMatter(t, db)
+	t.Cleanup(func() {
+		db.Where("user_id IN ?", []int{client.ID, lawyer.UserID}).Delete(&models.Notification{})
+	})
+	service := &DocumentService{DB: db}
+
+	folder := &models.DocumentFolder{MatterID: matter.ID, Name: "Court", CreatedBy: lawyer.UserID}
+	if err := service.SaveFolder(folder); err != nil {
+		t.Fatalf("SaveFolder: %v", err)
+	}
+	notes := createTestDocument(t, db, matter, "Case notes", models.DocumentVisibilityLawyerOnly, lawyer.UserID)
+	lease := createTestDocument(t, db, matter, "Lease", models.DocumentVisibilityShared, lawyer.UserID)
+	receipt := createTestDocument(t, db, matter, "Receipt", models.DocumentVisibilityShared, client.ID)
+	filing := &models.Document{Title: "Filing", Visibility: models.DocumentVisibilityShared, FolderID: &folder.ID, CreatedBy: lawyer.UserID}
+	if err := service.CreateDocument(matter, filing, nil, testDocumentFile(t, db)); err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+
+	// A document of another matter never shows up
+	other, otherLawyer, _ := createTestMatter(t, db)
+	createTestDocument(t, db, other, "Other lease", models.DocumentVisibilityShared, otherLawyer.UserID)
+
+	titles := func(folderID *int, forClient bool) []string {
+		t.Helper()
+		documents, err := service.ListDocuments(matter.ID, folderID, forClient)
+		if err != nil {
+			t.Fatalf("ListDocuments: %v", err)
+		}
+		got := make([]string, len(documents))
+		for i, document := range documents {
+			got[i] = document.Title
+		}
+		return got
+	}
+	tests := []struct {
+		name      string
+		folderID  *int
+		forClient bool
+		want      []string
+	}{
+		{name: "lawyer", want: []string{"Case notes", "Filing", "Lease", "Receipt"}},
+		{name: "client", forClient: true, want: []string{"Filing", "Lease", "Receipt"}},
+		{name: "folder", folderID: &folder.ID, forClient: true, want: []string{"Filing"}},
+	}
+	for _, tt := range tests {
+		if got := titles(tt.folderID, tt.forClient); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ListDocuments = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := service.GetDocument(matter.ID, notes.ID, true); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("client getting a lawyer-only document: error = %v, want ErrDocumentNotFound", err)
+	}
+	if _, err := service.GetDocument(matter.ID, notes.ID, false); err != nil {
+		t.Errorf("lawyer getting a lawyer-only document: %v", err)
+	}
+	for _, document := range []*models.Document{lease, receipt} {
+		if _, err := service.GetDocument(matter.ID, document.ID, true); err != nil {
+			t.Errorf("client getting shared document %q: %v", document.Title, err)
+		}
+	}
+	if _, err := service.GetDocument(other.ID, lease.ID, false); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("getting a document through another matter: error = %v, want ErrDocumentNotFound", err)
+	}
+
+	// The lawyer sharing a document makes it visible to the client
+	notes.Visibility = models.DocumentVisibilityShared
+	if err := service.UpdateDocument(matter, notes, false); err != nil {
+		t.Fatalf("UpdateDocument: %v", err)
+	}
+	if _, err := service.GetDocument(matter.ID, notes.ID, true); err != nil {
+		t.Errorf("client getting a document after it was shared: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// calendarInvite is an iCalendar document attached to an appointment email
+type calendarInvite struct {
+	Method string
+	Data   []byte
+}
+
+// appointmentInvite builds the .ics invite for one recipient of an appointment email,
+// with times in the recipient's time zone. The platform is the organizer so every
+// invite of an appointment shares one UID and calendars update the same event.
+func (s *EmailService) appointmentInvite(appointment models.Appointment, method, summary string, recipientName, recipientEmail string, loc *time.Location) *calendarInvite {
+	// Invites are usually sent long before the meeting link may be shown
+	if appointment.MeetingLink != nil && !MeetingLinkVisible(appointment.StartTime, appointment.EndTime, NewMeetingService().MeetingLinkWindow(), time.Now()) {
+		appointment.MeetingLink = nil
+	}
+	event := newAppointmentEvent(appointment, summary)
+	if method == ICSMethodCancel {
+		event.Status = "CANCELLED"
+	}
+	event.Organizer = &icsParty{Name: "べんごしっち", Email: s.Config.FromEmail}
+	event.Attendees = []icsParty{{Name: recipientName, Email: recipientEmail}}
+	return &calendarInvite{
+		Method: method,
+		Data:   buildICalendar(method, "", loc, []icsEvent{event}),
+	}
+}
+
+// appointmentInviteMethod picks the iTIP method for a status email: confirmed
+// appointments are sent as requests, rejected and cancelled ones as cancellations.
+// Other statuses carry no invite.
+func appointmentInviteMethod(status models.AppointmentStatus) string {
+	switch status {
+	case models.AppointmentStatusConfirmed:
+		return ICSMethodRequest
+	case models.AppointmentStatusRejected, models.AppointmentStatusCancelled:
+		return ICSMethodCancel
+	default:
+		return ""
+	}
+}
+
+// composeMessage renders the headers and HTML body of an email. With an invite the
+// message becomes multipart/mixed: the HTML and a text/calendar part as alternatives,
+// so mail clients show accept/decline controls, followed by the same invite as an
+// invite.ics attachment.
+func composeMessage(headers map[string]string, html string, invite *calendarInvite) string {
+	msg := ""
+	if invite == nil {
+		for k, v := range headers {
+			msg += fmt.Sprintf("%s: %s\r\n", k, v)
+		}
+		return msg + "\r\n" + html
+	}
+
+	mixed := randomBoundary("mixed")
+	alternative := randomBoundary("alt")
+	for k, v := range headers {
+		if k == "Content-Type" {
+			continue
+		}
+		msg += fmt.Sprintf("%s: %s\r\n", k, v)
+	}
+	msg += fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\n\r\n", mixed)
+
+	msg += "--" + mixed + "\r\n"
+	msg += fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n\r\n", alternative)
+	msg += "--" + alternative + "\r\n"
+	msg += "Content-Type: text/html; charset=UTF-8\r\n\r\n"
+	msg += html + "\r\n"
+	msg += "--" + alternative + "\r\n"
+	msg += fmt.Sprintf("Content-Type: text/calendar; charset=UTF-8; method=%s\r\n", invite.Method)
+	msg += "Content-Transfer-Encoding: base64\r\n\r\n"
+	msg += base64Lines(invite.Data)
+	msg += "--" + alternative + "--\r\n"
+
+	msg += "--" + mixed + "\r\n"
+	msg += "Content-Type: application/ics; name=\"invite.ics\"\r\n"
+	msg += "Content-Disposition: attachment; filename=\"invite.ics\"\r\n"
+	msg += "Content-Transfer-Encoding: base64\r\n\r\n"
+	msg += base64Lines(invite.Data)
+	msg += "--" + mixed + "--\r\n"
+	return msg
+}
+
+// base64Lines encodes data in 76 character lines as MIME requires
+func base64Lines(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// randomBoundary makes a MIME boundary that cannot occur in the parts it separates
+func randomBoundary(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + "-" + hex.EncodeToString(b)
+}
@@ -263,6 +263,17 @@ func (s *EmailService) SendResetPasswordEmail(user *models.User, token string) e
 	return smtp.SendMail(addr, auth, s.Config.FromEmail, []string{user.Email}, []byte(msg))
 }
 
+// appointmentDisplayTime formats an appointment start as a date and a time in loc.
+// The time names its zone unless it is the default Japan time.
+func appointmentDisplayTime(start time.Time, loc *time.Location) (string, string) {
+	local := start.In(loc)
+	clock := local.Format("15:04")
+	if loc.String() != models.DefaultTimezone {
+		clock = fmt.Sprintf("%s (%s)", clock, loc.String())
+	}
+	return local.Format("2006年01月02日"), clock
+}
+
 // SendAppointmentStatusUpdateEmail sends notification emails when an appointment status is updated by an admin
 func (s *EmailService) SendAppointmentStatusUpdateEmail(recipient models.User, appointment models.Appointment, otherPartyName, updatedStatusName string) error {
 	// noop if email not configured
@@ -296,9 +307,8 @@ func (s *EmailService) SendAppointmentStatusUpdateEmail(recipient models.User, a
 		}
 	}
 
-	// Format appointment date and time for display
-	appointmentDate := appointment.StartTime.Format("2006年01月02日")
-	appointmentTime := appointment.StartTime.Format("15:04")
+	// Format appointment date and time in the recipient's time zone
+	appointmentDate, appointmentTime := appointmentDisplayTime(appointment.StartTime, recipient.Location())
 
 	// Create template data
 	data := struct {
@@ -342,11 +352,13 @@ func (s *EmailService) SendAppointmentStatusUpdateEmail(recipient models.User, a
 		"Content-Type": "text/html; charset=UTF-8",
 	}
 
-	msg := ""
-	for k, v := range headers {
-		msg += fmt.Sprintf("%s: %s\r\n", k, v)
+	// Attach the calendar invite when the appointment was confirmed or called off
+	var invite *calendarInvite
+	if method := appointmentInviteMethod(appointment.Status); method != "" {
+		invite = s.appointmentInvite(appointment, method, appointmentEventSummary(otherPartyName), userName, recipient.Email, recipient.Location())
 	}
-	msg += "\r\n" + body.String()
+
+	msg := composeMessage(headers, body.String(), invite)
 
 	// Send email
 	addr := fmt.Sprintf("%s:%d", s.Config.Host, s.Config.Port)
@@ -363,16 +375,8 @@ func (s *EmailService) SendLawyerAppointmentStatusUpdateEmail(client models.User
 
 	auth := smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
 
-	// Format date/time in JST
-	loc, err := time.LoadLocation("Asia/Tokyo")
-	if err != nil {
-		// Fallback to fixed offset if LoadLocation fails (e.g. timezone data missing)
-		loc = time.FixedZone("JST", 9*60*60)
-	}
-
-	startJST := appointment.StartTime.In(loc)
-	appointmentDate := startJST.Format("2006年01月02日")
-	appointmentTime := startJST.Format("15:04")
+	// Format date/time in the client's time zone
+	appointmentDate, appointmentTime := appointmentDisplayTime(appointment.StartTime, client.Location())
 
 	// Get client nickname or fallback to email
 	clientName := client.Email
@@ -387,7 +391,7 @@ func (s *EmailService) SendLawyerAppointmentStatusUpdateEmail(client models.User
 	}
 
 	// Determine if we should show reject reason based on status
-	showRejectReason := appointment.Status == "rejected" && appointment.RejectReason != nil
+	showRejectReason := appointment.Status == models.AppointmentStatusRejected && appointment.RejectReason != nil
 	rejectReason := ""
 	if showRejectReason && appointment.RejectReason != nil {
 		rejectReason = *appointment.RejectReason
@@ -433,11 +437,13 @@ func (s *EmailService) SendLawyerAppointmentStatusUpdateEmail(client models.User
 		"Content-Type": "text/html; charset=UTF-8",
 	}
 
-	msg := ""
-	for k, v := range headers {
-		msg += fmt.Sprintf("%s: %s\r\n", k, v)
+	// Attach the calendar invite when the appointment was confirmed or called off
+	var invite *calendarInvite
+	if method := appointmentInviteMethod(appointment.Status); method != "" {
+		invite = s.appointmentInvite(appointment, method, appointmentEventSummary(lawyerName), clientName, client.Email, client.Location())
 	}
-	msg += "\r\n" + body.String()
+
+	msg := composeMessage(headers, body.String(), invite)
 
 	// Send email
 	addr := fmt.Sprintf("%s:%d", s.Config.Host, s.Config.Port)
@@ -609,16 +615,8 @@ func (s *EmailService) SendNewAppointmentEmail(lawyer models.Lawyer, appointment
 
 	auth := smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
 
-	// Format date/time in JST
-	loc, err := time.LoadLocation("Asia/Tokyo")
-	if err != nil {
-		// Fallback to fixed offset if LoadLocation fails (e.g. timezone data missing)
-		loc = time.FixedZone("JST", 9*60*60)
-	}
-
-	startJST := appointment.StartTime.In(loc)
-	appointmentDate := startJST.Format("2006年01月02日")
-	appointmentTime := startJST.Format("15:04")
+	// Format date/time in the lawyer's time zone
+	appointmentDate, appointmentTime := appointmentDisplayTime(appointment.StartTime, lawyer.Location())
 
 	lawyerName := lawyer.FullName
 
@@ -670,11 +668,10 @@ func (s *EmailService) SendNewAppointmentEmail(lawyer models.Lawyer, appointment
 		"Content-Type": "text/html; charset=UTF-8",
 	}
 
-	msg := ""
-	for k, v := range headers {
-		msg += fmt.Sprintf("%s: %s\r\n", k, v)
-	}
-	msg += "\r\n" + body.String()
+	// The pending appointment goes into the lawyer's calendar as tentative
+	invite := s.appointmentInvite(appointment, ICSMethodRequest, appointmentEventSummary(clientNickname), lawyerName, lawyer.User.Email, lawyer.Location())
+
+	msg := composeMessage(headers, body.String(), invite)
 
 	addr := fmt.Sprintf("%s:%d", s.Config.Host, s.Config.Port)
 	return smtp.SendMail(addr, auth, s.Config.FromEmail, []string{lawyer.User.Email}, []byte(msg))
@@ -690,16 +687,8 @@ func (s *EmailService) SendAppointmentCancelledEmail(lawyer models.Lawyer, appoi
 
 	auth := smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
 
-	// Format date/time in JST
-	loc, err := time.LoadLocation("Asia/Tokyo")
-	if err != nil {
-		// Fallback to fixed offset if LoadLocation fails (e.g. timezone data missing)
-		loc = time.FixedZone("JST", 9*60*60)
-	}
-
-	startJST := appointment.StartTime.In(loc)
-	appointmentDate := startJST.Format("2006年01月02日")
-	appointmentTime := startJST.Format("15:04")
+	// Format date/time in the lawyer's time zone
+	appointmentDate, appointmentTime := appointmentDisplayTime(appointment.StartTime, lawyer.Location())
 
 	lawyerName := lawyer.FullName
 
@@ -737,11 +726,10 @@ func (s *EmailService) SendAppointmentCancelledEmail(lawyer models.Lawyer, appoi
 		"Content-Type": "text/html; charset=UTF-8",
 	}
 
-	msg := ""
-	for k, v := range headers {
-		msg += fmt.Sprintf("%s: %s\r\n", k, v)
-	}
-	msg += "\r\n" + body.String()
+	// Remove the appointment from the lawyer's calendar
+	invite := s.appointmentInvite(appointment, ICSMethodCancel, appointmentEventSummary(clientNickname), lawyerName, lawyer.User.Email, lawyer.Location())
+
+	msg := composeMessage(headers, body.String(), invite)
 
 	addr := fmt.Sprintf("%s:%d", s.Config.Host, s.Config.Port)
 	return smtp.SendMail(addr, auth, s.Config.FromEmail, []string{lawyer.User.Email}, []byte(msg))
@@ -755,22 +743,22 @@ func (s *EmailService) SendLawyerVerificationNotificationEmail(lawyer models.Law
 		return nil
 	}
 
-	// Get admin emails from the database
+	// Get the emails of staff who can verify lawyers from the database
 	db := repository.GetDB()
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	// Query all users with admin role
-	var adminUsers []models.User
-	if err := db.Where("role = ?", "admin").Find(&adminUsers).Error; err != nil {
-		return fmt.Errorf("error retrieving admin users: %w", err)
+	// Query all users who hold the lawyers.verify permission
+	verifiers, err := (&PermissionService{DB: db}).UsersWithPermission(models.PermLawyersVerify)
+	if err != nil {
+		return fmt.Errorf("error retrieving lawyer verifiers: %w", err)
 	}
 
 	// Extract emails
-	adminEmails := make([]string, 0, len(adminUsers))
-	for _, admin := range adminUsers {
-		adminEmails = append(adminEmails, admin.Email)
+	adminEmails := make([]string, 0, len(verifiers))
+	for _, verifier := range verifiers {
+		adminEmails = append(adminEmails, verifier.Email)
 	}
 
 	// If no admin emails found, we can't send notifications
@@ -847,8 +835,6 @@ func (s *EmailService) SendLawyerVerificationSuccessEmail(lawyer models.Lawyer)
 		return fmt.Errorf("lawyer user email is empty - User relation may not be loaded")
 	}
 
-	fmt.Printf(lawyer.User.Email)
-
 	auth := smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
 
 	// Prepare lawyer information - prefer full name if available, fall back to email
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxCalendarResponseBytes bounds how much of a calendar server's response is read
+const maxCalendarResponseBytes = 10 << 20
+
+var (
+	// ErrInvalidCalendar is wrapped with the reason a calendar was refused
+	ErrInvalidCalendar          = errors.New("invalid calendar")
+	ErrExternalCalendarNotFound = errors.New("calendar not found")
+	// ErrCalendarSyncFailed is wrapped with the error the calendar server caused
+	ErrCalendarSyncFailed = errors.New("calendar sync failed")
+)
+
+// ExternalCalendarService imports busy time from lawyers' external calendars
+type ExternalCalendarService struct {
+	DB     *gorm.DB
+	Config config.CalendarSyncConfig
+	// Client fetches calendars; it refuses private addresses unless the config
+	// allows them
+	Client *http.Client
+}
+
+// NewExternalCalendarService creates a new external calendar service
+func NewExternalCalendarService() *ExternalCalendarService {
+	cfg := config.CalendarSyncConfig{Interval: 15 * time.Minute, Timeout: 20 * time.Second}
+	if appCfg := GetConfig(); appCfg != nil {
+		cfg = appCfg.CalendarSync
+	}
+	return &ExternalCalendarService{
+		DB:     repository.DB,
+		Config: cfg,
+		Client: newCalendarHTTPClient(cfg),
+	}
+}
+
+// newCalendarHTTPClient builds the client used to fetch calendars. Addresses are
+// checked when each connection is made, so neither redirects nor DNS answers that
+// change after validation can reach internal services. Redirects must stay on
+// https, as calendar URLs must, so credentials are never sent in the clear.
+func newCalendarHTTPClient(cfg config.CalendarSyncConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if cfg.AllowPrivateHosts {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateAddress(ip) {
+				return fmt.Errorf("calendar host %s is on a private network", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			// Plain http is only allowed for local development servers, and never
+			// as a downgrade from https
+			if req.URL.Scheme != "https" && (!cfg.AllowPrivateHosts || via[0].URL.Scheme == "https") {
+				return fmt.Errorf("calendar server redirected to a non-https url %s", req.URL.Redacted())
+			}
+			return nil
+		},
+	}
+}
+
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// validateCalendar checks the calendar's name, kind and URL
+func (s *ExternalCalendarService) validateCalendar(calendar *models.ExternalCalendar) error {
+	calendar.Name = strings.TrimSpace(calendar.Name)
+	calendar.URL = strings.TrimSpace(calendar.URL)
+	if calendar.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCalendar)
+	}
+	if calendar.Kind != models.ExternalCalendarICS && calendar.Kind != models.ExternalCalendarCalDAV {
+		return fmt.Errorf("%w: kind must be ics or caldav", ErrInvalidCalendar)
+	}
+
+	// webcal:// is how calendar apps link ICS subscriptions; it is plain HTTPS
+	if strings.HasPrefix(calendar.URL, "webcal://") {
+		calendar.URL = "https://" + strings.TrimPrefix(calendar.URL, "webcal://")
+	}
+	parsed, err := url.Parse(calendar.URL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%w: url is not valid", ErrInvalidCalendar)
+	}
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && s.Config.AllowPrivateHosts) {
+		return fmt.Errorf("%w: url must use https", ErrInvalidCalendar)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: put the username and password in their own fields, not the url", ErrInvalidCalendar)
+	}
+	return nil
+}
+
+// setPassword encrypts a new password for the calendar. An empty password removes
+// the stored one.
+func (s *ExternalCalendarService) setPassword(calendar *models.ExternalCalendar, password string) error {
+	if password == "" {
+		calendar.EncryptedPassword = nil
+		return nil
+	}
+	encrypted, err := encryptCredential(s.Config.CredentialsKey, password)
+	if errors.Is(err, ErrCredentialsKeyMissing) {
+		return fmt.Errorf("%w: calendars with a password cannot be added because credential encryption is not configured", ErrInvalidCalendar)
+	}
+	if err != nil {
+		return err
+	}
+	calendar.EncryptedPassword = &encrypted
+	return nil
+}
+
+func withPasswordFlag(calendar *models.ExternalCalendar) {
+	calendar.HasPassword = calendar.EncryptedPassword != nil && *calendar.EncryptedPassword != ""
+}
+
+// ListCalendars returns a lawyer's external calendars
+func (s *ExternalCalendarService) ListCalendars(lawyerID int) ([]models.ExternalCalendar, error) {
+	var calendars []models.ExternalCalendar
+	if err := s.DB.Where("lawyer_id = ?", lawyerID).Order("id ASC").Find(&calendars).Error; err != nil {
+		return nil, err
+	}
+	for i := range calendars {
+		withPasswordFlag(&calendars[i])
+	}
+	return calendars, nil
+}
+
+// GetCalendar returns one of a lawyer's external calendars
+func (s *ExternalCalendarService) GetCalendar(lawyerID, calendarID int) (*models.ExternalCalendar, error) {
+	var calendar models.ExternalCalendar
+	if err := s.DB.Where("id = ? AND lawyer_id = ?", calendarID, lawyerID).First(&calendar).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExternalCalendarNotFound
+		}
+		return nil, err
+	}
+	withPasswordFlag(&calendar)
+	return &calendar, nil
+}
+
+// CreateCalendar adds an external calendar. password may be nil for calendars that
+// need none.
+func (s *ExternalCalendarService) CreateCalendar(calendar *models.ExternalCalendar, password *string) error {
+	if err := s.validateCalendar(calendar); err != nil {
+		return err
+	}
+	if password != nil {
+		if err := s.setPassword(calendar, *password); err != nil {
+			return err
+		}
+	}
+	if err := s.DB.Create(calendar).Error; err != nil {
+		return err
+	}
+	withPasswordFlag(calendar)
+	return nil
+}
+
+// UpdateCalendar saves changes to an external calendar. A nil password keeps the
+// stored one. Disabling a calendar drops its busy time at once.
+func (s *ExternalCalendarService) UpdateCalendar(calendar *models.ExternalCalendar, password *string) error {
+	if err := s.validateCalendar(calendar); err != nil {
+		return err
+	}
+	if password != nil {
+		if err := s.setPassword(calendar, *password); err != nil {
+			return err
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(calendar).Updates(map[string]interface{}{
+			"name":               calendar.Name,
+			"kind":               calendar.Kind,
+			"url":                calendar.URL,
+			"username":           calendar.Username,
+			"encrypted_password": calendar.EncryptedPassword,
+			"enabled":            calendar.Enabled,
+		}).Error; err != nil {
+			return err
+		}
+		if !calendar.Enabled {
+			return tx.Where("calendar_id = ?", calendar.ID).Delete(&models.BusyBlock{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	withPasswordFlag(calendar)
+	return nil
+}
+
+// DeleteCalendar removes an external calendar and its busy time
+func (s *ExternalCalendarService) DeleteCalendar(lawyerID, calendarID int) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", calendarID).Delete(&models.BusyBlock{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND lawyer_id = ?", calendarID, lawyerID).Delete(&models.ExternalCalendar{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrExternalCalendarNotFound
+		}
+		return nil
+	})
+}
+
+// GetBusyBlocks returns the imported busy time of a calendar, earliest first
+func (s *ExternalCalendarService) GetBusyBlocks(calendarID int) ([]models.BusyBlock, error) {
+	var blocks []models.BusyBlock
+	err := s.DB.Where("calendar_id = ?", calendarID).Order("start_time ASC").Find(&blocks).Error
+	return blocks, err
+}
+
+// SyncCalendar fetches a calendar and replaces its busy time from a day ago up to
+// the lawyer's booking horizon. When the fetch fails the busy time from the last
+// successful sync is kept, since dropping it would open the lawyer's court dates
+// to bookings; the error is recorded on the calendar.
+func (s *ExternalCalendarService) SyncCalendar(calendar *models.ExternalCalendar) error {
+	var lawyer models.Lawyer
+	if err := s.DB.First(&lawyer, calendar.LawyerID).Error; err != nil {
+		return fmt.Errorf("failed to fetch lawyer: %w", err)
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -1)
+	to := now.AddDate(0, 0, lawyer.EffectiveBookingSettings().MaxAdvanceDays+2)
+
+	periods, syncErr := s.fetchBusyPeriods(calendar, lawyer.Location(), from, to)
+	calendar.LastAttemptAt = &now
+	if syncErr != nil {
+		message := syncErr.Error()
+		calendar.LastSyncError = &message
+		if err := s.DB.Model(calendar).Updates(map[string]interface{}{
+			"last_attempt_at": now,
+			"last_sync_error": message,
+		}).Error; err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrCalendarSyncFailed, syncErr)
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", calendar.ID).Delete(&models.BusyBlock{}).Error; err != nil {
+			return err
+		}
+		if len(periods) > 0 {
+			blocks := make([]models.BusyBlock, 0, len(periods))
+			for _, period := range periods {
+				blocks = append(blocks, models.BusyBlock{
+					CalendarID: calendar.ID,
+					LawyerID:   calendar.LawyerID,
+					StartTime:  period.Start,
+					EndTime:    period.End,
+				})
+			}
+			if err := tx.CreateInBatches(blocks, 500).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(calendar).Updates(map[string]interface{}{
+			"last_synced_at":  now,
+			"last_attempt_at": now,
+			"last_sync_error": nil,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	calendar.LastSyncedAt = &now
+	calendar.LastSyncError = nil
+	return nil
+}
+
+// SyncDueCalendars syncs every enabled calendar not tried within the sync interval.
+// It is meant to run periodically alongside the other scheduled appointment jobs.
+func (s *ExternalCalendarService) SyncDueCalendars() error {
+	var calendars []models.ExternalCalendar
+	cutoff := time.Now().Add(-s.Config.Interval)
+	if err := s.DB.Where("enabled = ? AND (last_attempt_at IS NULL OR last_attempt_at <= ?)", true, cutoff).
+		Order("last_attempt_at ASC NULLS FIRST").
+		Find(&calendars).Error; err != nil {
+		return err
+	}
+
+	for i := range calendars {
+		if err := s.SyncCalendar(&calendars[i]); err != nil {
+			fmt.Printf("Failed to sync external calendar %d of lawyer %d: %v\n", calendars[i].ID, calendars[i].LawyerID, err)
+		}
+	}
+	return nil
+}
+
+// fetchBusyPeriods reads the busy time of a calendar between from and to
+func (s *ExternalCalendarService) fetchBusyPeriods(calendar *models.ExternalCalendar, loc *time.Location, from, to time.Time) ([]busyPeriod, error) {
+	password := ""
+	if calendar.EncryptedPassword != nil && *calendar.EncryptedPassword != "" {
+		var err error
+		if password, err = decryptCredential(s.Config.CredentialsKey, *calendar.EncryptedPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if calendar.Kind == models.ExternalCalendarICS {
+		data, _, err := s.request(calendar, password, http.MethodGet, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		return parseBusyPeriods(data, loc, from, to)
+	}
+	return s.fetchCalDAVBusyPeriods(calendar, password, loc, from, to)
+}
+
+// fetchCalDAVBusyPeriods asks a CalDAV collection for its free/busy time (RFC 4791
+// section 7.10). Servers that do not support free-busy-query are asked for their
+// events in the range instead, expanded by the server.
+func (s *ExternalCalendarService) fetchCalDAVBusyPeriods(calendar *models.ExternalCalendar, password string, loc *time.Location, from, to time.Time) ([]busyPeriod, error) {
+	rangeStart := from.UTC().Format(icsUTCLayout)
+	rangeEnd := to.UTC().Format(icsUTCLayout)
+
+	freeBusyQuery := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<C:free-busy-query xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <C:time-range start="%s" end="%s"/>
+</C:free-busy-query>`, rangeStart, rangeEnd)
+	data, status, err := s.request(calendar, password, "REPORT", "1", []byte(freeBusyQuery))
+	if err == nil {
+		return parseBusyPeriods(data, loc, from, to)
+	}
+	if status != http.StatusForbidden && status != http.StatusNotImplemented &&
+		status != http.StatusBadRequest && status != http.StatusMethodNotAllowed {
+		return nil, err
+	}
+
+	calendarQuery := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data>
+      <C:expand start="%[1]s" end="%[2]s"/>
+    </C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="%[1]s" end="%[2]s"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`, rangeStart, rangeEnd)
+	data, _, err = s.request(calendar, password, "REPORT", "1", []byte(calendarQuery))
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := calendarDataFromMultistatus(data)
+	if err != nil {
+		return nil, err
+	}
+	var periods []busyPeriod
+	for _, document := range documents {
+		documentPeriods, err := parseBusyPeriods(document, loc, from, to)
+		if err != nil {
+			continue
+		}
+		periods = append(periods, documentPeriods...)
+	}
+	return mergeBusyPeriods(periods, from, to), nil
+}
+
+// request sends one request to the calendar server and returns the body of a
+// successful response along with the status code
+func (s *ExternalCalendarService) request(calendar *models.ExternalCalendar, password, method, depth string, body []byte) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.Timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, calendar.URL, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", "kotolino-lawyer-calendar-sync/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		req.Header.Set("Depth", depth)
+	} else {
+		req.Header.Set("Accept", "text/calendar")
+	}
+	if calendar.Username != nil && *calendar.Username != "" {
+		req.SetBasicAuth(*calendar.Username, password)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not reach the calendar server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCalendarResponseBytes+1))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("could not read the calendar: %w", err)
+	}
+	if len(data) > maxCalendarResponseBytes {
+		return nil, resp.StatusCode, errors.New("the calendar is too large to import")
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, resp.StatusCode, errors.New("the calendar server rejected the username or password")
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, resp.StatusCode, fmt.Errorf("the calendar server answered %s", resp.Status)
+	}
+	return data, resp.StatusCode, nil
+}
+
+// calendarDataFromMultistatus extracts every calendar-data document from a CalDAV
+// multistatus response
+func calendarDataFromMultistatus(data []byte) ([][]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var documents [][]byte
+	inCalendarData := false
+	var current bytes.Buffer
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read the CalDAV response: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "calendar-data" {
+				inCalendarData = true
+				current.Reset()
+			}
+		case xml.CharData:
+			if inCalendarData {
+				current.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "calendar-data" && inCalendarData {
+				inCalendarData = false
+				documents = append(documents, append([]byte(nil), current.Bytes()...))
+			}
+		}
+	}
+	return documents, nil
+}
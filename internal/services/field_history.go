@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// History struct tag values. Every persisted field of a model is tracked unless it is
+// tagged history:"-". Fields tagged history:"norestore" are recorded but never rolled back.
+const (
+	historyTag       = "history"
+	historySkip      = "-"
+	historyNoRestore = "norestore"
+)
+
+// trackedField is a struct field whose changes are recorded in history
+type trackedField struct {
+	Index      int
+	Name       string
+	Restorable bool
+}
+
+// trackedFields lists the fields of a struct type that history tracks
+func trackedFields(t reflect.Type) []trackedField {
+	fields := make([]trackedField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get(historyTag)
+		if tag == historySkip || field.Tag.Get("gorm") == "-" || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fields = append(fields, trackedField{
+			Index:      i,
+			Name:       field.Name,
+			Restorable: tag != historyNoRestore,
+		})
+	}
+	return fields
+}
+
+// diffTrackedFields compares two values of the same struct type and returns a change for
+// every tracked field that differs
+func diffTrackedFields(oldValue, newValue interface{}) []models.FieldChange {
+	oldVal := reflect.Indirect(reflect.ValueOf(oldValue))
+	newVal := reflect.Indirect(reflect.ValueOf(newValue))
+
+	changes := []models.FieldChange{}
+	for _, field := range trackedFields(oldVal.Type()) {
+		oldField := oldVal.Field(field.Index).Interface()
+		newField := newVal.Field(field.Index).Interface()
+		if !reflect.DeepEqual(oldField, newField) {
+			changes = append(changes, models.FieldChange{
+				Field: field.Name,
+				Old:   oldField,
+				New:   newField,
+			})
+		}
+	}
+	return changes
+}
+
+// setFieldFromHistory assigns a value recorded in history to a field. Recorded values have
+// been through JSON, so they are decoded back into the field's own type. It reports false
+// when the field no longer exists or must not be restored.
+func setFieldFromHistory(target reflect.Value, name string, value interface{}) (bool, error) {
+	var field *trackedField
+	for _, f := range trackedFields(target.Type()) {
+		if f.Name == name {
+			f := f
+			field = &f
+			break
+		}
+	}
+	if field == nil || !field.Restorable {
+		return false, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	dest := target.Field(field.Index)
+	decoded := reflect.New(dest.Type())
+	if err := json.Unmarshal(data, decoded.Interface()); err != nil {
+		return false, fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	dest.Set(decoded.Elem())
+	return true, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Kinds of calendar closures
+const (
+	ClosureTypeNationalHoliday = "national_holiday"
+	ClosureTypePlatform        = "platform_closure"
+)
+
+// CalendarClosure is a day off in the platform calendar
+type CalendarClosure struct {
+	Date models.Date `json:"date"`
+	Name string      `json:"name"`
+	Type string      `json:"type"`
+}
+
+var (
+	ErrPlatformClosureNotFound = errors.New("closure not found")
+	ErrPlatformClosureExists   = errors.New("closure already exists for this date")
+)
+
+// HolidayService combines the national holiday calendar with admin-managed
+// platform closures
+type HolidayService struct {
+	DB *gorm.DB
+}
+
+// NewHolidayService creates a new holiday service
+func NewHolidayService() *HolidayService {
+	return &HolidayService{
+		DB: repository.DB,
+	}
+}
+
+// GetCalendar lists national holidays and platform closures between two dates,
+// inclusive, in date order
+func (s *HolidayService) GetCalendar(from, to models.Date) ([]CalendarClosure, error) {
+	closures := []CalendarClosure{}
+	for year := from.Year(); year <= to.Year(); year++ {
+		for _, holiday := range JapaneseHolidays(year) {
+			if holiday.Date.Before(from.Time) || holiday.Date.After(to.Time) {
+				continue
+			}
+			closures = append(closures, CalendarClosure{
+				Date: holiday.Date,
+				Name: holiday.Name,
+				Type: ClosureTypeNationalHoliday,
+			})
+		}
+	}
+
+	platformClosures, err := s.GetPlatformClosures(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, closure := range platformClosures {
+		closures = append(closures, CalendarClosure{
+			Date: closure.Date,
+			Name: closure.Reason,
+			Type: ClosureTypePlatform,
+		})
+	}
+
+	sort.SliceStable(closures, func(i, j int) bool { return closures[i].Date.Before(closures[j].Date.Time) })
+	return closures, nil
+}
+
+// GetPlatformClosures lists platform closures between two dates, inclusive
+func (s *HolidayService) GetPlatformClosures(from, to models.Date) ([]models.PlatformClosure, error) {
+	var closures []models.PlatformClosure
+	err := s.DB.Where("date >= ? AND date <= ?", from, to).Order("date ASC").Find(&closures).Error
+	return closures, err
+}
+
+// GetPlatformClosureByID returns a platform closure
+func (s *HolidayService) GetPlatformClosureByID(id int) (*models.PlatformClosure, error) {
+	var closure models.PlatformClosure
+	if err := s.DB.First(&closure, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlatformClosureNotFound
+		}
+		return nil, err
+	}
+	return &closure, nil
+}
+
+// CreatePlatformClosure closes the platform on a date
+func (s *HolidayService) CreatePlatformClosure(closure *models.PlatformClosure) error {
+	err := s.DB.Create(closure).Error
+	if pgErrorCode(err) == pgUniqueViolation {
+		return ErrPlatformClosureExists
+	}
+	return err
+}
+
+// DeletePlatformClosure reopens the platform on a closed date
+func (s *HolidayService) DeletePlatformClosure(id int) error {
+	result := s.DB.Delete(&models.PlatformClosure{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPlatformClosureNotFound
+	}
+	return nil
+}
+
+// PlatformClosureOn returns the platform closure on a date, or nil if the platform is open
+func (s *HolidayService) PlatformClosureOn(date models.Date) (*models.PlatformClosure, error) {
+	var closures []models.PlatformClosure
+	if err := s.DB.Where("date = ?", date).Limit(1).Find(&closures).Error; err != nil {
+		return nil, err
+	}
+	if len(closures) == 0 {
+		return nil, nil
+	}
+	return &closures[0], nil
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// iCalendar (RFC 5545) documents for appointment invites and subscription feeds
+
+// iTIP methods (RFC 5546) used for appointment invites
+const (
+	ICSMethodRequest = "REQUEST"
+	ICSMethodCancel  = "CANCEL"
+)
+
+const (
+	icsProductID  = "-//kotolino//lawyer//JA"
+	icsUTCLayout  = "20060102T150405Z"
+	icsDateLayout = "20060102T150405"
+	icsLineLimit  = 75
+)
+
+// icsParty is an organizer or attendee of an event
+type icsParty struct {
+	Name  string
+	Email string
+}
+
+// icsEvent is one VEVENT
+type icsEvent struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Status      string
+	Organizer   *icsParty
+	Attendees   []icsParty
+	Updated     time.Time
+}
+
+// appointmentEventUID is the UID shared by every invite and feed entry of an appointment
+func appointmentEventUID(appointmentID int) string {
+	return fmt.Sprintf("appointment-%d@kotolino-lawyer", appointmentID)
+}
+
+// appointmentEventSummary titles an appointment after the other party
+func appointmentEventSummary(otherPartyName string) string {
+	return fmt.Sprintf("法律相談: %s", otherPartyName)
+}
+
+// appointmentEventStatus maps an appointment status to a VEVENT STATUS
+func appointmentEventStatus(status models.AppointmentStatus) string {
+	switch status {
+	case models.AppointmentStatusPending:
+		return "TENTATIVE"
+	case models.AppointmentStatusRejected, models.AppointmentStatusCancelled:
+		return "CANCELLED"
+	default:
+		return "CONFIRMED"
+	}
+}
+
+// newAppointmentEvent builds the VEVENT of an appointment. summary names the
+// appointment from the point of view of the calendar's owner.
+func newAppointmentEvent(appointment models.Appointment, summary string) icsEvent {
+	description := ""
+	if appointment.Description != nil {
+		description = *appointment.Description
+	}
+	if appointment.MeetingLink != nil && *appointment.MeetingLink != "" {
+		description = strings.TrimSpace(description + "\n\n" + *appointment.MeetingLink)
+	}
+	return icsEvent{
+		UID:         appointmentEventUID(appointment.ID),
+		Sequence:    appointment.CalendarSequence,
+		Start:       appointment.StartTime,
+		End:         appointment.EndTime,
+		Summary:     summary,
+		Description: description,
+		Status:      appointmentEventStatus(appointment.Status),
+		Updated:     appointment.UpdatedAt,
+	}
+}
+
+// buildICalendar renders a VCALENDAR with events expressed in loc. method is empty
+// for subscription feeds.
+func buildICalendar(method, name string, loc *time.Location, events []icsEvent) []byte {
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", icsProductID)
+	w.line("CALSCALE", "GREGORIAN")
+	if method != "" {
+		w.line("METHOD", method)
+	}
+	if name != "" {
+		w.line("X-WR-CALNAME", icsText(name))
+		w.line("X-WR-TIMEZONE", loc.String())
+	}
+
+	if len(events) > 0 {
+		from, to := events[0].Start, events[0].End
+		for _, event := range events {
+			if event.Start.Before(from) {
+				from = event.Start
+			}
+			if event.End.After(to) {
+				to = event.End
+			}
+		}
+		writeVTimezone(w, loc, from, to)
+	}
+
+	stamp := time.Now().UTC().Format(icsUTCLayout)
+	for _, event := range events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", event.UID)
+		w.line("DTSTAMP", stamp)
+		w.line("SEQUENCE", fmt.Sprint(event.Sequence))
+		w.line("DTSTART;TZID="+loc.String(), event.Start.In(loc).Format(icsDateLayout))
+		w.line("DTEND;TZID="+loc.String(), event.End.In(loc).Format(icsDateLayout))
+		w.line("SUMMARY", icsText(event.Summary))
+		if event.Description != "" {
+			w.line("DESCRIPTION", icsText(event.Description))
+		}
+		w.line("STATUS", event.Status)
+		if !event.Updated.IsZero() {
+			w.line("LAST-MODIFIED", event.Updated.UTC().Format(icsUTCLayout))
+		}
+		if event.Organizer != nil {
+			w.line("ORGANIZER;CN="+icsParam(event.Organizer.Name), "mailto:"+event.Organizer.Email)
+		}
+		for _, attendee := range event.Attendees {
+			w.line("ATTENDEE;CN="+icsParam(attendee.Name)+";ROLE=REQ-PARTICIPANT", "mailto:"+attendee.Email)
+		}
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+	return []byte(w.b.String())
+}
+
+// icsWriter writes content lines folded at 75 octets and ended with CRLF
+type icsWriter struct {
+	b strings.Builder
+}
+
+func (w *icsWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := icsLineLimit
+	for len(content) > limit {
+		// Never split a multi-byte character
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.b.WriteString(content[:cut])
+		w.b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = icsLineLimit - 1
+	}
+	w.b.WriteString(content)
+	w.b.WriteString("\r\n")
+}
+
+// icsText escapes a TEXT value
+func icsText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ";", "\\;")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "\r\n", "\\n")
+	return strings.ReplaceAll(s, "\n", "\\n")
+}
+
+// icsParam quotes a parameter value
+func icsParam(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+// zoneTransition is a change of UTC offset in a time zone
+type zoneTransition struct {
+	At         time.Time
+	FromOffset int
+	ToOffset   int
+	Name       string
+	DST        bool
+}
+
+// zoneTransitions finds the offset changes of loc between two instants
+func zoneTransitions(loc *time.Location, from, to time.Time) []zoneTransition {
+	var transitions []zoneTransition
+	const step = 24 * time.Hour
+	for t := from; t.Before(to); t = t.Add(step) {
+		_, before := t.In(loc).Zone()
+		_, after := t.Add(step).In(loc).Zone()
+		if before == after {
+			continue
+		}
+		// Narrow the change down to the second
+		lo, hi := t, t.Add(step)
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, offset := mid.In(loc).Zone(); offset == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		name, offset := hi.In(loc).Zone()
+		transitions = append(transitions, zoneTransition{
+			At:         hi,
+			FromOffset: before,
+			ToOffset:   offset,
+			Name:       name,
+			DST:        hi.In(loc).IsDST(),
+		})
+	}
+	return transitions
+}
+
+// writeVTimezone describes loc from a year before from until a year after to. Each
+// offset change is written as its own observance, taken from the Go zone database.
+func writeVTimezone(w *icsWriter, loc *time.Location, from, to time.Time) {
+	start := from.AddDate(-1, 0, 0)
+	end := to.AddDate(1, 0, 0)
+
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", loc.String())
+
+	// The offset in effect before the first change; zones without daylight saving
+	// time only have this one
+	name, offset := start.In(loc).Zone()
+	kind := "STANDARD"
+	if start.In(loc).IsDST() {
+		kind = "DAYLIGHT"
+	}
+	writeObservance(w, kind, "19700101T000000", offset, offset, name)
+
+	transitions := zoneTransitions(loc, start, end)
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].At.Before(transitions[j].At) })
+	for _, transition := range transitions {
+		kind := "STANDARD"
+		if transition.DST {
+			kind = "DAYLIGHT"
+		}
+		// DTSTART is the local time of the change in the offset being left
+		onset := transition.At.UTC().Add(time.Duration(transition.FromOffset) * time.Second).Format(icsDateLayout)
+		writeObservance(w, kind, onset, transition.FromOffset, transition.ToOffset, transition.Name)
+	}
+
+	w.line("END", "VTIMEZONE")
+}
+
+func writeObservance(w *icsWriter, kind, onset string, fromOffset, toOffset int, name string) {
+	w.line("BEGIN", kind)
+	w.line("DTSTART", onset)
+	w.line("TZOFFSETFROM", icsOffset(fromOffset))
+	w.line("TZOFFSETTO", icsOffset(toOffset))
+	if name != "" {
+		w.line("TZNAME", icsText(name))
+	}
+	w.line("END", kind)
+}
+
+// icsOffset formats a UTC offset in seconds as +hhmm
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
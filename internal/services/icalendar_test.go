@@ -0,0 +1,211 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// dtstampLine is replaced in golden output, since DTSTAMP is the time of writing
+var dtstampLine = regexp.MustCompile(`(?m)^DTSTAMP:\d{8}T\d{6}Z\r$`)
+
+// checkGolden compares an iCalendar document with testdata/name. Golden files are
+// stored with LF line endings; the document must use CRLF throughout.
+func checkGolden(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	got := string(data)
+	if !strings.HasSuffix(got, "\r\n") || strings.Count(got, "\n") != strings.Count(got, "\r\n") {
+		t.Errorf("%s: every line must end with CRLF", name)
+	}
+	got = dtstampLine.ReplaceAllString(got, "DTSTAMP:19700101T000000Z\r")
+	got = strings.ReplaceAll(got, "\r\n", "\n")
+
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("writing %s: %v", path, err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match the golden file:\n%s", name, got)
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestICSWriterFolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "short line",
+			value: "short",
+			want:  "SUMMARY:short\r\n",
+		},
+		{
+			name:  "exactly 75 octets",
+			value: strings.Repeat("a", 67),
+			want:  "SUMMARY:" + strings.Repeat("a", 67) + "\r\n",
+		},
+		{
+			name:  "ascii",
+			value: strings.Repeat("a", 150),
+			want: "SUMMARY:" + strings.Repeat("a", 67) + "\r\n " +
+				strings.Repeat("a", 74) + "\r\n " +
+				strings.Repeat("a", 9) + "\r\n",
+		},
+		{
+			// あ is three octets: 22 of them end at octet 74, and the 23rd would
+			// straddle the limit
+			name:  "multi-byte characters",
+			value: strings.Repeat("あ", 30),
+			want:  "SUMMARY:" + strings.Repeat("あ", 22) + "\r\n " + strings.Repeat("あ", 8) + "\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &icsWriter{}
+			w.line("SUMMARY", tt.value)
+			got := w.b.String()
+			if got != tt.want {
+				t.Errorf("line folded as\n%q\nwant\n%q", got, tt.want)
+			}
+
+			for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+				if len(line) > icsLineLimit {
+					t.Errorf("line of %d octets: %q", len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line splits a character: %q", line)
+				}
+			}
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(got, "\r\n"), "\r\n ", ""); unfolded != "SUMMARY:"+tt.value {
+				t.Errorf("unfolded to %q", unfolded)
+			}
+		})
+	}
+}
+
+func TestICSText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "法律相談", want: "法律相談"},
+		{in: "a;b,c", want: `a\;b\,c`},
+		{in: `C:\path`, want: `C:\\path`},
+		{in: "line 1\nline 2", want: `line 1\nline 2`},
+		{in: "line 1\r\nline 2", want: `line 1\nline 2`},
+		{in: `\n`, want: `\\n`},
+		{in: "Re: 相談; 日程, 変更", want: `Re: 相談\; 日程\, 変更`},
+	}
+	for _, tt := range tests {
+		if got := icsText(tt.in); got != tt.want {
+			t.Errorf("icsText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildICalendarTokyo(t *testing.T) {
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	description := "相続について; 資料あり,\n持参します"
+	appointment := models.Appointment{
+		ID:               7,
+		StartTime:        time.Date(2026, 7, 1, 10, 0, 0, 0, tokyo),
+		EndTime:          time.Date(2026, 7, 1, 11, 0, 0, 0, tokyo),
+		Status:           models.AppointmentStatusConfirmed,
+		Description:      &description,
+		CalendarSequence: 2,
+		UpdatedAt:        time.Date(2026, 6, 20, 3, 4, 5, 0, time.UTC),
+	}
+	data := buildICalendar("", "相談予定", tokyo, []icsEvent{newAppointmentEvent(appointment, appointmentEventSummary("山田 太郎"))})
+
+	// Japan has no daylight saving time, so there is a single observance
+	got := string(data)
+	if strings.Count(got, "BEGIN:STANDARD") != 1 || strings.Contains(got, "BEGIN:DAYLIGHT") {
+		t.Errorf("Asia/Tokyo VTIMEZONE should have one STANDARD observance and no DAYLIGHT:\n%s", got)
+	}
+	checkGolden(t, "tokyo.golden.ics", data)
+}
+
+func TestBuildICalendarNewYorkDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	// The first is before and the second after the change to daylight saving time
+	// on 8 March 2026
+	events := []icsEvent{
+		newAppointmentEvent(models.Appointment{
+			ID:        1,
+			StartTime: time.Date(2026, 3, 2, 9, 0, 0, 0, newYork),
+			EndTime:   time.Date(2026, 3, 2, 10, 0, 0, 0, newYork),
+			Status:    models.AppointmentStatusConfirmed,
+		}, appointmentEventSummary("Jane Doe")),
+		newAppointmentEvent(models.Appointment{
+			ID:        2,
+			StartTime: time.Date(2026, 3, 16, 9, 0, 0, 0, newYork),
+			EndTime:   time.Date(2026, 3, 16, 10, 0, 0, 0, newYork),
+			Status:    models.AppointmentStatusPending,
+		}, appointmentEventSummary("John Roe")),
+	}
+	data := buildICalendar("", "Consultations", newYork, events)
+
+	// The change itself: 02:00 EST becomes 03:00 EDT
+	if !strings.Contains(string(data), "BEGIN:DAYLIGHT\r\nDTSTART:20260308T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\n") {
+		t.Errorf("VTIMEZONE lacks the 2026 change to EDT:\n%s", data)
+	}
+	checkGolden(t, "new_york.golden.ics", data)
+}
+
+func TestAppointmentInviteCancel(t *testing.T) {
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	service := &EmailService{Config: &config.EmailConfig{FromEmail: "noreply@example.com"}}
+	appointment := models.Appointment{
+		ID:        42,
+		StartTime: time.Date(2026, 7, 1, 10, 0, 0, 0, tokyo),
+		EndTime:   time.Date(2026, 7, 1, 11, 0, 0, 0, tokyo),
+		// CANCEL marks the event cancelled whatever the appointment's status
+		Status:           models.AppointmentStatusConfirmed,
+		CalendarSequence: 3,
+	}
+
+	invite := service.appointmentInvite(appointment, ICSMethodCancel, appointmentEventSummary("山田 太郎"), "Client, \"A\"", "client@example.com", tokyo)
+	if invite.Method != ICSMethodCancel {
+		t.Errorf("invite method = %q, want CANCEL", invite.Method)
+	}
+	got := string(invite.Data)
+	for _, line := range []string{"METHOD:CANCEL", "UID:appointment-42@kotolino-lawyer", "SEQUENCE:3", "STATUS:CANCELLED"} {
+		if !strings.Contains(got, "\r\n"+line+"\r\n") {
+			t.Errorf("invite lacks %s:\n%s", line, got)
+		}
+	}
+	checkGolden(t, "invite_cancel.golden.ics", invite.Data)
+
+	// Every invite of an appointment shares its UID so calendars update one event
+	request := service.appointmentInvite(appointment, ICSMethodRequest, appointmentEventSummary("山田 太郎"), "Client", "client@example.com", tokyo)
+	if !strings.Contains(string(request.Data), "\r\nUID:appointment-42@kotolino-lawyer\r\n") {
+		t.Errorf("request invite has a different UID:\n%s", request.Data)
+	}
+}
@@ -0,0 +1,727 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on expanding one recurring event: occurrences kept in the sync window, and
+// recurrence periods walked from its first start
+const (
+	maxRecurrenceInstances = 2000
+	maxRecurrencePeriods   = 100000
+)
+
+// busyPeriod is a span of time taken on an external calendar
+type busyPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// icsProperty is one unfolded content line
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsComponent is a BEGIN/END block with its own properties
+type icsComponent struct {
+	Name       string
+	Properties []icsProperty
+}
+
+func (c *icsComponent) first(name string) *icsProperty {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+func (c *icsComponent) all(name string) []icsProperty {
+	var props []icsProperty
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// parseICSComponents unfolds an iCalendar document and returns its VEVENT and
+// VFREEBUSY components. Properties of nested components such as VALARM are not
+// mixed into their parent.
+func parseICSComponents(data []byte) ([]icsComponent, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	var components []icsComponent
+	var stack []string
+	var current *icsComponent
+	sawCalendar := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		prop, ok := parseICSLine(line)
+		if !ok {
+			continue
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			name := strings.ToUpper(prop.Value)
+			if name == "VCALENDAR" {
+				sawCalendar = true
+			}
+			stack = append(stack, name)
+			if current == nil && (name == "VEVENT" || name == "VFREEBUSY") {
+				current = &icsComponent{Name: name}
+			}
+			continue
+		case "END":
+			if len(stack) > 0 {
+				name := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if current != nil && name == current.Name && !insideComponent(stack, current.Name) {
+					components = append(components, *current)
+					current = nil
+				}
+			}
+			continue
+		}
+
+		if current != nil && len(stack) > 0 && stack[len(stack)-1] == current.Name {
+			current.Properties = append(current.Properties, prop)
+		}
+	}
+
+	if !sawCalendar {
+		return nil, errors.New("response is not an iCalendar document")
+	}
+	return components, nil
+}
+
+func insideComponent(stack []string, name string) bool {
+	for _, open := range stack {
+		if open == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseICSLine splits NAME;PARAM=VALUE;...:VALUE. Colons inside quoted parameter
+// values do not end the name.
+func parseICSLine(line string) (icsProperty, bool) {
+	inQuotes := false
+	split := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:split], ";")
+	prop := icsProperty{
+		Name:   strings.ToUpper(parts[0]),
+		Params: map[string]string{},
+		Value:  line[split+1:],
+	}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop, true
+}
+
+// parseICSTime reads a DATE or DATE-TIME value. UTC and TZID times keep their zone;
+// floating times and all-day dates are taken in loc, the lawyer's time zone.
+func parseICSTime(prop icsProperty, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.Value)
+	if prop.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	zone := loc
+	if tzid := prop.Params["TZID"]; tzid != "" {
+		// Zone names that Go does not know, such as Windows names, fall back to the
+		// lawyer's zone, which is almost always where the events happen
+		if named, err := time.LoadLocation(tzid); err == nil {
+			zone = named
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, zone)
+	return t, false, err
+}
+
+// parseICSDuration reads an RFC 5545 duration such as PT1H30M or P1D
+func parseICSDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	}
+	value = strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, r := range value[1:] {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9':
+			number += string(r)
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			number = ""
+			switch {
+			case r == 'W' && !inTime:
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				total += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * total, nil
+}
+
+// parseBusyPeriods extracts the busy time between from and to from an iCalendar
+// document. VEVENTs count unless they are cancelled or marked transparent, and
+// VFREEBUSY periods count unless they are free. Recurring events are expanded.
+func parseBusyPeriods(data []byte, loc *time.Location, from, to time.Time) ([]busyPeriod, error) {
+	components, err := parseICSComponents(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instances that were moved or cancelled individually replace the occurrence
+	// of the recurring event they came from
+	overridden := map[string]bool{}
+	for _, component := range components {
+		if component.Name != "VEVENT" {
+			continue
+		}
+		if recurrenceID := component.first("RECURRENCE-ID"); recurrenceID != nil {
+			if t, _, err := parseICSTime(*recurrenceID, loc); err == nil {
+				overridden[eventUID(component)+"|"+t.UTC().Format(time.RFC3339)] = true
+			}
+		}
+	}
+
+	var periods []busyPeriod
+	for _, component := range components {
+		switch component.Name {
+		case "VEVENT":
+			eventPeriods, err := eventBusyPeriods(component, loc, from, to, overridden)
+			if err != nil {
+				// One malformed event must not hide the rest of the calendar
+				fmt.Printf("Skipping unreadable calendar event %q: %v\n", eventUID(component), err)
+				continue
+			}
+			periods = append(periods, eventPeriods...)
+		case "VFREEBUSY":
+			periods = append(periods, freeBusyPeriods(component, loc)...)
+		}
+	}
+
+	return mergeBusyPeriods(periods, from, to), nil
+}
+
+func eventUID(component icsComponent) string {
+	if uid := component.first("UID"); uid != nil {
+		return uid.Value
+	}
+	return ""
+}
+
+// eventBusyPeriods lists the occurrences of one VEVENT
+func eventBusyPeriods(event icsComponent, loc *time.Location, from, to time.Time, overridden map[string]bool) ([]busyPeriod, error) {
+	if status := event.first("STATUS"); status != nil && strings.EqualFold(status.Value, "CANCELLED") {
+		return nil, nil
+	}
+	if transp := event.first("TRANSP"); transp != nil && strings.EqualFold(transp.Value, "TRANSPARENT") {
+		return nil, nil
+	}
+
+	startProp := event.first("DTSTART")
+	if startProp == nil {
+		return nil, errors.New("event has no DTSTART")
+	}
+	start, allDay, err := parseICSTime(*startProp, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var length time.Duration
+	if endProp := event.first("DTEND"); endProp != nil {
+		end, _, err := parseICSTime(*endProp, loc)
+		if err != nil {
+			return nil, err
+		}
+		length = end.Sub(start)
+	} else if durationProp := event.first("DURATION"); durationProp != nil {
+		if length, err = parseICSDuration(durationProp.Value); err != nil {
+			return nil, err
+		}
+	} else if allDay {
+		length = 24 * time.Hour
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+
+	starts := []time.Time{start}
+	rrule := event.first("RRULE")
+	if rrule != nil && event.first("RECURRENCE-ID") == nil {
+		// A rule we cannot expand still blocks its first occurrence
+		if rule, err := parseRecurrenceRule(rrule.Value, start.Location()); err == nil {
+			starts = rule.occurrences(start, from.Add(-length), to)
+		} else {
+			fmt.Printf("Expanding only the first occurrence of calendar event %q: %v\n", eventUID(event), err)
+		}
+	}
+
+	excluded := map[string]bool{}
+	for _, exdate := range event.all("EXDATE") {
+		for _, value := range strings.Split(exdate.Value, ",") {
+			exdate.Value = value
+			if t, _, err := parseICSTime(exdate, start.Location()); err == nil {
+				excluded[t.UTC().Format(time.RFC3339)] = true
+			}
+		}
+	}
+
+	uid := eventUID(event)
+	var periods []busyPeriod
+	for _, occurrence := range starts {
+		key := occurrence.UTC().Format(time.RFC3339)
+		if excluded[key] {
+			continue
+		}
+		if rrule != nil && overridden[uid+"|"+key] {
+			continue
+		}
+		end := occurrence.Add(length)
+		if allDay {
+			// All-day events cover whole calendar days even across DST changes
+			end = occurrence.AddDate(0, 0, int(length/(24*time.Hour)))
+		}
+		if end.After(from) && occurrence.Before(to) {
+			periods = append(periods, busyPeriod{Start: occurrence, End: end})
+		}
+	}
+	return periods, nil
+}
+
+// freeBusyPeriods lists the busy periods of a VFREEBUSY component
+func freeBusyPeriods(component icsComponent, loc *time.Location) []busyPeriod {
+	var periods []busyPeriod
+	for _, prop := range component.all("FREEBUSY") {
+		if fbType := strings.ToUpper(prop.Params["FBTYPE"]); fbType == "FREE" {
+			continue
+		}
+		for _, value := range strings.Split(prop.Value, ",") {
+			startValue, endValue, ok := strings.Cut(value, "/")
+			if !ok {
+				continue
+			}
+			start, _, err := parseICSTime(icsProperty{Value: startValue, Params: map[string]string{}}, loc)
+			if err != nil {
+				continue
+			}
+			var end time.Time
+			if strings.HasPrefix(endValue, "P") || strings.HasPrefix(endValue, "+P") {
+				length, err := parseICSDuration(endValue)
+				if err != nil {
+					continue
+				}
+				end = start.Add(length)
+			} else if end, _, err = parseICSTime(icsProperty{Value: endValue, Params: map[string]string{}}, loc); err != nil {
+				continue
+			}
+			if end.After(start) {
+				periods = append(periods, busyPeriod{Start: start, End: end})
+			}
+		}
+	}
+	return periods
+}
+
+// mergeBusyPeriods clips periods to the window and joins overlapping ones
+func mergeBusyPeriods(periods []busyPeriod, from, to time.Time) []busyPeriod {
+	var clipped []busyPeriod
+	for _, period := range periods {
+		if period.Start.Before(from) {
+			period.Start = from
+		}
+		if period.End.After(to) {
+			period.End = to
+		}
+		if period.End.After(period.Start) {
+			clipped = append(clipped, busyPeriod{Start: period.Start.UTC(), End: period.End.UTC()})
+		}
+	}
+	sort.Slice(clipped, func(i, j int) bool { return clipped[i].Start.Before(clipped[j].Start) })
+
+	var merged []busyPeriod
+	for _, period := range clipped {
+		if n := len(merged); n > 0 && !period.Start.After(merged[n-1].End) {
+			if period.End.After(merged[n-1].End) {
+				merged[n-1].End = period.End
+			}
+			continue
+		}
+		merged = append(merged, period)
+	}
+	return merged
+}
+
+// recurrenceRule is the subset of RFC 5545 RRULE that calendars use for
+// appointments: DAILY, WEEKLY, MONTHLY and YEARLY with INTERVAL, COUNT, UNTIL,
+// WKST, BYMONTH, BYMONTHDAY and BYDAY, including ordinal weekdays such as 2TU
+// within a month. Rules using other parts are rejected as unsupported.
+type recurrenceRule struct {
+	freq       string
+	interval   int
+	count      int
+	until      *time.Time
+	weekStart  time.Weekday
+	byDay      []ruleWeekday
+	byMonth    []time.Month
+	byMonthDay []int
+}
+
+type ruleWeekday struct {
+	ordinal int
+	day     time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRecurrenceRule(value string, loc *time.Location) (*recurrenceRule, error) {
+	rule := &recurrenceRule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.count = n
+		case "UNTIL":
+			until, _, err := parseICSTime(icsProperty{Value: val, Params: map[string]string{}}, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			if len(val) == 8 {
+				until = until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			rule.until = &until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				item = strings.ToUpper(strings.TrimSpace(item))
+				if len(item) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", val)
+				}
+				day, ok := icsWeekdays[item[len(item)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", val)
+				}
+				ordinal := 0
+				if prefix := item[:len(item)-2]; prefix != "" {
+					n, err := strconv.Atoi(prefix)
+					if err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q", val)
+					}
+					ordinal = n
+				}
+				rule.byDay = append(rule.byDay, ruleWeekday{ordinal: ordinal, day: day})
+			}
+		case "WKST":
+			day, ok := icsWeekdays[strings.ToUpper(val)]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.weekStart = day
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(item))
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", val)
+				}
+				rule.byMonth = append(rule.byMonth, time.Month(n))
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(item))
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", val)
+				}
+				rule.byMonthDay = append(rule.byMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+	}
+
+	switch rule.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported RRULE frequency %q", rule.freq)
+	}
+	if rule.freq == "WEEKLY" && len(rule.byMonthDay) > 0 {
+		return nil, errors.New("unsupported RRULE: BYMONTHDAY in a WEEKLY rule")
+	}
+	if rule.freq == "YEARLY" && len(rule.byDay) > 0 && len(rule.byMonth) == 0 {
+		return nil, errors.New("unsupported RRULE: BYDAY in a YEARLY rule without BYMONTH")
+	}
+	for _, wd := range rule.byDay {
+		if wd.ordinal != 0 && (len(rule.byMonthDay) > 0 || rule.freq == "DAILY" || rule.freq == "WEEKLY") {
+			return nil, fmt.Errorf("unsupported RRULE: ordinal BYDAY %d%s", wd.ordinal, wd.day)
+		}
+	}
+	return rule, nil
+}
+
+// occurrences lists the starts of a recurring event that fall between from and to.
+// COUNT is counted from the first start. Each occurrence keeps the wall-clock time
+// of the first one in its zone.
+func (r *recurrenceRule) occurrences(start, from, to time.Time) []time.Time {
+	var starts []time.Time
+	counted := 0
+	add := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if r.until != nil && t.After(*r.until) {
+			return false
+		}
+		if r.count > 0 && counted >= r.count {
+			return false
+		}
+		if !t.Before(to) {
+			return false
+		}
+		counted++
+		if !t.Before(from) {
+			starts = append(starts, t)
+		}
+		return len(starts) < maxRecurrenceInstances
+	}
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		var candidates []time.Time
+		switch r.freq {
+		case "DAILY":
+			candidate := at(start.Year(), start.Month(), start.Day()+period*r.interval)
+			if r.matchesWeekday(candidate) && r.matchesMonth(candidate.Month()) && r.matchesMonthDay(candidate) {
+				candidates = []time.Time{candidate}
+			}
+		case "WEEKLY":
+			weekStart := at(start.Year(), start.Month(), start.Day()-r.daysIntoWeek(start.Weekday())+period*7*r.interval)
+			days := r.byDay
+			if len(days) == 0 {
+				days = []ruleWeekday{{day: start.Weekday()}}
+			}
+			for _, wd := range days {
+				if candidate := weekStart.AddDate(0, 0, r.daysIntoWeek(wd.day)); r.matchesMonth(candidate.Month()) {
+					candidates = append(candidates, candidate)
+				}
+			}
+		case "MONTHLY":
+			first := at(start.Year(), start.Month()+time.Month(period*r.interval), 1)
+			if r.matchesMonth(first.Month()) {
+				candidates = r.daysOfMonth(first, start.Day())
+			}
+		case "YEARLY":
+			months := r.byMonth
+			if len(months) == 0 {
+				months = []time.Month{start.Month()}
+			}
+			for _, month := range months {
+				candidates = append(candidates, r.daysOfMonth(at(start.Year()+period*r.interval, month, 1), start.Day())...)
+			}
+		}
+
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+		for i, candidate := range candidates {
+			// BYMONTHDAY=1,-31 names the same day in long months
+			if i > 0 && candidate.Equal(candidates[i-1]) {
+				continue
+			}
+			if !add(candidate) {
+				return starts
+			}
+		}
+	}
+	return starts
+}
+
+// daysIntoWeek counts the days from the rule's week start (WKST) to day
+func (r *recurrenceRule) daysIntoWeek(day time.Weekday) int {
+	return (int(day) - int(r.weekStart) + 7) % 7
+}
+
+// daysOfMonth lists the occurrences in the month that starts at first: the
+// BYMONTHDAY days, narrowed to the BYDAY weekdays when both are given, else the
+// BYDAY weekdays, else defaultDay, the day of the month of the first start
+func (r *recurrenceRule) daysOfMonth(first time.Time, defaultDay int) []time.Time {
+	var days []time.Time
+	switch {
+	case len(r.byMonthDay) > 0:
+		last := first.AddDate(0, 1, -1).Day()
+		for _, day := range r.byMonthDay {
+			if day < 0 {
+				day = last + day + 1
+			}
+			if day < 1 || day > last {
+				continue
+			}
+			if candidate := first.AddDate(0, 0, day-1); r.matchesWeekday(candidate) {
+				days = append(days, candidate)
+			}
+		}
+	case len(r.byDay) > 0:
+		for _, wd := range r.byDay {
+			days = append(days, weekdaysOfMonth(first, wd)...)
+		}
+	default:
+		// Months without the day, such as the 31st, are skipped
+		if candidate := first.AddDate(0, 0, defaultDay-1); candidate.Month() == first.Month() {
+			days = append(days, candidate)
+		}
+	}
+	return days
+}
+
+// matchesMonth reports whether month is one of the BYMONTH months
+func (r *recurrenceRule) matchesMonth(month time.Month) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+	for _, m := range r.byMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthDay reports whether a DAILY occurrence falls on one of the
+// BYMONTHDAY days, counting negative days from the end of the month
+func (r *recurrenceRule) matchesMonthDay(t time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, day := range r.byMonthDay {
+		if day == t.Day() || last+day+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday reports whether an occurrence falls on one of the BYDAY days
+func (r *recurrenceRule) matchesWeekday(t time.Time) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, wd := range r.byDay {
+		if wd.day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdaysOfMonth finds e.g. the second Tuesday (2TU) or last Friday (-1FR) of the
+// month that starts at first. Without an ordinal every such weekday is returned.
+func weekdaysOfMonth(first time.Time, wd ruleWeekday) []time.Time {
+	if wd.ordinal == 0 {
+		var days []time.Time
+		for d := first.AddDate(0, 0, (int(wd.day)-int(first.Weekday())+7)%7); d.Month() == first.Month(); d = d.AddDate(0, 0, 7) {
+			days = append(days, d)
+		}
+		return days
+	}
+	if candidate, ok := nthWeekdayOfMonth(first, wd); ok {
+		return []time.Time{candidate}
+	}
+	return nil
+}
+
+// nthWeekdayOfMonth finds the ordinal weekday in the month that starts at first,
+// counting from the end for negative ordinals
+func nthWeekdayOfMonth(first time.Time, wd ruleWeekday) (time.Time, bool) {
+	if wd.ordinal > 0 {
+		offset := (int(wd.day) - int(first.Weekday()) + 7) % 7
+		candidate := first.AddDate(0, 0, offset+(wd.ordinal-1)*7)
+		return candidate, candidate.Month() == first.Month()
+	}
+
+	last := first.AddDate(0, 1, -1)
+	offset := (int(last.Weekday()) - int(wd.day) + 7) % 7
+	candidate := last.AddDate(0, 0, -offset+(wd.ordinal+1)*7)
+	return candidate, candidate.Month() == first.Month()
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRuleOccurrences(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	// Examples from RFC 5545 section 3.8.5.3 unless noted
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		to      string
+		want    []string
+	}{
+		{
+			name:    "every other week from Monday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart: "1997-08-05 09:00",
+			want:    []string{"1997-08-05", "1997-08-10", "1997-08-19", "1997-08-24"},
+		},
+		{
+			name:    "every other week from Sunday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart: "1997-08-05 09:00",
+			want:    []string{"1997-08-05", "1997-08-17", "1997-08-19", "1997-08-31"},
+		},
+		{
+			name:    "2nd and 15th of the month",
+			rule:    "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15",
+			dtstart: "1997-09-02 09:00",
+			want: []string{"1997-09-02", "1997-09-15", "1997-10-02", "1997-10-15", "1997-11-02",
+				"1997-11-15", "1997-12-02", "1997-12-15", "1998-01-02", "1998-01-15"},
+		},
+		{
+			name:    "third-to-last day of the month",
+			rule:    "FREQ=MONTHLY;COUNT=6;BYMONTHDAY=-3",
+			dtstart: "1997-09-28 09:00",
+			want:    []string{"1997-09-28", "1997-10-29", "1997-11-28", "1997-12-29", "1998-01-29", "1998-02-26"},
+		},
+		{
+			name:    "January to March every other year",
+			rule:    "FREQ=YEARLY;INTERVAL=2;COUNT=10;BYMONTH=1,2,3",
+			dtstart: "1997-03-10 09:00",
+			want: []string{"1997-03-10", "1999-01-10", "1999-02-10", "1999-03-10", "2001-01-10",
+				"2001-02-10", "2001-03-10", "2003-01-10", "2003-02-10", "2003-03-10"},
+		},
+		{
+			name:    "every Thursday in March",
+			rule:    "FREQ=YEARLY;COUNT=7;BYMONTH=3;BYDAY=TH",
+			dtstart: "1997-03-13 09:00",
+			want:    []string{"1997-03-13", "1997-03-20", "1997-03-27", "1998-03-05", "1998-03-12", "1998-03-19", "1998-03-26"},
+		},
+		{
+			name:    "every Friday the 13th",
+			rule:    "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			dtstart: "1998-02-13 09:00",
+			to:      "2001-01-01 00:00",
+			want:    []string{"1998-02-13", "1998-03-13", "1998-11-13", "1999-08-13", "2000-10-13"},
+		},
+		{
+			name:    "fourth Thursday of November",
+			rule:    "FREQ=YEARLY;COUNT=3;BYMONTH=11;BYDAY=4TH",
+			dtstart: "2024-11-28 12:00",
+			want:    []string{"2024-11-28", "2025-11-27", "2026-11-26"},
+		},
+		{
+			name:    "days in January only",
+			rule:    "FREQ=DAILY;BYMONTH=1",
+			dtstart: "1998-01-30 09:00",
+			to:      "1999-01-03 00:00",
+			want:    []string{"1998-01-30", "1998-01-31", "1999-01-01", "1999-01-02"},
+		},
+		{
+			name:    "31st skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: "2026-01-31 09:00",
+			want:    []string{"2026-01-31", "2026-03-31", "2026-05-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRecurrenceRule(tt.rule, newYork)
+			if err != nil {
+				t.Fatalf("parseRecurrenceRule: %v", err)
+			}
+			start, _ := time.ParseInLocation("2006-01-02 15:04", tt.dtstart, newYork)
+			to := start.AddDate(10, 0, 0)
+			if tt.to != "" {
+				to, _ = time.ParseInLocation("2006-01-02 15:04", tt.to, newYork)
+			}
+
+			var got []string
+			for _, occurrence := range rule.occurrences(start, start, to) {
+				if occurrence.Hour() != start.Hour() || occurrence.Location() != newYork {
+					t.Errorf("occurrence %s lost the wall-clock time of %s", occurrence, start)
+				}
+				got = append(got, occurrence.Format("2006-01-02"))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("occurrences =\n  %v\nwant\n  %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceRuleUnsupported(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=HOURLY",
+		"FREQ=DAILY;BYHOUR=9,17",
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=YEARLY;BYDAY=20MO",
+		"FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=2FR",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=WEEKLY;WKST=XX",
+	} {
+		if _, err := parseRecurrenceRule(rule, time.UTC); err == nil {
+			t.Errorf("parseRecurrenceRule(%q) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestParseBusyPeriods(t *testing.T) {
+	data, err := os.ReadFile("testdata/busy.ics")
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	from := time.Date(2026, 11, 1, 0, 0, 0, 0, tokyo)
+	to := time.Date(2026, 12, 1, 0, 0, 0, 0, tokyo)
+
+	periods, err := parseBusyPeriods(data, tokyo, from, to)
+	if err != nil {
+		t.Fatalf("parseBusyPeriods: %v", err)
+	}
+
+	want := []string{
+		// Weekly event; 9 November is an EXDATE and 16 November was moved
+		"2026-11-02T01:00:00Z/2026-11-02T02:00:00Z",
+		// All-day event on 5 November in Tokyo
+		"2026-11-04T15:00:00Z/2026-11-05T15:00:00Z",
+		// A rule with BYSETPOS only blocks its first occurrence
+		"2026-11-10T00:00:00Z/2026-11-10T00:30:00Z",
+		"2026-11-16T06:00:00Z/2026-11-16T07:00:00Z",
+		"2026-11-23T01:00:00Z/2026-11-23T02:00:00Z",
+		// Last day of the month
+		"2026-11-30T00:00:00Z/2026-11-30T02:00:00Z",
+	}
+	assertBusyPeriods(t, periods, want)
+}
+
+func TestParseBusyPeriodsRejectsNonCalendar(t *testing.T) {
+	if _, err := parseBusyPeriods([]byte("<html>Sign in</html>"), time.UTC, time.Now(), time.Now().Add(time.Hour)); err == nil {
+		t.Error("parseBusyPeriods accepted an HTML page")
+	}
+}
+
+func assertBusyPeriods(t *testing.T, periods []busyPeriod, want []string) {
+	t.Helper()
+	var got []string
+	for _, period := range periods {
+		got = append(got, period.Start.UTC().Format(time.RFC3339)+"/"+period.End.UTC().Format(time.RFC3339))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("busy periods =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Impersonation session limits
+const (
+	DefaultImpersonationDuration = 30 * time.Minute
+	MaxImpersonationDuration     = 2 * time.Hour
+)
+
+var (
+	ErrImpersonationNotFound = errors.New("impersonation session not found")
+	ErrImpersonationInactive = errors.New("impersonation session has ended")
+)
+
+// StartImpersonationInput describes a request to act as another user
+type StartImpersonationInput struct {
+	ActorID          int
+	SubjectID        int
+	Reason           string
+	AllowDestructive bool
+	Duration         time.Duration
+	IPAddress        string
+	UserAgent        string
+}
+
+// ImpersonationService manages impersonation sessions and their request logs
+type ImpersonationService struct {
+	DB *gorm.DB
+}
+
+// NewImpersonationService creates a new impersonation service
+func NewImpersonationService() *ImpersonationService {
+	return &ImpersonationService{
+		DB: repository.DB,
+	}
+}
+
+// Start opens a new impersonation session. Staff accounts cannot be impersonated so a
+// session can never be used to gain more privileges than the actor already has.
+func (s *ImpersonationService) Start(input StartImpersonationInput) (*models.ImpersonationSession, *models.User, error) {
+	if input.ActorID == input.SubjectID {
+		return nil, nil, errors.New("you cannot impersonate yourself")
+	}
+
+	var subject models.User
+	if err := s.DB.First(&subject, input.SubjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrUserNotFound
+		}
+		return nil, nil, err
+	}
+	if subject.Role != models.RoleClient.String() && subject.Role != models.RoleLawyer.String() {
+		return nil, nil, errors.New("only client and lawyer accounts can be impersonated")
+	}
+	if !subject.IsActive {
+		return nil, nil, errors.New("inactive accounts cannot be impersonated")
+	}
+
+	duration := input.Duration
+	if duration <= 0 {
+		duration = DefaultImpersonationDuration
+	}
+	if duration > MaxImpersonationDuration {
+		return nil, nil, fmt.Errorf("impersonation cannot last longer than %s", MaxImpersonationDuration)
+	}
+
+	session := &models.ImpersonationSession{
+		ActorID:          input.ActorID,
+		SubjectID:        input.SubjectID,
+		Reason:           input.Reason,
+		AllowDestructive: input.AllowDestructive,
+		ExpiresAt:        time.Now().Add(duration),
+	}
+	if input.IPAddress != "" {
+		session.IPAddress = &input.IPAddress
+	}
+	if input.UserAgent != "" {
+		session.UserAgent = &input.UserAgent
+	}
+
+	if err := s.DB.Create(session).Error; err != nil {
+		return nil, nil, err
+	}
+	return session, &subject, nil
+}
+
+// GetActiveSession returns a session that has not ended or expired
+func (s *ImpersonationService) GetActiveSession(id int) (*models.ImpersonationSession, error) {
+	var session models.ImpersonationSession
+	if err := s.DB.First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, err
+	}
+	if !session.IsActive(time.Now()) {
+		return nil, ErrImpersonationInactive
+	}
+	return &session, nil
+}
+
+// End closes a session so its token stops working immediately
+func (s *ImpersonationService) End(id int) error {
+	result := s.DB.Model(&models.ImpersonationSession{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Update("ended_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrImpersonationInactive
+	}
+	return nil
+}
+
+// LogRequest records a request made under impersonation. Failures are logged rather than
+// returned so auditing never breaks the request itself.
+func (s *ImpersonationService) LogRequest(entry *models.ImpersonationRequestLog) {
+	if err := s.DB.Create(entry).Error; err != nil {
+		fmt.Printf("Failed to log impersonated request for session %d: %v\n", entry.SessionID, err)
+	}
+}
+
+// GetSessions lists impersonation sessions, newest first. actorID and subjectID filter when non-zero.
+func (s *ImpersonationService) GetSessions(actorID, subjectID, page, limit int) ([]models.ImpersonationSession, int64, error) {
+	var sessions []models.ImpersonationSession
+	var total int64
+
+	query := s.DB.Model(&models.ImpersonationSession{})
+	if actorID != 0 {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if subjectID != 0 {
+		query = query.Where("subject_id = ?", subjectID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.
+		Preload("Actor").
+		Preload("Subject").
+		Order("started_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, total, nil
+}
+
+// GetRequestLogs lists the requests made during a session in the order they happened
+func (s *ImpersonationService) GetRequestLogs(sessionID, page, limit int) ([]models.ImpersonationRequestLog, int64, error) {
+	var logs []models.ImpersonationRequestLog
+	var total int64
+
+	query := s.DB.Model(&models.ImpersonationRequestLog{}).Where("session_id = ?", sessionID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at ASC, id ASC").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
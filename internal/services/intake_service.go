@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Attachment types of files uploaded for intake forms. A file is an IntakeUpload
+// owned by its uploader until it is submitted with a booking, when it moves to the
+// IntakeResponse.
+const (
+	AttachmentTypeIntakeUpload   = "IntakeUpload"
+	AttachmentTypeIntakeResponse = "IntakeResponse"
+)
+
+var (
+	// ErrInvalidIntakeForm is wrapped with the reason an intake form was refused
+	ErrInvalidIntakeForm  = errors.New("invalid intake form")
+	ErrIntakeFormNotFound = errors.New("intake form not found")
+	ErrIntakeFormExists   = errors.New("intake form already exists for this specialty")
+	// ErrInvalidIntake is wrapped with the reason a client's intake answers were
+	// refused
+	ErrInvalidIntake = errors.New("invalid intake")
+)
+
+// errIntakeFileUnavailable is returned when an intake answer refers to a file the
+// client did not upload or already submitted with another booking
+var errIntakeFileUnavailable = fmt.Errorf("%w: files must be uploaded by you and not used in another booking", ErrInvalidIntake)
+
+// IntakeService manages lawyers' intake forms and the answers clients give when
+// booking
+type IntakeService struct {
+	DB *gorm.DB
+}
+
+// NewIntakeService creates a new intake service
+func NewIntakeService() *IntakeService {
+	return &IntakeService{
+		DB: repository.DB,
+	}
+}
+
+// ListForms lists a lawyer's intake forms by specialty
+func (s *IntakeService) ListForms(lawyerID int) ([]models.IntakeForm, error) {
+	var forms []models.IntakeForm
+	err := s.DB.Where("lawyer_id = ?", lawyerID).Order("specialty ASC").Find(&forms).Error
+	return forms, err
+}
+
+// GetForm returns one of a lawyer's intake forms
+func (s *IntakeService) GetForm(lawyerID, formID int) (*models.IntakeForm, error) {
+	var form models.IntakeForm
+	if err := s.DB.Where("id = ? AND lawyer_id = ?", formID, lawyerID).First(&form).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIntakeFormNotFound
+		}
+		return nil, err
+	}
+	return &form, nil
+}
+
+// CreateForm adds an intake form for one of the lawyer's specialties. Validation
+// errors wrap ErrInvalidIntakeForm.
+func (s *IntakeService) CreateForm(lawyer *models.Lawyer, form *models.IntakeForm) error {
+	form.LawyerID = lawyer.ID
+	if err := validateIntakeForm(lawyer, form); err != nil {
+		return err
+	}
+
+	err := s.DB.Create(form).Error
+	if pgErrorCode(err) == pgUniqueViolation {
+		return ErrIntakeFormExists
+	}
+	return err
+}
+
+// UpdateForm replaces the specialty, title and schema of an intake form. Answers
+// already given keep the schema they were given against.
+func (s *IntakeService) UpdateForm(lawyer *models.Lawyer, form *models.IntakeForm) error {
+	if err := validateIntakeForm(lawyer, form); err != nil {
+		return err
+	}
+
+	err := s.DB.Model(form).Updates(map[string]interface{}{
+		"specialty": form.Specialty,
+		"title":     form.Title,
+		"schema":    form.Schema,
+	}).Error
+	if pgErrorCode(err) == pgUniqueViolation {
+		return ErrIntakeFormExists
+	}
+	return err
+}
+
+// DeleteForm removes one of a lawyer's intake forms
+func (s *IntakeService) DeleteForm(lawyerID, formID int) error {
+	result := s.DB.Where("id = ? AND lawyer_id = ?", formID, lawyerID).Delete(&models.IntakeForm{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIntakeFormNotFound
+	}
+	return nil
+}
+
+func validateIntakeForm(lawyer *models.Lawyer, form *models.IntakeForm) error {
+	form.Specialty = strings.TrimSpace(form.Specialty)
+	form.Title = strings.TrimSpace(form.Title)
+	if form.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidIntakeForm)
+	}
+	offered := false
+	for _, specialty := range lawyer.Specialties {
+		if specialty == form.Specialty {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return fmt.Errorf("%w: specialty must be one of the lawyer's specialties", ErrInvalidIntakeForm)
+	}
+	if err := form.Schema.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidIntakeForm, err)
+	}
+	return nil
+}
+
+// PrepareIntake checks a client's answers to one of a lawyer's intake forms before
+// booking. Lawyers with intake forms require one to be completed; a nil formID is
+// only accepted when the lawyer has none. Validation errors wrap
+// ErrInvalidIntake. The returned response is saved with the appointment by
+// CreateAppointment.
+func (s *IntakeService) PrepareIntake(lawyerID, clientID int, formID *int, answers models.IntakeAnswers) (*models.IntakeResponse, error) {
+	if formID == nil {
+		var count int64
+		if err := s.DB.Model(&models.IntakeForm{}).Where("lawyer_id = ?", lawyerID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("%w: this lawyer requires an intake form to be completed", ErrInvalidIntake)
+		}
+		return nil, nil
+	}
+
+	form, err := s.GetForm(lawyerID, *formID)
+	if err != nil {
+		if errors.Is(err, ErrIntakeFormNotFound) {
+			return nil, fmt.Errorf("%w: form not found for this lawyer", ErrInvalidIntake)
+		}
+		return nil, err
+	}
+
+	normalized, err := form.Schema.CheckAnswers(answers)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIntake, err)
+	}
+	response := &models.IntakeResponse{
+		FormID:    &form.ID,
+		Specialty: form.Specialty,
+		Title:     form.Title,
+		Schema:    form.Schema,
+		Answers:   normalized,
+	}
+
+	if fileIDs := response.FileIDs(); len(fileIDs) > 0 {
+		var count int64
+		if err := s.DB.Model(&models.Attachment{}).
+			Where("id IN ? AND attachmentable_type = ? AND uploaded_by = ?", fileIDs, AttachmentTypeIntakeUpload, clientID).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if int(count) != len(fileIDs) {
+			return nil, errIntakeFileUnavailable
+		}
+	}
+	return response, nil
+}
+
+// SaveUpload records a file uploaded for an intake answer. Until it is submitted
+// with a booking the file belongs to its uploader.
+func (s *IntakeService) SaveUpload(attachment *models.Attachment) error {
+	attachment.AttachmentableType = AttachmentTypeIntakeUpload
+	attachment.AttachmentableID = attachment.UploadedBy
+	return s.DB.Create(attachment).Error
+}
+
+// saveResponse stores a prepared intake response for a new appointment and moves
+// the files it refers to onto it. A file claimed by a concurrent booking fails the
+// whole booking.
+func (s *IntakeService) saveResponse(appointmentID, clientID int, response *models.IntakeResponse) error {
+	response.AppointmentID = appointmentID
+	if err := s.DB.Create(response).Error; err != nil {
+		return err
+	}
+
+	fileIDs := response.FileIDs()
+	if len(fileIDs) == 0 {
+		return nil
+	}
+	result := s.DB.Model(&models.Attachment{}).
+		Where("id IN ? AND attachmentable_type = ? AND uploaded_by = ?", fileIDs, AttachmentTypeIntakeUpload, clientID).
+		Updates(map[string]interface{}{
+			"attachmentable_type": AttachmentTypeIntakeResponse,
+			"attachmentable_id":   response.ID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if int(result.RowsAffected) != len(fileIDs) {
+		return errIntakeFileUnavailable
+	}
+	return nil
+}
+
+// GetResponse returns the intake answers stored with an appointment, or nil if none
+// were required
+func (s *IntakeService) GetResponse(appointmentID int) (*models.IntakeResponse, error) {
+	var response models.IntakeResponse
+	if err := s.DB.Where("appointment_id = ?", appointmentID).First(&response).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetResponseByID returns an intake response by ID
+func (s *IntakeService) GetResponseByID(id int) (*models.IntakeResponse, error) {
+	var response models.IntakeResponse
+	if err := s.DB.First(&response, id).Error; err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
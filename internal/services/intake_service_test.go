@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func TestPrepareIntake(t *testing.T) {
+	db := openTestDB(t)
+	service := &IntakeService{DB: db}
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	other := createTestUser(t, db, models.RoleClient)
+
+	// Without forms the lawyer can be booked without an intake
+	if response, err := service.PrepareIntake(lawyer.ID, client.ID, nil, nil); err != nil || response != nil {
+		t.Fatalf("PrepareIntake without forms = %v, %v, want no intake", response, err)
+	}
+
+	form := &models.IntakeForm{
+		LawyerID:  lawyer.ID,
+		Specialty: "civil",
+		Title:     "Contract dispute",
+		Schema: models.IntakeSchema{
+			Type: "object",
+			Properties: map[string]models.IntakeField{
+				"summary":  {Type: "string", Title: "Summary"},
+				"contract": {Type: "string", Title: "Contract", Format: "file"},
+			},
+			Required: []string{"summary"},
+		},
+	}
+	if err := service.CreateForm(lawyer, form); err != nil {
+		t.Fatalf("CreateForm: %v", err)
+	}
+	if _, err := service.PrepareIntake(lawyer.ID, client.ID, nil, nil); !errors.Is(err, ErrInvalidIntake) {
+		t.Errorf("PrepareIntake without a form error = %v, want ErrInvalidIntake", err)
+	}
+	if _, err := service.PrepareIntake(lawyer.ID, client.ID, &form.ID, models.IntakeAnswers{}); !errors.Is(err, ErrInvalidIntake) {
+		t.Errorf("PrepareIntake without a required answer error = %v, want ErrInvalidIntake", err)
+	}
+
+	upload := func(uploader *models.User) float64 {
+		attachment := &models.Attachment{FileName: "contract.pdf", FileSize: 10, FileType: "application/pdf", FilePath: "intake/contract.pdf", UploadedBy: uploader.ID}
+		if err := service.SaveUpload(attachment); err != nil {
+			t.Fatalf("SaveUpload: %v", err)
+		}
+		t.Cleanup(func() {
+			db.Unscoped().Delete(&models.Attachment{}, attachment.ID)
+		})
+		return float64(attachment.ID)
+	}
+
+	// A file uploaded by someone else cannot be attached
+	answers := models.IntakeAnswers{"summary": " Unpaid invoice ", "contract": upload(other)}
+	if _, err := service.PrepareIntake(lawyer.ID, client.ID, &form.ID, answers); !errors.Is(err, errIntakeFileUnavailable) {
+		t.Errorf("PrepareIntake with another client's file error = %v, want errIntakeFileUnavailable", err)
+	}
+
+	fileID := upload(client)
+	answers["contract"] = fileID
+	response, err := service.PrepareIntake(lawyer.ID, client.ID, &form.ID, answers)
+	if err != nil {
+		t.Fatalf("PrepareIntake: %v", err)
+	}
+	if response.Title != form.Title || response.Specialty != form.Specialty || response.FormID == nil || *response.FormID != form.ID {
+		t.Errorf("response copies form %v %q %q, want %d %q %q", response.FormID, response.Title, response.Specialty, form.ID, form.Title, form.Specialty)
+	}
+	if response.Answers["summary"] != "Unpaid invoice" || response.Answers["contract"] != int(fileID) {
+		t.Errorf("answers = %#v, want them normalized", response.Answers)
+	}
+}
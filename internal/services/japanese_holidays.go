@@ -0,0 +1,188 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// Japanese national holidays under the 国民の祝日に関する法律, generated from its rules
+// rather than a fixed table. The rules cover 2000 onwards; the equinox formula is
+// valid until 2099.
+
+const (
+	holidayNameSubstitute = "振替休日"
+	holidayNameCitizens   = "国民の休日"
+)
+
+// Holiday is a named day off
+type Holiday struct {
+	Date models.Date `json:"date"`
+	Name string      `json:"name"`
+}
+
+var (
+	japaneseHolidayCacheMu sync.Mutex
+	japaneseHolidayCache   = map[int]map[models.Date]string{}
+)
+
+// JapaneseHolidays returns the national holidays of a year, including substitute
+// holidays (振替休日) and citizens' holidays (国民の休日), in date order
+func JapaneseHolidays(year int) []Holiday {
+	byDate := japaneseHolidayMap(year)
+
+	holidays := make([]Holiday, 0, len(byDate))
+	for date, name := range byDate {
+		holidays = append(holidays, Holiday{Date: date, Name: name})
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date.Time) })
+	return holidays
+}
+
+// JapaneseHolidayName returns the name of the national holiday on a date, or ""
+func JapaneseHolidayName(date models.Date) string {
+	return japaneseHolidayMap(date.Year())[date]
+}
+
+func japaneseHolidayMap(year int) map[models.Date]string {
+	japaneseHolidayCacheMu.Lock()
+	defer japaneseHolidayCacheMu.Unlock()
+
+	if holidays, ok := japaneseHolidayCache[year]; ok {
+		return holidays
+	}
+	holidays := generateJapaneseHolidays(year)
+	japaneseHolidayCache[year] = holidays
+	return holidays
+}
+
+func generateJapaneseHolidays(year int) map[models.Date]string {
+	statutory := map[models.Date]string{}
+	add := func(month time.Month, day int, name string) {
+		statutory[calendarDate(year, month, day)] = name
+	}
+
+	add(time.January, 1, "元日")
+	add(time.January, nthWeekday(year, time.January, time.Monday, 2), "成人の日")
+	add(time.February, 11, "建国記念の日")
+	switch {
+	case year >= 2020:
+		add(time.February, 23, "天皇誕生日")
+	case year <= 2018:
+		add(time.December, 23, "天皇誕生日")
+	}
+	add(time.March, vernalEquinoxDay(year), "春分の日")
+	if year >= 2007 {
+		add(time.April, 29, "昭和の日")
+		add(time.May, 4, "みどりの日")
+	} else {
+		add(time.April, 29, "みどりの日")
+	}
+	add(time.May, 3, "憲法記念日")
+	add(time.May, 5, "こどもの日")
+
+	// Marine Day, Mountain Day and Sports Day moved for the Tokyo Olympics
+	switch year {
+	case 2020:
+		add(time.July, 23, "海の日")
+		add(time.July, 24, "スポーツの日")
+		add(time.August, 10, "山の日")
+	case 2021:
+		add(time.July, 22, "海の日")
+		add(time.July, 23, "スポーツの日")
+		add(time.August, 8, "山の日")
+	default:
+		if year >= 2003 {
+			add(time.July, nthWeekday(year, time.July, time.Monday, 3), "海の日")
+		} else {
+			add(time.July, 20, "海の日")
+		}
+		if year >= 2016 {
+			add(time.August, 11, "山の日")
+		}
+		name := "体育の日"
+		if year >= 2020 {
+			name = "スポーツの日"
+		}
+		add(time.October, nthWeekday(year, time.October, time.Monday, 2), name)
+	}
+
+	if year >= 2003 {
+		add(time.September, nthWeekday(year, time.September, time.Monday, 3), "敬老の日")
+	} else {
+		add(time.September, 15, "敬老の日")
+	}
+	add(time.September, autumnalEquinoxDay(year), "秋分の日")
+	add(time.November, 3, "文化の日")
+	add(time.November, 23, "勤労感謝の日")
+
+	// One-off holidays for the 2019 enthronement
+	if year == 2019 {
+		add(time.May, 1, "休日（即位の日）")
+		add(time.October, 22, "休日（即位礼正殿の儀の行われる日）")
+	}
+
+	holidays := make(map[models.Date]string, len(statutory)+4)
+	for date, name := range statutory {
+		holidays[date] = name
+	}
+
+	// 国民の休日: a weekday sandwiched between two statutory holidays is also a day off
+	for date := range statutory {
+		between := models.Date{Time: date.AddDate(0, 0, 1)}
+		after := models.Date{Time: date.AddDate(0, 0, 2)}
+		if _, ok := statutory[between]; ok {
+			continue
+		}
+		if _, ok := statutory[after]; ok && between.Weekday() != time.Sunday {
+			holidays[between] = holidayNameCitizens
+		}
+	}
+
+	// 振替休日: a statutory holiday on a Sunday moves to the next day that is not
+	// already a holiday
+	for date := range statutory {
+		if date.Weekday() != time.Sunday {
+			continue
+		}
+		substitute := models.Date{Time: date.AddDate(0, 0, 1)}
+		for {
+			if _, taken := holidays[substitute]; !taken {
+				break
+			}
+			substitute = models.Date{Time: substitute.AddDate(0, 0, 1)}
+		}
+		holidays[substitute] = holidayNameSubstitute
+	}
+
+	return holidays
+}
+
+func calendarDate(year int, month time.Month, day int) models.Date {
+	return models.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// nthWeekday returns the day of the month of the nth given weekday
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) int {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	offset := (int(weekday) - int(first) + 7) % 7
+	return 1 + offset + (n-1)*7
+}
+
+// vernalEquinoxDay approximates the March equinox for 1980-2099
+func vernalEquinoxDay(year int) int {
+	return equinoxDay(year, 20.8431)
+}
+
+// autumnalEquinoxDay approximates the September equinox for 1980-2099
+func autumnalEquinoxDay(year int) int {
+	return equinoxDay(year, 23.2488)
+}
+
+func equinoxDay(year int, base float64) int {
+	y := year - 1980
+	return int(math.Floor(base+0.242194*float64(y))) - y/4
+}
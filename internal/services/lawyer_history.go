@@ -1,12 +1,19 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 
 	"github.com/kotolino/lawyer/internal/models"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrHistoryEntryNotFound   = errors.New("history entry not found")
+	ErrLawyerVersionUnchanged = errors.New("lawyer profile already matches this version")
+)
+
 // GetLawyerHistory retrieves the history of changes for a specific lawyer
 func GetLawyerHistory(db *gorm.DB, lawyerID, page, limit int) ([]models.LawyerHistory, *models.Pagination, error) {
 	var histories []models.LawyerHistory
@@ -68,68 +75,92 @@ func LogLawyerChange(db *gorm.DB, lawyerID, userID int, changes []models.FieldCh
 	return db.Create(&history).Error
 }
 
-// CompareAndTrackLawyerChanges compares old and new lawyer data and tracks changes
+// CompareAndTrackLawyerChanges compares old and new lawyer data and tracks changes.
+// Tracked fields come from the struct tags on models.Lawyer.
 func CompareAndTrackLawyerChanges(db *gorm.DB, oldLawyer, newLawyer *models.Lawyer, userID int) error {
-	changes := []models.FieldChange{}
-
-	// Compare fields to detect changes
-	oldVal := reflect.ValueOf(oldLawyer).Elem()
-	newVal := reflect.ValueOf(newLawyer).Elem()
-	typeOfLawyer := oldVal.Type()
-
-	// Fields to track changes for
-	trackFields := map[string]bool{
-		"FullName":                 true,
-		"Email":                    true,
-		"Phone":                    true,
-		"OfficeName":               true,
-		"Address":                  true,
-		"Gender":                   true,
-		"FaxNumber":                true,
-		"Affiliation":              true,
-		"LawyerRegistrationNumber": true,
-		"ProfileText":              true,
-		"Notes":                    true,
-		"Status":                   true,
-		"Rating":                   true,
+	changes := diffTrackedFields(oldLawyer, newLawyer)
+
+	// If there are changes, log them
+	if len(changes) > 0 {
+		return LogLawyerChange(db, oldLawyer.ID, userID, changes)
 	}
 
-	// Iterate through fields and check for differences
-	for i := 0; i < typeOfLawyer.NumField(); i++ {
-		field := typeOfLawyer.Field(i)
-		fieldName := field.Name
+	return nil
+}
 
-		// Skip fields we don't want to track
-		if !trackFields[fieldName] {
-			continue
+// RestoreLawyerVersion rolls a lawyer profile back to how it looked right after the given
+// history entry was recorded. The restore is itself recorded as a new history entry.
+func RestoreLawyerVersion(db *gorm.DB, lawyerID, historyID, userID int) (*models.Lawyer, error) {
+	var target models.LawyerHistory
+	if err := db.Where("id = ? AND lawyer_id = ?", historyID, lawyerID).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHistoryEntryNotFound
 		}
+		return nil, err
+	}
 
-		oldField := oldVal.Field(i).Interface()
-		newField := newVal.Field(i).Interface()
+	var current models.Lawyer
+	if err := db.First(&current, lawyerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLawyerNotFound
+		}
+		return nil, err
+	}
 
-		// Compare values and record changes
-		if !reflect.DeepEqual(oldField, newField) {
-			changes = append(changes, models.FieldChange{
-				Field: fieldName,
-				Old:   oldField,
-				New:   newField,
-			})
+	// Undo every later change, newest first
+	var newer []models.LawyerHistory
+	if err := db.Where("lawyer_id = ? AND id > ?", lawyerID, historyID).Order("id DESC").Find(&newer).Error; err != nil {
+		return nil, err
+	}
+
+	restored := current
+	restoredVal := reflect.ValueOf(&restored).Elem()
+	for _, entry := range newer {
+		for _, change := range entry.Changes {
+			if _, err := setFieldFromHistory(restoredVal, change.Field, change.Old); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	// Special handling for array fields like specialties
-	if !reflect.DeepEqual(oldLawyer.Specialties, newLawyer.Specialties) {
-		changes = append(changes, models.FieldChange{
-			Field: "Specialties",
-			Old:   oldLawyer.Specialties,
-			New:   newLawyer.Specialties,
-		})
+	changes := diffTrackedFields(&current, &restored)
+	if len(changes) == 0 {
+		return nil, ErrLawyerVersionUnchanged
 	}
 
-	// If there are changes, log them
-	if len(changes) > 0 {
-		return LogLawyerChange(db, oldLawyer.ID, userID, changes)
+	// Map the changed fields to their columns
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&models.Lawyer{}); err != nil {
+		return nil, err
+	}
+	updates := make(map[string]interface{}, len(changes))
+	for _, change := range changes {
+		field := stmt.Schema.LookUpField(change.Field)
+		if field == nil {
+			return nil, fmt.Errorf("unknown lawyer field: %s", change.Field)
+		}
+		value := restoredVal.FieldByName(change.Field)
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			updates[field.DBName] = nil
+		} else {
+			updates[field.DBName] = value.Interface()
+		}
 	}
 
-	return nil
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Lawyer{}).Where("id = ?", lawyerID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LawyerHistory{
+			LawyerID:     lawyerID,
+			UserID:       userID,
+			Changes:      models.FieldChanges(changes),
+			RestoredFrom: &historyID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restored, nil
 }
@@ -12,6 +12,14 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrLawyerNotFound is returned when the lawyer an operation names does not exist
+	ErrLawyerNotFound = errors.New("lawyer not found")
+	// ErrInvalidTimezone is wrapped with the reason a user's or lawyer's time zone
+	// was refused
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
 // LawyerService handles business logic related to lawyers
 type LawyerService struct {
 	DB *gorm.DB
@@ -35,7 +43,7 @@ func (s *LawyerService) GetLawyerByID(id int) (*models.Lawyer, error) {
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("lawyer not found")
+			return nil, ErrLawyerNotFound
 		}
 		return nil, result.Error
 	}
@@ -54,7 +62,7 @@ func (s *LawyerService) GetLawyerByUserID(userID int) (*models.Lawyer, error) {
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("lawyer not found")
+			return nil, ErrLawyerNotFound
 		}
 		return nil, result.Error
 	}
@@ -133,7 +141,7 @@ func (s *LawyerService) UpdateLawyer(lawyerID int, updates map[string]interface{
 	var existingLawyer models.Lawyer
 	if err := s.DB.First(&existingLawyer, lawyerID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("lawyer not found")
+			return ErrLawyerNotFound
 		}
 		return err
 	}
@@ -226,6 +234,38 @@ func (s *LawyerService) UpdateLawyer(lawyerID int, updates map[string]interface{
 		updates["languages"] = models.StringArray(languages)
 	}
 
+	if availabilityRaw, ok := updates["availability"]; ok && availabilityRaw != nil {
+		// Decode into the typed schedule so malformed or overlapping hours are rejected
+		var availability models.Availability
+		availabilityJson, _ := json.Marshal(availabilityRaw)
+		if err := json.Unmarshal(availabilityJson, &availability); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAvailability, err)
+		}
+		if err := availability.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAvailability, err)
+		}
+		updates["availability"] = availability
+	}
+
+	if raw, ok := updates["timezone"]; ok {
+		name, _ := raw.(string)
+		if err := models.ValidateTimezone(name); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTimezone, err)
+		}
+	}
+
+	if settingsRaw, ok := updates["booking_settings"]; ok && settingsRaw != nil {
+		var settings models.BookingSettings
+		settingsJson, _ := json.Marshal(settingsRaw)
+		if err := json.Unmarshal(settingsJson, &settings); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidBookingSettings, err)
+		}
+		if err := settings.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidBookingSettings, err)
+		}
+		updates["booking_settings"] = settings
+	}
+
 	// Apply updates to the lawyer model
 	result := s.DB.Model(&models.Lawyer{}).Where("id = ?", lawyerID).Updates(updates)
 	return result.Error
@@ -409,7 +449,7 @@ func (s *LawyerService) UpdateLawyerVerificationStatus(lawyerID uint, isVerified
 	var existingLawyer models.Lawyer
 	if err := s.DB.First(&existingLawyer, lawyerID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("lawyer not found")
+			return ErrLawyerNotFound
 		}
 		return err
 	}
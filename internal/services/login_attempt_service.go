@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// LoginAttemptFilter narrows the login attempt audit list
+type LoginAttemptFilter struct {
+	Email     string
+	IPAddress string
+	Action    string
+	Success   *bool
+	From      *time.Time
+	To        *time.Time
+}
+
+// LoginAttemptService records and lists authentication attempts
+type LoginAttemptService struct {
+	DB *gorm.DB
+}
+
+// NewLoginAttemptService creates a new login attempt service
+func NewLoginAttemptService() *LoginAttemptService {
+	return &LoginAttemptService{
+		DB: repository.DB,
+	}
+}
+
+// Record stores an attempt. Failures are logged rather than returned so auditing
+// never blocks authentication.
+func (s *LoginAttemptService) Record(attempt *models.LoginAttempt) {
+	attempt.Email = normalizeEmail(attempt.Email)
+	if err := s.DB.Create(attempt).Error; err != nil {
+		fmt.Printf("Failed to record login attempt for %s: %v\n", attempt.Email, err)
+	}
+}
+
+// GetLoginAttempts returns attempts matching the filter, newest first
+func (s *LoginAttemptService) GetLoginAttempts(filter LoginAttemptFilter, page, limit int) ([]models.LoginAttempt, int64, error) {
+	var attempts []models.LoginAttempt
+	var total int64
+
+	query := s.DB.Model(&models.LoginAttempt{})
+	if filter.Email != "" {
+		query = query.Where("email = ?", normalizeEmail(filter.Email))
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return attempts, total, nil
+}
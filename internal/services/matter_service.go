@@ -0,0 +1,380 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxMatterDescriptionLength bounds a matter's description
+const maxMatterDescriptionLength = 10000
+
+var (
+	// ErrInvalidMatter is wrapped with the reason a matter or its use was refused
+	ErrInvalidMatter  = errors.New("invalid matter")
+	ErrMatterNotFound = errors.New("matter not found")
+)
+
+// MatterService manages clients' cases with a lawyer and the appointments grouped
+// under them
+type MatterService struct {
+	DB *gorm.DB
+}
+
+// NewMatterService creates a new matter service
+func NewMatterService() *MatterService {
+	return &MatterService{
+		DB: repository.DB,
+	}
+}
+
+// ListMatters lists a lawyer's or a client's matters, most recently updated first,
+// optionally only those in one status. A zero lawyerID or clientID is not filtered on.
+func (s *MatterService) ListMatters(lawyerID, clientID int, status string) ([]models.Matter, error) {
+	query := s.DB.Model(&models.Matter{})
+	if lawyerID > 0 {
+		query = query.Where("lawyer_id = ?", lawyerID)
+	}
+	if clientID > 0 {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var matters []models.Matter
+	if err := query.Order("updated_at DESC, id DESC").Find(&matters).Error; err != nil {
+		return nil, err
+	}
+	if len(matters) == 0 {
+		return matters, nil
+	}
+
+	ids := make([]int, len(matters))
+	for i, matter := range matters {
+		ids[i] = matter.ID
+	}
+	var parties []models.OpposingParty
+	if err := s.DB.Where("matter_id IN ?", ids).Order("id ASC").Find(&parties).Error; err != nil {
+		return nil, err
+	}
+	byMatter := make(map[int][]models.OpposingParty)
+	for _, party := range parties {
+		byMatter[*party.MatterID] = append(byMatter[*party.MatterID], party)
+	}
+	for i := range matters {
+		matters[i].OpposingParties = byMatter[matters[i].ID]
+		if matters[i].OpposingParties == nil {
+			matters[i].OpposingParties = []models.OpposingParty{}
+		}
+	}
+	return matters, nil
+}
+
+// GetMatter returns a matter with its opposing parties
+func (s *MatterService) GetMatter(id int) (*models.Matter, error) {
+	var matter models.Matter
+	if err := s.DB.First(&matter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMatterNotFound
+		}
+		return nil, err
+	}
+	parties, err := (&ConflictCheckService{DB: s.DB}).GetMatterParties(matter.ID)
+	if err != nil {
+		return nil, err
+	}
+	matter.OpposingParties = parties
+	return &matter, nil
+}
+
+// CreateMatter opens a matter between a lawyer and one of their clients, with its
+// opposing parties, and groups the given appointments under it. Validation errors
+// wrap ErrInvalidMatter or ErrInvalidOpposingParties.
+func (s *MatterService) CreateMatter(lawyer *models.Lawyer, matter *models.Matter, appointmentIDs []int) error {
+	matter.LawyerID = lawyer.ID
+	if matter.Status == "" {
+		matter.Status = models.MatterStatusOpen
+	}
+	if err := validateMatter(lawyer, matter); err != nil {
+		return err
+	}
+	parties, err := (&ConflictCheckService{DB: s.DB}).PrepareOpposingParties(matter.OpposingParties)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.Appointment{}).
+		Where("lawyer_id = ? AND user_id = ?", lawyer.ID, matter.ClientID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: the client has no appointments with you", ErrInvalidMatter)
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(matter).Error; err != nil {
+			return err
+		}
+		if err := (&ConflictCheckService{DB: tx}).replaceMatterParties(matter.ID, parties); err != nil {
+			return err
+		}
+		matter.OpposingParties = parties
+		for _, appointmentID := range appointmentIDs {
+			if err := (&MatterService{DB: tx}).LinkAppointment(matter, appointmentID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateMatter saves a matter's title, description, practice area and status, and
+// its opposing parties when replaceParties is set, checking the matter's upcoming
+// appointments again. Closing a matter records when.
+func (s *MatterService) UpdateMatter(lawyer *models.Lawyer, matter *models.Matter, replaceParties bool) error {
+	if err := validateMatter(lawyer, matter); err != nil {
+		return err
+	}
+	var parties []models.OpposingParty
+	if replaceParties {
+		var err error
+		if parties, err = (&ConflictCheckService{DB: s.DB}).PrepareOpposingParties(matter.OpposingParties); err != nil {
+			return err
+		}
+	}
+
+	if matter.Status != models.MatterStatusClosed {
+		matter.ClosedAt = nil
+	} else if matter.ClosedAt == nil {
+		now := time.Now().UTC()
+		matter.ClosedAt = &now
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(matter).Updates(map[string]interface{}{
+			"title":         matter.Title,
+			"description":   matter.Description,
+			"practice_area": matter.PracticeArea,
+			"status":        matter.Status,
+			"closed_at":     matter.ClosedAt,
+		}).Error; err != nil {
+			return err
+		}
+		if !replaceParties {
+			return nil
+		}
+		if err := (&ConflictCheckService{DB: tx}).replaceMatterParties(matter.ID, parties); err != nil {
+			return err
+		}
+		matter.OpposingParties = parties
+		return (&ConflictCheckService{DB: tx}).RecheckMatter(matter)
+	})
+}
+
+func validateMatter(lawyer *models.Lawyer, matter *models.Matter) error {
+	matter.Title = strings.TrimSpace(matter.Title)
+	matter.PracticeArea = strings.TrimSpace(matter.PracticeArea)
+	matter.Description = trimOptional(matter.Description)
+	switch {
+	case matter.Title == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidMatter)
+	case utf8.RuneCountInString(matter.Title) > 255:
+		return fmt.Errorf("%w: title must be at most 255 characters", ErrInvalidMatter)
+	case matter.Description != nil && utf8.RuneCountInString(*matter.Description) > maxMatterDescriptionLength:
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidMatter, maxMatterDescriptionLength)
+	case !models.ValidMatterStatus(matter.Status):
+		return fmt.Errorf("%w: status must be open, on_hold or closed", ErrInvalidMatter)
+	}
+	for _, specialty := range lawyer.Specialties {
+		if specialty == matter.PracticeArea {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: practice area must be one of the lawyer's specialties", ErrInvalidMatter)
+}
+
+// LinkAppointment groups one of the matter's client's appointments with its lawyer
+// under the matter, checking it for conflicts again if it was checked without the
+// matter's parties. An appointment belongs to at most one matter.
+func (s *MatterService) LinkAppointment(matter *models.Matter, appointmentID int) error {
+	var appointment models.Appointment
+	if err := s.DB.First(&appointment, appointmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAppointmentNotFound
+		}
+		return err
+	}
+	if appointment.LawyerID != matter.LawyerID || appointment.UserID != matter.ClientID {
+		return fmt.Errorf("%w: appointment %d is not between the matter's lawyer and client", ErrInvalidMatter, appointmentID)
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Appointment{}).
+			Where("id = ? AND (matter_id IS NULL OR matter_id = ?)", appointmentID, matter.ID).
+			Update("matter_id", matter.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: appointment %d already belongs to another matter", ErrInvalidMatter, appointmentID)
+		}
+		return (&ConflictCheckService{DB: tx}).RecheckMatter(matter)
+	})
+}
+
+// UnlinkAppointment removes an appointment from a matter
+func (s *MatterService) UnlinkAppointment(matter *models.Matter, appointmentID int) error {
+	result := s.DB.Model(&models.Appointment{}).
+		Where("id = ? AND matter_id = ?", appointmentID, matter.ID).
+		Update("matter_id", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAppointmentNotFound
+	}
+	return nil
+}
+
+// CheckBookingMatter checks that a client booking under a matter is booking one of
+// their own open matters with the lawyer. Errors wrap ErrInvalidMatter.
+func (s *MatterService) CheckBookingMatter(matterID, clientID, lawyerID int) error {
+	var matter models.Matter
+	err := s.DB.Where("id = ? AND client_id = ? AND lawyer_id = ?", matterID, clientID, lawyerID).First(&matter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: not one of your matters with this lawyer", ErrInvalidMatter)
+	}
+	if err != nil {
+		return err
+	}
+	if matter.Status == models.MatterStatusClosed {
+		return fmt.Errorf("%w: the matter is closed", ErrInvalidMatter)
+	}
+	return nil
+}
+
+// Timeline lists everything that happened in a matter's appointments in time
+// order: the appointments themselves, their status changes, chat messages with
+// their files, intake files and consultation records. The client's timeline only
+// has shared consultation records, as the client sees them.
+func (s *MatterService) Timeline(matter *models.Matter, forClient bool) ([]responses.MatterTimelineItem, error) {
+	var appointments []models.Appointment
+	if err := s.DB.Where("matter_id = ?", matter.ID).Order("start_time ASC").Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	items := []responses.MatterTimelineItem{}
+	if len(appointments) == 0 {
+		return items, nil
+	}
+
+	ids := make([]int, len(appointments))
+	for i, appointment := range appointments {
+		ids[i] = appointment.ID
+		items = append(items, responses.MatterTimelineItem{
+			Type:          responses.TimelineAppointment,
+			OccurredAt:    appointment.StartTime,
+			AppointmentID: appointment.ID,
+			Appointment: &responses.MatterAppointment{
+				ID:          appointment.ID,
+				Status:      appointment.Status.String(),
+				StartTime:   appointment.StartTime,
+				EndTime:     appointment.EndTime,
+				Description: appointment.Description,
+			},
+		})
+	}
+
+	var history []models.AppointmentStatusHistory
+	if err := s.DB.Where("appointment_id IN ?", ids).Find(&history).Error; err != nil {
+		return nil, err
+	}
+	for i := range history {
+		items = append(items, responses.MatterTimelineItem{
+			Type:          responses.TimelineStatusChange,
+			OccurredAt:    history[i].CreatedAt,
+			AppointmentID: history[i].AppointmentID,
+			StatusChange:  &history[i],
+		})
+	}
+
+	var messages []models.ChatMessage
+	if err := s.DB.Where("appointment_id IN ?", ids).
+		Preload("Attachment", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "file_path", "file_name", "file_size", "attachmentable_type", "attachmentable_id")
+		}).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		items = append(items, responses.MatterTimelineItem{
+			Type:          responses.TimelineChatMessage,
+			OccurredAt:    messages[i].CreatedAt,
+			AppointmentID: messages[i].AppointmentID,
+			ChatMessage:   &messages[i],
+		})
+	}
+
+	var intakes []models.IntakeResponse
+	if err := s.DB.Select("id", "appointment_id").Where("appointment_id IN ?", ids).Find(&intakes).Error; err != nil {
+		return nil, err
+	}
+	if len(intakes) > 0 {
+		appointmentOf := make(map[int]int, len(intakes))
+		intakeIDs := make([]int, len(intakes))
+		for i, intake := range intakes {
+			appointmentOf[intake.ID] = intake.AppointmentID
+			intakeIDs[i] = intake.ID
+		}
+		var files []models.Attachment
+		if err := s.DB.Where("attachmentable_type = ? AND attachmentable_id IN ?", AttachmentTypeIntakeResponse, intakeIDs).
+			Find(&files).Error; err != nil {
+			return nil, err
+		}
+		for i := range files {
+			items = append(items, responses.MatterTimelineItem{
+				Type:          responses.TimelineAttachment,
+				OccurredAt:    files[i].CreatedAt,
+				AppointmentID: appointmentOf[files[i].AttachmentableID],
+				Attachment:    &files[i],
+			})
+		}
+	}
+
+	var records []models.ConsultationRecord
+	if err := s.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("due_date ASC, id ASC")
+	}).Where("appointment_id IN ?", ids).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		occurredAt := record.CreatedAt
+		if forClient {
+			if record.SharedAt == nil {
+				continue
+			}
+			record = record.ForClient()
+			occurredAt = *record.SharedAt
+		}
+		items = append(items, responses.MatterTimelineItem{
+			Type:               responses.TimelineConsultationRecord,
+			OccurredAt:         occurredAt,
+			AppointmentID:      record.AppointmentID,
+			ConsultationRecord: &record,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OccurredAt.Before(items[j].OccurredAt)
+	})
+	return items, nil
+}
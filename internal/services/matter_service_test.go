@@ -0,0 +1,135 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/handlers/responses"
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// createTestMatter inserts an open civil matter between a new lawyer and client
+func createTestMatter(t *testing.T, db *gorm.DB) (*models.Matter, *models.Lawyer, *models.User) {
+	t.Helper()
+
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	matter := &models.Matter{
+		LawyerID:     lawyer.ID,
+		ClientID:     client.ID,
+		Title:        "Lease dispute",
+		PracticeArea: "civil",
+		Status:       models.MatterStatusOpen,
+	}
+	if err := db.Create(matter).Error; err != nil {
+		t.Fatalf("creating test matter: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Delete(&models.Matter{}, matter.ID)
+	})
+	return matter, lawyer, client
+}
+
+func TestMatterTimeline(t *testing.T) {
+	db := openTestDB(t)
+	matter, lawyer, client := createTestMatter(t, db)
+	base := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+
+	first := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusCompleted, base, time.Hour)
+	second := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusCompleted, base.Add(7*24*time.Hour), time.Hour)
+	unrelated := createTestAppointmentAt(t, db, client.ID, lawyer.ID, models.AppointmentStatusCompleted, base.Add(-7*24*time.Hour), time.Hour)
+	if err := db.Model(&models.Appointment{}).Where("id IN ?", []int{first.ID, second.ID}).Update("matter_id", matter.ID).Error; err != nil {
+		t.Fatalf("linking appointments: %v", err)
+	}
+
+	confirmed := models.AppointmentStatusConfirmed
+	for _, row := range []interface{}{
+		&models.AppointmentStatusHistory{AppointmentID: first.ID, FromStatus: nil, ToStatus: confirmed, CreatedAt: base.Add(-48 * time.Hour)},
+		&models.ChatMessage{AppointmentID: first.ID, SenderID: client.ID, ReceiverID: lawyer.UserID, Content: "See you then", CreatedAt: base.Add(-time.Hour)},
+		&models.ChatMessage{AppointmentID: unrelated.ID, SenderID: client.ID, ReceiverID: lawyer.UserID, Content: "Another case", CreatedAt: base.Add(-time.Hour)},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("creating %T: %v", row, err)
+		}
+	}
+
+	notes := "Client may settle"
+	summary := "Reviewed the lease"
+	sharedAt := base.Add(26 * time.Hour)
+	shared := &models.ConsultationRecord{
+		AppointmentID: first.ID, LawyerID: lawyer.ID, PrivateNotes: &notes, Summary: &summary, SharedAt: &sharedAt,
+		CreatedAt: base.Add(2 * time.Hour),
+		Tasks: []models.FollowUpTask{
+			{LawyerID: lawyer.ID, Assignee: models.FollowUpAssigneeLawyer, Title: "Draft the notice", DueDate: models.NewDate(base), Status: models.FollowUpTaskOpen},
+			{LawyerID: lawyer.ID, Assignee: models.FollowUpAssigneeClient, Title: "Send the lease", DueDate: models.NewDate(base), Status: models.FollowUpTaskOpen},
+		},
+	}
+	unshared := &models.ConsultationRecord{AppointmentID: second.ID, LawyerID: lawyer.ID, PrivateNotes: &notes, CreatedAt: second.EndTime}
+	for _, record := range []*models.ConsultationRecord{shared, unshared} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("creating consultation record: %v", err)
+		}
+	}
+
+	service := &MatterService{DB: db}
+	types := func(items []responses.MatterTimelineItem) []string {
+		got := make([]string, len(items))
+		for i, item := range items {
+			got[i] = item.Type
+		}
+		return got
+	}
+
+	items, err := service.Timeline(matter, false)
+	if err != nil {
+		t.Fatalf("Timeline: %v", err)
+	}
+	want := []string{
+		responses.TimelineStatusChange,
+		responses.TimelineChatMessage,
+		responses.TimelineAppointment,
+		responses.TimelineConsultationRecord,
+		responses.TimelineAppointment,
+		responses.TimelineConsultationRecord,
+	}
+	if got := types(items); !slices.Equal(got, want) {
+		t.Fatalf("lawyer's timeline = %v, want %v", got, want)
+	}
+	for _, item := range items {
+		if item.AppointmentID == unrelated.ID {
+			t.Errorf("timeline has %s of an appointment outside the matter", item.Type)
+		}
+	}
+	if record := items[3].ConsultationRecord; record.PrivateNotes == nil || len(record.Tasks) != 2 {
+		t.Errorf("lawyer's record has notes %v and %d tasks, want notes and 2 tasks", record.PrivateNotes, len(record.Tasks))
+	}
+
+	// The client sees only the shared record, when it was shared, without the
+	// private notes or the lawyer's tasks
+	items, err = service.Timeline(matter, true)
+	if err != nil {
+		t.Fatalf("Timeline: %v", err)
+	}
+	want = []string{
+		responses.TimelineStatusChange,
+		responses.TimelineChatMessage,
+		responses.TimelineAppointment,
+		responses.TimelineConsultationRecord,
+		responses.TimelineAppointment,
+	}
+	if got := types(items); !slices.Equal(got, want) {
+		t.Fatalf("client's timeline = %v, want %v", got, want)
+	}
+	record := items[3]
+	if !record.OccurredAt.Equal(sharedAt) || record.ConsultationRecord.ID != shared.ID {
+		t.Errorf("client's record %d at %v, want record %d at %v", record.ConsultationRecord.ID, record.OccurredAt, shared.ID, sharedAt)
+	}
+	if record.ConsultationRecord.PrivateNotes != nil {
+		t.Error("client's record has the private notes")
+	}
+	if tasks := record.ConsultationRecord.Tasks; len(tasks) != 1 || tasks[0].Assignee != models.FollowUpAssigneeClient {
+		t.Errorf("client's record tasks = %+v, want only the client's task", tasks)
+	}
+}
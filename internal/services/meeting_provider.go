@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// Meeting is an online meeting created for an appointment
+type Meeting struct {
+	// ID identifies the meeting to its provider so it can be deleted later
+	ID  string
+	URL string
+}
+
+// MeetingProvider creates and deletes online meetings for confirmed appointments
+type MeetingProvider interface {
+	// Name is stored with each meeting so it is only ever deleted by the provider
+	// that created it
+	Name() string
+	CreateMeeting(ctx context.Context, appointment *models.Appointment) (*Meeting, error)
+	DeleteMeeting(ctx context.Context, meetingID string) error
+}
+
+// newMeetingProvider returns the provider selected in cfg, or nil when links are
+// left to lawyers
+func newMeetingProvider(cfg config.MeetingConfig) MeetingProvider {
+	switch cfg.Provider {
+	case "jitsi":
+		return &JitsiMeetingProvider{BaseURL: cfg.JitsiBaseURL}
+	case "http":
+		return &HTTPMeetingProvider{
+			BaseURL: cfg.APIURL,
+			Token:   cfg.APIToken,
+			Client:  &http.Client{Timeout: cfg.Timeout},
+		}
+	default:
+		return nil
+	}
+}
+
+// JitsiMeetingProvider generates Jitsi Meet room URLs. Rooms exist while someone is
+// in them, so nothing is created up front; the random room name is what keeps
+// others out.
+type JitsiMeetingProvider struct {
+	BaseURL string
+}
+
+// Name implements MeetingProvider
+func (p *JitsiMeetingProvider) Name() string {
+	return "jitsi"
+}
+
+// CreateMeeting implements MeetingProvider
+func (p *JitsiMeetingProvider) CreateMeeting(ctx context.Context, appointment *models.Appointment) (*Meeting, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	room := fmt.Sprintf("bengoshicchi-%d-%s", appointment.ID, hex.EncodeToString(b))
+	return &Meeting{ID: room, URL: p.BaseURL + "/" + room}, nil
+}
+
+// DeleteMeeting implements MeetingProvider. A Jitsi room cannot be deleted; once
+// its link is no longer shown nobody can find it.
+func (p *JitsiMeetingProvider) DeleteMeeting(ctx context.Context, meetingID string) error {
+	return nil
+}
+
+// HTTPMeetingProvider creates meetings through a Zoom-like conferencing API:
+//
+//	POST   {BaseURL}/meetings       {"topic", "start_time", "duration", "timezone"} -> {"id", "join_url"}
+//	DELETE {BaseURL}/meetings/{id}
+//
+// Requests carry Token as a bearer token.
+type HTTPMeetingProvider struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+type httpMeetingRequest struct {
+	Topic     string    `json:"topic"`
+	StartTime time.Time `json:"start_time"`
+	// Duration is in minutes
+	Duration int    `json:"duration"`
+	Timezone string `json:"timezone"`
+}
+
+type httpMeetingResponse struct {
+	// ID may be a number or a string depending on the API
+	ID      json.RawMessage `json:"id"`
+	JoinURL string          `json:"join_url"`
+}
+
+// Name implements MeetingProvider
+func (p *HTTPMeetingProvider) Name() string {
+	return "http"
+}
+
+// CreateMeeting implements MeetingProvider. Only the appointment number is sent;
+// names and descriptions stay on the platform.
+func (p *HTTPMeetingProvider) CreateMeeting(ctx context.Context, appointment *models.Appointment) (*Meeting, error) {
+	body, err := json.Marshal(httpMeetingRequest{
+		Topic:     fmt.Sprintf("法律相談 #%d", appointment.ID),
+		StartTime: appointment.StartTime.UTC(),
+		Duration:  int(appointment.EndTime.Sub(appointment.StartTime).Minutes()),
+		Timezone:  "UTC",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, "/meetings", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("meeting API returned %s", resp.Status)
+	}
+
+	var created httpMeetingResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&created); err != nil {
+		return nil, fmt.Errorf("invalid meeting API response: %v", err)
+	}
+	id := string(created.ID)
+	var quoted string
+	if json.Unmarshal(created.ID, &quoted) == nil {
+		id = quoted
+	}
+	if id == "" || id == "null" || created.JoinURL == "" {
+		return nil, fmt.Errorf("meeting API response is missing id or join_url")
+	}
+	return &Meeting{ID: id, URL: created.JoinURL}, nil
+}
+
+// DeleteMeeting implements MeetingProvider. A meeting that is already gone counts
+// as deleted.
+func (p *HTTPMeetingProvider) DeleteMeeting(ctx context.Context, meetingID string) error {
+	resp, err := p.do(ctx, http.MethodDelete, "/meetings/"+url.PathEscape(meetingID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("meeting API returned %s", resp.Status)
+	}
+}
+
+func (p *HTTPMeetingProvider) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	return p.Client.Do(req)
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// MeetingService manages the meeting links of online consultations. Links are
+// created when an appointment is confirmed, replaced when it moves and removed when
+// it is cancelled. Links entered by hand are left alone.
+type MeetingService struct {
+	DB *gorm.DB
+	// Provider is nil when meeting links are left to lawyers
+	Provider MeetingProvider
+	Timeout  time.Duration
+}
+
+// NewMeetingService creates a meeting service using the configured provider
+func NewMeetingService() *MeetingService {
+	cfg := config.MeetingConfig{Provider: "none", Timeout: 10 * time.Second}
+	if appCfg := GetConfig(); appCfg != nil {
+		cfg = appCfg.Meeting
+	}
+	return &MeetingService{
+		DB:       repository.DB,
+		Provider: newMeetingProvider(cfg),
+		Timeout:  cfg.Timeout,
+	}
+}
+
+// isManagedMeeting reports whether an appointment's link was created automatically
+func isManagedMeeting(appointment *models.Appointment) bool {
+	return appointment.MeetingProvider != nil
+}
+
+// hasManualMeetingLink reports whether a lawyer entered the appointment's link
+func hasManualMeetingLink(appointment *models.Appointment) bool {
+	return !isManagedMeeting(appointment) && appointment.MeetingLink != nil && *appointment.MeetingLink != ""
+}
+
+// SyncMeetingForStatus brings an appointment's meeting in line with its status:
+// confirmed appointments get a meeting, rejected and cancelled ones lose theirs.
+// Failures are logged; the appointment can still take place with a link entered by
+// hand.
+func (s *MeetingService) SyncMeetingForStatus(appointment *models.Appointment) {
+	switch appointment.Status {
+	case models.AppointmentStatusConfirmed:
+		if err := s.ProvisionMeeting(appointment); err != nil {
+			fmt.Printf("Failed to create meeting for appointment %d: %v\n", appointment.ID, err)
+		}
+	case models.AppointmentStatusRejected, models.AppointmentStatusCancelled:
+		if err := s.RevokeMeeting(appointment); err != nil {
+			fmt.Printf("Failed to revoke meeting for appointment %d: %v\n", appointment.ID, err)
+		}
+	}
+}
+
+// ProvisionMeeting creates a meeting for an appointment that has no link yet
+func (s *MeetingService) ProvisionMeeting(appointment *models.Appointment) error {
+	if s.Provider == nil || isManagedMeeting(appointment) || hasManualMeetingLink(appointment) {
+		return nil
+	}
+
+	meeting, err := s.createMeeting(appointment)
+	if err != nil {
+		return err
+	}
+
+	// A link entered or created meanwhile wins; the new meeting is thrown away
+	result := s.DB.Model(&models.Appointment{}).
+		Where("id = ? AND meeting_provider IS NULL AND (meeting_link IS NULL OR meeting_link = '')", appointment.ID).
+		Updates(s.meetingColumns(meeting))
+	if result.Error != nil || result.RowsAffected == 0 {
+		s.deleteMeeting(s.Provider.Name(), meeting.ID)
+		return result.Error
+	}
+
+	s.applyMeeting(appointment, meeting)
+	return nil
+}
+
+// RegenerateMeeting replaces the meeting of a confirmed appointment whose time has
+// changed. Appointments with a link entered by hand keep it.
+func (s *MeetingService) RegenerateMeeting(appointment *models.Appointment) error {
+	if appointment.Status != models.AppointmentStatusConfirmed || s.Provider == nil || hasManualMeetingLink(appointment) {
+		return nil
+	}
+	if !isManagedMeeting(appointment) {
+		return s.ProvisionMeeting(appointment)
+	}
+
+	meeting, err := s.createMeeting(appointment)
+	if err != nil {
+		return err
+	}
+
+	// Only replace the meeting this appointment was loaded with
+	previousProvider, previousID := *appointment.MeetingProvider, appointment.MeetingID
+	result := s.DB.Model(&models.Appointment{}).
+		Where("id = ? AND meeting_provider = ? AND meeting_link = ?", appointment.ID, previousProvider, appointment.MeetingLink).
+		Updates(s.meetingColumns(meeting))
+	if result.Error != nil || result.RowsAffected == 0 {
+		s.deleteMeeting(s.Provider.Name(), meeting.ID)
+		return result.Error
+	}
+
+	if previousID != nil {
+		s.deleteMeeting(previousProvider, *previousID)
+	}
+	s.applyMeeting(appointment, meeting)
+	return nil
+}
+
+// RevokeMeeting deletes an appointment's automatically created meeting and removes
+// its link
+func (s *MeetingService) RevokeMeeting(appointment *models.Appointment) error {
+	if !isManagedMeeting(appointment) {
+		return nil
+	}
+
+	if err := s.DB.Model(&models.Appointment{}).Where("id = ?", appointment.ID).Updates(map[string]interface{}{
+		"meeting_link":     nil,
+		"meeting_provider": nil,
+		"meeting_id":       nil,
+	}).Error; err != nil {
+		return err
+	}
+
+	if appointment.MeetingID != nil {
+		s.deleteMeeting(*appointment.MeetingProvider, *appointment.MeetingID)
+	}
+	appointment.MeetingLink = nil
+	appointment.MeetingProvider = nil
+	appointment.MeetingID = nil
+	return nil
+}
+
+// ReleaseMeeting deletes a meeting whose link has been replaced by hand. The
+// appointment's columns have already been updated by the caller.
+func (s *MeetingService) ReleaseMeeting(provider, meetingID string) {
+	s.deleteMeeting(provider, meetingID)
+}
+
+func (s *MeetingService) createMeeting(appointment *models.Appointment) (*Meeting, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	return s.Provider.CreateMeeting(ctx, appointment)
+}
+
+// deleteMeeting deletes a meeting at its provider, logging failures. Meetings made
+// by a provider that is no longer configured cannot be reached and are skipped.
+func (s *MeetingService) deleteMeeting(provider, meetingID string) {
+	if s.Provider == nil || s.Provider.Name() != provider {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	if err := s.Provider.DeleteMeeting(ctx, meetingID); err != nil {
+		fmt.Printf("Failed to delete %s meeting %s: %v\n", provider, meetingID, err)
+	}
+}
+
+func (s *MeetingService) meetingColumns(meeting *Meeting) map[string]interface{} {
+	return map[string]interface{}{
+		"meeting_link":     meeting.URL,
+		"meeting_provider": s.Provider.Name(),
+		"meeting_id":       meeting.ID,
+	}
+}
+
+func (s *MeetingService) applyMeeting(appointment *models.Appointment, meeting *Meeting) {
+	provider := s.Provider.Name()
+	appointment.MeetingLink = &meeting.URL
+	appointment.MeetingProvider = &provider
+	appointment.MeetingID = &meeting.ID
+}
+
+func (s *MeetingService) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return s.Timeout
+}
+
+// MeetingLinkWindow returns how long before its start an appointment's meeting
+// link is shown to its client and lawyer
+func (s *MeetingService) MeetingLinkWindow() time.Duration {
+	minutes, err := (&PlatformSettingService{DB: s.DB}).GetMeetingLinkWindowMinutes()
+	if err != nil {
+		minutes = DefaultMeetingLinkWindowMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// MeetingLinkAvailableAt returns when the meeting link of an appointment starting
+// at start is first shown to its parties
+func MeetingLinkAvailableAt(start time.Time, window time.Duration) time.Time {
+	return start.Add(-window)
+}
+
+// MeetingLinkVisible reports whether the parties may see an appointment's meeting
+// link at now: from the start of the window until the appointment ends
+func MeetingLinkVisible(start, end time.Time, window time.Duration, now time.Time) bool {
+	return !now.Before(MeetingLinkAvailableAt(start, window)) && now.Before(end)
+}
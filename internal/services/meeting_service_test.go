@@ -0,0 +1,286 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// fakeMeetingAPI is a conferencing API in the shape HTTPMeetingProvider expects
+type fakeMeetingAPI struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	nextID  int
+	created []httpMeetingRequest
+	deleted []string
+	// failCreate makes POST /meetings answer 500
+	failCreate bool
+}
+
+func newFakeMeetingAPI(t *testing.T) *fakeMeetingAPI {
+	t.Helper()
+
+	api := &fakeMeetingAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/meetings":
+			if api.failCreate {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			var req httpMeetingRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			api.nextID++
+			api.created = append(api.created, req)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"id": %d, "join_url": "https://meet.example.com/j/%d"}`, api.nextID, api.nextID)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/meetings/"):
+			api.deleted = append(api.deleted, strings.TrimPrefix(r.URL.Path, "/meetings/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// calls returns the meetings created and the IDs deleted so far
+func (api *fakeMeetingAPI) calls() (created []httpMeetingRequest, deleted []string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]httpMeetingRequest(nil), api.created...), append([]string(nil), api.deleted...)
+}
+
+func (api *fakeMeetingAPI) setFailCreate(fail bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.failCreate = fail
+}
+
+func (api *fakeMeetingAPI) provider() *HTTPMeetingProvider {
+	return &HTTPMeetingProvider{BaseURL: api.URL, Token: "test-token", Client: api.Client()}
+}
+
+func TestHTTPMeetingProviderCreateMeeting(t *testing.T) {
+	api := newFakeMeetingAPI(t)
+	start := time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC)
+	appointment := &models.Appointment{ID: 42, StartTime: start, EndTime: start.Add(time.Hour)}
+
+	meeting, err := api.provider().CreateMeeting(t.Context(), appointment)
+	if err != nil {
+		t.Fatalf("CreateMeeting: %v", err)
+	}
+	if meeting.ID != "1" || meeting.URL != "https://meet.example.com/j/1" {
+		t.Errorf("meeting = %+v, want id 1 with its join URL", meeting)
+	}
+
+	created, _ := api.calls()
+	req := created[0]
+	if !req.StartTime.Equal(start) || req.Duration != 60 || req.Timezone != "UTC" {
+		t.Errorf("request = %+v, want a 60 minute meeting at %v UTC", req, start)
+	}
+	if !strings.Contains(req.Topic, "#42") {
+		t.Errorf("topic %q does not name appointment 42", req.Topic)
+	}
+}
+
+func TestHTTPMeetingProviderResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantID  string
+		wantErr bool
+	}{
+		{name: "numeric id", status: http.StatusCreated, body: `{"id": 85746065432, "join_url": "https://meet.example.com/j/1"}`, wantID: "85746065432"},
+		{name: "string id", status: http.StatusOK, body: `{"id": "abc-def", "join_url": "https://meet.example.com/j/2"}`, wantID: "abc-def"},
+		{name: "server error", status: http.StatusBadGateway, body: `{}`, wantErr: true},
+		{name: "missing join_url", status: http.StatusCreated, body: `{"id": 1}`, wantErr: true},
+		{name: "null id", status: http.StatusCreated, body: `{"id": null, "join_url": "https://meet.example.com/j/3"}`, wantErr: true},
+		{name: "not json", status: http.StatusOK, body: `<html>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			provider := &HTTPMeetingProvider{BaseURL: server.URL, Client: server.Client()}
+			meeting, err := provider.CreateMeeting(t.Context(), &models.Appointment{ID: 1})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("CreateMeeting = %+v, want an error", meeting)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateMeeting: %v", err)
+			}
+			if meeting.ID != tt.wantID {
+				t.Errorf("meeting ID = %q, want %q", meeting.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestHTTPMeetingProviderDeleteMeeting(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNoContent, http.StatusNotFound, http.StatusInternalServerError} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodDelete || r.URL.EscapedPath() != "/meetings/a%2Fb" {
+				t.Errorf("request = %s %s, want DELETE /meetings/a%%2Fb", r.Method, r.URL.EscapedPath())
+			}
+			w.WriteHeader(status)
+		}))
+
+		provider := &HTTPMeetingProvider{BaseURL: server.URL, Client: server.Client()}
+		err := provider.DeleteMeeting(t.Context(), "a/b")
+		if wantErr := status == http.StatusInternalServerError; (err != nil) != wantErr {
+			t.Errorf("status %d: DeleteMeeting error = %v, want error %v", status, err, wantErr)
+		}
+		server.Close()
+	}
+}
+
+// createTestAppointment inserts a confirmed appointment with a new client and lawyer
+func createTestAppointment(t *testing.T, db *gorm.DB) *models.Appointment {
+	t.Helper()
+
+	lawyer := createTestLawyer(t, db)
+	client := createTestUser(t, db, models.RoleClient)
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	appointment := &models.Appointment{
+		UserID:    client.ID,
+		LawyerID:  lawyer.ID,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Status:    models.AppointmentStatusConfirmed,
+	}
+	if err := db.Omit("User", "Lawyer").Create(appointment).Error; err != nil {
+		t.Fatalf("creating test appointment: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&models.Appointment{}, appointment.ID)
+	})
+	return appointment
+}
+
+// storedMeeting reloads an appointment's meeting columns
+func storedMeeting(t *testing.T, db *gorm.DB, appointmentID int) *models.Appointment {
+	t.Helper()
+
+	var stored models.Appointment
+	if err := db.Select("id", "meeting_link", "meeting_provider", "meeting_id").First(&stored, appointmentID).Error; err != nil {
+		t.Fatalf("loading appointment: %v", err)
+	}
+	return &stored
+}
+
+func TestMeetingServiceProvisionAndRegenerate(t *testing.T) {
+	db := openTestDB(t)
+	api := newFakeMeetingAPI(t)
+	service := &MeetingService{DB: db, Provider: api.provider(), Timeout: 5 * time.Second}
+	appointment := createTestAppointment(t, db)
+
+	if err := service.ProvisionMeeting(appointment); err != nil {
+		t.Fatalf("ProvisionMeeting: %v", err)
+	}
+	stored := storedMeeting(t, db, appointment.ID)
+	if stored.MeetingLink == nil || *stored.MeetingLink != "https://meet.example.com/j/1" ||
+		stored.MeetingProvider == nil || *stored.MeetingProvider != "http" ||
+		stored.MeetingID == nil || *stored.MeetingID != "1" {
+		t.Fatalf("stored meeting = %v %v %v, want meeting 1 of the http provider", stored.MeetingLink, stored.MeetingProvider, stored.MeetingID)
+	}
+
+	// Provisioning again keeps the meeting
+	if err := service.ProvisionMeeting(appointment); err != nil {
+		t.Fatalf("second ProvisionMeeting: %v", err)
+	}
+	if created, _ := api.calls(); len(created) != 1 {
+		t.Errorf("provider created %d meetings, want 1", len(created))
+	}
+
+	if err := service.RegenerateMeeting(appointment); err != nil {
+		t.Fatalf("RegenerateMeeting: %v", err)
+	}
+	stored = storedMeeting(t, db, appointment.ID)
+	if stored.MeetingID == nil || *stored.MeetingID != "2" || *appointment.MeetingID != "2" {
+		t.Errorf("meeting ID after regenerating = %v, want 2", stored.MeetingID)
+	}
+	if _, deleted := api.calls(); len(deleted) != 1 || deleted[0] != "1" {
+		t.Errorf("provider deleted %v, want the old meeting 1", deleted)
+	}
+}
+
+func TestMeetingServiceProviderFailure(t *testing.T) {
+	db := openTestDB(t)
+	api := newFakeMeetingAPI(t)
+	service := &MeetingService{DB: db, Provider: api.provider(), Timeout: 5 * time.Second}
+	appointment := createTestAppointment(t, db)
+
+	if err := service.ProvisionMeeting(appointment); err != nil {
+		t.Fatalf("ProvisionMeeting: %v", err)
+	}
+
+	api.setFailCreate(true)
+	if err := service.RegenerateMeeting(appointment); err == nil {
+		t.Fatal("RegenerateMeeting succeeded while the provider is failing")
+	}
+	stored := storedMeeting(t, db, appointment.ID)
+	if stored.MeetingID == nil || *stored.MeetingID != "1" || *appointment.MeetingID != "1" {
+		t.Errorf("meeting ID after a failed regeneration = %v, want the original 1", stored.MeetingID)
+	}
+	if _, deleted := api.calls(); len(deleted) != 0 {
+		t.Errorf("provider deleted %v, want the original meeting kept", deleted)
+	}
+
+	// A failed provision leaves the appointment without a link
+	other := createTestAppointment(t, db)
+	if err := service.ProvisionMeeting(other); err == nil {
+		t.Fatal("ProvisionMeeting succeeded while the provider is failing")
+	}
+	if stored := storedMeeting(t, db, other.ID); stored.MeetingLink != nil || stored.MeetingProvider != nil {
+		t.Errorf("appointment got meeting %v after a failed provision", stored.MeetingLink)
+	}
+}
+
+func TestMeetingServiceKeepsManualLinks(t *testing.T) {
+	api := newFakeMeetingAPI(t)
+	service := &MeetingService{Provider: api.provider()}
+	link := "https://meet.example.com/lawyers-own-room"
+	appointment := &models.Appointment{Status: models.AppointmentStatusConfirmed, MeetingLink: &link}
+
+	if err := service.ProvisionMeeting(appointment); err != nil {
+		t.Fatalf("ProvisionMeeting: %v", err)
+	}
+	if err := service.RegenerateMeeting(appointment); err != nil {
+		t.Fatalf("RegenerateMeeting: %v", err)
+	}
+	if err := service.RevokeMeeting(appointment); err != nil {
+		t.Fatalf("RevokeMeeting: %v", err)
+	}
+	if created, _ := api.calls(); len(created) != 0 || *appointment.MeetingLink != link {
+		t.Errorf("manual link replaced: created %d meetings, link %q", len(created), *appointment.MeetingLink)
+	}
+}
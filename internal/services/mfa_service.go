@@ -0,0 +1,402 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// TOTP parameters (RFC 6238 defaults, which every authenticator app supports)
+const (
+	totpIssuer        = "べんごしっち"
+	totpPeriodSeconds = 30
+	totpDigits        = 6
+	totpSkewSteps     = 1
+	recoveryCodeCount = 10
+	totpQRCodeSize    = 256
+)
+
+var (
+	ErrMFANotEnrolled     = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode     = errors.New("invalid authentication code")
+	ErrMFARequiredForRole = errors.New("two-factor authentication is required for this role")
+)
+
+// MFAEnrollment holds the data an authenticator app needs to register a user
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	// QRCode is the otpauth URI as a PNG data URI, ready for an <img> tag
+	QRCode string `json:"qr_code"`
+}
+
+// MFAService handles TOTP enrollment, verification and recovery codes
+type MFAService struct {
+	DB *gorm.DB
+	// Key encrypts TOTP secrets at rest
+	Key []byte
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService() *MFAService {
+	var key []byte
+	if cfg := GetConfig(); cfg != nil {
+		key = cfg.CredentialsKey
+	}
+	return &MFAService{
+		DB:  repository.DB,
+		Key: key,
+	}
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. The secret is stored
+// but two-factor authentication stays disabled until ConfirmEnrollment succeeds.
+func (s *MFAService) BeginEnrollment(user *models.User) (*MFAEnrollment, error) {
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	sealed, err := encryptCredential(s.Key, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	otpAuthURL := buildOTPAuthURL(user.Email, secret)
+	qrCode, err := otpAuthQRCode(otpAuthURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"totp_secret":         sealed,
+		"totp_last_used_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = &sealed
+
+	return &MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURL: otpAuthURL,
+		QRCode:     qrCode,
+	}, nil
+}
+
+// totpSecret returns the user's TOTP secret in the clear. Secrets stored before
+// encryption was introduced are plaintext and returned as they are.
+func (s *MFAService) totpSecret(user *models.User) (secret string, legacy bool, err error) {
+	stored := *user.TOTPSecret
+	if !strings.HasPrefix(stored, credentialCipherVersion) {
+		return stored, true, nil
+	}
+	secret, err = decryptCredential(s.Key, stored)
+	return secret, false, err
+}
+
+// sealLegacySecret encrypts a plaintext secret left from before encryption once
+// the user has proved they hold it. Failures are logged; the plaintext secret
+// keeps working and is sealed on a later login.
+func (s *MFAService) sealLegacySecret(user *models.User, secret string) {
+	if len(s.Key) == 0 {
+		return
+	}
+	sealed, err := encryptCredential(s.Key, secret)
+	if err == nil {
+		err = s.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("totp_secret", sealed).Error
+	}
+	if err != nil {
+		fmt.Printf("Failed to encrypt TOTP secret of user %d: %v\n", user.ID, err)
+		return
+	}
+	user.TOTPSecret = &sealed
+}
+
+// ConfirmEnrollment checks the first code from the authenticator app, enables
+// two-factor authentication and returns a fresh set of recovery codes
+func (s *MFAService) ConfirmEnrollment(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == nil || *user.TOTPSecret == "" {
+		return nil, errors.New("enrollment has not been started")
+	}
+
+	secret, legacy, err := s.totpSecret(user)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(secret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"totp_enabled":        true,
+			"totp_enabled_at":     now,
+			"totp_last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	if legacy {
+		s.sealLegacySecret(user, secret)
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code or, if that fails, a recovery code. Each TOTP time
+// step and each recovery code can only be used once.
+func (s *MFAService) Verify(user *models.User, code string) error {
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return ErrMFANotEnrolled
+	}
+
+	secret, legacy, err := s.totpSecret(user)
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := validateTOTP(secret, code, time.Now(), user.TOTPLastUsedStep); ok {
+		// Conditional update so two concurrent requests cannot both use the same step
+		result := s.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_used_step < ?", user.ID, step).
+			Update("totp_last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		user.TOTPLastUsedStep = step
+		if legacy {
+			s.sealLegacySecret(user, secret)
+		}
+		return nil
+	}
+
+	return s.useRecoveryCode(user.ID, code)
+}
+
+// RegenerateRecoveryCodes invalidates all existing recovery codes and issues new ones
+func (s *MFAService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if err := s.Verify(user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// CountRemainingRecoveryCodes returns how many unused recovery codes the user has left
+func (s *MFAService) CountRemainingRecoveryCodes(userID int) (int64, error) {
+	var count int64
+	err := s.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Disable turns off two-factor authentication for a user and removes their secret
+// and recovery codes. It refuses if the user's role requires two-factor authentication.
+func (s *MFAService) Disable(user *models.User) error {
+	required, err := NewPlatformSettingService().IsMFARequiredForRole(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredForRole
+	}
+	return s.Reset(user.ID)
+}
+
+// Reset removes a user's two-factor configuration regardless of role policy.
+// Admins use this when a user has lost their device and their recovery codes.
+func (s *MFAService) Reset(userID int) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":         nil,
+			"totp_enabled":        false,
+			"totp_enabled_at":     nil,
+			"totp_last_used_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// useRecoveryCode marks a matching unused recovery code as used
+func (s *MFAService) useRecoveryCode(userID int, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidMFACode
+	}
+
+	result := s.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and creates a new set inside tx
+func replaceRecoveryCodes(tx *gorm.DB, userID int) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// normalizeRecoveryCode lowercases the code and strips separators users may type
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// hashRecoveryCode returns the hex SHA-256 of a normalized recovery code
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildOTPAuthURL builds the otpauth:// URI encoded into enrollment QR codes
+func buildOTPAuthURL(accountName, secret string) string {
+	label := url.PathEscape(totpIssuer + ":" + accountName)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", totpDigits))
+	params.Set("period", fmt.Sprintf("%d", totpPeriodSeconds))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// otpAuthQRCode renders an otpauth URI as a QR code PNG data URI
+func otpAuthQRCode(otpAuthURL string) (string, error) {
+	png, err := qrcode.Encode(otpAuthURL, qrcode.Medium, totpQRCodeSize)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// validateTOTP checks code against the time steps around now and returns the
+// matching step. Steps at or before lastUsedStep are rejected to prevent replay.
+func validateTOTP(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriodSeconds
+	for offset := int64(-totpSkewSteps); offset <= totpSkewSteps; offset++ {
+		step := current + offset
+		if step <= lastUsedStep {
+			continue
+		}
+		if hmac.Equal([]byte(hotp(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp computes an RFC 4226 HMAC-SHA1 one-time password for the given counter
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func testCredentialsKey() []byte {
+	return bytes.Repeat([]byte{0x42}, 32)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		unix     int64
+		code     string
+		lastUsed int64
+		wantOK   bool
+		wantStep int64
+	}{
+		// RFC 6238 vectors, truncated to six digits
+		{name: "rfc vector 59", unix: 59, code: "287082", wantOK: true, wantStep: 1},
+		{name: "rfc vector 1111111109", unix: 1111111109, code: "081804", wantOK: true, wantStep: 37037036},
+		{name: "previous step within skew", unix: 89, code: "287082", wantOK: true, wantStep: 1},
+		{name: "outside skew", unix: 150, code: "287082"},
+		{name: "replayed step", unix: 59, code: "287082", lastUsed: 1},
+		{name: "wrong code", unix: 59, code: "123456"},
+		{name: "wrong length", unix: 59, code: "28708"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0), tt.lastUsed)
+			if ok != tt.wantOK {
+				t.Fatalf("validateTOTP ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Errorf("validateTOTP step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	s := &MFAService{Key: testCredentialsKey()}
+
+	sealed, err := encryptCredential(s.Key, rfc6238Secret)
+	if err != nil {
+		t.Fatalf("encryptCredential: %v", err)
+	}
+	if strings.Contains(sealed, rfc6238Secret) {
+		t.Fatal("sealed secret contains the plaintext")
+	}
+
+	secret, legacy, err := s.totpSecret(&models.User{TOTPSecret: &sealed})
+	if err != nil || legacy || secret != rfc6238Secret {
+		t.Errorf("totpSecret(sealed) = %q, %v, %v; want the secret, not legacy", secret, legacy, err)
+	}
+
+	plain := rfc6238Secret
+	secret, legacy, err = s.totpSecret(&models.User{TOTPSecret: &plain})
+	if err != nil || !legacy || secret != rfc6238Secret {
+		t.Errorf("totpSecret(plaintext) = %q, %v, %v; want the secret, legacy", secret, legacy, err)
+	}
+
+	other := &MFAService{Key: bytes.Repeat([]byte{0x24}, 32)}
+	if _, _, err := other.totpSecret(&models.User{TOTPSecret: &sealed}); err == nil {
+		t.Error("totpSecret with the wrong key succeeded")
+	}
+}
+
+func TestBeginEnrollmentRequiresKey(t *testing.T) {
+	_, err := (&MFAService{}).BeginEnrollment(&models.User{ID: 1, Email: "lawyer@example.com"})
+	if !errors.Is(err, ErrCredentialsKeyMissing) {
+		t.Fatalf("BeginEnrollment without a key = %v, want ErrCredentialsKeyMissing", err)
+	}
+}
+
+func TestOTPAuthQRCode(t *testing.T) {
+	uri := buildOTPAuthURL("lawyer@example.com", rfc6238Secret)
+	dataURI, err := otpAuthQRCode(uri)
+	if err != nil {
+		t.Fatalf("otpAuthQRCode: %v", err)
+	}
+
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(dataURI, prefix) {
+		t.Fatalf("QR code %q is not a PNG data URI", dataURI[:min(len(dataURI), 40)])
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURI, prefix))
+	if err != nil {
+		t.Fatalf("QR code is not base64: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("QR code is not a PNG: %v", err)
+	}
+	if size := img.Bounds().Dx(); size != totpQRCodeSize {
+		t.Errorf("QR code is %dpx wide, want %d", size, totpQRCodeSize)
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrUserNotFound is returned when the user an operation names does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// UserPermissionSummary describes where a user's permissions come from
+type UserPermissionSummary struct {
+	UserID          int      `json:"user_id"`
+	Role            string   `json:"role"`
+	RolePermissions []string `json:"role_permissions"`
+	Granted         []string `json:"granted"`
+	Effective       []string `json:"effective"`
+}
+
+// PermissionService handles role and user permission lookups and grants
+type PermissionService struct {
+	DB *gorm.DB
+}
+
+// NewPermissionService creates a new permission service
+func NewPermissionService() *PermissionService {
+	return &PermissionService{
+		DB: repository.DB,
+	}
+}
+
+// GetEffectivePermissions returns the union of the role's permissions and the user's own grants
+func (s *PermissionService) GetEffectivePermissions(userID int, role string) ([]string, error) {
+	var permissions []string
+	err := s.DB.Raw(`
+		SELECT permission FROM role_permissions WHERE role = ?
+		UNION
+		SELECT permission FROM user_permissions WHERE user_id = ?
+		ORDER BY permission`, role, userID).
+		Scan(&permissions).Error
+	return permissions, err
+}
+
+// UsersWithPermission returns the active users who hold a permission through their
+// role or their own grants
+func (s *PermissionService) UsersWithPermission(permission string) ([]models.User, error) {
+	var users []models.User
+	err := s.DB.
+		Where("is_active AND (role IN (?) OR id IN (?))",
+			s.DB.Model(&models.RolePermission{}).Select("role").Where("permission = ?", permission),
+			s.DB.Model(&models.UserPermission{}).Select("user_id").Where("permission = ?", permission)).
+		Order("id ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// ListPermissions returns every defined permission
+func (s *PermissionService) ListPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := s.DB.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+// GetRolePermissionMap returns the permissions of every role that has at least one
+func (s *PermissionService) GetRolePermissionMap() (map[string][]string, error) {
+	var rows []models.RolePermission
+	if err := s.DB.Order("role, permission").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]string)
+	for _, row := range rows {
+		result[row.Role] = append(result[row.Role], row.Permission)
+	}
+	return result, nil
+}
+
+// GetRolePermissions returns the permissions granted to a role
+func (s *PermissionService) GetRolePermissions(role string) ([]string, error) {
+	permissions := []string{}
+	err := s.DB.Model(&models.RolePermission{}).
+		Where("role = ?", role).
+		Order("permission").
+		Pluck("permission", &permissions).Error
+	return permissions, err
+}
+
+// SetRolePermissions replaces the permissions granted to a role
+func (s *PermissionService) SetRolePermissions(role string, permissions []string) error {
+	if !models.UserRole(role).IsValid() {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+	if err := s.validatePermissions(permissions); err != nil {
+		return err
+	}
+
+	// Keep at least one way to manage permissions, otherwise nobody could undo the change
+	if role == models.RoleAdmin.String() && !containsString(permissions, models.PermPermissionsManage) {
+		return errors.New("the admin role must keep the permissions.manage permission")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		rows := make([]models.RolePermission, 0, len(permissions))
+		for _, p := range dedupe(permissions) {
+			rows = append(rows, models.RolePermission{Role: role, Permission: p})
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// GetUserPermissionSummary returns a user's role permissions, direct grants and the effective union
+func (s *PermissionService) GetUserPermissionSummary(userID int) (*UserPermissionSummary, error) {
+	var user models.User
+	if err := s.DB.Select("id", "role").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	rolePermissions, err := s.GetRolePermissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := []string{}
+	if err := s.DB.Model(&models.UserPermission{}).
+		Where("user_id = ?", userID).
+		Order("permission").
+		Pluck("permission", &granted).Error; err != nil {
+		return nil, err
+	}
+
+	effective := dedupe(append(append([]string{}, rolePermissions...), granted...))
+	sort.Strings(effective)
+
+	return &UserPermissionSummary{
+		UserID:          user.ID,
+		Role:            user.Role,
+		RolePermissions: rolePermissions,
+		Granted:         granted,
+		Effective:       effective,
+	}, nil
+}
+
+// SetUserPermissions replaces the permissions granted directly to a user
+func (s *PermissionService) SetUserPermissions(userID int, permissions []string, grantedBy int) error {
+	if err := s.validatePermissions(permissions); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrUserNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserPermission{}).Error; err != nil {
+			return err
+		}
+		rows := make([]models.UserPermission, 0, len(permissions))
+		for _, p := range dedupe(permissions) {
+			rows = append(rows, models.UserPermission{UserID: userID, Permission: p, GrantedBy: &grantedBy})
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// validatePermissions checks that every name is a defined permission
+func (s *PermissionService) validatePermissions(permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	var known []string
+	if err := s.DB.Model(&models.Permission{}).
+		Where("name IN ?", permissions).
+		Pluck("name", &known).Error; err != nil {
+		return err
+	}
+
+	for _, p := range permissions {
+		if !containsString(known, p) {
+			return fmt.Errorf("unknown permission: %s", p)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(list []string) []string {
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		if !containsString(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
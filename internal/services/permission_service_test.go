@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func TestUsersWithPermission(t *testing.T) {
+	db := openTestDB(t)
+	admin := createTestUser(t, db, models.RoleAdmin)
+	moderator := createTestUser(t, db, models.RoleModerator)
+	granted := createTestUser(t, db, models.RoleClient)
+	client := createTestUser(t, db, models.RoleClient)
+	inactive := createTestUser(t, db, models.RoleAdmin)
+	if err := db.Model(inactive).Update("is_active", false).Error; err != nil {
+		t.Fatalf("deactivating user: %v", err)
+	}
+	if err := db.Create(&models.UserPermission{UserID: granted.ID, Permission: models.PermLawyersVerify}).Error; err != nil {
+		t.Fatalf("granting permission: %v", err)
+	}
+
+	users, err := (&PermissionService{DB: db}).UsersWithPermission(models.PermLawyersVerify)
+	if err != nil {
+		t.Fatalf("UsersWithPermission: %v", err)
+	}
+	found := make(map[int]bool)
+	for _, user := range users {
+		found[user.ID] = true
+	}
+
+	for _, tt := range []struct {
+		name string
+		user *models.User
+		want bool
+	}{
+		{name: "admin", user: admin, want: true},
+		{name: "moderator", user: moderator, want: true},
+		{name: "client with a grant", user: granted, want: true},
+		{name: "client", user: client, want: false},
+		{name: "inactive admin", user: inactive, want: false},
+	} {
+		if found[tt.user.ID] != tt.want {
+			t.Errorf("%s listed = %v, want %v", tt.name, found[tt.user.ID], tt.want)
+		}
+	}
+}
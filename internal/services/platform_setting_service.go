@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PlatformSettingService handles reading and writing admin-managed platform settings
+type PlatformSettingService struct {
+	DB *gorm.DB
+}
+
+// NewPlatformSettingService creates a new platform setting service
+func NewPlatformSettingService() *PlatformSettingService {
+	return &PlatformSettingService{
+		DB: repository.DB,
+	}
+}
+
+// Get returns the value of a setting, or an empty string if it has never been set
+func (s *PlatformSettingService) Get(key string) (string, error) {
+	var setting models.PlatformSetting
+	if err := s.DB.Where("key = ?", key).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// Set creates or updates a setting
+func (s *PlatformSettingService) Set(key, value string, updatedBy int) error {
+	setting := models.PlatformSetting{
+		Key:       key,
+		Value:     value,
+		UpdatedBy: &updatedBy,
+	}
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&setting).Error
+}
+
+// GetMFARequiredRoles returns the roles that must have two-factor authentication enabled
+func (s *PlatformSettingService) GetMFARequiredRoles() ([]string, error) {
+	value, err := s.Get(models.SettingMFARequiredRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	for _, role := range strings.Split(value, ",") {
+		role = strings.TrimSpace(role)
+		if role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// SetMFARequiredRoles stores the roles that must have two-factor authentication enabled
+func (s *PlatformSettingService) SetMFARequiredRoles(roles []string, updatedBy int) error {
+	for _, role := range roles {
+		if role != models.RoleAdmin.String() && role != models.RoleLawyer.String() && role != models.RoleModerator.String() {
+			return errors.New("two-factor authentication can only be required for admin, moderator and lawyer roles")
+		}
+	}
+	return s.Set(models.SettingMFARequiredRoles, strings.Join(roles, ","), updatedBy)
+}
+
+// IsMFARequiredForRole reports whether users with the given role must use two-factor authentication
+func (s *PlatformSettingService) IsMFARequiredForRole(role string) (bool, error) {
+	roles, err := s.GetMFARequiredRoles()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DefaultRescheduleWindowHours applies until admins set a reschedule window
+const DefaultRescheduleWindowHours = 48
+
+// maxRescheduleWindowHours bounds the reschedule window to 30 days
+const maxRescheduleWindowHours = 720
+
+// GetRescheduleWindowHours returns how many hours before its start an appointment
+// can still be rescheduled
+func (s *PlatformSettingService) GetRescheduleWindowHours() (int, error) {
+	value, err := s.Get(models.SettingRescheduleWindowHours)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return DefaultRescheduleWindowHours, nil
+	}
+	hours, err := strconv.Atoi(value)
+	if err != nil {
+		return DefaultRescheduleWindowHours, nil
+	}
+	return hours, nil
+}
+
+// SetRescheduleWindowHours stores the reschedule window
+func (s *PlatformSettingService) SetRescheduleWindowHours(hours int, updatedBy int) error {
+	if hours < 0 || hours > maxRescheduleWindowHours {
+		return fmt.Errorf("reschedule window must be between 0 and %d hours", maxRescheduleWindowHours)
+	}
+	return s.Set(models.SettingRescheduleWindowHours, strconv.Itoa(hours), updatedBy)
+}
+
+// DefaultMeetingLinkWindowMinutes applies until admins set when meeting links are shown
+const DefaultMeetingLinkWindowMinutes = 30
+
+// maxMeetingLinkWindowMinutes bounds the meeting link window to 7 days
+const maxMeetingLinkWindowMinutes = 10080
+
+// GetMeetingLinkWindowMinutes returns how many minutes before its start an
+// appointment's meeting link is shown to the client and the lawyer
+func (s *PlatformSettingService) GetMeetingLinkWindowMinutes() (int, error) {
+	value, err := s.Get(models.SettingMeetingLinkWindowMins)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return DefaultMeetingLinkWindowMinutes, nil
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil {
+		return DefaultMeetingLinkWindowMinutes, nil
+	}
+	return minutes, nil
+}
+
+// SetMeetingLinkWindowMinutes stores the meeting link window
+func (s *PlatformSettingService) SetMeetingLinkWindowMinutes(minutes int, updatedBy int) error {
+	if minutes < 0 || minutes > maxMeetingLinkWindowMinutes {
+		return fmt.Errorf("meeting link window must be between 0 and %d minutes", maxMeetingLinkWindowMinutes)
+	}
+	return s.Set(models.SettingMeetingLinkWindowMins, strconv.Itoa(minutes), updatedBy)
+}
+
+// DefaultWaitlistHoldMinutes applies until admins set how long a waitlist hold lasts
+const DefaultWaitlistHoldMinutes = 120
+
+// maxWaitlistHoldMinutes bounds waitlist holds to 3 days
+const maxWaitlistHoldMinutes = 4320
+
+// GetWaitlistHoldMinutes returns how many minutes a slot offered to a waitlisted
+// client stays reserved for them
+func (s *PlatformSettingService) GetWaitlistHoldMinutes() (int, error) {
+	value, err := s.Get(models.SettingWaitlistHoldMinutes)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return DefaultWaitlistHoldMinutes, nil
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil {
+		return DefaultWaitlistHoldMinutes, nil
+	}
+	return minutes, nil
+}
+
+// SetWaitlistHoldMinutes stores how long waitlist holds last
+func (s *PlatformSettingService) SetWaitlistHoldMinutes(minutes int, updatedBy int) error {
+	if minutes < 5 || minutes > maxWaitlistHoldMinutes {
+		return fmt.Errorf("waitlist hold must be between 5 and %d minutes", maxWaitlistHoldMinutes)
+	}
+	return s.Set(models.SettingWaitlistHoldMinutes, strconv.Itoa(minutes), updatedBy)
+}
+
+// AttendanceSettings control how long lawyers have to report a no-show and whether
+// clients who repeatedly miss appointments may keep booking
+type AttendanceSettings struct {
+	// GraceHours is how long after an appointment ends attendance can be reported
+	// before the appointment is completed automatically
+	GraceHours int `json:"grace_hours"`
+	// NoShowLimit is the number of no-shows after which a client cannot book. Zero
+	// turns the restriction off.
+	NoShowLimit int `json:"no_show_limit"`
+	// RestrictionDays is how long after their last no-show a client stays
+	// restricted. Zero keeps the restriction until an admin resets the counter.
+	RestrictionDays int `json:"restriction_days"`
+}
+
+// DefaultAttendanceSettings apply until admins change them
+var DefaultAttendanceSettings = AttendanceSettings{GraceHours: 24, NoShowLimit: 0, RestrictionDays: 90}
+
+// GetAttendanceSettings returns the attendance settings
+func (s *PlatformSettingService) GetAttendanceSettings() (AttendanceSettings, error) {
+	settings := DefaultAttendanceSettings
+	values := []struct {
+		key    string
+		target *int
+	}{
+		{models.SettingAttendanceGraceHours, &settings.GraceHours},
+		{models.SettingNoShowLimit, &settings.NoShowLimit},
+		{models.SettingNoShowRestrictionDays, &settings.RestrictionDays},
+	}
+	for _, v := range values {
+		value, err := s.Get(v.key)
+		if err != nil {
+			return settings, err
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			*v.target = n
+		}
+	}
+	return settings, nil
+}
+
+// SetAttendanceSettings validates and stores the attendance settings
+func (s *PlatformSettingService) SetAttendanceSettings(settings AttendanceSettings, updatedBy int) error {
+	switch {
+	case settings.GraceHours < 0 || settings.GraceHours > 168:
+		return errors.New("grace period must be between 0 and 168 hours")
+	case settings.NoShowLimit < 0 || settings.NoShowLimit > 20:
+		return errors.New("no-show limit must be between 0 and 20")
+	case settings.RestrictionDays < 0 || settings.RestrictionDays > 365:
+		return errors.New("restriction period must be between 0 and 365 days")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		txService := &PlatformSettingService{DB: tx}
+		values := map[string]int{
+			models.SettingAttendanceGraceHours:  settings.GraceHours,
+			models.SettingNoShowLimit:           settings.NoShowLimit,
+			models.SettingNoShowRestrictionDays: settings.RestrictionDays,
+		}
+		for key, value := range values {
+			if err := txService.Set(key, strconv.Itoa(value), updatedBy); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+// RateLimitError is returned when a request must be refused because of too many recent attempts
+type RateLimitError struct {
+	// Reason is one of the models.LoginFailure* constants
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many attempts (%s), retry after %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// Locked reports whether the error represents a temporary account lockout
+func (e *RateLimitError) Locked() bool {
+	return e.Reason == models.LoginFailureLocked
+}
+
+// RateLimitService applies sliding-window limits to login attempts and account emails
+type RateLimitService struct {
+	store  RateLimitStore
+	config config.RateLimitConfig
+}
+
+// NewRateLimitService creates a rate limit service backed by the given store
+func NewRateLimitService(store RateLimitStore, cfg config.RateLimitConfig) *RateLimitService {
+	return &RateLimitService{
+		store:  store,
+		config: cfg,
+	}
+}
+
+// rateLimitRetention returns how long a store must keep hits to answer every configured window
+func rateLimitRetention(cfg config.RateLimitConfig) time.Duration {
+	retention := cfg.LoginFailureWindow
+	for _, d := range []time.Duration{cfg.LoginLockoutDuration, cfg.LoginIPWindow, cfg.EmailRequestWindow} {
+		if d > retention {
+			retention = d
+		}
+	}
+	return retention
+}
+
+// CheckLogin decides whether a login (or second-factor) attempt for email from ip may proceed.
+// It returns a *RateLimitError when the account is locked, the caller has not waited out the
+// progressive delay, or the IP has failed too often. The per-account limits come first: they
+// hold however many addresses an attacker spreads attempts over, while the IP limit only
+// slows one source stuffing many accounts.
+func (s *RateLimitService) CheckLogin(email, ip string) error {
+	now := time.Now()
+
+	failures, err := s.store.Window(loginEmailKey(email), now.Add(-s.config.LoginFailureWindow))
+	if err != nil {
+		return err
+	}
+
+	if failures.Count >= s.config.LoginMaxFailures {
+		if unlockAt := failures.Newest.Add(s.config.LoginLockoutDuration); now.Before(unlockAt) {
+			return &RateLimitError{Reason: models.LoginFailureLocked, RetryAfter: unlockAt.Sub(now)}
+		}
+	} else if failures.Count >= s.config.LoginDelayAfter {
+		if nextAt := failures.Newest.Add(s.loginDelay(failures.Count)); now.Before(nextAt) {
+			return &RateLimitError{Reason: models.LoginFailureDelayNotRespected, RetryAfter: nextAt.Sub(now)}
+		}
+	}
+
+	ipWindow, err := s.store.Window(loginIPKey(ip), now.Add(-s.config.LoginIPWindow))
+	if err != nil {
+		return err
+	}
+	if ipWindow.Count >= s.config.LoginIPMaxFailures {
+		return &RateLimitError{
+			Reason:     models.LoginFailureRateLimited,
+			RetryAfter: ipWindow.Oldest.Add(s.config.LoginIPWindow).Sub(now),
+		}
+	}
+	return nil
+}
+
+// loginDelay returns the wait required after the given number of consecutive failures.
+// It doubles with every failure past LoginDelayAfter, up to LoginMaxDelay.
+func (s *RateLimitService) loginDelay(failures int) time.Duration {
+	delay := s.config.LoginBaseDelay
+	for i := s.config.LoginDelayAfter; i < failures && delay < s.config.LoginMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.LoginMaxDelay {
+		delay = s.config.LoginMaxDelay
+	}
+	return delay
+}
+
+// RecordLoginFailure counts a failed attempt against both the email and the IP
+func (s *RateLimitService) RecordLoginFailure(email, ip string) error {
+	now := time.Now()
+	if err := s.store.Add(loginEmailKey(email), now); err != nil {
+		return err
+	}
+	return s.store.Add(loginIPKey(ip), now)
+}
+
+// RecordLoginSuccess clears the failure count for email. IP failures are kept so one
+// valid account cannot be used to reset the limit while stuffing others.
+func (s *RateLimitService) RecordLoginSuccess(email string) error {
+	return s.store.Clear(loginEmailKey(email))
+}
+
+// AllowEmailRequest records a request to send an account email (action is one of the
+// models.LoginAction* constants) and returns a *RateLimitError if the address or IP
+// has exceeded its quota
+func (s *RateLimitService) AllowEmailRequest(action, email, ip string) error {
+	now := time.Now()
+	since := now.Add(-s.config.EmailRequestWindow)
+
+	limits := []struct {
+		key   string
+		limit int
+	}{
+		{action + ":ip:" + ip, s.config.EmailIPRequestLimit},
+		{action + ":email:" + normalizeEmail(email), s.config.EmailRequestLimit},
+	}
+
+	for _, l := range limits {
+		window, err := s.store.Window(l.key, since)
+		if err != nil {
+			return err
+		}
+		if window.Count >= l.limit {
+			return &RateLimitError{
+				Reason:     models.LoginFailureRateLimited,
+				RetryAfter: window.Oldest.Add(s.config.EmailRequestWindow).Sub(now),
+			}
+		}
+	}
+
+	for _, l := range limits {
+		if err := s.store.Add(l.key, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loginEmailKey(email string) string {
+	return "login:email:" + normalizeEmail(email)
+}
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// normalizeEmail makes limiter keys case- and whitespace-insensitive
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
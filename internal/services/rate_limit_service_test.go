@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/config"
+	"github.com/kotolino/lawyer/internal/models"
+)
+
+func testRateLimitConfig() config.RateLimitConfig {
+	return config.RateLimitConfig{
+		LoginMaxFailures:     5,
+		LoginFailureWindow:   15 * time.Minute,
+		LoginLockoutDuration: 15 * time.Minute,
+		LoginDelayAfter:      100,
+		LoginBaseDelay:       time.Second,
+		LoginMaxDelay:        time.Minute,
+		LoginIPMaxFailures:   3,
+		LoginIPWindow:        15 * time.Minute,
+	}
+}
+
+func TestCheckLoginLocksAccountAcrossIPs(t *testing.T) {
+	cfg := testRateLimitConfig()
+	s := NewRateLimitService(NewMemoryRateLimitStore(rateLimitRetention(cfg)), cfg)
+
+	// Each attempt comes from a fresh address, as with a spoofed or rotating client IP
+	for i := 0; i < cfg.LoginMaxFailures; i++ {
+		ip := fmt.Sprintf("203.0.113.%d", i+1)
+		if err := s.CheckLogin("Client@Example.com", ip); err != nil {
+			t.Fatalf("attempt %d refused: %v", i+1, err)
+		}
+		if err := s.RecordLoginFailure("client@example.com", ip); err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+	}
+
+	var rle *RateLimitError
+	if err := s.CheckLogin("client@example.com", "198.51.100.7"); !errors.As(err, &rle) || !rle.Locked() {
+		t.Fatalf("CheckLogin from a new IP = %v, want the account locked", err)
+	}
+	if err := s.CheckLogin("other@example.com", "198.51.100.7"); err != nil {
+		t.Errorf("CheckLogin for another account = %v, want allowed", err)
+	}
+}
+
+func TestCheckLoginLimitsIPAcrossAccounts(t *testing.T) {
+	cfg := testRateLimitConfig()
+	s := NewRateLimitService(NewMemoryRateLimitStore(rateLimitRetention(cfg)), cfg)
+
+	for i := 0; i < cfg.LoginIPMaxFailures; i++ {
+		if err := s.RecordLoginFailure(fmt.Sprintf("user%d@example.com", i), "203.0.113.1"); err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+	}
+
+	var rle *RateLimitError
+	if err := s.CheckLogin("fresh@example.com", "203.0.113.1"); !errors.As(err, &rle) || rle.Reason != models.LoginFailureRateLimited {
+		t.Fatalf("CheckLogin from the same IP = %v, want rate limited", err)
+	}
+	if err := s.CheckLogin("fresh@example.com", "203.0.113.2"); err != nil {
+		t.Errorf("CheckLogin from another IP = %v, want allowed", err)
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// RateLimitWindow summarises the hits recorded for a key inside a sliding window
+type RateLimitWindow struct {
+	Count  int
+	Oldest time.Time
+	Newest time.Time
+}
+
+// RateLimitStore persists timestamped hits per key. Implementations must be safe
+// for concurrent use.
+type RateLimitStore interface {
+	// Add records a hit for key at the given time
+	Add(key string, at time.Time) error
+	// Window returns the hits recorded for key at or after since
+	Window(key string, since time.Time) (RateLimitWindow, error)
+	// Clear removes every hit recorded for key
+	Clear(key string) error
+}
+
+// MemoryRateLimitStore keeps hits in process memory. Use it when a single API node is running.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	retention time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an in-memory store that forgets hits older than retention
+func NewMemoryRateLimitStore(retention time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		hits:      make(map[string][]time.Time),
+		retention: retention,
+		lastSweep: time.Now(),
+	}
+}
+
+// Add records a hit for key
+func (s *MemoryRateLimitStore) Add(key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hits[key] = append(prune(s.hits[key], at.Add(-s.retention)), at)
+
+	// Occasionally drop keys that have gone quiet so the map does not grow forever
+	if at.Sub(s.lastSweep) > s.retention {
+		for k, v := range s.hits {
+			if v = prune(v, at.Add(-s.retention)); len(v) == 0 {
+				delete(s.hits, k)
+			} else {
+				s.hits[k] = v
+			}
+		}
+		s.lastSweep = at
+	}
+	return nil
+}
+
+// Window returns the hits recorded for key at or after since
+func (s *MemoryRateLimitStore) Window(key string, since time.Time) (RateLimitWindow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var window RateLimitWindow
+	for _, t := range s.hits[key] {
+		if t.Before(since) {
+			continue
+		}
+		if window.Count == 0 || t.Before(window.Oldest) {
+			window.Oldest = t
+		}
+		if t.After(window.Newest) {
+			window.Newest = t
+		}
+		window.Count++
+	}
+	return window, nil
+}
+
+// Clear removes every hit recorded for key
+func (s *MemoryRateLimitStore) Clear(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hits, key)
+	return nil
+}
+
+// prune drops timestamps before cutoff. Hits are appended in order, so the slice stays sorted.
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && hits[i].Before(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
+
+// PostgresRateLimitStore keeps hits in the rate_limit_hits table so every API node
+// sees the same counters
+type PostgresRateLimitStore struct {
+	DB        *gorm.DB
+	retention time.Duration
+}
+
+// NewPostgresRateLimitStore creates a database-backed store that deletes hits older than retention
+func NewPostgresRateLimitStore(db *gorm.DB, retention time.Duration) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{
+		DB:        db,
+		retention: retention,
+	}
+}
+
+// Add records a hit for key and removes that key's expired hits
+func (s *PostgresRateLimitStore) Add(key string, at time.Time) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("key = ? AND hit_at < ?", key, at.Add(-s.retention)).
+			Delete(&models.RateLimitHit{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RateLimitHit{Key: key, HitAt: at}).Error
+	})
+}
+
+// Window returns the hits recorded for key at or after since
+func (s *PostgresRateLimitStore) Window(key string, since time.Time) (RateLimitWindow, error) {
+	var row struct {
+		Count  int
+		Oldest *time.Time
+		Newest *time.Time
+	}
+	err := s.DB.Model(&models.RateLimitHit{}).
+		Select("COUNT(*) AS count, MIN(hit_at) AS oldest, MAX(hit_at) AS newest").
+		Where("key = ? AND hit_at >= ?", key, since).
+		Scan(&row).Error
+	if err != nil {
+		return RateLimitWindow{}, err
+	}
+
+	window := RateLimitWindow{Count: row.Count}
+	if row.Oldest != nil {
+		window.Oldest = *row.Oldest
+	}
+	if row.Newest != nil {
+		window.Newest = *row.Newest
+	}
+	return window, nil
+}
+
+// Clear removes every hit recorded for key
+func (s *PostgresRateLimitStore) Clear(key string) error {
+	return s.DB.Where("key = ?", key).Delete(&models.RateLimitHit{}).Error
+}
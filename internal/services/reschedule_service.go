@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationTypeAppointmentReschedule is the in-app notification sent for reschedule proposals
+const NotificationTypeAppointmentReschedule = "appointment_reschedule"
+
+// maxRescheduleOptions limits how many alternative times one proposal can offer
+const maxRescheduleOptions = 5
+
+var (
+	ErrProposalNotFound         = errors.New("proposal not found")
+	ErrRescheduleOptionNotFound = errors.New("option not found")
+	ErrOwnProposal              = errors.New("you cannot respond to your own proposal")
+	ErrProposalNotPending       = errors.New("proposal is no longer pending")
+	ErrProposalSuperseded       = errors.New("another reschedule proposal was just made")
+	// ErrInvalidReschedule and ErrNotReschedulable are wrapped with the reason
+	ErrInvalidReschedule = errors.New("invalid reschedule")
+	ErrNotReschedulable  = errors.New("appointment cannot be rescheduled")
+)
+
+// RescheduleService handles proposals to move an appointment to another time
+type RescheduleService struct {
+	DB *gorm.DB
+}
+
+// NewRescheduleService creates a new reschedule service
+func NewRescheduleService() *RescheduleService {
+	return &RescheduleService{
+		DB: repository.DB,
+	}
+}
+
+// checkRescheduleWindow rejects changes to appointments that start too soon, or that
+// are no longer active
+func (s *RescheduleService) checkRescheduleWindow(appointment *models.Appointment) error {
+	if appointment.Status != models.AppointmentStatusPending && appointment.Status != models.AppointmentStatusConfirmed {
+		return fmt.Errorf("%w: it is %s", ErrNotReschedulable, appointment.Status)
+	}
+
+	windowHours, err := (&PlatformSettingService{DB: s.DB}).GetRescheduleWindowHours()
+	if err != nil {
+		return err
+	}
+	if time.Until(appointment.StartTime) < time.Duration(windowHours)*time.Hour {
+		return fmt.Errorf("%w: changes must be made at least %d hours before it starts", ErrNotReschedulable, windowHours)
+	}
+	return nil
+}
+
+// ProposeReschedule offers alternative times for an appointment. Every option must
+// be bookable under the lawyer's booking policy. A pending proposal from either
+// party is superseded by the new one.
+func (s *RescheduleService) ProposeReschedule(appointment *models.Appointment, proposerID int, proposerRole string, options []models.RescheduleOption, reason *string) (*models.RescheduleProposal, error) {
+	if err := s.checkRescheduleWindow(appointment); err != nil {
+		return nil, err
+	}
+	if len(options) == 0 || len(options) > maxRescheduleOptions {
+		return nil, fmt.Errorf("%w: between 1 and %d options are required", ErrInvalidReschedule, maxRescheduleOptions)
+	}
+
+	var lawyer models.Lawyer
+	if err := s.DB.First(&lawyer, appointment.LawyerID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch lawyer: %w", err)
+	}
+
+	availabilityService := &AvailabilityService{DB: s.DB}
+	for i := range options {
+		options[i].ID = 0
+		options[i].StartTime = options[i].StartTime.UTC()
+		options[i].EndTime = options[i].EndTime.UTC()
+		if !options[i].StartTime.Before(options[i].EndTime) {
+			return nil, fmt.Errorf("%w: each option must start before it ends", ErrInvalidReschedule)
+		}
+		if options[i].StartTime.Equal(appointment.StartTime) && options[i].EndTime.Equal(appointment.EndTime) {
+			return nil, fmt.Errorf("%w: an option matches the current time", ErrInvalidReschedule)
+		}
+		for j := 0; j < i; j++ {
+			if options[j].StartTime.Equal(options[i].StartTime) {
+				return nil, fmt.Errorf("%w: options must not repeat", ErrInvalidReschedule)
+			}
+		}
+		if err := availabilityService.CheckBooking(&lawyer, options[i].StartTime, options[i].EndTime, appointment.ID, appointment.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	proposal := &models.RescheduleProposal{
+		AppointmentID:  appointment.ID,
+		ProposedBy:     proposerID,
+		ProposedByRole: proposerRole,
+		Reason:         reason,
+		Status:         models.RescheduleStatusPending,
+		OriginalStart:  appointment.StartTime,
+		OriginalEnd:    appointment.EndTime,
+		Options:        options,
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RescheduleProposal{}).
+			Where("appointment_id = ? AND status = ?", appointment.ID, models.RescheduleStatusPending).
+			Update("status", models.RescheduleStatusSuperseded).Error; err != nil {
+			return err
+		}
+		return tx.Create(proposal).Error
+	})
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, ErrProposalSuperseded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyOtherParty(appointment, proposerID, "予約日時の変更が提案されました。候補の日時をご確認ください")
+	return proposal, nil
+}
+
+// loadPendingProposal locks a pending proposal of the appointment for a response
+func loadPendingProposal(tx *gorm.DB, appointmentID, proposalID int) (*models.RescheduleProposal, error) {
+	var proposal models.RescheduleProposal
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND appointment_id = ?", proposalID, appointmentID).
+		First(&proposal).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProposalNotFound
+		}
+		return nil, err
+	}
+	if proposal.Status != models.RescheduleStatusPending {
+		return nil, fmt.Errorf("%w: it was %s", ErrProposalNotPending, proposal.Status)
+	}
+	return &proposal, nil
+}
+
+// AcceptReschedule moves the appointment to one of the proposal's options. The
+// appointment keeps its ID and chat, and its reminders are sent again for the new
+// time. Only the party that did not make the proposal can accept it.
+func (s *RescheduleService) AcceptReschedule(appointmentID, proposalID, optionID, responderID int, responderRole string) (*models.Appointment, error) {
+	var appointment models.Appointment
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		proposal, err := loadPendingProposal(tx, appointmentID, proposalID)
+		if err != nil {
+			return err
+		}
+		if proposal.ProposedByRole == responderRole {
+			return ErrOwnProposal
+		}
+
+		var option models.RescheduleOption
+		if err := tx.Where("id = ? AND proposal_id = ?", optionID, proposal.ID).First(&option).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRescheduleOptionNotFound
+			}
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&appointment, appointmentID).Error; err != nil {
+			return err
+		}
+		if err := (&RescheduleService{DB: tx}).checkRescheduleWindow(&appointment); err != nil {
+			return err
+		}
+
+		// The slot may have been taken or closed since the proposal was made
+		var lawyer models.Lawyer
+		if err := tx.First(&lawyer, appointment.LawyerID).Error; err != nil {
+			return err
+		}
+		if err := (&AvailabilityService{DB: tx}).CheckBooking(&lawyer, option.StartTime, option.EndTime, appointment.ID, appointment.UserID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&appointment).Updates(map[string]interface{}{
+			"start_time":         option.StartTime,
+			"end_time":           option.EndTime,
+			"day_reminder_sent":  false,
+			"hour_reminder_sent": false,
+			"calendar_sequence":  gorm.Expr("calendar_sequence + 1"),
+		}).Error; err != nil {
+			if pgErrorCode(err) == pgExclusionViolation {
+				return ErrSlotUnavailable
+			}
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(proposal).Updates(map[string]interface{}{
+			"status":             models.RescheduleStatusAccepted,
+			"accepted_option_id": option.ID,
+			"responded_by":       responderID,
+			"responded_at":       now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.First(&appointment, appointmentID).Error; err != nil {
+		return nil, err
+	}
+	if err := NewMeetingService().RegenerateMeeting(&appointment); err != nil {
+		fmt.Printf("Failed to regenerate meeting for appointment %d: %v\n", appointment.ID, err)
+	}
+	s.notifyOtherParty(&appointment, responderID, "予約日時の変更が承認されました")
+	return &appointment, nil
+}
+
+// DeclineReschedule turns down every option of a proposal. The appointment keeps
+// its current time.
+func (s *RescheduleService) DeclineReschedule(appointmentID, proposalID, responderID int, responderRole string, reason *string) (*models.RescheduleProposal, error) {
+	var proposal *models.RescheduleProposal
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		proposal, err = loadPendingProposal(tx, appointmentID, proposalID)
+		if err != nil {
+			return err
+		}
+		if proposal.ProposedByRole == responderRole {
+			return ErrOwnProposal
+		}
+
+		now := time.Now()
+		proposal.Status = models.RescheduleStatusDeclined
+		proposal.RespondedBy = &responderID
+		proposal.RespondedAt = &now
+		proposal.DeclineReason = reason
+		return tx.Model(proposal).Updates(map[string]interface{}{
+			"status":         proposal.Status,
+			"responded_by":   responderID,
+			"responded_at":   now,
+			"decline_reason": reason,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var appointment models.Appointment
+	if err := s.DB.First(&appointment, appointmentID).Error; err == nil {
+		s.notifyOtherParty(&appointment, responderID, "予約日時の変更が辞退されました。現在の日時のままとなります")
+	}
+	return s.GetProposal(appointmentID, proposal.ID)
+}
+
+// GetProposal returns one proposal of an appointment with its options
+func (s *RescheduleService) GetProposal(appointmentID, proposalID int) (*models.RescheduleProposal, error) {
+	var proposal models.RescheduleProposal
+	err := s.DB.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("start_time ASC") }).
+		Where("id = ? AND appointment_id = ?", proposalID, appointmentID).
+		First(&proposal).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProposalNotFound
+		}
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// GetProposals returns the full reschedule history of an appointment, newest first
+func (s *RescheduleService) GetProposals(appointmentID int) ([]models.RescheduleProposal, error) {
+	var proposals []models.RescheduleProposal
+	err := s.DB.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("start_time ASC") }).
+		Where("appointment_id = ?", appointmentID).
+		Order("created_at DESC, id DESC").
+		Find(&proposals).Error
+	return proposals, err
+}
+
+// notifyOtherParty leaves an in-app notification for the party that did not act
+func (s *RescheduleService) notifyOtherParty(appointment *models.Appointment, actorID int, message string) {
+	var lawyer models.Lawyer
+	if err := s.DB.Select("id", "user_id").First(&lawyer, appointment.LawyerID).Error; err != nil {
+		fmt.Printf("Failed to load lawyer for appointment %d reschedule notification: %v\n", appointment.ID, err)
+		return
+	}
+
+	recipientID := lawyer.UserID
+	if actorID == lawyer.UserID {
+		recipientID = appointment.UserID
+	}
+	notification := &models.Notification{
+		UserID:  recipientID,
+		Type:    NotificationTypeAppointmentReschedule,
+		Content: message,
+	}
+	if err := NewNotificationService().CreateNotification(notification); err != nil {
+		fmt.Printf("Failed to create reschedule notification for user %d: %v\n", recipientID, err)
+	}
+}
@@ -15,6 +15,7 @@ var (
 	emailService      *EmailService
 	utilService       *UtilService
 	supportService    *SupportService
+	rateLimitService  *RateLimitService
 )
 
 // InitServices initializes all services with the provided configuration
@@ -59,6 +60,16 @@ func InitServices(cfg *config.Config) {
 	
 	// Initialize support service
 	supportService = NewSupportService()
+
+	// Initialize brute-force protection with the configured store
+	var store RateLimitStore
+	retention := rateLimitRetention(cfg.RateLimit)
+	if cfg.RateLimit.Store == "postgres" {
+		store = NewPostgresRateLimitStore(repository.GetDB(), retention)
+	} else {
+		store = NewMemoryRateLimitStore(retention)
+	}
+	rateLimitService = NewRateLimitService(store, cfg.RateLimit)
 }
 
 // GetConfig returns the application configuration
@@ -96,6 +107,11 @@ func GetEmailService() *EmailService {
 	return emailService
 }
 
+// GetRateLimitService returns the rate limit service instance
+func GetRateLimitService() *RateLimitService {
+	return rateLimitService
+}
+
 // GetSupportService returns the support service instance
 func GetSupportService() *SupportService {
 	return supportService
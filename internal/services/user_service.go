@@ -154,6 +154,13 @@ func (s *UserService) UpdateUser(userID int, updates map[string]interface{}) err
 		}
 	}
 
+	if raw, ok := updates["timezone"]; ok {
+		name, _ := raw.(string)
+		if err := models.ValidateTimezone(name); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTimezone, err)
+		}
+	}
+
 	if err := s.DB.Model(&models.User{}).
 		Where("id = ?", userID).
 		Updates(updates).Error; err != nil {
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"github.com/kotolino/lawyer/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationTypeWaitlistOffer is the in-app notification offering a held slot to a
+// waitlisted client
+const NotificationTypeWaitlistOffer = "waitlist_offer"
+
+// maxWaitlistDateRanges bounds how many date ranges one waitlist entry may list
+const maxWaitlistDateRanges = 10
+
+var (
+	// ErrInvalidWaitlist is wrapped with the reason a waitlist entry was refused
+	ErrInvalidWaitlist        = errors.New("invalid waitlist")
+	ErrAlreadyWaitlisted      = errors.New("already on this lawyer's waitlist")
+	ErrWaitlistEntryNotFound  = errors.New("waitlist entry not found")
+	ErrWaitlistEntryNotActive = errors.New("waitlist entry is not active")
+	ErrWaitlistHoldNotFound   = errors.New("hold not found")
+	ErrWaitlistHoldNotActive  = errors.New("hold is no longer active")
+)
+
+// WaitlistService manages clients waiting for a slot with a fully booked lawyer.
+// When a slot opens, it is held for the longest-waiting client whose preferences
+// match, and only that client can book it until the hold expires.
+type WaitlistService struct {
+	DB *gorm.DB
+}
+
+// NewWaitlistService creates a new waitlist service
+func NewWaitlistService() *WaitlistService {
+	return &WaitlistService{
+		DB: repository.DB,
+	}
+}
+
+// JoinWaitlist adds a client to a lawyer's waitlist and offers them any open slot
+// that already matches. Validation errors wrap ErrInvalidWaitlist.
+func (s *WaitlistService) JoinWaitlist(entry *models.WaitlistEntry) error {
+	var client models.User
+	if err := s.DB.Select("id", "timezone").First(&client, entry.UserID).Error; err != nil {
+		return err
+	}
+	if err := validateWaitlistEntry(entry, models.NewDate(time.Now().In(client.Location()))); err != nil {
+		return err
+	}
+
+	var lawyer models.Lawyer
+	if err := s.DB.Select("id", "user_id").First(&lawyer, entry.LawyerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLawyerNotFound
+		}
+		return err
+	}
+	if lawyer.UserID == entry.UserID {
+		return fmt.Errorf("%w: lawyers cannot join their own waitlist", ErrInvalidWaitlist)
+	}
+
+	entry.Status = models.WaitlistStatusActive
+	if entry.TimesOfDay == nil {
+		entry.TimesOfDay = models.TimeIntervals{}
+	}
+	err := s.DB.Create(entry).Error
+	if pgErrorCode(err) == pgUniqueViolation {
+		return ErrAlreadyWaitlisted
+	}
+	if err != nil {
+		return err
+	}
+
+	s.offerLogged(entry.LawyerID)
+	return nil
+}
+
+func validateWaitlistEntry(entry *models.WaitlistEntry, today models.Date) error {
+	if len(entry.DateRanges) == 0 {
+		return fmt.Errorf("%w: at least one date range is required", ErrInvalidWaitlist)
+	}
+	if len(entry.DateRanges) > maxWaitlistDateRanges {
+		return fmt.Errorf("%w: at most %d date ranges are allowed", ErrInvalidWaitlist, maxWaitlistDateRanges)
+	}
+	for _, r := range entry.DateRanges {
+		if r.From.IsZero() || r.To.IsZero() {
+			return fmt.Errorf("%w: date ranges need both from and to", ErrInvalidWaitlist)
+		}
+		if r.To.Before(r.From.Time) {
+			return fmt.Errorf("%w: to must not be before from", ErrInvalidWaitlist)
+		}
+		if r.To.Before(today.Time) {
+			return fmt.Errorf("%w: date ranges must not be in the past", ErrInvalidWaitlist)
+		}
+	}
+	if err := entry.TimesOfDay.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWaitlist, err)
+	}
+	return nil
+}
+
+// GetEntriesByUser lists a client's waitlist entries, newest first
+func (s *WaitlistService) GetEntriesByUser(userID int) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// GetLawyerQueue lists the active entries on a lawyer's waitlist in the order they
+// are served
+func (s *WaitlistService) GetLawyerQueue(lawyerID int) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	err := s.DB.Where("lawyer_id = ? AND status = ?", lawyerID, models.WaitlistStatusActive).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// CancelEntry takes a client off a waitlist. A slot held for them is released and
+// offered to the next client.
+func (s *WaitlistService) CancelEntry(userID, entryID int) error {
+	var entry models.WaitlistEntry
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", entryID, userID).
+			First(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWaitlistEntryNotFound
+			}
+			return err
+		}
+		if entry.Status != models.WaitlistStatusActive {
+			return ErrWaitlistEntryNotActive
+		}
+
+		if err := tx.Model(&entry).Update("status", models.WaitlistStatusCancelled).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SlotHold{}).
+			Where("entry_id = ? AND status = ?", entry.ID, models.SlotHoldStatusHeld).
+			Update("status", models.SlotHoldStatusReleased).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.offerLogged(entry.LawyerID)
+	return nil
+}
+
+// GetHoldsByUser lists the slots currently held for a client, soonest first
+func (s *WaitlistService) GetHoldsByUser(userID int) ([]models.SlotHold, error) {
+	var holds []models.SlotHold
+	err := s.DB.Where("user_id = ? AND status = ? AND expires_at > ?", userID, models.SlotHoldStatusHeld, time.Now()).
+		Order("start_time ASC").
+		Find(&holds).Error
+	return holds, err
+}
+
+// DeclineHold gives up a held slot. The client stays on the waitlist but is not
+// offered the same slot again; it goes to the next client.
+func (s *WaitlistService) DeclineHold(userID, holdID int) error {
+	var hold models.SlotHold
+	if err := s.DB.Where("id = ? AND user_id = ?", holdID, userID).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWaitlistHoldNotFound
+		}
+		return err
+	}
+
+	result := s.DB.Model(&models.SlotHold{}).
+		Where("id = ? AND status = ? AND expires_at > ?", hold.ID, models.SlotHoldStatusHeld, time.Now()).
+		Update("status", models.SlotHoldStatusDeclined)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWaitlistHoldNotActive
+	}
+
+	s.offerLogged(hold.LawyerID)
+	return nil
+}
+
+// lockHolds locks the live holds overlapping a lawyer's time range for the rest of
+// the transaction
+func (s *WaitlistService) lockHolds(lawyerID int, start, end time.Time) ([]models.SlotHold, error) {
+	var holds []models.SlotHold
+	err := s.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("lawyer_id = ? AND status = ? AND expires_at > ? AND start_time < ? AND end_time > ?",
+			lawyerID, models.SlotHoldStatusHeld, time.Now(), end, start).
+		Find(&holds).Error
+	return holds, err
+}
+
+// markBooked closes a client's waitlist entry for the lawyer of a new appointment.
+// The hold on the booked slot is used up and any other hold of theirs is released.
+func (s *WaitlistService) markBooked(appointment *models.Appointment) error {
+	if err := s.DB.Model(&models.SlotHold{}).
+		Where("user_id = ? AND lawyer_id = ? AND status = ? AND start_time = ?",
+			appointment.UserID, appointment.LawyerID, models.SlotHoldStatusHeld, appointment.StartTime).
+		Updates(map[string]interface{}{
+			"status":         models.SlotHoldStatusBooked,
+			"appointment_id": appointment.ID,
+		}).Error; err != nil {
+		return err
+	}
+	if err := s.DB.Model(&models.SlotHold{}).
+		Where("user_id = ? AND lawyer_id = ? AND status = ?", appointment.UserID, appointment.LawyerID, models.SlotHoldStatusHeld).
+		Update("status", models.SlotHoldStatusReleased).Error; err != nil {
+		return err
+	}
+	return s.DB.Model(&models.WaitlistEntry{}).
+		Where("user_id = ? AND lawyer_id = ? AND status = ?", appointment.UserID, appointment.LawyerID, models.WaitlistStatusActive).
+		Update("status", models.WaitlistStatusBooked).Error
+}
+
+// OfferOpenSlots holds open slots of a lawyer for waitlisted clients. Entries are
+// served oldest first; each entry without a live hold gets the earliest open slot
+// that falls within its date ranges and times of day, judged in the client's time
+// zone, and that it has not been offered before. The client is notified of the
+// hold.
+func (s *WaitlistService) OfferOpenSlots(lawyerID int) error {
+	now := time.Now()
+	if err := s.expireHolds(now, lawyerID); err != nil {
+		return err
+	}
+
+	var entries []models.WaitlistEntry
+	if err := s.DB.Where("lawyer_id = ? AND status = ?", lawyerID, models.WaitlistStatusActive).
+		Where("NOT EXISTS (SELECT 1 FROM slot_holds h WHERE h.entry_id = waitlist_entries.id AND h.status = ?)", models.SlotHoldStatusHeld).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var lawyer models.Lawyer
+	if err := s.DB.First(&lawyer, lawyerID).Error; err != nil {
+		return err
+	}
+	loc := lawyer.Location()
+	settings := lawyer.EffectiveBookingSettings()
+	from := models.NewDate(now.In(loc))
+	to := models.NewDate(now.In(loc).AddDate(0, 0, settings.MaxAdvanceDays))
+	days, err := (&AvailabilityService{DB: s.DB}).loadBookingRange(&lawyer, from, to, 0)
+	if err != nil {
+		return err
+	}
+
+	clients, err := s.clientLocations(entries)
+	if err != nil {
+		return err
+	}
+	offered, err := s.offeredSlots(entries)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := now.Add(s.holdDuration())
+	length := time.Duration(settings.SlotMinutes) * time.Minute
+	for i := range entries {
+		entry := &entries[i]
+		hold := s.holdFirstMatch(entry, days, clients[entry.UserID], offered[entry.ID], length, now, expiresAt)
+		if hold == nil {
+			continue
+		}
+		s.notifyOffer(hold, clients[entry.UserID])
+	}
+	return nil
+}
+
+// holdFirstMatch creates a hold on the earliest open slot matching an entry. A slot
+// taken by a concurrent offer is skipped. Each new hold is added to its day so later
+// entries see the slot as taken.
+func (s *WaitlistService) holdFirstMatch(entry *models.WaitlistEntry, days []*bookingDay, clientLoc *time.Location, offered map[int64]bool, length time.Duration, now, expiresAt time.Time) *models.SlotHold {
+	for _, day := range days {
+		for _, start := range day.slotStarts() {
+			end := start.Add(length)
+			if offered[start.Unix()] || !waitlistMatches(entry, start, end, clientLoc) {
+				continue
+			}
+			if day.check(start, end, now) != nil {
+				continue
+			}
+
+			hold := &models.SlotHold{
+				EntryID:   entry.ID,
+				UserID:    entry.UserID,
+				LawyerID:  entry.LawyerID,
+				StartTime: start,
+				EndTime:   end,
+				ExpiresAt: expiresAt,
+				Status:    models.SlotHoldStatusHeld,
+			}
+			err := s.DB.Create(hold).Error
+			if pgErrorCode(err) == pgUniqueViolation {
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Failed to hold slot for waitlist entry %d: %v\n", entry.ID, err)
+				return nil
+			}
+			day.holds = append(day.holds, *hold)
+			return hold
+		}
+	}
+	return nil
+}
+
+// waitlistMatches reports whether a slot falls within an entry's date ranges and,
+// if it lists any, one of its times of day, all in the client's time zone
+func waitlistMatches(entry *models.WaitlistEntry, start, end time.Time, loc *time.Location) bool {
+	localStart := start.In(loc)
+	date := models.NewDate(localStart)
+	inRange := false
+	for _, r := range entry.DateRanges {
+		if r.Contains(date) {
+			inRange = true
+			break
+		}
+	}
+	if !inRange {
+		return false
+	}
+	if len(entry.TimesOfDay) == 0 {
+		return true
+	}
+
+	startMinute := localStart.Hour()*60 + localStart.Minute()
+	endMinute := startMinute + int(end.Sub(start).Minutes())
+	for _, interval := range entry.TimesOfDay {
+		from, until, err := interval.Minutes()
+		if err != nil {
+			continue
+		}
+		if startMinute >= from && endMinute <= until {
+			return true
+		}
+	}
+	return false
+}
+
+// clientLocations loads the time zone of each entry's client
+func (s *WaitlistService) clientLocations(entries []models.WaitlistEntry) (map[int]*time.Location, error) {
+	userIDs := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		userIDs = append(userIDs, entry.UserID)
+	}
+	var users []models.User
+	if err := s.DB.Select("id", "timezone").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	locations := make(map[int]*time.Location, len(users))
+	for _, user := range users {
+		locations[user.ID] = user.Location()
+	}
+	for _, entry := range entries {
+		if locations[entry.UserID] == nil {
+			locations[entry.UserID] = models.LoadTimezone("")
+		}
+	}
+	return locations, nil
+}
+
+// offeredSlots returns the start times, as Unix seconds, each entry has been
+// offered before
+func (s *WaitlistService) offeredSlots(entries []models.WaitlistEntry) (map[int]map[int64]bool, error) {
+	entryIDs := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		entryIDs = append(entryIDs, entry.ID)
+	}
+	var holds []models.SlotHold
+	if err := s.DB.Select("entry_id", "start_time").Where("entry_id IN ?", entryIDs).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	offered := make(map[int]map[int64]bool, len(entries))
+	for _, hold := range holds {
+		if offered[hold.EntryID] == nil {
+			offered[hold.EntryID] = map[int64]bool{}
+		}
+		offered[hold.EntryID][hold.StartTime.Unix()] = true
+	}
+	return offered, nil
+}
+
+// notifyOffer tells a client that a slot is held for them and until when
+func (s *WaitlistService) notifyOffer(hold *models.SlotHold, loc *time.Location) {
+	slotDate, slotTime := appointmentDisplayTime(hold.StartTime, loc)
+	expiryDate, expiryTime := appointmentDisplayTime(hold.ExpiresAt, loc)
+	notification := &models.Notification{
+		UserID: hold.UserID,
+		Type:   NotificationTypeWaitlistOffer,
+		Content: fmt.Sprintf("キャンセル待ちの弁護士に空きが出ました。%s %sの枠を%s %sまで確保しています",
+			slotDate, slotTime, expiryDate, expiryTime),
+	}
+	if err := NewNotificationService().CreateNotification(notification); err != nil {
+		fmt.Printf("Failed to create waitlist notification for user %d: %v\n", hold.UserID, err)
+	}
+}
+
+func (s *WaitlistService) holdDuration() time.Duration {
+	minutes, err := (&PlatformSettingService{DB: s.DB}).GetWaitlistHoldMinutes()
+	if err != nil {
+		minutes = DefaultWaitlistHoldMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// expireHolds marks holds past their expiry as expired, for one lawyer or, with a
+// zero lawyerID, for all lawyers
+func (s *WaitlistService) expireHolds(now time.Time, lawyerID int) error {
+	query := s.DB.Model(&models.SlotHold{}).Where("status = ? AND expires_at <= ?", models.SlotHoldStatusHeld, now)
+	if lawyerID > 0 {
+		query = query.Where("lawyer_id = ?", lawyerID)
+	}
+	return query.Update("status", models.SlotHoldStatusExpired).Error
+}
+
+// offerLogged offers a lawyer's open slots to their waitlist, logging failures. A
+// failed offer is retried by the next ProcessWaitlists run.
+func (s *WaitlistService) offerLogged(lawyerID int) {
+	if err := s.OfferOpenSlots(lawyerID); err != nil {
+		fmt.Printf("Failed to offer slots to waitlist of lawyer %d: %v\n", lawyerID, err)
+	}
+}
+
+// ProcessWaitlists expires holds that ran out, closes entries whose date ranges have
+// all passed and offers open slots to every lawyer's remaining waitlist. Run it
+// periodically; it also picks up slots freed by anything that did not trigger an
+// offer directly.
+func (s *WaitlistService) ProcessWaitlists() error {
+	now := time.Now()
+	if err := s.expireHolds(now, 0); err != nil {
+		return err
+	}
+
+	// Dates are compared a day late so no client time zone still has them ahead
+	yesterday := models.NewDate(now.UTC().AddDate(0, 0, -1))
+	if err := s.DB.Model(&models.WaitlistEntry{}).
+		Where("status = ?", models.WaitlistStatusActive).
+		Where("NOT EXISTS (SELECT 1 FROM jsonb_array_elements(date_ranges) r WHERE (r->>'to')::date >= ?)", yesterday).
+		Update("status", models.WaitlistStatusExpired).Error; err != nil {
+		return err
+	}
+
+	var lawyerIDs []int
+	if err := s.DB.Model(&models.WaitlistEntry{}).
+		Where("status = ?", models.WaitlistStatusActive).
+		Distinct("lawyer_id").
+		Pluck("lawyer_id", &lawyerIDs).Error; err != nil {
+		return err
+	}
+	for _, lawyerID := range lawyerIDs {
+		s.offerLogged(lawyerID)
+	}
+	return nil
+}
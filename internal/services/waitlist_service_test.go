@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kotolino/lawyer/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestWaitlistMatches(t *testing.T) {
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	newYork := mustLoadLocation(t, "America/New_York")
+	date := func(day int) models.Date {
+		return models.NewDate(time.Date(2026, 5, day, 0, 0, 0, 0, time.UTC))
+	}
+	entry := &models.WaitlistEntry{
+		DateRanges: models.DateRanges{{From: date(11), To: date(12)}},
+		TimesOfDay: models.TimeIntervals{{Start: "10:00", End: "12:00"}},
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		loc   *time.Location
+		want  bool
+	}{
+		{name: "within range and time", start: time.Date(2026, 5, 11, 10, 0, 0, 0, tokyo), loc: tokyo, want: true},
+		{name: "ends at the end of the time", start: time.Date(2026, 5, 12, 11, 0, 0, 0, tokyo), loc: tokyo, want: true},
+		{name: "runs past the time", start: time.Date(2026, 5, 11, 11, 30, 0, 0, tokyo), loc: tokyo, want: false},
+		{name: "day after the range", start: time.Date(2026, 5, 13, 10, 0, 0, 0, tokyo), loc: tokyo, want: false},
+		// 23:00 on the 11th in Tokyo is 10:00 on the 11th in New York
+		{name: "judged in the client's time zone", start: time.Date(2026, 5, 11, 23, 0, 0, 0, tokyo), loc: newYork, want: true},
+		{name: "outside the time in the client's time zone", start: time.Date(2026, 5, 11, 10, 0, 0, 0, tokyo), loc: newYork, want: false},
+	}
+	for _, tt := range tests {
+		if got := waitlistMatches(entry, tt.start, tt.start.Add(time.Hour), tt.loc); got != tt.want {
+			t.Errorf("%s: waitlistMatches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	anyTime := &models.WaitlistEntry{DateRanges: entry.DateRanges}
+	if !waitlistMatches(anyTime, time.Date(2026, 5, 11, 20, 0, 0, 0, tokyo), time.Date(2026, 5, 11, 21, 0, 0, 0, tokyo), tokyo) {
+		t.Error("an entry without times of day should match any time within its dates")
+	}
+}
+
+// liveHolds returns the held slots of a lawyer, keyed by entry
+func liveHolds(t *testing.T, db *gorm.DB, lawyerID int) map[int]models.SlotHold {
+	t.Helper()
+
+	var holds []models.SlotHold
+	if err := db.Where("lawyer_id = ? AND status = ?", lawyerID, models.SlotHoldStatusHeld).Find(&holds).Error; err != nil {
+		t.Fatalf("loading holds: %v", err)
+	}
+	byEntry := make(map[int]models.SlotHold, len(holds))
+	for _, hold := range holds {
+		byEntry[hold.EntryID] = hold
+	}
+	return byEntry
+}
+
+func TestOfferOpenSlotsServesOldestFirst(t *testing.T) {
+	db := openTestDB(t)
+	useRepositoryDB(t, db)
+	lawyer := createTestLawyer(t, db)
+	settings := models.BookingSettings{SlotMinutes: 60, MaxAdvanceDays: 7}
+	if err := db.Model(lawyer).Updates(map[string]interface{}{
+		"availability":     allDayLawyer(settings).Availability,
+		"booking_settings": &settings,
+	}).Error; err != nil {
+		t.Fatalf("updating test lawyer: %v", err)
+	}
+
+	day := time.Now().In(lawyer.Location()).AddDate(0, 0, 2)
+	at := func(hour int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, lawyer.Location())
+	}
+	// Only the 10:00 and 11:00 slots match the entries
+	entries := make([]*models.WaitlistEntry, 3)
+	clients := make([]*models.User, 3)
+	for i := range entries {
+		clients[i] = createTestUser(t, db, models.RoleClient)
+		entries[i] = &models.WaitlistEntry{
+			UserID:     clients[i].ID,
+			LawyerID:   lawyer.ID,
+			DateRanges: models.DateRanges{{From: models.NewDate(day), To: models.NewDate(day)}},
+			TimesOfDay: models.TimeIntervals{{Start: "10:00", End: "12:00"}},
+			Status:     models.WaitlistStatusActive,
+			CreatedAt:  time.Now().Add(time.Duration(i-3) * time.Hour),
+		}
+		if err := db.Create(entries[i]).Error; err != nil {
+			t.Fatalf("creating waitlist entry: %v", err)
+		}
+	}
+	first, second, third := entries[0], entries[1], entries[2]
+
+	service := &WaitlistService{DB: db}
+	if err := service.OfferOpenSlots(lawyer.ID); err != nil {
+		t.Fatalf("OfferOpenSlots: %v", err)
+	}
+	holds := liveHolds(t, db, lawyer.ID)
+	if hold, ok := holds[first.ID]; !ok || !hold.StartTime.Equal(at(10)) {
+		t.Errorf("oldest entry holds %v, want the 10:00 slot", hold.StartTime)
+	}
+	if hold, ok := holds[second.ID]; !ok || !hold.StartTime.Equal(at(11)) {
+		t.Errorf("second entry holds %v, want the 11:00 slot", hold.StartTime)
+	}
+	if hold, ok := holds[third.ID]; ok {
+		t.Errorf("newest entry holds %v, want no slot left for it", hold.StartTime)
+	}
+
+	// Offering again leaves live holds as they are
+	if err := service.OfferOpenSlots(lawyer.ID); err != nil {
+		t.Fatalf("OfferOpenSlots: %v", err)
+	}
+	if got := len(liveHolds(t, db, lawyer.ID)); got != 2 {
+		t.Errorf("%d live holds after offering again, want 2", got)
+	}
+
+	// Only the client it is held for can book a held slot
+	appointments := &AppointmentService{DB: db}
+	book := func(client *models.User) (*models.Appointment, error) {
+		appointment := &models.Appointment{UserID: client.ID, LawyerID: lawyer.ID, StartTime: at(10), EndTime: at(11)}
+		err := appointments.CreateAppointment(appointment)
+		if err == nil {
+			t.Cleanup(func() {
+				db.Unscoped().Delete(&models.Appointment{}, appointment.ID)
+			})
+		}
+		return appointment, err
+	}
+	if _, err := book(clients[2]); !errors.Is(err, ErrSlotUnavailable) {
+		t.Fatalf("booking a slot held for another client: error = %v, want ErrSlotUnavailable", err)
+	}
+
+	// A declined slot goes to the next entry without one, not back to the same client
+	if err := service.DeclineHold(clients[0].ID, holds[first.ID].ID); err != nil {
+		t.Fatalf("DeclineHold: %v", err)
+	}
+	holds = liveHolds(t, db, lawyer.ID)
+	if hold, ok := holds[first.ID]; ok {
+		t.Errorf("declining client was offered %v again", hold.StartTime)
+	}
+	if hold, ok := holds[third.ID]; !ok || !hold.StartTime.Equal(at(10)) {
+		t.Errorf("newest entry holds %v after the decline, want the 10:00 slot", hold.StartTime)
+	}
+
+	appointment, err := book(clients[2])
+	if err != nil {
+		t.Fatalf("booking a slot held for the client: %v", err)
+	}
+	var hold models.SlotHold
+	if err := db.First(&hold, holds[third.ID].ID).Error; err != nil {
+		t.Fatalf("loading hold: %v", err)
+	}
+	if hold.Status != models.SlotHoldStatusBooked || hold.AppointmentID == nil || *hold.AppointmentID != appointment.ID {
+		t.Errorf("hold after booking: status %s, appointment %v, want booked for %d", hold.Status, hold.AppointmentID, appointment.ID)
+	}
+	var entry models.WaitlistEntry
+	if err := db.First(&entry, third.ID).Error; err != nil {
+		t.Fatalf("loading entry: %v", err)
+	}
+	if entry.Status != models.WaitlistStatusBooked {
+		t.Errorf("entry status after booking = %s, want booked", entry.Status)
+	}
+}